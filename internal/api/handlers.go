@@ -1,20 +1,34 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 
 	"github.com/atlanssia/fustgo/internal/database"
 	"github.com/atlanssia/fustgo/internal/jobmanager"
+	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/metrics"
 	"github.com/atlanssia/fustgo/internal/models"
 	"github.com/atlanssia/fustgo/internal/plugin"
 	"github.com/atlanssia/fustgo/internal/worker"
 )
 
+// maxExecutionLogs is the most log lines returned by a request
+const maxExecutionLogs = 10000
+
+// logPollInterval is how often followed execution logs are read
+const logPollInterval = time.Second
+
 // Handler holds dependencies for API handlers
 type Handler struct {
 	jobManager *jobmanager.Manager
+	executor   *jobmanager.Executor
 	workerPool *worker.Pool
 	registry   *plugin.Registry
 	store      database.MetadataStore
@@ -23,12 +37,14 @@ type Handler struct {
 // NewHandler creates a new API handler
 func NewHandler(
 	jobManager *jobmanager.Manager,
+	executor *jobmanager.Executor,
 	workerPool *worker.Pool,
 	registry *plugin.Registry,
 	store database.MetadataStore,
 ) *Handler {
 	return &Handler{
 		jobManager: jobManager,
+		executor:   executor,
 		workerPool: workerPool,
 		registry:   registry,
 		store:      store,
@@ -170,7 +186,7 @@ func (h *Handler) DeleteJob(c *gin.Context) {
 func (h *Handler) StartJob(c *gin.Context) {
 	jobID := c.Param("id")
 
-	if err := h.jobManager.StartJob(jobID); err != nil {
+	if err := h.executor.Submit(c.Request.Context(), jobID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -203,7 +219,9 @@ func (h *Handler) PauseJob(c *gin.Context) {
 func (h *Handler) ResumeJob(c *gin.Context) {
 	jobID := c.Param("id")
 
-	if err := h.jobManager.ResumeJob(jobID); err != nil {
+	// Paused jobs are resumed by running their pipeline again from the
+	// last saved checkpoint
+	if err := h.executor.Submit(c.Request.Context(), jobID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -211,6 +229,199 @@ func (h *Handler) ResumeJob(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "job resumed successfully"})
 }
 
+// Execution Handlers
+
+func (h *Handler) ListExecutions(c *gin.Context) {
+	jobID := c.Param("id")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	executions, err := h.jobManager.ListExecutions(jobID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"executions": executions,
+		"total":      len(executions),
+	})
+}
+
+func (h *Handler) GetExecution(c *gin.Context) {
+	jobID := c.Param("id")
+	executionID := c.Param("exec_id")
+
+	execution, err := h.jobManager.GetExecution(executionID)
+	if err != nil || execution.JobID != jobID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"execution": execution})
+}
+
+// GetExecutionLogs returns the captured log lines of an execution, in the
+// order they were logged. Query parameters:
+//
+//	level   minimum level of the lines, such as warn
+//	after   ID of the line after which to start
+//	limit   most lines returned, 1000 by default
+//	tail    return the last lines rather than the first
+//	follow  stream the lines as server-sent events until the execution ends
+func (h *Handler) GetExecutionLogs(c *gin.Context) {
+	jobID := c.Param("id")
+	executionID := c.Param("exec_id")
+
+	execution, err := h.jobManager.GetExecution(executionID)
+	if err != nil || execution.JobID != jobID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+		return
+	}
+
+	query, err := executionLogQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.ExecutionID = executionID
+
+	if c.Query("follow") == "true" {
+		h.followExecutionLogs(c, query)
+		return
+	}
+
+	logs, err := h.jobManager.GetExecutionLogs(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if logs == nil {
+		logs = []*models.ExecutionLog{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":   logs,
+		"total":  len(logs),
+		"status": execution.Status,
+	})
+}
+
+// executionLogQuery parses the query parameters of GetExecutionLogs
+func executionLogQuery(c *gin.Context) (*database.ExecutionLogQuery, error) {
+	query := &database.ExecutionLogQuery{Tail: c.Query("tail") == "true"}
+
+	if name := c.Query("level"); name != "" {
+		minimum, err := logger.ParseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		for level := minimum; level <= logger.LevelFatal; level++ {
+			query.Levels = append(query.Levels, level.String())
+		}
+	}
+
+	// Clients reconnecting to a stream resume after the last event received
+	after := c.DefaultQuery("after", c.GetHeader("Last-Event-ID"))
+	if after != "" {
+		id, err := strconv.ParseInt(after, 10, 64)
+		if err != nil || id < 0 {
+			return nil, fmt.Errorf("invalid after: %s", after)
+		}
+		query.AfterID = id
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "1000"))
+	if err != nil || limit < 1 || limit > maxExecutionLogs {
+		return nil, fmt.Errorf("invalid limit, must be between 1 and %d", maxExecutionLogs)
+	}
+	query.Limit = limit
+	return query, nil
+}
+
+// followExecutionLogs streams the log lines of an execution as they are
+// saved, as "log" events with the line ID as event ID. The stream ends
+// with an "end" event carrying the final status once the execution has
+// ended and all its lines were sent.
+func (h *Handler) followExecutionLogs(c *gin.Context, query *database.ExecutionLogQuery) {
+	// Streams outlive the write timeout of the server
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warn("Failed to clear write deadline of log stream: %v", err)
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Read the status first: once it shows the end, the lines read
+		// next are the last ones
+		execution, err := h.jobManager.GetExecution(query.ExecutionID)
+		if err != nil {
+			c.Render(-1, sse.Event{Event: "error", Data: gin.H{"error": err.Error()}})
+			return
+		}
+		running := execution.Status == models.ExecutionStatusRunning || execution.Status == models.ExecutionStatusPending
+
+		logs, err := h.jobManager.GetExecutionLogs(query)
+		if err != nil {
+			c.Render(-1, sse.Event{Event: "error", Data: gin.H{"error": err.Error()}})
+			return
+		}
+		for _, line := range logs {
+			c.Render(-1, sse.Event{Id: strconv.FormatInt(line.ID, 10), Event: "log", Data: line})
+			query.AfterID = line.ID
+		}
+		c.Writer.Flush()
+
+		// Only the first read takes the tail; later reads take what follows
+		query.Tail = false
+		if len(logs) == query.Limit {
+			continue
+		}
+		if !running {
+			c.Render(-1, sse.Event{Event: "end", Data: gin.H{"status": execution.Status}})
+			c.Writer.Flush()
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+func (h *Handler) GetCheckpoints(c *gin.Context) {
+	jobID := c.Param("id")
+
+	if _, err := h.jobManager.GetJob(jobID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+
+	stored, err := h.store.GetCheckpoints(jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	checkpoints := make(map[string]json.RawMessage, len(stored))
+	for stage, data := range stored {
+		checkpoints[stage] = json.RawMessage(data)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"checkpoints": checkpoints})
+}
+
 // Plugin Management Handlers
 
 func (h *Handler) ListPlugins(c *gin.Context) {
@@ -321,10 +532,5 @@ func (h *Handler) GetStats(c *gin.Context) {
 }
 
 func (h *Handler) GetMetrics(c *gin.Context) {
-	// TODO: Implement Prometheus metrics
-	c.JSON(http.StatusOK, gin.H{
-		"metrics": map[string]interface{}{
-			"placeholder": "metrics endpoint",
-		},
-	})
+	metrics.Handler().ServeHTTP(c.Writer, c.Request)
 }
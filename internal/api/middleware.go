@@ -1,12 +1,19 @@
 package api
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/tracing"
 )
 
 // LoggerMiddleware logs HTTP requests
@@ -51,11 +58,53 @@ func RequestIDMiddleware() gin.HandlerFunc {
 		// Set request ID in context and response header
 		c.Set("request_id", requestID)
 		c.Header("X-Request-ID", requestID)
+		c.Request = c.Request.WithContext(tracing.WithRequestID(c.Request.Context(), requestID))
 
 		c.Next()
 	}
 }
 
+// TracingMiddleware traces each request in a span carrying its request
+// ID. Requests with a W3C traceparent header, such as requests from other
+// FustGo processes, continue the trace of the caller. The trace ID is
+// returned in the X-Trace-ID header.
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("fustgo.request_id", c.GetString("request_id")),
+			),
+		)
+		defer span.End()
+
+		if span.SpanContext().IsSampled() {
+			c.Header("X-Trace-ID", span.SpanContext().TraceID().String())
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+	}
+}
+
 // AuthMiddleware provides basic authentication (placeholder)
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
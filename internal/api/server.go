@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -61,6 +62,7 @@ func NewServer(config *ServerConfig, handler *Handler) *Server {
 	router.Use(gin.Recovery())
 	router.Use(LoggerMiddleware())
 	router.Use(RequestIDMiddleware())
+	router.Use(TracingMiddleware())
 
 	// CORS configuration
 	if config.EnableCORS {
@@ -109,6 +111,10 @@ func (s *Server) setupRoutes() {
 			jobs.POST("/:id/stop", s.handler.StopJob)
 			jobs.POST("/:id/pause", s.handler.PauseJob)
 			jobs.POST("/:id/resume", s.handler.ResumeJob)
+			jobs.GET("/:id/executions", s.handler.ListExecutions)
+			jobs.GET("/:id/executions/:exec_id", s.handler.GetExecution)
+			jobs.GET("/:id/executions/:exec_id/logs", s.handler.GetExecutionLogs)
+			jobs.GET("/:id/checkpoints", s.handler.GetCheckpoints)
 		}
 
 		// Plugins endpoints
@@ -138,13 +144,18 @@ func (s *Server) setupRoutes() {
 func (s *Server) Start() error {
 	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
 
+	// Requests are cancelled on shutdown, which ends streams such as
+	// followed execution logs
+	ctx, cancel := context.WithCancel(context.Background())
 	s.server = &http.Server{
 		Addr:           addr,
 		Handler:        s.router,
 		ReadTimeout:    s.config.ReadTimeout,
 		WriteTimeout:   s.config.WriteTimeout,
 		MaxHeaderBytes: s.config.MaxHeaderBytes,
+		BaseContext:    func(net.Listener) context.Context { return ctx },
 	}
+	s.server.RegisterOnShutdown(cancel)
 
 	logger.Info("Starting API server on %s", addr)
 
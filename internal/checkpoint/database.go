@@ -0,0 +1,94 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/atlanssia/fustgo/internal/database"
+	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// DatabaseStorage implements checkpoint storage in the metadata store.
+// Checkpoints are saved with the execution that produced them and loaded
+// from the most recent execution of the job that saved each stage.
+type DatabaseStorage struct {
+	store       database.MetadataStore
+	executionID string
+}
+
+// NewDatabaseStorage creates a checkpoint storage that saves to the given
+// execution
+func NewDatabaseStorage(store database.MetadataStore, executionID string) (*DatabaseStorage, error) {
+	if store == nil {
+		return nil, fmt.Errorf("database storage requires a metadata store")
+	}
+	if executionID == "" {
+		return nil, fmt.Errorf("database storage requires an execution ID")
+	}
+
+	return &DatabaseStorage{
+		store:       store,
+		executionID: executionID,
+	}, nil
+}
+
+// Save saves a checkpoint to the current execution. The job ID is implied
+// by the execution.
+func (ds *DatabaseStorage) Save(jobID string, stage string, checkpoint *types.Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	if err := ds.store.SaveCheckpoint(ds.executionID, stage, string(data)); err != nil {
+		return fmt.Errorf("failed to save checkpoint to execution %s: %w", ds.executionID, err)
+	}
+
+	return nil
+}
+
+// Load loads the latest checkpoint of a stage
+func (ds *DatabaseStorage) Load(jobID string, stage string) (*types.Checkpoint, error) {
+	checkpoints, err := ds.List(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoints[stage], nil
+}
+
+// List lists the latest checkpoint of each stage of a job
+func (ds *DatabaseStorage) List(jobID string) (map[string]*types.Checkpoint, error) {
+	stored, err := ds.store.GetCheckpoints(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoints: %w", err)
+	}
+
+	checkpoints := make(map[string]*types.Checkpoint, len(stored))
+	for stage, data := range stored {
+		var checkpoint types.Checkpoint
+		if err := json.Unmarshal([]byte(data), &checkpoint); err != nil {
+			logger.Warn("Failed to load checkpoint %s: %v", stage, err)
+			continue
+		}
+		checkpoints[stage] = &checkpoint
+	}
+
+	return checkpoints, nil
+}
+
+// Delete deletes the checkpoints of a stage from all executions of a job
+func (ds *DatabaseStorage) Delete(jobID string, stage string) error {
+	if err := ds.store.DeleteCheckpoint(jobID, stage); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Clear clears the checkpoints of all executions of a job
+func (ds *DatabaseStorage) Clear(jobID string) error {
+	if err := ds.store.ClearCheckpoints(jobID); err != nil {
+		return fmt.Errorf("failed to clear checkpoints: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package checkpoint
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/database"
+	"github.com/atlanssia/fustgo/internal/models"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func newTestStore(t *testing.T, path string) *database.SQLiteStore {
+	store, err := database.NewSQLiteStore(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func createExecution(t *testing.T, store database.MetadataStore, jobID, executionID string, start time.Time) {
+	require.NoError(t, store.SaveExecution(&models.Execution{
+		ExecutionID: executionID,
+		JobID:       jobID,
+		Status:      models.ExecutionStatusRunning,
+		StartTime:   start,
+	}))
+}
+
+func TestNewManagerDatabaseStorage(t *testing.T) {
+	store := newTestStore(t, filepath.Join(t.TempDir(), "meta.db"))
+
+	_, err := NewManager("job-1", &Config{Enabled: true, StorageType: "database", ExecutionID: "exec-1"})
+	assert.Error(t, err, "store is required")
+
+	_, err = NewManager("job-1", &Config{Enabled: true, StorageType: "database", Store: store})
+	assert.Error(t, err, "execution ID is required")
+
+	manager, err := NewManager("job-1", &Config{Enabled: true, StorageType: "database", Store: store, ExecutionID: "exec-1"})
+	require.NoError(t, err)
+	assert.IsType(t, &DatabaseStorage{}, manager.storage)
+}
+
+func TestDatabaseStorageSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta.db")
+	store := newTestStore(t, path)
+	start := time.Now()
+	createExecution(t, store, "job-1", "exec-1", start)
+
+	manager, err := NewManager("job-1", &Config{Enabled: true, StorageType: "database", Store: store, ExecutionID: "exec-1"})
+	require.NoError(t, err)
+	require.NoError(t, manager.SaveCheckpoint("input", &types.Checkpoint{Position: 10}))
+	require.NoError(t, manager.SaveCheckpoint("output", &types.Checkpoint{Position: 8, Metadata: map[string]string{"file": "a.csv"}}))
+	require.NoError(t, manager.SaveCheckpoint("output", &types.Checkpoint{Position: 20}))
+
+	// Recording the execution outcome keeps the checkpoint data
+	end := time.Now()
+	require.NoError(t, store.UpdateExecution(&models.Execution{
+		ExecutionID: "exec-1",
+		Status:      models.ExecutionStatusFailed,
+		EndTime:     &end,
+	}))
+	exec, err := store.GetExecution("exec-1")
+	require.NoError(t, err)
+	assert.Contains(t, exec.CheckpointData, `"output"`)
+
+	require.NoError(t, store.Close())
+
+	// A new execution after a restart sees the checkpoints of the last one
+	store = newTestStore(t, path)
+	createExecution(t, store, "job-1", "exec-2", start.Add(time.Minute))
+
+	manager, err = NewManager("job-1", &Config{Enabled: true, StorageType: "database", Store: store, ExecutionID: "exec-2"})
+	require.NoError(t, err)
+
+	checkpoint, err := manager.LoadCheckpoint("output")
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, float64(20), checkpoint.Position)
+	assert.Nil(t, checkpoint.Metadata)
+
+	// New saves go to the new execution and take precedence
+	require.NoError(t, manager.SaveCheckpoint("output", &types.Checkpoint{Position: 30}))
+
+	storage, err := NewDatabaseStorage(store, "exec-2")
+	require.NoError(t, err)
+	checkpoints, err := storage.List("job-1")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	assert.Equal(t, float64(10), checkpoints["input"].Position)
+	assert.Equal(t, float64(30), checkpoints["output"].Position)
+
+	exec, err = store.GetExecution("exec-1")
+	require.NoError(t, err)
+	assert.Contains(t, exec.CheckpointData, "20")
+}
+
+func TestDatabaseStorageSaveUnknownExecution(t *testing.T) {
+	store := newTestStore(t, filepath.Join(t.TempDir(), "meta.db"))
+
+	storage, err := NewDatabaseStorage(store, "missing")
+	require.NoError(t, err)
+	assert.Error(t, storage.Save("job-1", "output", &types.Checkpoint{Position: 1}))
+}
+
+func TestDatabaseStorageDeleteAndClear(t *testing.T) {
+	store := newTestStore(t, filepath.Join(t.TempDir(), "meta.db"))
+	start := time.Now()
+	createExecution(t, store, "job-1", "exec-1", start)
+	createExecution(t, store, "job-1", "exec-2", start.Add(time.Second))
+	createExecution(t, store, "job-2", "exec-3", start)
+
+	first, err := NewDatabaseStorage(store, "exec-1")
+	require.NoError(t, err)
+	second, err := NewDatabaseStorage(store, "exec-2")
+	require.NoError(t, err)
+	other, err := NewDatabaseStorage(store, "exec-3")
+	require.NoError(t, err)
+
+	require.NoError(t, first.Save("job-1", "input", &types.Checkpoint{Position: 1}))
+	require.NoError(t, first.Save("job-1", "output", &types.Checkpoint{Position: 1}))
+	require.NoError(t, second.Save("job-1", "output", &types.Checkpoint{Position: 2}))
+	require.NoError(t, other.Save("job-2", "output", &types.Checkpoint{Position: 3}))
+
+	// Deleting a stage does not bring back an older checkpoint
+	require.NoError(t, second.Delete("job-1", "output"))
+	checkpoint, err := second.Load("job-1", "output")
+	require.NoError(t, err)
+	assert.Nil(t, checkpoint)
+	checkpoint, err = second.Load("job-1", "input")
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+
+	require.NoError(t, second.Clear("job-1"))
+	checkpoints, err := second.List("job-1")
+	require.NoError(t, err)
+	assert.Empty(t, checkpoints)
+
+	// Other jobs are untouched
+	checkpoints, err = other.List("job-2")
+	require.NoError(t, err)
+	assert.Len(t, checkpoints, 1)
+}
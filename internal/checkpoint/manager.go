@@ -8,18 +8,32 @@ import (
 	"sync"
 	"time"
 
+	"github.com/atlanssia/fustgo/internal/database"
 	"github.com/atlanssia/fustgo/internal/logger"
 	"github.com/atlanssia/fustgo/pkg/types"
 )
 
-// Manager handles checkpoint persistence for fault recovery
+// Manager handles checkpoint persistence for fault recovery.
+//
+// With a positive interval, saved checkpoints are kept in memory and a
+// background flusher persists the latest one of each stage on every tick,
+// so that frequent saves cost a single write per interval. Flush persists
+// them at once and Close persists them on shutdown. Without an interval
+// every save is written through.
 type Manager struct {
 	mu          sync.RWMutex
 	jobID       string
 	checkpoints map[string]*types.Checkpoint // stage name -> checkpoint
+	pending     map[string]*types.Checkpoint // stage name -> checkpoint not yet persisted
 	storage     Storage
 	interval    time.Duration
 	enabled     bool
+	closed      bool
+
+	flushMu   sync.Mutex // Serializes writes of pending checkpoints to storage
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 // Storage defines the interface for checkpoint persistence
@@ -36,7 +50,12 @@ type Config struct {
 	Enabled     bool
 	StorageType string        // "file" or "database"
 	StoragePath string        // For file storage
-	Interval    time.Duration // Auto-save interval
+	Interval    time.Duration // Auto-save interval, 0 writes every save through
+
+	// For database storage: the metadata store and the execution that
+	// checkpoints are saved to
+	Store       database.MetadataStore
+	ExecutionID string
 }
 
 // DefaultConfig returns default checkpoint configuration
@@ -64,6 +83,11 @@ func NewManager(jobID string, config *Config) (*Manager, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create file storage: %w", err)
 		}
+	case "database":
+		storage, err = NewDatabaseStorage(config.Store, config.ExecutionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create database storage: %w", err)
+		}
 	default:
 		return nil, fmt.Errorf("unsupported storage type: %s", config.StorageType)
 	}
@@ -71,6 +95,7 @@ func NewManager(jobID string, config *Config) (*Manager, error) {
 	manager := &Manager{
 		jobID:       jobID,
 		checkpoints: make(map[string]*types.Checkpoint),
+		pending:     make(map[string]*types.Checkpoint),
 		storage:     storage,
 		interval:    config.Interval,
 		enabled:     config.Enabled,
@@ -87,9 +112,34 @@ func NewManager(jobID string, config *Config) (*Manager, error) {
 		}
 	}
 
+	if config.Enabled && config.Interval > 0 {
+		manager.stop = make(chan struct{})
+		manager.done = make(chan struct{})
+		go manager.runFlusher()
+	}
+
 	return manager, nil
 }
 
+// runFlusher persists pending checkpoints on every interval until Close
+func (m *Manager) runFlusher() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Flush(); err != nil {
+				logger.Warn("Failed to flush checkpoints for job %s: %v", m.jobID, err)
+			}
+		case <-m.stop:
+			return
+		}
+	}
+}
+
 // SaveCheckpoint saves a checkpoint for a specific stage
 func (m *Manager) SaveCheckpoint(stage string, checkpoint *types.Checkpoint) error {
 	if !m.enabled {
@@ -102,6 +152,12 @@ func (m *Manager) SaveCheckpoint(stage string, checkpoint *types.Checkpoint) err
 	checkpoint.Timestamp = time.Now()
 	m.checkpoints[stage] = checkpoint
 
+	// Leave it to the flusher, replacing any checkpoint still pending
+	if m.interval > 0 && !m.closed {
+		m.pending[stage] = checkpoint
+		return nil
+	}
+
 	if err := m.storage.Save(m.jobID, stage, checkpoint); err != nil {
 		return fmt.Errorf("failed to save checkpoint: %w", err)
 	}
@@ -110,6 +166,57 @@ func (m *Manager) SaveCheckpoint(stage string, checkpoint *types.Checkpoint) err
 	return nil
 }
 
+// Flush persists the pending checkpoints. Checkpoints that fail to persist
+// stay pending unless a newer one was saved meanwhile.
+func (m *Manager) Flush() error {
+	if !m.enabled {
+		return nil
+	}
+
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[string]*types.Checkpoint)
+	m.mu.Unlock()
+
+	var firstErr error
+	for stage, checkpoint := range pending {
+		if err := m.storage.Save(m.jobID, stage, checkpoint); err != nil {
+			m.mu.Lock()
+			if _, newer := m.pending[stage]; !newer {
+				m.pending[stage] = checkpoint
+			}
+			m.mu.Unlock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to save checkpoint for stage %s: %w", stage, err)
+			}
+			continue
+		}
+		logger.Debug("Saved checkpoint for stage %s", stage)
+	}
+
+	return firstErr
+}
+
+// Close stops the background flusher and persists the pending checkpoints.
+// Checkpoints saved after Close are written through.
+func (m *Manager) Close() error {
+	m.closeOnce.Do(func() {
+		m.mu.Lock()
+		m.closed = true
+		m.mu.Unlock()
+
+		if m.stop != nil {
+			close(m.stop)
+			<-m.done
+		}
+	})
+
+	return m.Flush()
+}
+
 // LoadCheckpoint loads a checkpoint for a specific stage
 func (m *Manager) LoadCheckpoint(stage string) (*types.Checkpoint, error) {
 	if !m.enabled {
@@ -146,10 +253,13 @@ func (m *Manager) DeleteCheckpoint(stage string) error {
 		return nil
 	}
 
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	delete(m.checkpoints, stage)
+	delete(m.pending, stage)
 
 	if err := m.storage.Delete(m.jobID, stage); err != nil {
 		return fmt.Errorf("failed to delete checkpoint: %w", err)
@@ -165,10 +275,13 @@ func (m *Manager) Clear() error {
 		return nil
 	}
 
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.checkpoints = make(map[string]*types.Checkpoint)
+	m.pending = make(map[string]*types.Checkpoint)
 
 	if err := m.storage.Clear(m.jobID); err != nil {
 		return fmt.Errorf("failed to clear checkpoints: %w", err)
@@ -212,9 +325,33 @@ func (fs *FileStorage) Save(jobID string, stage string, checkpoint *types.Checkp
 		return fmt.Errorf("failed to marshal checkpoint: %w", err)
 	}
 
-	if err := os.WriteFile(filename, data, 0644); err != nil {
+	// Write to a temporary file and rename it over the checkpoint, so that
+	// a crash never leaves a partly written checkpoint behind
+	tmp, err := os.CreateTemp(jobDir, fmt.Sprintf("%s.json.tmp-*", stage))
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync checkpoint file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
 		return fmt.Errorf("failed to write checkpoint file: %w", err)
 	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to replace checkpoint file: %w", err)
+	}
 
 	return nil
 }
@@ -3,6 +3,7 @@ package checkpoint
 import (
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -389,3 +390,132 @@ func TestManagerLoadExistingCheckpoints(t *testing.T) {
 	assert.NotNil(t, all["input"])
 	assert.NotNil(t, all["output"])
 }
+
+// countingStorage counts the saves reaching the wrapped storage
+type countingStorage struct {
+	Storage
+	mu    sync.Mutex
+	saves int
+}
+
+func (cs *countingStorage) Save(jobID string, stage string, checkpoint *types.Checkpoint) error {
+	cs.mu.Lock()
+	cs.saves++
+	cs.mu.Unlock()
+	return cs.Storage.Save(jobID, stage, checkpoint)
+}
+
+func (cs *countingStorage) count() int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.saves
+}
+
+func TestSaveCheckpointCoalesced(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	manager, err := NewManager("test-job-7", &Config{
+		Enabled:     true,
+		StorageType: "file",
+		StoragePath: tmpDir,
+		Interval:    time.Hour,
+	})
+	require.NoError(t, err)
+	storage := &countingStorage{Storage: manager.storage}
+	manager.storage = storage
+
+	for i := 1; i <= 100; i++ {
+		require.NoError(t, manager.SaveCheckpoint("input", &types.Checkpoint{Position: i}))
+		require.NoError(t, manager.SaveCheckpoint("output", &types.Checkpoint{Position: i}))
+	}
+	assert.Equal(t, 0, storage.count())
+
+	// The latest checkpoint is served from memory before it is persisted
+	loaded, err := manager.LoadCheckpoint("output")
+	require.NoError(t, err)
+	assert.Equal(t, 100, loaded.Position)
+
+	require.NoError(t, manager.Flush())
+	assert.Equal(t, 2, storage.count())
+
+	// Nothing is pending after a flush
+	require.NoError(t, manager.Flush())
+	assert.Equal(t, 2, storage.count())
+
+	persisted, err := storage.Load("test-job-7", "output")
+	require.NoError(t, err)
+	assert.Equal(t, float64(100), persisted.Position)
+
+	// Close persists what was saved since the last flush
+	require.NoError(t, manager.SaveCheckpoint("output", &types.Checkpoint{Position: 101}))
+	require.NoError(t, manager.Close())
+	assert.Equal(t, 3, storage.count())
+
+	persisted, err = storage.Load("test-job-7", "output")
+	require.NoError(t, err)
+	assert.Equal(t, float64(101), persisted.Position)
+
+	// Saves after Close are written through
+	require.NoError(t, manager.SaveCheckpoint("output", &types.Checkpoint{Position: 102}))
+	assert.Equal(t, 4, storage.count())
+}
+
+func TestSaveCheckpointInterval(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	manager, err := NewManager("test-job-8", &Config{
+		Enabled:     true,
+		StorageType: "file",
+		StoragePath: tmpDir,
+		Interval:    10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer manager.Close()
+
+	require.NoError(t, manager.SaveCheckpoint("input", &types.Checkpoint{Position: "42"}))
+
+	filename := filepath.Join(tmpDir, "test-job-8", "input.json")
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filename)
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestDeleteDropsPendingCheckpoint(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	manager, err := NewManager("test-job-9", &Config{
+		Enabled:     true,
+		StorageType: "file",
+		StoragePath: tmpDir,
+		Interval:    time.Hour,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, manager.SaveCheckpoint("input", &types.Checkpoint{Position: 1}))
+	require.NoError(t, manager.DeleteCheckpoint("input"))
+	require.NoError(t, manager.Close())
+
+	_, err = os.Stat(filepath.Join(tmpDir, "test-job-9", "input.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileStorageSaveLeavesNoTempFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	storage, err := NewFileStorage(tmpDir)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, storage.Save("job-3", "stage-3", &types.Checkpoint{Position: i}))
+	}
+
+	entries, err := os.ReadDir(filepath.Join(tmpDir, "job-3"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "stage-3.json", entries[0].Name())
+
+	loaded, err := storage.Load("job-3", "stage-3")
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), loaded.Position)
+}
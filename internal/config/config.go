@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,6 +17,7 @@ type Config struct {
 	Cache        CacheConfig        `yaml:"cache"`
 	Queue        QueueConfig        `yaml:"queue"`
 	Worker       WorkerConfig       `yaml:"worker"`
+	Checkpoint   CheckpointConfig   `yaml:"checkpoint"`
 	Observability ObservabilityConfig `yaml:"observability"`
 	Deployment   DeploymentConfig   `yaml:"deployment"`
 	Plugins      PluginsConfig      `yaml:"plugins"`
@@ -59,6 +63,13 @@ type WorkerConfig struct {
 	WorkerCount        int    `yaml:"worker_count"`
 }
 
+// CheckpointConfig contains checkpoint storage configuration
+type CheckpointConfig struct {
+	Storage  string `yaml:"storage"`  // database, file
+	Path     string `yaml:"path"`     // For file storage
+	Interval string `yaml:"interval"`
+}
+
 // ObservabilityConfig contains observability configuration
 type ObservabilityConfig struct {
 	Logs    LogsConfig    `yaml:"logs"`
@@ -68,8 +79,18 @@ type ObservabilityConfig struct {
 
 // LogsConfig contains logging configuration
 type LogsConfig struct {
+	Level        string                 `yaml:"level"`  // debug, info, warn, error
+	Format       string                 `yaml:"format"` // text, json
 	Local        LocalLogsConfig        `yaml:"local"`
 	OpenObserve  OpenObserveLogsConfig  `yaml:"openobserve"`
+	Execution    ExecutionLogsConfig    `yaml:"execution"`
+}
+
+// ExecutionLogsConfig contains the configuration of the log lines captured
+// with each execution
+type ExecutionLogsConfig struct {
+	Enabled  bool `yaml:"enabled"`
+	MaxLines int  `yaml:"max_lines"` // Per execution, older lines are removed
 }
 
 // LocalLogsConfig contains local file logging configuration
@@ -82,6 +103,24 @@ type LocalLogsConfig struct {
 	Compress   bool   `yaml:"compress"`
 }
 
+// MaxSizeMB returns the size at which log files are rotated, in megabytes.
+// MaxSize is a number of megabytes or gigabytes, such as "100MB" or "1GB".
+func (c LocalLogsConfig) MaxSizeMB() (int, error) {
+	size := strings.ToUpper(strings.TrimSpace(c.MaxSize))
+	unit := 1
+	switch {
+	case strings.HasSuffix(size, "GB"):
+		size, unit = strings.TrimSuffix(size, "GB"), 1024
+	case strings.HasSuffix(size, "MB"):
+		size = strings.TrimSuffix(size, "MB")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(size))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid log file max size: %s", c.MaxSize)
+	}
+	return n * unit, nil
+}
+
 // OpenObserveLogsConfig contains OpenObserve logging configuration
 type OpenObserveLogsConfig struct {
 	Enabled       bool   `yaml:"enabled"`
@@ -115,11 +154,12 @@ type TracesConfig struct {
 
 // OpenObserveTracesConfig contains OpenObserve tracing configuration
 type OpenObserveTracesConfig struct {
-	Enabled      bool   `yaml:"enabled"`
-	Endpoint     string `yaml:"endpoint"`
-	Organization string `yaml:"organization"`
-	Username     string `yaml:"username"`
-	Password     string `yaml:"password"`
+	Enabled      bool    `yaml:"enabled"`
+	Endpoint     string  `yaml:"endpoint"`
+	Organization string  `yaml:"organization"`
+	Username     string  `yaml:"username"`
+	Password     string  `yaml:"password"`
+	SampleRatio  float64 `yaml:"sample_ratio"` // Fraction of traces recorded, 1 for all
 }
 
 // DeploymentConfig contains deployment mode configuration
@@ -202,10 +242,35 @@ func (c *Config) applyDefaults() {
 		c.Worker.WorkerCount = 4
 	}
 
+	if c.Checkpoint.Storage == "" {
+		c.Checkpoint.Storage = "database"
+	}
+	if c.Checkpoint.Path == "" {
+		c.Checkpoint.Path = "./data/checkpoints"
+	}
+	if c.Checkpoint.Interval == "" {
+		c.Checkpoint.Interval = "30s"
+	}
+
 	if c.Deployment.Mode == "" {
 		c.Deployment.Mode = "standalone"
 	}
 
+	if c.Observability.Logs.Level == "" {
+		c.Observability.Logs.Level = "info"
+	}
+	if c.Observability.Logs.Format == "" {
+		c.Observability.Logs.Format = "text"
+	}
+	if c.Observability.Traces.OpenObserve.Organization == "" {
+		c.Observability.Traces.OpenObserve.Organization = "default"
+	}
+	if c.Observability.Traces.OpenObserve.SampleRatio == 0 {
+		c.Observability.Traces.OpenObserve.SampleRatio = 1
+	}
+	if c.Observability.Logs.Execution.MaxLines == 0 {
+		c.Observability.Logs.Execution.MaxLines = 10000
+	}
 	if c.Observability.Logs.Local.Path == "" {
 		c.Observability.Logs.Local.Path = "/var/log/fustgo"
 	}
@@ -227,9 +292,64 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("unsupported database type: %s", c.Database.Type)
 	}
 
+	if c.Checkpoint.Storage != "database" && c.Checkpoint.Storage != "file" {
+		return fmt.Errorf("unsupported checkpoint storage: %s", c.Checkpoint.Storage)
+	}
+
 	if c.Deployment.Mode != "standalone" && c.Deployment.Mode != "lightweight" && c.Deployment.Mode != "distributed" {
 		return fmt.Errorf("invalid deployment mode: %s", c.Deployment.Mode)
 	}
 
+	logs := c.Observability.Logs
+	if logs.Format != "text" && logs.Format != "json" {
+		return fmt.Errorf("invalid log format: %s", logs.Format)
+	}
+	switch strings.ToLower(logs.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("invalid log level: %s", logs.Level)
+	}
+	if logs.Local.Enabled {
+		if _, err := logs.Local.MaxSizeMB(); err != nil {
+			return err
+		}
+	}
+	if logs.Execution.Enabled && logs.Execution.MaxLines < 0 {
+		return fmt.Errorf("invalid execution log max lines: %d", logs.Execution.MaxLines)
+	}
+	if shipping := logs.OpenObserve; shipping.Enabled {
+		if shipping.Endpoint == "" {
+			return fmt.Errorf("log shipping endpoint is required")
+		}
+		if shipping.BatchSize < 0 {
+			return fmt.Errorf("invalid log shipping batch size: %d", shipping.BatchSize)
+		}
+		if shipping.FlushInterval != "" {
+			if d, err := time.ParseDuration(shipping.FlushInterval); err != nil || d <= 0 {
+				return fmt.Errorf("invalid log shipping flush interval: %s", shipping.FlushInterval)
+			}
+		}
+	}
+
+	if metrics := c.Observability.Metrics.OpenObserve; metrics.Enabled {
+		if metrics.Endpoint == "" {
+			return fmt.Errorf("metrics push endpoint is required")
+		}
+		if metrics.PushInterval != "" {
+			if d, err := time.ParseDuration(metrics.PushInterval); err != nil || d <= 0 {
+				return fmt.Errorf("invalid metrics push interval: %s", metrics.PushInterval)
+			}
+		}
+	}
+
+	if traces := c.Observability.Traces.OpenObserve; traces.Enabled {
+		if traces.Endpoint == "" {
+			return fmt.Errorf("traces endpoint is required")
+		}
+		if traces.SampleRatio < 0 || traces.SampleRatio > 1 {
+			return fmt.Errorf("invalid trace sample ratio: %v", traces.SampleRatio)
+		}
+	}
+
 	return nil
 }
@@ -22,12 +22,21 @@ type PipelineConfig struct {
 type InputConfig struct {
 	Type   string                 `yaml:"type"`
 	Config map[string]interface{} `yaml:"config,omitempty"`
+
+	// Parallelism is the number of shards a splittable input is divided
+	// into and read concurrently
+	Parallelism int `yaml:"parallelism,omitempty"`
 }
 
 // ProcessorConfig represents processor configuration
 type ProcessorConfig struct {
 	Type   string                 `yaml:"type"`
 	Config map[string]interface{} `yaml:"config,omitempty"`
+
+	// Parallelism is the number of workers of the stage, each with its own
+	// plugin instance. Batches keep their order unless Ordered is false.
+	Parallelism int   `yaml:"parallelism,omitempty"`
+	Ordered     *bool `yaml:"ordered,omitempty"`
 }
 
 // OutputConfig represents output configuration
@@ -38,8 +47,31 @@ type OutputConfig struct {
 
 // SettingsConfig represents pipeline settings
 type SettingsConfig struct {
-	BatchSize int    `yaml:"batch_size,omitempty"`
-	Mode      string `yaml:"mode,omitempty"` // "sync" or "async"
+	BatchSize   int                `yaml:"batch_size,omitempty"`
+	Mode        string             `yaml:"mode,omitempty"` // "sync" or "async"
+	ErrorPolicy *ErrorPolicyConfig `yaml:"error_policy,omitempty"`
+
+	// SchemaPolicy handles batches whose schema differs from the schema
+	// established by the first batch written
+	SchemaPolicy *SchemaPolicyConfig `yaml:"schema_policy,omitempty"`
+
+	// QueueSize is the number of batches queued between stages, and
+	// QueueMaxBytes an optional limit on the memory of the queued records
+	QueueSize     int   `yaml:"queue_size,omitempty"`
+	QueueMaxBytes int64 `yaml:"queue_max_bytes,omitempty"`
+}
+
+// ErrorPolicyConfig represents the handling of records that fail in a stage
+type ErrorPolicyConfig struct {
+	Mode         string        `yaml:"mode,omitempty"` // "fail", "skip" or "dead_letter"
+	MaxErrorRate float64       `yaml:"max_error_rate,omitempty"`
+	MinRecords   int64         `yaml:"min_records,omitempty"`
+	DeadLetter   *OutputConfig `yaml:"dead_letter,omitempty"` // Any output plugin
+}
+
+// SchemaPolicyConfig represents the handling of schema drift between batches
+type SchemaPolicyConfig struct {
+	Mode string `yaml:"mode,omitempty"` // "fail", "add_columns", "drop_unknown" or "widen"
 }
 
 // Converter converts YAML configuration to pipeline
@@ -81,46 +113,45 @@ func (c *Converter) ValidateConfig(config *PipelineConfig) error {
 		return fmt.Errorf("output type is required")
 	}
 
-	return nil
-}
-
-// BuildPipeline builds a pipeline from configuration
-func (c *Converter) BuildPipeline(config *PipelineConfig) (*pipeline.Pipeline, error) {
-	// Get input plugin
-	input, err := c.registry.GetInput(config.Input.Type)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get input plugin '%s': %w", config.Input.Type, err)
+	if config.Input.Parallelism < 0 {
+		return fmt.Errorf("input parallelism must not be negative")
 	}
 
-	// Initialize input plugin
-	if err := input.Initialize(config.Input.Config); err != nil {
-		return nil, fmt.Errorf("failed to initialize input plugin: %w", err)
+	if config.Settings.QueueSize < 0 || config.Settings.QueueMaxBytes < 0 {
+		return fmt.Errorf("queue_size and queue_max_bytes must not be negative")
 	}
 
-	// Get processor plugins
-	processors := make([]types.ProcessorPlugin, 0, len(config.Processors))
+	// Validate processors
 	for i, procConfig := range config.Processors {
-		processor, err := c.registry.GetProcessor(procConfig.Type)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get processor plugin '%s': %w", procConfig.Type, err)
+		if procConfig.Parallelism < 0 {
+			return fmt.Errorf("processor %d: parallelism must not be negative", i)
 		}
+	}
 
-		if err := processor.Initialize(procConfig.Config); err != nil {
-			return nil, fmt.Errorf("failed to initialize processor %d: %w", i, err)
+	// Validate error policy
+	if policy := config.Settings.ErrorPolicy; policy != nil {
+		if policy.Mode == pipeline.ErrorModeDeadLetter && (policy.DeadLetter == nil || policy.DeadLetter.Type == "") {
+			return fmt.Errorf("dead_letter output type is required for the dead_letter error mode")
 		}
-
-		processors = append(processors, processor)
 	}
 
-	// Get output plugin
-	output, err := c.registry.GetOutput(config.Output.Type)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get output plugin '%s': %w", config.Output.Type, err)
+	// Validate schema policy
+	if policy := config.Settings.SchemaPolicy; policy != nil && policy.Mode != "" {
+		if err := (&pipeline.SchemaPolicy{Mode: policy.Mode}).Validate(); err != nil {
+			return fmt.Errorf("invalid schema policy: %w", err)
+		}
 	}
 
-	// Initialize output plugin
-	if err := output.Initialize(config.Output.Config); err != nil {
-		return nil, fmt.Errorf("failed to initialize output plugin: %w", err)
+	return nil
+}
+
+// BuildPipeline builds a pipeline from configuration.
+// Plugin instances are created in the given session, which the caller must
+// close when the execution ends.
+func (c *Converter) BuildPipeline(config *PipelineConfig, session *plugin.Session) (*pipeline.Pipeline, error) {
+	input, processors, output, err := c.buildPlugins(config, session)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create pipeline
@@ -134,53 +165,163 @@ func (c *Converter) BuildPipeline(config *PipelineConfig) (*pipeline.Pipeline, e
 	return p, nil
 }
 
-// BuildConcurrentPipeline builds a concurrent pipeline from configuration
-func (c *Converter) BuildConcurrentPipeline(config *PipelineConfig) (*pipeline.ConcurrentPipeline, error) {
+// BuildConcurrentPipeline builds a concurrent pipeline from configuration.
+// Plugin instances are created in the given session, which the caller must
+// close when the execution ends. pipelineConfig may be nil to use defaults;
+// settings from the YAML configuration take precedence over it.
+func (c *Converter) BuildConcurrentPipeline(
+	config *PipelineConfig,
+	session *plugin.Session,
+	pipelineConfig *pipeline.ConcurrentPipelineConfig,
+) (*pipeline.ConcurrentPipeline, error) {
+	input, processors, output, err := c.buildPlugins(config, session)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create concurrent pipeline configuration
+	if pipelineConfig == nil {
+		pipelineConfig = pipeline.DefaultConcurrentConfig()
+	}
+	if config.Settings.BatchSize > 0 {
+		pipelineConfig.BatchSize = config.Settings.BatchSize
+	}
+	if size := config.Settings.QueueSize; size > 0 {
+		pipelineConfig.InputBufferSize = size
+		pipelineConfig.ProcessorBufferSize = size
+		pipelineConfig.OutputBufferSize = size
+	}
+	if limit := config.Settings.QueueMaxBytes; limit > 0 {
+		pipelineConfig.InputBufferBytes = limit
+		pipelineConfig.ProcessorBufferBytes = limit
+		pipelineConfig.OutputBufferBytes = limit
+	}
+	if config.Input.Parallelism > 1 {
+		pipelineConfig.InputShards = config.Input.Parallelism
+	}
+	if config.Settings.ErrorPolicy != nil {
+		policy, err := c.buildErrorPolicy(config.Settings.ErrorPolicy, session)
+		if err != nil {
+			return nil, err
+		}
+		pipelineConfig.ErrorPolicy = policy
+	}
+	if policy := config.Settings.SchemaPolicy; policy != nil && policy.Mode != "" {
+		pipelineConfig.SchemaPolicy = &pipeline.SchemaPolicy{Mode: policy.Mode}
+	}
+
+	for i, procConfig := range config.Processors {
+		if procConfig.Parallelism <= 1 {
+			continue
+		}
+		if pipelineConfig.Parallelism == nil {
+			pipelineConfig.Parallelism = make(map[int]*pipeline.ProcessorParallelism)
+		}
+		procConfig := procConfig
+		pipelineConfig.Parallelism[i] = &pipeline.ProcessorParallelism{
+			Workers:   procConfig.Parallelism,
+			Unordered: procConfig.Ordered != nil && !*procConfig.Ordered,
+			NewInstance: func() (types.ProcessorPlugin, error) {
+				return c.newProcessor(procConfig, session)
+			},
+		}
+	}
+
+	// Create pipeline
+	p := pipeline.NewConcurrentPipeline(input, processors, output, pipelineConfig)
+
+	return p, nil
+}
+
+// buildPlugins creates and initializes fresh plugin instances for a pipeline
+func (c *Converter) buildPlugins(config *PipelineConfig, session *plugin.Session) (
+	types.InputPlugin, []types.ProcessorPlugin, types.OutputPlugin, error,
+) {
+	if session == nil {
+		return nil, nil, nil, fmt.Errorf("plugin session is required")
+	}
+
 	// Get input plugin
-	input, err := c.registry.GetInput(config.Input.Type)
+	input, err := session.NewInput(config.Input.Type)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get input plugin '%s': %w", config.Input.Type, err)
+		return nil, nil, nil, fmt.Errorf("failed to get input plugin '%s': %w", config.Input.Type, err)
 	}
 
+	// Initialize input plugin
 	if err := input.Initialize(config.Input.Config); err != nil {
-		return nil, fmt.Errorf("failed to initialize input plugin: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to initialize input plugin: %w", err)
 	}
 
 	// Get processor plugins
 	processors := make([]types.ProcessorPlugin, 0, len(config.Processors))
 	for i, procConfig := range config.Processors {
-		processor, err := c.registry.GetProcessor(procConfig.Type)
+		processor, err := c.newProcessor(procConfig, session)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get processor plugin '%s': %w", procConfig.Type, err)
-		}
-
-		if err := processor.Initialize(procConfig.Config); err != nil {
-			return nil, fmt.Errorf("failed to initialize processor %d: %w", i, err)
+			return nil, nil, nil, fmt.Errorf("processor %d: %w", i, err)
 		}
 
 		processors = append(processors, processor)
 	}
 
 	// Get output plugin
-	output, err := c.registry.GetOutput(config.Output.Type)
+	output, err := session.NewOutput(config.Output.Type)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get output plugin '%s': %w", config.Output.Type, err)
+		return nil, nil, nil, fmt.Errorf("failed to get output plugin '%s': %w", config.Output.Type, err)
 	}
 
+	// Initialize output plugin
 	if err := output.Initialize(config.Output.Config); err != nil {
-		return nil, fmt.Errorf("failed to initialize output plugin: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to initialize output plugin: %w", err)
 	}
 
-	// Create concurrent pipeline configuration
-	pipelineConfig := pipeline.DefaultConcurrentConfig()
-	if config.Settings.BatchSize > 0 {
-		pipelineConfig.BatchSize = config.Settings.BatchSize
+	return input, processors, output, nil
+}
+
+// buildErrorPolicy creates the error policy and its dead-letter output
+func (c *Converter) buildErrorPolicy(config *ErrorPolicyConfig, session *plugin.Session) (*pipeline.ErrorPolicy, error) {
+	policy := pipeline.DefaultErrorPolicy()
+	if config.Mode != "" {
+		policy.Mode = config.Mode
+	}
+	policy.MaxErrorRate = config.MaxErrorRate
+	if config.MinRecords > 0 {
+		policy.MinRecords = config.MinRecords
 	}
 
-	// Create pipeline
-	p := pipeline.NewConcurrentPipeline(input, processors, output, pipelineConfig)
+	if policy.Mode == pipeline.ErrorModeDeadLetter && config.DeadLetter != nil {
+		deadLetter, err := session.NewOutput(config.DeadLetter.Type)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dead-letter output plugin '%s': %w", config.DeadLetter.Type, err)
+		}
+		if err := deadLetter.Initialize(config.DeadLetter.Config); err != nil {
+			return nil, fmt.Errorf("failed to initialize dead-letter output plugin: %w", err)
+		}
+		policy.DeadLetter = deadLetter
+	}
 
-	return p, nil
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid error policy: %w", err)
+	}
+	return policy, nil
+}
+
+// newProcessor creates, initializes and validates a processor plugin
+// instance
+func (c *Converter) newProcessor(procConfig ProcessorConfig, session *plugin.Session) (types.ProcessorPlugin, error) {
+	processor, err := session.NewProcessor(procConfig.Type)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get processor plugin '%s': %w", procConfig.Type, err)
+	}
+
+	if err := processor.Initialize(procConfig.Config); err != nil {
+		return nil, fmt.Errorf("failed to initialize processor plugin '%s': %w", procConfig.Type, err)
+	}
+
+	if err := processor.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid processor plugin '%s' configuration: %w", procConfig.Type, err)
+	}
+
+	return processor, nil
 }
 
 // ConfigToYAML converts pipeline config back to YAML
@@ -208,8 +349,9 @@ processors:
       mode: include
   
   - type: mapping
+    parallelism: 4
     config:
-      mappings:
+      field_mappings:
         old_name: new_name
         user_id: id
 
@@ -223,5 +365,16 @@ output:
 settings:
   batch_size: 1000
   mode: async
+  queue_size: 10
+  queue_max_bytes: 67108864
+  schema_policy:
+    mode: drop_unknown
+  error_policy:
+    mode: dead_letter
+    max_error_rate: 0.05
+    dead_letter:
+      type: json
+      config:
+        path: /data/rejected.ndjson
 `
 }
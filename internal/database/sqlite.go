@@ -2,11 +2,14 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/atlanssia/fustgo/internal/models"
+	"github.com/atlanssia/fustgo/pkg/types"
 )
 
 // MetadataStore defines the interface for metadata storage
@@ -30,6 +33,19 @@ type MetadataStore interface {
 	GetExecutions(jobID string, limit int) ([]*models.Execution, error)
 	UpdateExecution(exec *models.Execution) error
 
+	// Checkpoint operations. Checkpoints are stored per stage as JSON in the
+	// checkpoint data of the execution that saved them.
+	SaveCheckpoint(executionID string, stage string, data string) error
+	GetCheckpoints(jobID string) (map[string]string, error)
+	DeleteCheckpoint(jobID string, stage string) error
+	ClearCheckpoints(jobID string) error
+
+	// Execution log operations. Lines are numbered in the order they are
+	// saved, across executions.
+	SaveExecutionLogs(logs []*models.ExecutionLog) error
+	GetExecutionLogs(query *ExecutionLogQuery) ([]*models.ExecutionLog, error)
+	TrimExecutionLogs(executionID string, keep int) error
+
 	// Worker operations
 	RegisterWorker(worker *models.Worker) error
 	UpdateWorkerHeartbeat(workerID string) error
@@ -44,6 +60,15 @@ type MetadataStore interface {
 	UpdatePluginStatus(pluginName string, enabled bool) error
 }
 
+// ExecutionLogQuery selects the log lines of an execution
+type ExecutionLogQuery struct {
+	ExecutionID string
+	AfterID     int64    // Only lines after this one
+	Levels      []string // Only lines of these levels, all when empty
+	Limit       int
+	Tail        bool // The last Limit lines rather than the first
+}
+
 // SQLiteStore implements MetadataStore using SQLite
 type SQLiteStore struct {
 	db *sql.DB
@@ -107,9 +132,20 @@ func (s *SQLiteStore) initSchema() error {
 		error_message TEXT,
 		worker_id TEXT,
 		checkpoint_data TEXT,
+		schema_events TEXT,
 		FOREIGN KEY (job_id) REFERENCES jobs(job_id)
 	);
 
+	CREATE TABLE IF NOT EXISTS execution_logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		execution_id TEXT NOT NULL,
+		time TIMESTAMP NOT NULL,
+		level TEXT NOT NULL,
+		message TEXT NOT NULL,
+		fields TEXT,
+		FOREIGN KEY (execution_id) REFERENCES executions(execution_id)
+	);
+
 	CREATE TABLE IF NOT EXISTS workers (
 		worker_id TEXT PRIMARY KEY,
 		hostname TEXT NOT NULL,
@@ -147,11 +183,40 @@ func (s *SQLiteStore) initSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
 	CREATE INDEX IF NOT EXISTS idx_executions_job_id ON executions(job_id);
 	CREATE INDEX IF NOT EXISTS idx_executions_status ON executions(status);
+	CREATE INDEX IF NOT EXISTS idx_execution_logs_execution_id ON execution_logs(execution_id, id);
 	CREATE INDEX IF NOT EXISTS idx_workers_status ON workers(status);
 	`
 
-	_, err := s.db.Exec(schema)
-	return err
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+	return s.migrate()
+}
+
+// addedColumns are the columns added to tables after their creation, which
+// databases created earlier lack
+var addedColumns = []struct {
+	table, column, definition string
+}{
+	{"executions", "schema_events", "TEXT"},
+}
+
+// migrate adds the columns that existing databases lack
+func (s *SQLiteStore) migrate() error {
+	for _, added := range addedColumns {
+		var count int
+		err := s.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", added.table, added.column).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to read columns of %s: %w", added.table, err)
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", added.table, added.column, added.definition)); err != nil {
+			return fmt.Errorf("failed to add column %s to %s: %w", added.column, added.table, err)
+		}
+	}
+	return nil
 }
 
 // Close implements MetadataStore.Close
@@ -252,13 +317,17 @@ func (s *SQLiteStore) SaveExecution(exec *models.Execution) error {
 	query := `
 		INSERT INTO executions (execution_id, job_id, status, start_time, 
 			end_time, records_read, records_written, records_failed, 
-			bytes_transferred, error_message, worker_id, checkpoint_data)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			bytes_transferred, error_message, worker_id, checkpoint_data, schema_events)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := s.db.Exec(query,
+	events, err := encodeSchemaEvents(exec.SchemaEvents)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(query,
 		exec.ExecutionID, exec.JobID, exec.Status, exec.StartTime,
 		exec.EndTime, exec.RecordsRead, exec.RecordsWritten, exec.RecordsFailed,
-		exec.BytesTransferred, exec.ErrorMessage, exec.WorkerID, exec.CheckpointData,
+		exec.BytesTransferred, exec.ErrorMessage, exec.WorkerID, exec.CheckpointData, events,
 	)
 	return err
 }
@@ -268,15 +337,10 @@ func (s *SQLiteStore) GetExecution(executionID string) (*models.Execution, error
 	query := `
 		SELECT execution_id, job_id, status, start_time, end_time, 
 			records_read, records_written, records_failed, bytes_transferred, 
-			error_message, worker_id, checkpoint_data
+			error_message, worker_id, checkpoint_data, schema_events
 		FROM executions WHERE execution_id = ?
 	`
-	exec := &models.Execution{}
-	err := s.db.QueryRow(query, executionID).Scan(
-		&exec.ExecutionID, &exec.JobID, &exec.Status, &exec.StartTime, &exec.EndTime,
-		&exec.RecordsRead, &exec.RecordsWritten, &exec.RecordsFailed,
-		&exec.BytesTransferred, &exec.ErrorMessage, &exec.WorkerID, &exec.CheckpointData,
-	)
+	exec, err := scanExecution(s.db.QueryRow(query, executionID))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("execution not found: %s", executionID)
 	}
@@ -288,7 +352,7 @@ func (s *SQLiteStore) GetExecutions(jobID string, limit int) ([]*models.Executio
 	query := `
 		SELECT execution_id, job_id, status, start_time, end_time, 
 			records_read, records_written, records_failed, bytes_transferred, 
-			error_message, worker_id, checkpoint_data
+			error_message, worker_id, checkpoint_data, schema_events
 		FROM executions WHERE job_id = ? ORDER BY start_time DESC LIMIT ?
 	`
 	rows, err := s.db.Query(query, jobID, limit)
@@ -299,12 +363,7 @@ func (s *SQLiteStore) GetExecutions(jobID string, limit int) ([]*models.Executio
 
 	var executions []*models.Execution
 	for rows.Next() {
-		exec := &models.Execution{}
-		err := rows.Scan(
-			&exec.ExecutionID, &exec.JobID, &exec.Status, &exec.StartTime, &exec.EndTime,
-			&exec.RecordsRead, &exec.RecordsWritten, &exec.RecordsFailed,
-			&exec.BytesTransferred, &exec.ErrorMessage, &exec.WorkerID, &exec.CheckpointData,
-		)
+		exec, err := scanExecution(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -318,17 +377,237 @@ func (s *SQLiteStore) UpdateExecution(exec *models.Execution) error {
 	query := `
 		UPDATE executions SET status = ?, end_time = ?, records_read = ?, 
 			records_written = ?, records_failed = ?, bytes_transferred = ?, 
-			error_message = ?, checkpoint_data = ?
+			error_message = ?, schema_events = ?
 		WHERE execution_id = ?
 	`
-	_, err := s.db.Exec(query,
+	events, err := encodeSchemaEvents(exec.SchemaEvents)
+	if err != nil {
+		return err
+	}
+	// checkpoint_data is owned by the checkpoint operations and left alone
+	_, err = s.db.Exec(query,
 		exec.Status, exec.EndTime, exec.RecordsRead, exec.RecordsWritten,
-		exec.RecordsFailed, exec.BytesTransferred, exec.ErrorMessage,
-		exec.CheckpointData, exec.ExecutionID,
+		exec.RecordsFailed, exec.BytesTransferred, exec.ErrorMessage, events,
+		exec.ExecutionID,
 	)
 	return err
 }
 
+// scanExecution scans a row of the execution columns
+func scanExecution(row interface{ Scan(...interface{}) error }) (*models.Execution, error) {
+	exec := &models.Execution{}
+	var events sql.NullString
+	err := row.Scan(
+		&exec.ExecutionID, &exec.JobID, &exec.Status, &exec.StartTime, &exec.EndTime,
+		&exec.RecordsRead, &exec.RecordsWritten, &exec.RecordsFailed,
+		&exec.BytesTransferred, &exec.ErrorMessage, &exec.WorkerID, &exec.CheckpointData, &events,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if events.String != "" {
+		if err := json.Unmarshal([]byte(events.String), &exec.SchemaEvents); err != nil {
+			return nil, fmt.Errorf("invalid schema events in execution %s: %w", exec.ExecutionID, err)
+		}
+	}
+	return exec, nil
+}
+
+// encodeSchemaEvents encodes schema events as JSON, or as NULL if there
+// are none
+func encodeSchemaEvents(events []types.SchemaEvent) (interface{}, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema events: %w", err)
+	}
+	return string(data), nil
+}
+
+// SaveCheckpoint implements MetadataStore.SaveCheckpoint. The checkpoint of
+// the stage is merged into the execution's checkpoint data by a single
+// UPDATE, so concurrent saves of other stages are not lost.
+func (s *SQLiteStore) SaveCheckpoint(executionID string, stage string, data string) error {
+	query := `
+		UPDATE executions SET checkpoint_data = json_set(
+			CASE WHEN checkpoint_data IS NULL OR checkpoint_data = '' THEN '{}' ELSE checkpoint_data END,
+			?, json(?))
+		WHERE execution_id = ?
+	`
+	result, err := s.db.Exec(query, checkpointPath(stage), data, executionID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("execution not found: %s", executionID)
+	}
+	return nil
+}
+
+// GetCheckpoints implements MetadataStore.GetCheckpoints. For each stage it
+// returns the checkpoint of the most recent execution of the job that saved
+// one.
+func (s *SQLiteStore) GetCheckpoints(jobID string) (map[string]string, error) {
+	query := `
+		SELECT execution_id, checkpoint_data FROM executions
+		WHERE job_id = ? AND checkpoint_data IS NOT NULL AND checkpoint_data != ''
+		ORDER BY start_time DESC, rowid DESC
+	`
+	rows, err := s.db.Query(query, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]string)
+	for rows.Next() {
+		var executionID, data string
+		if err := rows.Scan(&executionID, &data); err != nil {
+			return nil, err
+		}
+		var checkpoints map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(data), &checkpoints); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint data in execution %s: %w", executionID, err)
+		}
+		for stage, checkpoint := range checkpoints {
+			if _, ok := result[stage]; !ok {
+				result[stage] = string(checkpoint)
+			}
+		}
+	}
+	return result, rows.Err()
+}
+
+// DeleteCheckpoint implements MetadataStore.DeleteCheckpoint. The stage is
+// removed from all executions of the job, so that an older checkpoint does
+// not take its place.
+func (s *SQLiteStore) DeleteCheckpoint(jobID string, stage string) error {
+	query := `
+		UPDATE executions SET checkpoint_data = json_remove(checkpoint_data, ?)
+		WHERE job_id = ? AND checkpoint_data IS NOT NULL AND checkpoint_data != ''
+	`
+	_, err := s.db.Exec(query, checkpointPath(stage), jobID)
+	return err
+}
+
+// ClearCheckpoints implements MetadataStore.ClearCheckpoints
+func (s *SQLiteStore) ClearCheckpoints(jobID string) error {
+	_, err := s.db.Exec("UPDATE executions SET checkpoint_data = '' WHERE job_id = ?", jobID)
+	return err
+}
+
+// checkpointPath returns the JSON path of a stage in checkpoint data
+func checkpointPath(stage string) string {
+	return `$."` + stage + `"`
+}
+
+// SaveExecutionLogs implements MetadataStore.SaveExecutionLogs. The lines
+// are saved in a single transaction and their IDs set.
+func (s *SQLiteStore) SaveExecutionLogs(logs []*models.ExecutionLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO execution_logs (execution_id, time, level, message, fields)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, line := range logs {
+		var fields interface{}
+		if len(line.Fields) > 0 {
+			data, err := json.Marshal(line.Fields)
+			if err != nil {
+				return fmt.Errorf("failed to encode log fields: %w", err)
+			}
+			fields = string(data)
+		}
+		result, err := stmt.Exec(line.ExecutionID, line.Time, line.Level, line.Message, fields)
+		if err != nil {
+			return err
+		}
+		if line.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetExecutionLogs implements MetadataStore.GetExecutionLogs. Lines are
+// returned in the order they were saved.
+func (s *SQLiteStore) GetExecutionLogs(query *ExecutionLogQuery) ([]*models.ExecutionLog, error) {
+	where := "execution_id = ? AND id > ?"
+	args := []interface{}{query.ExecutionID, query.AfterID}
+	if len(query.Levels) > 0 {
+		where += " AND level IN (?" + strings.Repeat(", ?", len(query.Levels)-1) + ")"
+		for _, level := range query.Levels {
+			args = append(args, level)
+		}
+	}
+	order := "ASC"
+	if query.Tail {
+		order = "DESC"
+	}
+	args = append(args, query.Limit)
+
+	rows, err := s.db.Query(`
+		SELECT id, execution_id, time, level, message, fields FROM execution_logs
+		WHERE `+where+` ORDER BY id `+order+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []*models.ExecutionLog
+	for rows.Next() {
+		line := &models.ExecutionLog{}
+		var fields sql.NullString
+		if err := rows.Scan(&line.ID, &line.ExecutionID, &line.Time, &line.Level, &line.Message, &fields); err != nil {
+			return nil, err
+		}
+		if fields.String != "" {
+			if err := json.Unmarshal([]byte(fields.String), &line.Fields); err != nil {
+				return nil, fmt.Errorf("invalid fields in log line %d: %w", line.ID, err)
+			}
+		}
+		logs = append(logs, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if query.Tail {
+		for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+			logs[i], logs[j] = logs[j], logs[i]
+		}
+	}
+	return logs, nil
+}
+
+// TrimExecutionLogs implements MetadataStore.TrimExecutionLogs. Only the
+// last keep lines of the execution are kept.
+func (s *SQLiteStore) TrimExecutionLogs(executionID string, keep int) error {
+	query := `
+		DELETE FROM execution_logs WHERE execution_id = ? AND id <= (
+			SELECT id FROM execution_logs WHERE execution_id = ?
+			ORDER BY id DESC LIMIT 1 OFFSET ?)
+	`
+	_, err := s.db.Exec(query, executionID, executionID, keep)
+	return err
+}
+
 // RegisterWorker implements MetadataStore.RegisterWorker
 func (s *SQLiteStore) RegisterWorker(worker *models.Worker) error {
 	query := `
@@ -0,0 +1,424 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// node is a compiled expression node
+type node interface {
+	eval(env Env) (interface{}, error)
+	dataType(env typeEnv) types.DataType
+}
+
+// literalNode is a constant value
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(env Env) (interface{}, error) {
+	return n.value, nil
+}
+
+// fieldNode references a field of the current record
+type fieldNode struct {
+	name string
+}
+
+func (n *fieldNode) eval(env Env) (interface{}, error) {
+	value, ok := env.Lookup(n.name)
+	if !ok {
+		return nil, fmt.Errorf("unknown field: %s", n.name)
+	}
+	return normalize(value), nil
+}
+
+// notNode negates a boolean expression
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+// negNode negates a number
+type negNode struct {
+	operand node
+}
+
+func (n *negNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	num, ok := toNumber(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate %s", typeName(v))
+	}
+	switch val := num.(type) {
+	case int64:
+		if val == math.MinInt64 {
+			return nil, fmt.Errorf("integer overflow: -(%d)", val)
+		}
+		return -val, nil
+	default:
+		return -val.(float64), nil
+	}
+}
+
+// logicalNode implements AND and OR with short-circuit evaluation
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicalNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.and && !truthy(l) {
+		return false, nil
+	}
+	if !n.and && truthy(l) {
+		return true, nil
+	}
+
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(r), nil
+}
+
+// compareNode implements comparison operators
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "=", "==":
+		return equal(l, r)
+	case "!=", "<>":
+		eq, err := equal(l, r)
+		return !eq, err
+	}
+
+	// Ordering comparisons with null are never true
+	if l == nil || r == nil {
+		return false, nil
+	}
+
+	c, err := compare(l, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	default:
+		return nil, fmt.Errorf("unknown comparison operator: %s", n.op)
+	}
+}
+
+// arithNode implements arithmetic operators
+type arithNode struct {
+	op          string
+	left, right node
+}
+
+func (n *arithNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	return arithmetic(n.op, l, r)
+}
+
+// arithmetic applies an arithmetic operator to two non-null values.
+// Integer results out of the range of int64 are errors rather than
+// wrapping around.
+func arithmetic(op string, l, r interface{}) (interface{}, error) {
+	ln, okL := toNumber(l)
+	rn, okR := toNumber(r)
+	if !okL || !okR {
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", op, typeName(l), typeName(r))
+	}
+
+	li, intL := ln.(int64)
+	ri, intR := rn.(int64)
+	if intL && intR {
+		switch op {
+		case "+":
+			if sum := li + ri; (sum > li) == (ri > 0) {
+				return sum, nil
+			}
+			return nil, fmt.Errorf("integer overflow: %d + %d", li, ri)
+		case "-":
+			if diff := li - ri; (diff < li) == (ri > 0) {
+				return diff, nil
+			}
+			return nil, fmt.Errorf("integer overflow: %d - %d", li, ri)
+		case "*":
+			product := li * ri
+			if li == 0 || (product/li == ri && !(li == -1 && ri == math.MinInt64)) {
+				return product, nil
+			}
+			return nil, fmt.Errorf("integer overflow: %d * %d", li, ri)
+		case "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("modulo by zero")
+			}
+			return li % ri, nil
+		}
+	}
+
+	lf, _ := toFloat(ln)
+	rf, _ := toFloat(rn)
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, fmt.Errorf("modulo by zero")
+		}
+		return math.Mod(lf, rf), nil
+	default:
+		return nil, fmt.Errorf("unknown arithmetic operator: %s", op)
+	}
+}
+
+// inNode implements [NOT] IN (list)
+type inNode struct {
+	operand node
+	list    []node
+	negate  bool
+}
+
+func (n *inNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return false, nil
+	}
+
+	for _, item := range n.list {
+		candidate, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if candidate == nil {
+			continue
+		}
+		eq, err := equal(v, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if eq {
+			return !n.negate, nil
+		}
+	}
+	return n.negate, nil
+}
+
+// betweenNode implements [NOT] BETWEEN low AND high (inclusive)
+type betweenNode struct {
+	operand   node
+	low, high node
+	negate    bool
+}
+
+func (n *betweenNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	low, err := n.low.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	high, err := n.high.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil || low == nil || high == nil {
+		return false, nil
+	}
+
+	cLow, err := compare(v, low)
+	if err != nil {
+		return nil, err
+	}
+	cHigh, err := compare(v, high)
+	if err != nil {
+		return nil, err
+	}
+
+	inRange := cLow >= 0 && cHigh <= 0
+	return inRange != n.negate, nil
+}
+
+// isNullNode implements IS [NOT] NULL
+type isNullNode struct {
+	operand node
+	negate  bool
+}
+
+func (n *isNullNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return (v == nil) != n.negate, nil
+}
+
+// matchNode implements regex matching (=~, !~, MATCHES), LIKE and CONTAINS
+type matchNode struct {
+	kind    string // "regex", "like" or "contains"
+	operand node
+	pattern node
+	regex   *regexp.Regexp // Precompiled when the pattern is a literal
+	negate  bool
+}
+
+func (n *matchNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return false, nil
+	}
+	s := toString(v)
+
+	if n.kind == "contains" {
+		p, err := n.pattern.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return false, nil
+		}
+		return strings.Contains(s, toString(p)) != n.negate, nil
+	}
+
+	re := n.regex
+	if re == nil {
+		p, err := n.pattern.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return false, nil
+		}
+		re, err = compilePattern(n.kind, toString(p))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return re.MatchString(s) != n.negate, nil
+}
+
+// compilePattern compiles a regex or SQL LIKE pattern
+func compilePattern(kind, pattern string) (*regexp.Regexp, error) {
+	if kind == "like" {
+		pattern = likeToRegex(pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// likeToRegex converts a SQL LIKE pattern (% and _ wildcards) to a regex
+func likeToRegex(pattern string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, ch := range pattern {
+		switch ch {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+	return "(?s)" + sb.String()
+}
+
+// callNode invokes a built-in function
+type callNode struct {
+	name string
+	fn   *function
+	args []node
+}
+
+func (n *callNode) eval(env Env) (interface{}, error) {
+	// Conditional functions evaluate their arguments lazily
+	if n.fn.lazy != nil {
+		return n.fn.lazy(env, n.args)
+	}
+
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	result, err := n.fn.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", n.name, err)
+	}
+	return result, nil
+}
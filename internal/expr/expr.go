@@ -0,0 +1,129 @@
+// Package expr implements the expression language used by processors to
+// filter and compute record values.
+//
+// Expressions are compiled once and evaluated per record:
+//
+//	age >= 18 AND country IN ('US', 'CA')
+//	amount BETWEEN 10 AND 99.5 OR NOT active
+//	email =~ '^[a-z]+@example\.com$' AND deleted_at IS NULL
+//	created_at > '2024-01-01' AND lower(status) != 'closed'
+//
+// Values are typed: numbers compare numerically (so 10 > 9 even when one side
+// is a numeric string), timestamps compare chronologically and null only
+// equals null.
+package expr
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// Env resolves field references during evaluation
+type Env interface {
+	// Lookup returns the value of a field and whether the field exists
+	Lookup(name string) (interface{}, bool)
+}
+
+// MapEnv is an Env backed by a map
+type MapEnv map[string]interface{}
+
+// Lookup implements Env
+func (m MapEnv) Lookup(name string) (interface{}, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+// Program is a compiled expression. A Program is immutable and safe for
+// concurrent use.
+type Program struct {
+	source string
+	root   node
+	fields []string
+}
+
+// Compile parses an expression into a Program
+func Compile(source string) (*Program, error) {
+	root, fields, err := parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return &Program{source: source, root: root, fields: names}, nil
+}
+
+// MustCompile is like Compile but panics on error
+func MustCompile(source string) *Program {
+	p, err := Compile(source)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the source of the expression
+func (p *Program) String() string {
+	return p.source
+}
+
+// Fields returns the names of the fields referenced by the expression
+func (p *Program) Fields() []string {
+	return p.fields
+}
+
+// Eval evaluates the expression. The result is nil, int64, float64, string,
+// bool or time.Time.
+func (p *Program) Eval(env Env) (interface{}, error) {
+	return p.root.eval(env)
+}
+
+// EvalBool evaluates the expression as a condition. Null is false and
+// non-boolean values are true.
+func (p *Program) EvalBool(env Env) (bool, error) {
+	v, err := p.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+// RecordEnv is an Env over the records of a batch. Field positions are
+// resolved once per schema and records are swapped in with SetRecord.
+type RecordEnv struct {
+	index  map[string]int
+	record *types.Record
+}
+
+// NewRecordEnv creates a RecordEnv for the given schema
+func NewRecordEnv(schema types.Schema) *RecordEnv {
+	index := make(map[string]int, len(schema.Columns))
+	for i, col := range schema.Columns {
+		index[col.Name] = i
+	}
+	return &RecordEnv{index: index}
+}
+
+// SetRecord sets the record that field references resolve against
+func (e *RecordEnv) SetRecord(record *types.Record) {
+	e.record = record
+}
+
+// Lookup implements Env. Fields in the schema that are missing from a short
+// record resolve to null.
+func (e *RecordEnv) Lookup(name string) (interface{}, bool) {
+	i, ok := e.index[name]
+	if !ok {
+		return nil, false
+	}
+	if e.record == nil || i >= len(e.record.Values) {
+		return nil, true
+	}
+	return e.record.Values[i], true
+}
@@ -0,0 +1,334 @@
+package expr
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func evalBool(t *testing.T, source string, env Env) bool {
+	t.Helper()
+	program, err := Compile(source)
+	require.NoError(t, err)
+	result, err := program.EvalBool(env)
+	require.NoError(t, err)
+	return result
+}
+
+func evalValue(t *testing.T, source string, env Env) interface{} {
+	t.Helper()
+	program, err := Compile(source)
+	require.NoError(t, err)
+	result, err := program.Eval(env)
+	require.NoError(t, err)
+	return result
+}
+
+func TestNumericComparison(t *testing.T) {
+	env := MapEnv{"age": int64(10), "price": 9.5, "code": "10"}
+
+	assert.True(t, evalBool(t, "age > 9", env))
+	assert.True(t, evalBool(t, "age >= 10", env))
+	assert.False(t, evalBool(t, "age < 9", env))
+	assert.True(t, evalBool(t, "price < age", env))
+	assert.True(t, evalBool(t, "price == 9.5", env))
+
+	// Numeric strings compare as numbers against numbers, not lexically
+	assert.True(t, evalBool(t, "code > 9", env))
+	assert.True(t, evalBool(t, "code = 10", env))
+	assert.True(t, evalBool(t, "age = '10'", env))
+}
+
+func TestStringComparison(t *testing.T) {
+	env := MapEnv{"status": "active", "name": "O'Brien"}
+
+	assert.True(t, evalBool(t, "status = 'active'", env))
+	assert.True(t, evalBool(t, `status != "inactive"`, env))
+	assert.True(t, evalBool(t, "status <> 'inactive'", env))
+	assert.True(t, evalBool(t, "name = 'O''Brien'", env))
+	assert.True(t, evalBool(t, "status > 'abc'", env))
+}
+
+func TestBooleanLogic(t *testing.T) {
+	env := MapEnv{"a": int64(1), "b": int64(2), "active": true}
+
+	assert.True(t, evalBool(t, "a = 1 AND b = 2", env))
+	assert.False(t, evalBool(t, "a = 1 and b = 3", env))
+	assert.True(t, evalBool(t, "a = 5 OR b = 2", env))
+	assert.True(t, evalBool(t, "a = 5 || b = 2", env))
+	assert.True(t, evalBool(t, "NOT a = 5", env))
+	assert.True(t, evalBool(t, "!(a = 5) && active", env))
+	assert.True(t, evalBool(t, "active = true", env))
+
+	// AND binds tighter than OR
+	assert.True(t, evalBool(t, "a = 1 OR a = 5 AND b = 5", env))
+	assert.False(t, evalBool(t, "(a = 1 OR a = 5) AND b = 5", env))
+}
+
+func TestShortCircuit(t *testing.T) {
+	env := MapEnv{"a": int64(1)}
+
+	// The right-hand side would fail with an unknown field
+	assert.False(t, evalBool(t, "a = 2 AND missing = 1", env))
+	assert.True(t, evalBool(t, "a = 1 OR missing = 1", env))
+}
+
+func TestInAndBetween(t *testing.T) {
+	env := MapEnv{"country": "US", "amount": 42.5, "qty": int64(7)}
+
+	assert.True(t, evalBool(t, "country IN ('US', 'CA')", env))
+	assert.False(t, evalBool(t, "country NOT IN ('US', 'CA')", env))
+	assert.True(t, evalBool(t, "qty in (1, 7, 9)", env))
+	assert.True(t, evalBool(t, "amount BETWEEN 10 AND 99.5", env))
+	assert.True(t, evalBool(t, "qty BETWEEN 7 AND 7", env))
+	assert.False(t, evalBool(t, "amount NOT BETWEEN 10 AND 99.5", env))
+	assert.True(t, evalBool(t, "amount BETWEEN 10 AND 50 AND country = 'US'", env))
+}
+
+func TestNullHandling(t *testing.T) {
+	env := MapEnv{"deleted_at": nil, "name": "x", "score": nil}
+
+	assert.True(t, evalBool(t, "deleted_at IS NULL", env))
+	assert.False(t, evalBool(t, "deleted_at IS NOT NULL", env))
+	assert.True(t, evalBool(t, "name IS NOT NULL", env))
+	assert.True(t, evalBool(t, "deleted_at = NULL", env))
+	assert.False(t, evalBool(t, "score > 1", env))
+	assert.False(t, evalBool(t, "score < 1", env))
+	assert.False(t, evalBool(t, "score IN (1, 2)", env))
+	assert.Nil(t, evalValue(t, "score + 1", env))
+
+	// A bare field is true when it is not null
+	assert.True(t, evalBool(t, "name", env))
+	assert.False(t, evalBool(t, "deleted_at", env))
+}
+
+func TestPatternMatching(t *testing.T) {
+	env := MapEnv{"email": "alice@example.com", "sku": "AB-123"}
+
+	assert.True(t, evalBool(t, `email =~ '^[a-z]+@example\.com$'`, env))
+	assert.False(t, evalBool(t, `email !~ 'example'`, env))
+	assert.True(t, evalBool(t, `sku MATCHES '^[A-Z]{2}-\d+$'`, env))
+	assert.True(t, evalBool(t, "email LIKE '%@example.com'", env))
+	assert.True(t, evalBool(t, "sku LIKE 'AB-1_3'", env))
+	assert.False(t, evalBool(t, "sku NOT LIKE 'AB%'", env))
+	assert.True(t, evalBool(t, "email CONTAINS 'example'", env))
+	assert.True(t, evalBool(t, "email contains 'alice'", env))
+}
+
+func TestTimeComparison(t *testing.T) {
+	created := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	env := MapEnv{"created_at": created, "updated": "2024-06-01T00:00:00Z"}
+
+	assert.True(t, evalBool(t, "created_at > '2024-01-01'", env))
+	assert.True(t, evalBool(t, "created_at < '2024-03-15 11:00:00'", env))
+	assert.True(t, evalBool(t, "created_at BETWEEN '2024-03-01' AND '2024-04-01'", env))
+	assert.True(t, evalBool(t, "timestamp(updated) > created_at", env))
+	assert.True(t, evalBool(t, "year(created_at) = 2024 AND month(created_at) = 3", env))
+	assert.True(t, evalBool(t, "date(created_at) = '2024-03-15'", env))
+	assert.True(t, evalBool(t, "created_at < now()", env))
+}
+
+func TestArithmetic(t *testing.T) {
+	env := MapEnv{"amount": 19.99, "qty": int64(3), "price": "2.5"}
+
+	assert.Equal(t, int64(7), evalValue(t, "1 + 2 * 3", env))
+	assert.Equal(t, int64(9), evalValue(t, "(1 + 2) * 3", env))
+	assert.Equal(t, int64(1), evalValue(t, "qty % 2", env))
+	assert.Equal(t, 1.5, evalValue(t, "qty / 2", env))
+	assert.Equal(t, int64(-3), evalValue(t, "-qty", env))
+	assert.Equal(t, 7.5, evalValue(t, "price * qty", env))
+	assert.Equal(t, int64(1999), evalValue(t, "int(amount * 100)", env))
+	assert.True(t, evalBool(t, "qty * 2 > 5", env))
+
+	program, err := Compile("qty / 0")
+	require.NoError(t, err)
+	_, err = program.Eval(env)
+	assert.Error(t, err)
+
+	// Integer results beyond the range of int64 fail rather than wrap
+	env = MapEnv{"max": int64(math.MaxInt64), "min": int64(math.MinInt64)}
+	assert.Equal(t, int64(math.MaxInt64), evalValue(t, "max - 0", env))
+	assert.Equal(t, int64(math.MinInt64), evalValue(t, "-max - 1", env))
+	assert.Equal(t, int64(math.MinInt64), evalValue(t, "min * 1", env))
+	assert.Equal(t, int64(-math.MaxInt64), evalValue(t, "max * -1", env))
+	assert.Equal(t, 9.223372036854775807e18+1, evalValue(t, "max + 1.0", env))
+	for _, source := range []string{
+		"9223372036854775807 + 1",
+		"max + 1",
+		"min - 1",
+		"min + -1",
+		"0 - min",
+		"max * 2",
+		"min * -1",
+		"-1 * min",
+		"min * min",
+		"-min",
+		"abs(min)",
+	} {
+		program, err := Compile(source)
+		require.NoError(t, err, source)
+		_, err = program.Eval(env)
+		assert.ErrorContains(t, err, "integer overflow", source)
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	env := MapEnv{"first": "Ada", "last": "Lovelace", "note": nil, "score": -4.6}
+
+	assert.Equal(t, "Ada Lovelace", evalValue(t, "concat(first, ' ', last)", env))
+	assert.Equal(t, "ada", evalValue(t, "lower(first)", env))
+	assert.Equal(t, "LOVELACE", evalValue(t, "UPPER(last)", env))
+	assert.Equal(t, "x", evalValue(t, "trim('  x ')", env))
+	assert.Equal(t, int64(8), evalValue(t, "length(last)", env))
+	assert.Equal(t, "Love", evalValue(t, "substr(last, 1, 4)", env))
+	assert.Equal(t, "lace", evalValue(t, "substr(last, 5)", env))
+	assert.Equal(t, "Lovelady", evalValue(t, "replace(last, 'ace', 'ady')", env))
+	assert.Equal(t, "n/a", evalValue(t, "coalesce(note, 'n/a')", env))
+	assert.Nil(t, evalValue(t, "lower(note)", env))
+	assert.Equal(t, 4.6, evalValue(t, "abs(score)", env))
+	assert.Equal(t, -5.0, evalValue(t, "round(score)", env))
+	assert.Equal(t, -4.6, evalValue(t, "round(score, 1)", env))
+	assert.Equal(t, -5.0, evalValue(t, "floor(score)", env))
+	assert.Equal(t, int64(42), evalValue(t, "int('42')", env))
+	assert.Equal(t, "42", evalValue(t, "string(42)", env))
+	assert.Equal(t, int64(1), evalValue(t, "min(3, 1, 2)", env))
+	assert.Equal(t, "big", evalValue(t, "if(abs(score) > 4, 'big', 'small')", env))
+	assert.True(t, evalBool(t, "starts_with(first, 'A') AND ends_with(last, 'lace')", env))
+	assert.True(t, evalBool(t, `regex_match(last, '^L\w+$')`, env))
+}
+
+func TestCompileErrors(t *testing.T) {
+	invalid := []string{
+		"",
+		"age >",
+		"(age > 1",
+		"age > 1)",
+		"age IN 1, 2",
+		"age BETWEEN 1",
+		"name = 'unterminated",
+		"unknown_fn(age)",
+		"lower(a, b)",
+		"name =~ '['",
+		"age IS 5",
+		"age # 1",
+	}
+
+	for _, source := range invalid {
+		_, err := Compile(source)
+		assert.Error(t, err, source)
+	}
+}
+
+func TestUnknownFieldError(t *testing.T) {
+	program, err := Compile("missing > 1")
+	require.NoError(t, err)
+
+	_, err = program.EvalBool(MapEnv{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown field: missing")
+}
+
+func TestProgramFields(t *testing.T) {
+	program, err := Compile("b > 1 AND lower(a) = 'x' OR `first name` IS NULL")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "first name"}, program.Fields())
+}
+
+func TestRecordEnv(t *testing.T) {
+	schema := types.Schema{Columns: []types.Column{
+		{Name: "id", DataType: types.DataTypeInt},
+		{Name: "name", DataType: types.DataTypeString},
+	}}
+	program, err := Compile("id > 1 AND name IS NOT NULL")
+	require.NoError(t, err)
+
+	env := NewRecordEnv(schema)
+	records := []types.Record{
+		{Values: []interface{}{1, "a"}},
+		{Values: []interface{}{2, "b"}},
+		{Values: []interface{}{3, nil}},
+		{Values: []interface{}{4}},
+	}
+
+	var matched []interface{}
+	for i := range records {
+		env.SetRecord(&records[i])
+		ok, err := program.EvalBool(env)
+		require.NoError(t, err)
+		if ok {
+			matched = append(matched, records[i].Values[0])
+		}
+	}
+	assert.Equal(t, []interface{}{2}, matched)
+}
+
+func TestResultType(t *testing.T) {
+	schema := types.Schema{Columns: []types.Column{
+		{Name: "first", DataType: types.DataTypeString},
+		{Name: "qty", DataType: types.DataTypeInt},
+		{Name: "amount", DataType: types.DataTypeDouble},
+		{Name: "created_at", DataType: types.DataTypeTimestamp},
+	}}
+
+	cases := map[string]types.DataType{
+		"concat(first, ' x')":           types.DataTypeString,
+		"qty * 2":                       types.DataTypeBigInt,
+		"qty / 2":                       types.DataTypeDouble,
+		"amount * qty":                  types.DataTypeDouble,
+		"int(amount * 100)":             types.DataTypeBigInt,
+		"qty > 1 AND first IS NULL":     types.DataTypeBool,
+		"coalesce(NULL, created_at)":    types.DataTypeTimestamp,
+		"date(created_at)":              types.DataTypeDate,
+		"if(qty > 1, 'many', 'one')":    types.DataTypeString,
+		"abs(qty)":                      types.DataTypeBigInt,
+		"NULL":                          types.DataTypeUnknown,
+		"year(created_at) - qty":        types.DataTypeBigInt,
+		"round(amount, 2)":              types.DataTypeDouble,
+		"length(first) + 0.5":           types.DataTypeDouble,
+		"first":                         types.DataTypeString,
+		"upper(first) = 'A' OR qty < 0": types.DataTypeBool,
+	}
+
+	for source, expected := range cases {
+		program, err := Compile(source)
+		require.NoError(t, err, source)
+		assert.Equal(t, expected, program.ResultType(schema), source)
+	}
+}
+
+func TestCast(t *testing.T) {
+	v, err := Cast("42", types.DataTypeBigInt)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+
+	v, err = Cast(int64(3), types.DataTypeDouble)
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, v)
+
+	v, err = Cast(1.5, types.DataTypeString)
+	require.NoError(t, err)
+	assert.Equal(t, "1.5", v)
+
+	v, err = Cast("2024-03-15T10:30:00Z", types.DataTypeDate)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), v)
+
+	v, err = Cast(nil, types.DataTypeInt)
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	_, err = Cast("abc", types.DataTypeInt)
+	assert.Error(t, err)
+
+	dt, err := ParseDataType("bigint")
+	require.NoError(t, err)
+	assert.Equal(t, types.DataTypeBigInt, dt)
+
+	_, err = ParseDataType("uuid")
+	assert.Error(t, err)
+}
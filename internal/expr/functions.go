@@ -0,0 +1,307 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// function is a built-in function
+type function struct {
+	minArgs int
+	maxArgs int // -1 for variadic functions
+	returns func(args []types.DataType) types.DataType
+	call    func(args []interface{}) (interface{}, error)
+	lazy    func(env Env, args []node) (interface{}, error)
+}
+
+// functions holds the built-in functions by lower-case name
+var functions map[string]*function
+
+func init() {
+	functions = map[string]*function{
+		// String functions
+		"lower":  {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeString), call: nullSafe(func(args []interface{}) (interface{}, error) { return strings.ToLower(toString(args[0])), nil })},
+		"upper":  {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeString), call: nullSafe(func(args []interface{}) (interface{}, error) { return strings.ToUpper(toString(args[0])), nil })},
+		"trim":   {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeString), call: nullSafe(func(args []interface{}) (interface{}, error) { return strings.TrimSpace(toString(args[0])), nil })},
+		"length": {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeBigInt), call: nullSafe(fnLength)},
+		"len":    {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeBigInt), call: nullSafe(fnLength)},
+		"concat": {minArgs: 1, maxArgs: -1, returns: returnsType(types.DataTypeString), call: fnConcat},
+		"substr": {minArgs: 2, maxArgs: 3, returns: returnsType(types.DataTypeString), call: nullSafe(fnSubstr)},
+		"contains": {minArgs: 2, maxArgs: 2, returns: returnsType(types.DataTypeBool), call: nullSafe(func(args []interface{}) (interface{}, error) {
+			return strings.Contains(toString(args[0]), toString(args[1])), nil
+		})},
+		"starts_with": {minArgs: 2, maxArgs: 2, returns: returnsType(types.DataTypeBool), call: nullSafe(func(args []interface{}) (interface{}, error) {
+			return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+		})},
+		"ends_with": {minArgs: 2, maxArgs: 2, returns: returnsType(types.DataTypeBool), call: nullSafe(func(args []interface{}) (interface{}, error) {
+			return strings.HasSuffix(toString(args[0]), toString(args[1])), nil
+		})},
+		"replace": {minArgs: 3, maxArgs: 3, returns: returnsType(types.DataTypeString), call: nullSafe(func(args []interface{}) (interface{}, error) {
+			return strings.ReplaceAll(toString(args[0]), toString(args[1]), toString(args[2])), nil
+		})},
+		"regex_match": {minArgs: 2, maxArgs: 2, returns: returnsType(types.DataTypeBool), call: nullSafe(fnRegexMatch)},
+
+		// Numeric functions
+		"abs":   {minArgs: 1, maxArgs: 1, returns: returnsNumeric, call: nullSafe(fnAbs)},
+		"round": {minArgs: 1, maxArgs: 2, returns: returnsType(types.DataTypeDouble), call: nullSafe(fnRound)},
+		"floor": {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeDouble), call: nullSafe(mathFunc(math.Floor))},
+		"ceil":  {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeDouble), call: nullSafe(mathFunc(math.Ceil))},
+		"min":   {minArgs: 1, maxArgs: -1, returns: returnsFirstKnown, call: func(args []interface{}) (interface{}, error) { return extreme(args, -1) }},
+		"max":   {minArgs: 1, maxArgs: -1, returns: returnsFirstKnown, call: func(args []interface{}) (interface{}, error) { return extreme(args, 1) }},
+
+		// Conversion functions
+		"int":    {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeBigInt), call: nullSafe(fnInt)},
+		"float":  {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeDouble), call: nullSafe(fnFloat)},
+		"string": {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeString), call: nullSafe(func(args []interface{}) (interface{}, error) { return toString(args[0]), nil })},
+		"bool":   {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeBool), call: nullSafe(fnBool)},
+
+		// Time functions
+		"now":       {minArgs: 0, maxArgs: 0, returns: returnsType(types.DataTypeTimestamp), call: func(args []interface{}) (interface{}, error) { return time.Now().UTC(), nil }},
+		"timestamp": {minArgs: 1, maxArgs: 2, returns: returnsType(types.DataTypeTimestamp), call: nullSafe(fnTimestamp)},
+		"date":      {minArgs: 1, maxArgs: 2, returns: returnsType(types.DataTypeDate), call: nullSafe(fnDate)},
+		"year":      {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeBigInt), call: nullSafe(timePart(func(t time.Time) int64 { return int64(t.Year()) }))},
+		"month":     {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeBigInt), call: nullSafe(timePart(func(t time.Time) int64 { return int64(t.Month()) }))},
+		"day":       {minArgs: 1, maxArgs: 1, returns: returnsType(types.DataTypeBigInt), call: nullSafe(timePart(func(t time.Time) int64 { return int64(t.Day()) }))},
+
+		// Conditional functions
+		"coalesce": {minArgs: 1, maxArgs: -1, returns: returnsFirstKnown, lazy: fnCoalesce},
+		"if":       {minArgs: 3, maxArgs: 3, returns: returnsBranch, lazy: fnIf},
+	}
+}
+
+// Functions returns the names of all built-in functions
+func Functions() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nullSafe wraps a function so that it returns null when its first argument
+// is null
+func nullSafe(fn func(args []interface{}) (interface{}, error)) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) > 0 && args[0] == nil {
+			return nil, nil
+		}
+		return fn(args)
+	}
+}
+
+func fnLength(args []interface{}) (interface{}, error) {
+	return int64(utf8.RuneCountInString(toString(args[0]))), nil
+}
+
+func fnConcat(args []interface{}) (interface{}, error) {
+	var sb strings.Builder
+	for _, arg := range args {
+		sb.WriteString(toString(arg))
+	}
+	return sb.String(), nil
+}
+
+// fnSubstr returns a substring using a 1-based start position, as in SQL
+func fnSubstr(args []interface{}) (interface{}, error) {
+	runes := []rune(toString(args[0]))
+
+	start, ok := toInt(args[1])
+	if !ok {
+		return nil, fmt.Errorf("start must be a number")
+	}
+	if start < 1 {
+		start = 1
+	}
+	if start > int64(len(runes)) {
+		return "", nil
+	}
+
+	end := int64(len(runes))
+	if len(args) == 3 {
+		length, ok := toInt(args[2])
+		if !ok || length < 0 {
+			return nil, fmt.Errorf("length must be a non-negative number")
+		}
+		end = min(start-1+length, end)
+	}
+	return string(runes[start-1 : end]), nil
+}
+
+func fnRegexMatch(args []interface{}) (interface{}, error) {
+	if args[1] == nil {
+		return nil, nil
+	}
+	re, err := regexp.Compile(toString(args[1]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re.MatchString(toString(args[0])), nil
+}
+
+func fnAbs(args []interface{}) (interface{}, error) {
+	n, ok := toNumber(args[0])
+	if !ok {
+		return nil, fmt.Errorf("expected number, got %s", typeName(args[0]))
+	}
+	switch v := n.(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil, fmt.Errorf("integer overflow: abs(%d)", v)
+		}
+		if v < 0 {
+			return -v, nil
+		}
+		return v, nil
+	default:
+		return math.Abs(v.(float64)), nil
+	}
+}
+
+func fnRound(args []interface{}) (interface{}, error) {
+	f, ok := toFloat(args[0])
+	if !ok {
+		return nil, fmt.Errorf("expected number, got %s", typeName(args[0]))
+	}
+	if len(args) == 1 {
+		return math.Round(f), nil
+	}
+	places, ok := toInt(args[1])
+	if !ok {
+		return nil, fmt.Errorf("decimal places must be a number")
+	}
+	scale := math.Pow(10, float64(places))
+	return math.Round(f*scale) / scale, nil
+}
+
+func mathFunc(fn func(float64) float64) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		f, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("expected number, got %s", typeName(args[0]))
+		}
+		return fn(f), nil
+	}
+}
+
+// extreme returns the smallest (sign -1) or largest (sign 1) non-null argument
+func extreme(args []interface{}, sign int) (interface{}, error) {
+	var result interface{}
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		if result == nil {
+			result = arg
+			continue
+		}
+		c, err := compare(arg, result)
+		if err != nil {
+			return nil, err
+		}
+		if c*sign > 0 {
+			result = arg
+		}
+	}
+	return result, nil
+}
+
+func fnInt(args []interface{}) (interface{}, error) {
+	if t, ok := args[0].(time.Time); ok {
+		return t.Unix(), nil
+	}
+	n, ok := toNumber(args[0])
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %q to int", toString(args[0]))
+	}
+	if f, isFloat := n.(float64); isFloat {
+		// Round away float noise so that int(19.99 * 100) is 1999, not 1998
+		i, ok := toInt(math.Round(f*1e6) / 1e6)
+		if !ok {
+			return nil, fmt.Errorf("cannot convert %v to int", f)
+		}
+		return i, nil
+	}
+	return n, nil
+}
+
+func fnFloat(args []interface{}) (interface{}, error) {
+	f, ok := toFloat(args[0])
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %q to float", toString(args[0]))
+	}
+	return f, nil
+}
+
+func fnBool(args []interface{}) (interface{}, error) {
+	b, ok := toBool(args[0])
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %q to bool", toString(args[0]))
+	}
+	return b, nil
+}
+
+func fnTimestamp(args []interface{}) (interface{}, error) {
+	if len(args) == 2 {
+		t, ok := parseTime(toString(args[0]), toString(args[1]))
+		if !ok {
+			return nil, fmt.Errorf("cannot parse %q with layout %q", toString(args[0]), toString(args[1]))
+		}
+		return t, nil
+	}
+	t, ok := toTime(args[0])
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %q to timestamp", toString(args[0]))
+	}
+	return t, nil
+}
+
+func fnDate(args []interface{}) (interface{}, error) {
+	v, err := fnTimestamp(args)
+	if err != nil {
+		return nil, err
+	}
+	t := v.(time.Time)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+}
+
+func timePart(part func(time.Time) int64) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		t, ok := toTime(args[0])
+		if !ok {
+			return nil, fmt.Errorf("cannot convert %q to timestamp", toString(args[0]))
+		}
+		return part(t), nil
+	}
+}
+
+func fnCoalesce(env Env, args []node) (interface{}, error) {
+	for _, arg := range args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+func fnIf(env Env, args []node) (interface{}, error) {
+	cond, err := args[0].eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(cond) {
+		return args[1].eval(env)
+	}
+	return args[2].eval(env)
+}
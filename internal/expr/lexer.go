@@ -0,0 +1,190 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the kind of a lexical token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenKeyword
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+// token is a lexical token of an expression
+type token struct {
+	kind  tokenKind
+	text  string // Keywords are upper-cased, identifiers keep their case
+	pos   int
+	quote bool // Identifier was quoted with backticks
+}
+
+// keywords recognised by the lexer (case-insensitive)
+var keywords = map[string]bool{
+	"AND":      true,
+	"OR":       true,
+	"NOT":      true,
+	"IN":       true,
+	"BETWEEN":  true,
+	"IS":       true,
+	"NULL":     true,
+	"TRUE":     true,
+	"FALSE":    true,
+	"LIKE":     true,
+	"CONTAINS": true,
+	"MATCHES":  true,
+}
+
+// operators ordered so that longer operators match first
+var operators = []string{
+	"==", "!=", "<>", "<=", ">=", "=~", "!~", "&&", "||",
+	"=", "<", ">", "+", "-", "*", "/", "%", "!",
+}
+
+// tokenize splits an expression into tokens
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	pos := 0
+
+	for pos < len(input) {
+		ch := rune(input[pos])
+
+		switch {
+		case unicode.IsSpace(ch):
+			pos++
+
+		case ch == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: pos})
+			pos++
+
+		case ch == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: pos})
+			pos++
+
+		case ch == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: pos})
+			pos++
+
+		case ch == '\'' || ch == '"':
+			text, next, err := scanQuoted(input, pos, byte(ch))
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: pos})
+			pos = next
+
+		case ch == '`':
+			text, next, err := scanQuoted(input, pos, '`')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: text, pos: pos, quote: true})
+			pos = next
+
+		case unicode.IsDigit(ch) || (ch == '.' && pos+1 < len(input) && unicode.IsDigit(rune(input[pos+1]))):
+			start := pos
+			for pos < len(input) && (unicode.IsDigit(rune(input[pos])) || input[pos] == '.') {
+				pos++
+			}
+			// Exponent, e.g. 1e6 or 2.5E-3
+			if pos < len(input) && (input[pos] == 'e' || input[pos] == 'E') {
+				next := pos + 1
+				if next < len(input) && (input[next] == '+' || input[next] == '-') {
+					next++
+				}
+				if next < len(input) && unicode.IsDigit(rune(input[next])) {
+					pos = next
+					for pos < len(input) && unicode.IsDigit(rune(input[pos])) {
+						pos++
+					}
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: input[start:pos], pos: start})
+
+		case isIdentStart(ch):
+			start := pos
+			for pos < len(input) && isIdentPart(rune(input[pos])) {
+				pos++
+			}
+			word := input[start:pos]
+			if upper := strings.ToUpper(word); keywords[upper] {
+				tokens = append(tokens, token{kind: tokenKeyword, text: upper, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdent, text: word, pos: start})
+			}
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(input[pos:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", ch, pos)
+			}
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: pos})
+	return tokens, nil
+}
+
+// scanQuoted scans a quoted string starting at pos. A doubled quote or a
+// backslash escapes the quote character.
+func scanQuoted(input string, pos int, quote byte) (string, int, error) {
+	var sb strings.Builder
+	i := pos + 1
+
+	for i < len(input) {
+		ch := input[i]
+		switch {
+		case ch == '\\' && i+1 < len(input):
+			next := input[i+1]
+			switch next {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case quote, '\\':
+				sb.WriteByte(next)
+			default:
+				// Keep unknown escapes, so regex patterns like '\d' survive
+				sb.WriteByte('\\')
+				sb.WriteByte(next)
+			}
+			i += 2
+		case ch == quote && i+1 < len(input) && input[i+1] == quote:
+			sb.WriteByte(quote)
+			i += 2
+		case ch == quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(ch)
+			i++
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated quoted string at position %d", pos)
+}
+
+func isIdentStart(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+func isIdentPart(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' || ch == '.'
+}
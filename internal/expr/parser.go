@@ -0,0 +1,414 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parser is a recursive-descent parser producing a node tree.
+//
+// Precedence, from lowest to highest:
+//
+//	OR, ||
+//	AND, &&
+//	NOT, !
+//	comparisons, IN, BETWEEN, IS NULL, LIKE, CONTAINS, MATCHES, =~, !~
+//	+, -
+//	*, /, %
+//	unary -
+//	literals, fields, function calls, parentheses
+type parser struct {
+	tokens []token
+	pos    int
+	fields map[string]bool
+}
+
+// parse parses a complete expression
+func parse(input string) (node, map[string]bool, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := &parser{tokens: tokens, fields: make(map[string]bool)}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+	return n, p.fields, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// peekKeyword reports whether the token at offset is the given keyword
+func (p *parser) peekKeyword(offset int, keyword string) bool {
+	if p.pos+offset >= len(p.tokens) {
+		return false
+	}
+	tok := p.tokens[p.pos+offset]
+	return tok.kind == tokenKeyword && tok.text == keyword
+}
+
+// acceptKeyword consumes the keyword if it is next
+func (p *parser) acceptKeyword(keyword string) bool {
+	if p.peekKeyword(0, keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// acceptOperator consumes one of the operators if it is next
+func (p *parser) acceptOperator(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	tok := p.next()
+	if tok.kind != kind {
+		return p.errorf(tok, "expected %q", text)
+	}
+	return nil
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	if tok.kind == tokenEOF {
+		return fmt.Errorf("%s at end of expression", fmt.Sprintf(format, args...))
+	}
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), tok.pos)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("||"); !ok && !p.acceptKeyword("OR") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: false, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("&&"); !ok && !p.acceptKeyword("AND") {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.acceptOperator("!"); ok || p.acceptKeyword("NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	if op, ok := p.acceptOperator("==", "=", "!=", "<>", "<=", ">=", "<", ">"); ok {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op, left: left, right: right}, nil
+	}
+
+	if op, ok := p.acceptOperator("=~", "!~"); ok {
+		return p.parseMatch("regex", left, op == "!~")
+	}
+
+	if p.acceptKeyword("IS") {
+		negate := p.acceptKeyword("NOT")
+		if !p.acceptKeyword("NULL") {
+			return nil, p.errorf(p.peek(), "expected NULL after IS")
+		}
+		return &isNullNode{operand: left, negate: negate}, nil
+	}
+
+	// Optional NOT before IN, BETWEEN, LIKE, CONTAINS and MATCHES
+	negate := false
+	if p.peekKeyword(0, "NOT") {
+		for _, kw := range []string{"IN", "BETWEEN", "LIKE", "CONTAINS", "MATCHES"} {
+			if p.peekKeyword(1, kw) {
+				p.pos++
+				negate = true
+				break
+			}
+		}
+	}
+
+	switch {
+	case p.acceptKeyword("IN"):
+		return p.parseIn(left, negate)
+	case p.acceptKeyword("BETWEEN"):
+		low, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("AND") {
+			return nil, p.errorf(p.peek(), "expected AND in BETWEEN")
+		}
+		high, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &betweenNode{operand: left, low: low, high: high, negate: negate}, nil
+	case p.acceptKeyword("LIKE"):
+		return p.parseMatch("like", left, negate)
+	case p.acceptKeyword("CONTAINS"):
+		return p.parseMatch("contains", left, negate)
+	case p.acceptKeyword("MATCHES"):
+		return p.parseMatch("regex", left, negate)
+	}
+
+	return left, nil
+}
+
+// parseIn parses the parenthesised value list of an IN expression
+func (p *parser) parseIn(operand node, negate bool) (node, error) {
+	if err := p.expect(tokenLParen, "("); err != nil {
+		return nil, err
+	}
+
+	var list []node
+	for {
+		item, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+
+		if p.peek().kind == tokenComma {
+			p.next()
+			continue
+		}
+		if err := p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return &inNode{operand: operand, list: list, negate: negate}, nil
+	}
+}
+
+// parseMatch parses the pattern of a regex, LIKE or CONTAINS expression.
+// Literal patterns are compiled once here.
+func (p *parser) parseMatch(kind string, operand node, negate bool) (node, error) {
+	pattern, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &matchNode{kind: kind, operand: operand, pattern: pattern, negate: negate}
+	if lit, ok := pattern.(*literalNode); ok && kind != "contains" {
+		s, isString := lit.value.(string)
+		if !isString {
+			return nil, fmt.Errorf("pattern must be a string")
+		}
+		if n.regex, err = compilePattern(kind, s); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOperator("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOperator("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.acceptOperator("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		// Fold negative number literals
+		if lit, ok := operand.(*literalNode); ok {
+			switch v := lit.value.(type) {
+			case int64:
+				return &literalNode{value: -v}, nil
+			case float64:
+				return &literalNode{value: -v}, nil
+			}
+		}
+		return &negNode{operand: operand}, nil
+	}
+	if _, ok := p.acceptOperator("+"); ok {
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		return parseNumber(tok)
+
+	case tokenString:
+		return &literalNode{value: tok.text}, nil
+
+	case tokenKeyword:
+		switch tok.text {
+		case "NULL":
+			return &literalNode{value: nil}, nil
+		case "TRUE":
+			return &literalNode{value: true}, nil
+		case "FALSE":
+			return &literalNode{value: false}, nil
+		}
+		return nil, p.errorf(tok, "unexpected keyword %s", tok.text)
+
+	case tokenIdent:
+		if !tok.quote && p.peek().kind == tokenLParen {
+			return p.parseCall(tok)
+		}
+		p.fields[tok.text] = true
+		return &fieldNode{name: tok.text}, nil
+
+	case tokenLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+
+	if tok.kind == tokenEOF {
+		return nil, p.errorf(tok, "unexpected end of expression")
+	}
+	return nil, p.errorf(tok, "unexpected %q", tok.text)
+}
+
+// parseCall parses a function call whose name has already been consumed
+func (p *parser) parseCall(name token) (node, error) {
+	fnName := strings.ToLower(name.text)
+	fn, ok := functions[fnName]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %s", name.text)
+	}
+
+	p.next() // (
+	var args []node
+	if p.peek().kind == tokenRParen {
+		p.next()
+	} else {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			if p.peek().kind == tokenComma {
+				p.next()
+				continue
+			}
+			if err := p.expect(tokenRParen, ")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, p.errorf(name, "wrong number of arguments for %s: got %d", fnName, len(args))
+	}
+
+	return &callNode{name: fnName, fn: fn, args: args}, nil
+}
+
+// parseNumber converts a number token to an int64 or float64 literal
+func parseNumber(tok token) (node, error) {
+	if i, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+		return &literalNode{value: i}, nil
+	}
+	f, err := strconv.ParseFloat(tok.text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+	}
+	return &literalNode{value: f}, nil
+}
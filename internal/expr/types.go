@@ -0,0 +1,204 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// typeEnv maps field names to their declared data types
+type typeEnv map[string]types.DataType
+
+// ResultType infers the data type of the expression result from the column
+// types of the schema. DataTypeUnknown is returned when the type depends on
+// the data, e.g. for a bare NULL.
+func (p *Program) ResultType(schema types.Schema) types.DataType {
+	env := make(typeEnv, len(schema.Columns))
+	for _, col := range schema.Columns {
+		env[col.Name] = col.DataType
+	}
+	return p.root.dataType(env)
+}
+
+// ValueType returns the data type of an evaluated value
+func ValueType(v interface{}) types.DataType {
+	switch normalize(v).(type) {
+	case int64:
+		return types.DataTypeBigInt
+	case float64:
+		return types.DataTypeDouble
+	case string:
+		return types.DataTypeString
+	case bool:
+		return types.DataTypeBool
+	case time.Time:
+		return types.DataTypeTimestamp
+	default:
+		return types.DataTypeUnknown
+	}
+}
+
+// ParseDataType parses a data type name such as "int", "bigint", "double",
+// "string", "bool", "date" or "timestamp"
+func ParseDataType(name string) (types.DataType, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "STRING", "VARCHAR", "TEXT":
+		return types.DataTypeString, nil
+	case "INT", "INTEGER":
+		return types.DataTypeInt, nil
+	case "BIGINT", "LONG":
+		return types.DataTypeBigInt, nil
+	case "FLOAT":
+		return types.DataTypeFloat, nil
+	case "DOUBLE", "DECIMAL", "NUMBER":
+		return types.DataTypeDouble, nil
+	case "BOOL", "BOOLEAN":
+		return types.DataTypeBool, nil
+	case "DATE":
+		return types.DataTypeDate, nil
+	case "TIMESTAMP", "DATETIME":
+		return types.DataTypeTimestamp, nil
+	case "BYTES", "BINARY":
+		return types.DataTypeBytes, nil
+	case "JSON":
+		return types.DataTypeJSON, nil
+	default:
+		return types.DataTypeUnknown, fmt.Errorf("unknown data type: %s", name)
+	}
+}
+
+// Cast converts a value to the given data type. Null stays null and
+// DataTypeUnknown leaves the value unchanged.
+func Cast(v interface{}, dt types.DataType) (interface{}, error) {
+	v = normalize(v)
+	if v == nil {
+		return nil, nil
+	}
+
+	switch dt {
+	case types.DataTypeString:
+		return toString(v), nil
+	case types.DataTypeInt, types.DataTypeBigInt:
+		return fnInt([]interface{}{v})
+	case types.DataTypeFloat, types.DataTypeDouble:
+		return fnFloat([]interface{}{v})
+	case types.DataTypeBool:
+		return fnBool([]interface{}{v})
+	case types.DataTypeDate:
+		return fnDate([]interface{}{v})
+	case types.DataTypeTimestamp:
+		return fnTimestamp([]interface{}{v})
+	case types.DataTypeBytes:
+		return []byte(toString(v)), nil
+	case types.DataTypeJSON:
+		if s, ok := v.(string); ok && json.Valid([]byte(s)) {
+			return s, nil
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %s to json: %w", typeName(v), err)
+		}
+		return string(data), nil
+	default:
+		return v, nil
+	}
+}
+
+func isIntegerType(dt types.DataType) bool {
+	return dt == types.DataTypeInt || dt == types.DataTypeBigInt
+}
+
+// firstKnown returns the first known data type
+func firstKnown(dts []types.DataType) types.DataType {
+	for _, dt := range dts {
+		if dt != types.DataTypeUnknown {
+			return dt
+		}
+	}
+	return types.DataTypeUnknown
+}
+
+func (n *literalNode) dataType(env typeEnv) types.DataType {
+	return ValueType(n.value)
+}
+
+func (n *fieldNode) dataType(env typeEnv) types.DataType {
+	return env[n.name]
+}
+
+func (n *notNode) dataType(env typeEnv) types.DataType {
+	return types.DataTypeBool
+}
+
+func (n *negNode) dataType(env typeEnv) types.DataType {
+	if dt := n.operand.dataType(env); isIntegerType(dt) {
+		return types.DataTypeBigInt
+	}
+	return types.DataTypeDouble
+}
+
+func (n *logicalNode) dataType(env typeEnv) types.DataType {
+	return types.DataTypeBool
+}
+
+func (n *compareNode) dataType(env typeEnv) types.DataType {
+	return types.DataTypeBool
+}
+
+func (n *arithNode) dataType(env typeEnv) types.DataType {
+	if n.op != "/" && isIntegerType(n.left.dataType(env)) && isIntegerType(n.right.dataType(env)) {
+		return types.DataTypeBigInt
+	}
+	return types.DataTypeDouble
+}
+
+func (n *inNode) dataType(env typeEnv) types.DataType {
+	return types.DataTypeBool
+}
+
+func (n *betweenNode) dataType(env typeEnv) types.DataType {
+	return types.DataTypeBool
+}
+
+func (n *isNullNode) dataType(env typeEnv) types.DataType {
+	return types.DataTypeBool
+}
+
+func (n *matchNode) dataType(env typeEnv) types.DataType {
+	return types.DataTypeBool
+}
+
+func (n *callNode) dataType(env typeEnv) types.DataType {
+	argTypes := make([]types.DataType, len(n.args))
+	for i, arg := range n.args {
+		argTypes[i] = arg.dataType(env)
+	}
+	return n.fn.returns(argTypes)
+}
+
+// returnsType is a function return type that does not depend on the arguments
+func returnsType(dt types.DataType) func([]types.DataType) types.DataType {
+	return func([]types.DataType) types.DataType { return dt }
+}
+
+// returnsNumeric keeps integer arguments integral and widens anything else
+func returnsNumeric(args []types.DataType) types.DataType {
+	if isIntegerType(args[0]) {
+		return types.DataTypeBigInt
+	}
+	return types.DataTypeDouble
+}
+
+// returnsFirstKnown returns the first known argument type, used for
+// functions that return one of their arguments
+func returnsFirstKnown(args []types.DataType) types.DataType {
+	return firstKnown(args)
+}
+
+// returnsBranch returns the type of the branches of if()
+func returnsBranch(args []types.DataType) types.DataType {
+	return firstKnown(args[1:])
+}
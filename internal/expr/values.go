@@ -0,0 +1,321 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are tried in order when a string is compared with a time
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// normalize converts Go values to the canonical types used by the engine:
+// nil, int64, float64, string, bool and time.Time
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil, int64, float64, string, bool, time.Time:
+		return val
+	case int:
+		return int64(val)
+	case int8:
+		return int64(val)
+	case int16:
+		return int64(val)
+	case int32:
+		return int64(val)
+	case uint:
+		return int64(val)
+	case uint8:
+		return int64(val)
+	case uint16:
+		return int64(val)
+	case uint32:
+		return int64(val)
+	case uint64:
+		if val > math.MaxInt64 {
+			return float64(val)
+		}
+		return int64(val)
+	case float32:
+		return float64(val)
+	case []byte:
+		return string(val)
+	case *time.Time:
+		if val == nil {
+			return nil
+		}
+		return *val
+	default:
+		return val
+	}
+}
+
+// isNumber reports whether v is an int64 or float64
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int64, float64:
+		return true
+	}
+	return false
+}
+
+// toNumber converts a value to int64 or float64. Numeric strings are parsed.
+func toNumber(v interface{}) (interface{}, bool) {
+	switch val := v.(type) {
+	case int64, float64:
+		return val, true
+	case bool:
+		if val {
+			return int64(1), true
+		}
+		return int64(0), true
+	case string:
+		s := strings.TrimSpace(val)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// toFloat converts a value to float64
+func toFloat(v interface{}) (float64, bool) {
+	n, ok := toNumber(v)
+	if !ok {
+		return 0, false
+	}
+	switch val := n.(type) {
+	case int64:
+		return float64(val), true
+	case float64:
+		return val, true
+	}
+	return 0, false
+}
+
+// toInt converts a value to int64, truncating floats
+func toInt(v interface{}) (int64, bool) {
+	n, ok := toNumber(v)
+	if !ok {
+		return 0, false
+	}
+	switch val := n.(type) {
+	case int64:
+		return val, true
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return 0, false
+		}
+		return int64(val), true
+	}
+	return 0, false
+}
+
+// toString converts a value to its string representation
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// toBool converts a value to bool
+func toBool(v interface{}) (bool, bool) {
+	switch val := v.(type) {
+	case bool:
+		return val, true
+	case int64:
+		return val != 0, true
+	case float64:
+		return val != 0, true
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(val))
+		return b, err == nil
+	}
+	return false, false
+}
+
+// toTime converts a value to time.Time. Strings are parsed with common
+// layouts and numbers are interpreted as Unix seconds.
+func toTime(v interface{}) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, true
+	case string:
+		return parseTime(val, "")
+	case int64:
+		return time.Unix(val, 0).UTC(), true
+	case float64:
+		sec, frac := math.Modf(val)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// parseTime parses a time string with the given layout, or the default
+// layouts when layout is empty
+func parseTime(s string, layout string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if layout != "" {
+		t, err := time.Parse(layout, s)
+		return t, err == nil
+	}
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// truthy reports whether a value counts as true in a boolean context.
+// Null is false, booleans are themselves and any other value is true.
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	default:
+		return true
+	}
+}
+
+// compare compares two non-null values and returns -1, 0 or 1. Values are
+// compared numerically, as times or as booleans when both sides can be
+// converted; otherwise they are compared as strings.
+func compare(a, b interface{}) (int, error) {
+	// Numeric comparison when at least one side is a number
+	if isNumber(a) || isNumber(b) {
+		fa, okA := toFloat(a)
+		fb, okB := toFloat(b)
+		if okA && okB {
+			ia, intA := a.(int64)
+			ib, intB := b.(int64)
+			if intA && intB {
+				return compareOrdered(ia, ib), nil
+			}
+			return compareOrdered(fa, fb), nil
+		}
+		if _, isTime := a.(time.Time); !isTime {
+			if _, isTime := b.(time.Time); !isTime {
+				return 0, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+			}
+		}
+	}
+
+	// Time comparison when at least one side is a time
+	_, timeA := a.(time.Time)
+	_, timeB := b.(time.Time)
+	if timeA || timeB {
+		ta, okA := toTime(a)
+		tb, okB := toTime(b)
+		if !okA || !okB {
+			return 0, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+		}
+		return ta.Compare(tb), nil
+	}
+
+	// Boolean comparison
+	_, boolA := a.(bool)
+	_, boolB := b.(bool)
+	if boolA || boolB {
+		ba, okA := toBool(a)
+		bb, okB := toBool(b)
+		if !okA || !okB {
+			return 0, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+		}
+		switch {
+		case ba == bb:
+			return 0, nil
+		case !ba:
+			return -1, nil
+		default:
+			return 1, nil
+		}
+	}
+
+	return strings.Compare(toString(a), toString(b)), nil
+}
+
+// equal reports whether two values are equal. Null equals only null.
+func equal(a, b interface{}) (bool, error) {
+	if a == nil || b == nil {
+		return a == nil && b == nil, nil
+	}
+	c, err := compare(a, b)
+	if err != nil {
+		return false, err
+	}
+	return c == 0, nil
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// typeName returns a readable name for a value's type
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case time.Time:
+		return "timestamp"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// Compare compares two values with the ordering used by expressions and
+// returns -1, 0 or 1. Null sorts before any other value.
+func Compare(a, b interface{}) (int, error) {
+	a, b = normalize(a), normalize(b)
+	switch {
+	case a == nil && b == nil:
+		return 0, nil
+	case a == nil:
+		return -1, nil
+	case b == nil:
+		return 1, nil
+	}
+	return compare(a, b)
+}
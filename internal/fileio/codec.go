@@ -0,0 +1,126 @@
+// Package fileio provides the compression codecs and the rolling output
+// files shared by the file-based input and output plugins.
+package fileio
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is the compression format of a file
+type Codec string
+
+const (
+	CodecNone   Codec = "none"
+	CodecGzip   Codec = "gzip"
+	CodecZstd   Codec = "zstd"
+	CodecBzip2  Codec = "bzip2"  // Read only
+	CodecSnappy Codec = "snappy" // Snappy framing format
+)
+
+// CompressionAuto selects the codec by the file extension
+const CompressionAuto = "auto"
+
+// extensions maps file extensions to the codec they denote
+var extensions = map[string]Codec{
+	".gz":     CodecGzip,
+	".gzip":   CodecGzip,
+	".zst":    CodecZstd,
+	".zstd":   CodecZstd,
+	".bz2":    CodecBzip2,
+	".sz":     CodecSnappy,
+	".snappy": CodecSnappy,
+}
+
+// DetectCodec returns the codec denoted by the extension of a path, or
+// CodecNone for other extensions
+func DetectCodec(path string) Codec {
+	if codec, ok := extensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return codec
+	}
+	return CodecNone
+}
+
+// TrimExtension removes the compression extension of a path, if any, so
+// that data.csv.gz becomes data.csv
+func TrimExtension(path string) string {
+	if DetectCodec(path) == CodecNone {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// ParseCodec returns the codec of a configured compression for a file.
+// Empty and "auto" select the codec by the extension of the path.
+func ParseCodec(compression, path string) (Codec, error) {
+	name := strings.ToLower(strings.TrimSpace(compression))
+	switch name {
+	case "", CompressionAuto:
+		return DetectCodec(path), nil
+	case string(CodecNone), string(CodecGzip), string(CodecZstd), string(CodecBzip2), string(CodecSnappy):
+		return Codec(name), nil
+	}
+	if codec, ok := extensions["."+name]; ok {
+		return codec, nil
+	}
+	return CodecNone, fmt.Errorf("unsupported compression %q, must be auto, none, gzip, zstd, bzip2 or snappy", compression)
+}
+
+// NewReader returns a reader of the data decompressed from r. Closing it
+// releases the decompressor but not r.
+func NewReader(r io.Reader, codec Codec) (io.ReadCloser, error) {
+	switch codec {
+	case CodecNone:
+		return io.NopCloser(r), nil
+	case CodecGzip:
+		return gzip.NewReader(r)
+	case CodecZstd:
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case CodecBzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case CodecSnappy:
+		return io.NopCloser(s2.NewReader(r)), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", codec)
+	}
+}
+
+// NewWriter returns a writer compressing to w. Closing it completes the
+// compressed stream but does not close w. Compressed streams can be
+// concatenated, so a file can hold several of them one after the other.
+func NewWriter(w io.Writer, codec Codec) (io.WriteCloser, error) {
+	switch codec {
+	case CodecNone:
+		return nopWriteCloser{w}, nil
+	case CodecGzip:
+		return gzip.NewWriter(w), nil
+	case CodecZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	case CodecBzip2:
+		return nil, fmt.Errorf("bzip2 compression is only supported for reading")
+	case CodecSnappy:
+		return s2.NewWriter(w, s2.WriterSnappyCompat(), s2.WriterConcurrency(1)), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", codec)
+	}
+}
+
+// nopWriteCloser is a writer whose Close does nothing
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package fileio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bzip2Data is "id,name\n1,a\n2,b\n" compressed with the bzip2 tool, as
+// the standard library only decompresses bzip2
+var bzip2Data = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xa0, 0x30,
+	0x7b, 0x7e, 0x00, 0x00, 0x06, 0xd9, 0x00, 0x00, 0x10, 0x00, 0x04, 0x30,
+	0x00, 0x36, 0x23, 0x20, 0x00, 0x31, 0x00, 0xd3, 0x4d, 0x04, 0x03, 0x10,
+	0x20, 0x41, 0x45, 0x97, 0x46, 0xf5, 0xed, 0xf8, 0xbb, 0x92, 0x29, 0xc2,
+	0x84, 0x85, 0x01, 0x83, 0xdb, 0xf0,
+}
+
+func TestDetectCodec(t *testing.T) {
+	assert.Equal(t, CodecGzip, DetectCodec("/data/a.csv.gz"))
+	assert.Equal(t, CodecZstd, DetectCodec("a.csv.ZST"))
+	assert.Equal(t, CodecBzip2, DetectCodec("a.csv.bz2"))
+	assert.Equal(t, CodecSnappy, DetectCodec("a.csv.sz"))
+	assert.Equal(t, CodecNone, DetectCodec("a.csv"))
+	assert.Equal(t, "a.csv", TrimExtension("a.csv.gz"))
+	assert.Equal(t, "a.csv", TrimExtension("a.csv"))
+}
+
+func TestParseCodec(t *testing.T) {
+	codec, err := ParseCodec("", "a.csv.gz")
+	require.NoError(t, err)
+	assert.Equal(t, CodecGzip, codec)
+
+	codec, err = ParseCodec("auto", "a.csv")
+	require.NoError(t, err)
+	assert.Equal(t, CodecNone, codec)
+
+	codec, err = ParseCodec("zst", "a.csv.gz")
+	require.NoError(t, err)
+	assert.Equal(t, CodecZstd, codec)
+
+	_, err = ParseCodec("lz4", "a.csv")
+	assert.Error(t, err)
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{CodecNone, CodecGzip, CodecZstd, CodecSnappy} {
+		t.Run(string(codec), func(t *testing.T) {
+			// Concatenated streams read as one
+			var buf bytes.Buffer
+			for _, part := range []string{"id,name\n1,a\n", "2,b\n"} {
+				w, err := NewWriter(&buf, codec)
+				require.NoError(t, err)
+				_, err = io.WriteString(w, part)
+				require.NoError(t, err)
+				require.NoError(t, w.Close())
+			}
+
+			r, err := NewReader(&buf, codec)
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, "id,name\n1,a\n2,b\n", string(data))
+		})
+	}
+}
+
+func TestBzip2(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(bzip2Data), CodecBzip2)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "id,name\n1,a\n2,b\n", string(data))
+
+	_, err = NewWriter(io.Discard, CodecBzip2)
+	assert.Error(t, err)
+}
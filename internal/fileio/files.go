@@ -0,0 +1,138 @@
+package fileio
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ListFiles returns the files a path refers to, in lexical order: the file
+// itself, the files with the given extension in a directory, compressed or
+// not, and, when recursive, in its subdirectories, or the files matching a
+// glob pattern, in which ** matches any number of directories.
+func ListFiles(path, ext string, recursive bool) ([]string, error) {
+	if hasMeta(path) {
+		return globFiles(path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	pattern := filepath.Join(path, "*"+ext+"*")
+	if recursive {
+		pattern = filepath.Join(path, "**", "*"+ext+"*")
+	}
+	matches, err := globFiles(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the files with the extension, compressed or not
+	var files []string
+	for _, file := range matches {
+		if filepath.Ext(TrimExtension(file)) == ext {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+	return files, nil
+}
+
+// globFiles returns the regular files matching a pattern, in lexical order
+func globFiles(pattern string) ([]string, error) {
+	// Walk from the longest leading part without wildcards
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	base := 0
+	for base < len(segments)-1 && !hasMeta(segments[base]) {
+		base++
+	}
+	root := filepath.FromSlash(strings.Join(segments[:base], "/"))
+	switch {
+	case root == "" && strings.HasPrefix(filepath.ToSlash(pattern), "/"):
+		root = string(filepath.Separator)
+	case root == "":
+		root = "."
+	}
+	segments = segments[base:]
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+
+		if entry.IsDir() {
+			if !matchPrefix(segments, parts) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Type().IsRegular() && match(segments, parts) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// match reports whether the segments of a path match those of a pattern
+func match(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		return match(pattern[1:], parts) || (len(parts) > 0 && match(pattern, parts[1:]))
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	ok, _ := filepath.Match(pattern[0], parts[0])
+	return ok && match(pattern[1:], parts[1:])
+}
+
+// matchPrefix reports whether files below a directory can match a pattern
+func matchPrefix(pattern, parts []string) bool {
+	if len(parts) == 0 {
+		return true
+	}
+	if len(pattern) == 0 {
+		return false
+	}
+	if pattern[0] == "**" {
+		return true
+	}
+	ok, _ := filepath.Match(pattern[0], parts[0])
+	return ok && matchPrefix(pattern[1:], parts[1:])
+}
+
+// hasMeta reports whether a path contains glob wildcards
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
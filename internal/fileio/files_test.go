@@ -0,0 +1,64 @@
+package fileio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates files with the given contents below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"b.csv":            "",
+		"a.csv":            "",
+		"notes.txt":        "",
+		"2024/01/c.csv":    "",
+		"2024/02/d.csv":    "",
+		"2024/02/e.txt":    "",
+		"other/2024/f.csv": "",
+	})
+	rel := func(files []string) []string {
+		for i, file := range files {
+			files[i], _ = filepath.Rel(dir, file)
+			files[i] = filepath.ToSlash(files[i])
+		}
+		return files
+	}
+
+	tests := []struct {
+		path      string
+		recursive bool
+		expected  []string
+	}{
+		{"a.csv", false, []string{"a.csv"}},
+		{".", false, []string{"a.csv", "b.csv"}},
+		{".", true, []string{"2024/01/c.csv", "2024/02/d.csv", "a.csv", "b.csv", "other/2024/f.csv"}},
+		{"*.csv", false, []string{"a.csv", "b.csv"}},
+		{"2024/*/*.csv", false, []string{"2024/01/c.csv", "2024/02/d.csv"}},
+		{"2024/**", false, []string{"2024/01/c.csv", "2024/02/d.csv", "2024/02/e.txt"}},
+		{"**/2024/**/*.csv", false, []string{"2024/01/c.csv", "2024/02/d.csv", "other/2024/f.csv"}},
+	}
+	for _, tt := range tests {
+		files, err := ListFiles(filepath.Join(dir, tt.path), ".csv", tt.recursive)
+		require.NoError(t, err, tt.path)
+		assert.Equal(t, tt.expected, rel(files), tt.path)
+	}
+
+	_, err := ListFiles(filepath.Join(dir, "*.parquet"), ".csv", false)
+	assert.Error(t, err)
+	_, err = ListFiles(filepath.Join(dir, "missing.csv"), ".csv", false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,359 @@
+package fileio
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// OutputConfig configures the files written by an Output
+type OutputConfig struct {
+	// Path of the file. When rolling, it holds a verb such as %05d that is
+	// replaced with the index of each file, starting at 0.
+	Path string
+
+	// Compression is a codec name, or auto to select it by the extension
+	// of the path
+	Compression string
+
+	// RollSize and RollRecords start a new file once the current one holds
+	// that many bytes or records. Zero disables the limit.
+	RollSize    int64
+	RollRecords int64
+
+	// Append continues an existing file instead of replacing it. It cannot
+	// be combined with rolling.
+	Append bool
+}
+
+// Output writes the files of a file-based output plugin.
+//
+// Compressed data is written as a new compressed stream after each Flush,
+// so that the flushed size of a file is a position the output can resume
+// from by truncating the file. Rolled files are written under a temporary
+// name, hidden in the same directory, and renamed to their final name when
+// complete, so that readers never see a partial file.
+type Output struct {
+	config  OutputConfig
+	codec   Codec
+	rolling bool
+	index   int // Index of the current file when rolling
+	file    *os.File
+	stream  io.WriteCloser // Compressor of the open file, nil between streams
+	size    int64          // Bytes in the open file
+	records int64          // Records in the open file
+	written int64          // Bytes written to all files
+}
+
+// NewOutput creates the output described by config
+func NewOutput(config OutputConfig) (*Output, error) {
+	o := &Output{
+		config:  config,
+		rolling: config.RollSize > 0 || config.RollRecords > 0,
+	}
+
+	codec, err := ParseCodec(config.Compression, config.Path)
+	if err != nil {
+		return nil, err
+	}
+	if codec == CodecBzip2 {
+		return nil, fmt.Errorf("bzip2 compression is only supported for reading")
+	}
+	o.codec = codec
+
+	if o.rolling {
+		if config.Append {
+			return nil, fmt.Errorf("append cannot be combined with rolling")
+		}
+		name := filepath.Base(config.Path)
+		if !strings.Contains(name, "%") || strings.Contains(fmt.Sprintf(config.Path, 0), "%!") {
+			return nil, fmt.Errorf("file name of rolled files %s must hold one verb for the file index, such as %%05d", config.Path)
+		}
+	}
+	return o, nil
+}
+
+// Codec returns the codec the files are compressed with
+func (o *Output) Codec() Codec {
+	return o.codec
+}
+
+// Open opens the output. A nil position starts a new output, replacing the
+// file or, when rolling, the files of an earlier run. Otherwise the output
+// continues at a position returned by Position, dropping anything written
+// after it.
+func (o *Output) Open(position map[string]interface{}) error {
+	o.written = 0
+	if position == nil {
+		if o.rolling {
+			o.index = 0
+			return o.removeFrom(0)
+		}
+
+		flags := os.O_CREATE | os.O_RDWR | os.O_TRUNC
+		if o.config.Append {
+			flags = os.O_CREATE | os.O_RDWR | os.O_APPEND
+		}
+		file, err := os.OpenFile(o.config.Path, flags, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to stat file: %w", err)
+		}
+		o.file, o.size = file, info.Size()
+		return nil
+	}
+
+	offset, ok := types.ToInt64(position["offset"])
+	if !ok {
+		return fmt.Errorf("invalid position %v", position)
+	}
+	index, ok := types.ToInt64(position["file"])
+	if !ok {
+		index = 0
+	}
+
+	path := o.config.Path
+	if o.rolling {
+		o.index = int(index)
+		if err := o.removeFrom(o.index + 1); err != nil {
+			return err
+		}
+
+		// The file may have been completed after the position was taken
+		path = o.tempPath(o.index)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if err := os.Rename(o.finalPath(o.index), path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to reopen file: %w", err)
+			}
+		}
+		if offset == 0 {
+			os.Remove(path)
+			return nil
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	if err := truncateAt(file, offset); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to resume file: %w", err)
+	}
+	o.file, o.size = file, offset
+	return nil
+}
+
+// Write compresses and writes data to the current file, creating it when
+// rolling
+func (o *Output) Write(b []byte) (int, error) {
+	if o.file == nil {
+		if !o.rolling {
+			return 0, fmt.Errorf("output is closed")
+		}
+		file, err := os.OpenFile(o.tempPath(o.index), os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create file: %w", err)
+		}
+		o.file, o.size, o.records = file, 0, 0
+	}
+	if o.stream == nil {
+		stream, err := NewWriter(fileWriter{o}, o.codec)
+		if err != nil {
+			return 0, err
+		}
+		o.stream = stream
+	}
+	return o.stream.Write(b)
+}
+
+// ReadAt reads back the current file, as written. Formats with a footer,
+// such as Parquet, use it to rebuild the footer of a resumed file.
+func (o *Output) ReadAt(b []byte, off int64) (int, error) {
+	if o.file == nil {
+		return 0, io.EOF
+	}
+	return o.file.ReadAt(b, off)
+}
+
+// AddRecords counts records written to the current file
+func (o *Output) AddRecords(n int64) {
+	o.records += n
+}
+
+// Full reports whether the current file has reached a rolling limit. As
+// data is buffered before compression, the size of a file is only known
+// up to the size of the buffers.
+func (o *Output) Full() bool {
+	if !o.rolling {
+		return false
+	}
+	return (o.config.RollRecords > 0 && o.records >= o.config.RollRecords) ||
+		(o.config.RollSize > 0 && o.size >= o.config.RollSize)
+}
+
+// Empty reports whether nothing was written to the current file yet
+func (o *Output) Empty() bool {
+	return o.file == nil || o.size == 0
+}
+
+// Roll completes the current file and moves on to the next one, which is
+// created on the next write
+func (o *Output) Roll() error {
+	if !o.rolling || o.file == nil {
+		return nil
+	}
+	if err := o.closeFile(); err != nil {
+		return err
+	}
+	o.index++
+	o.size, o.records = 0, 0
+	return nil
+}
+
+// Flush completes the current compressed stream and syncs the file
+func (o *Output) Flush() error {
+	if err := o.endStream(); err != nil {
+		return err
+	}
+	if o.file != nil {
+		if err := o.file.Sync(); err != nil {
+			return fmt.Errorf("sync error: %w", err)
+		}
+	}
+	return nil
+}
+
+// Position returns the position of the flushed output: the size of the
+// current file and, when rolling, its index
+func (o *Output) Position() map[string]interface{} {
+	position := map[string]interface{}{"offset": o.size}
+	if o.rolling {
+		position["file"] = o.index
+	}
+	return position
+}
+
+// BytesWritten returns the number of bytes written to all files since the
+// output was opened
+func (o *Output) BytesWritten() int64 {
+	return o.written
+}
+
+// Close completes the current file. Calling Close more than once is a
+// no-op.
+func (o *Output) Close() error {
+	if o.file == nil {
+		return nil
+	}
+	return o.closeFile()
+}
+
+// closeFile completes the compressed stream, closes the file and, when
+// rolling, gives it its final name
+func (o *Output) closeFile() error {
+	err := o.endStream()
+	if err == nil {
+		err = o.file.Sync()
+	}
+	if closeErr := o.file.Close(); err == nil {
+		err = closeErr
+	}
+	o.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	if o.rolling {
+		if err := os.Rename(o.tempPath(o.index), o.finalPath(o.index)); err != nil {
+			return fmt.Errorf("failed to complete file: %w", err)
+		}
+	}
+	return nil
+}
+
+// endStream completes the current compressed stream
+func (o *Output) endStream() error {
+	if o.stream == nil {
+		return nil
+	}
+	err := o.stream.Close()
+	o.stream = nil
+	if err != nil {
+		return fmt.Errorf("failed to complete compressed stream: %w", err)
+	}
+	return nil
+}
+
+// finalPath returns the path of the rolled file with the given index
+func (o *Output) finalPath(index int) string {
+	return fmt.Sprintf(o.config.Path, index)
+}
+
+// tempPath returns the path the rolled file with the given index is
+// written to until it is complete
+func (o *Output) tempPath(index int) string {
+	dir, name := filepath.Split(o.finalPath(index))
+	return filepath.Join(dir, "."+name+".tmp")
+}
+
+// removeFrom removes the rolled files from the given index on, complete or
+// not
+func (o *Output) removeFrom(index int) error {
+	dir, pattern := filepath.Split(o.config.Path)
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".tmp") {
+			name = strings.TrimSuffix(strings.TrimPrefix(name, "."), ".tmp")
+		}
+		var i int
+		if _, err := fmt.Sscanf(name, pattern, &i); err != nil || fmt.Sprintf(pattern, i) != name || i < index {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove file: %w", err)
+		}
+	}
+	return nil
+}
+
+// fileWriter writes to the open file of an output and counts the bytes
+type fileWriter struct {
+	o *Output
+}
+
+func (w fileWriter) Write(b []byte) (int, error) {
+	n, err := w.o.file.Write(b)
+	w.o.size += int64(n)
+	w.o.written += int64(n)
+	return n, err
+}
+
+// truncateAt cuts the file to offset and continues writing there
+func truncateAt(file *os.File, offset int64) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < offset {
+		return fmt.Errorf("file is shorter (%d bytes) than the checkpoint (%d bytes)", info.Size(), offset)
+	}
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err = file.Seek(offset, io.SeekStart)
+	return err
+}
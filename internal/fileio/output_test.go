@@ -0,0 +1,164 @@
+package fileio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readFile returns the decompressed contents of a file
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	r, err := NewReader(file, DetectCodec(path))
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// writeRecords writes numbered lines, rolling files when full
+func writeRecords(t *testing.T, o *Output, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		if o.Full() {
+			require.NoError(t, o.Roll())
+		}
+		_, err := fmt.Fprintf(o, "%d\n", i)
+		require.NoError(t, err)
+		o.AddRecords(1)
+	}
+}
+
+// roundTrip encodes and decodes a position the way checkpoint storage does
+func roundTrip(t *testing.T, position map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(position)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return decoded
+}
+
+func TestOutputRollRecords(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out-%05d.csv.gz")
+
+	// Files of an earlier run are replaced
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "out-00004.csv.gz"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "out-00000.csv.gz"), nil, 0644))
+
+	o, err := NewOutput(OutputConfig{Path: path, RollRecords: 2})
+	require.NoError(t, err)
+	assert.Equal(t, CodecGzip, o.Codec())
+	require.NoError(t, o.Open(nil))
+
+	writeRecords(t, o, 0, 3)
+
+	// The first file is complete, the second is still hidden
+	assert.Equal(t, "0\n1\n", readFile(t, filepath.Join(dir, "out-00000.csv.gz")))
+	assert.FileExists(t, filepath.Join(dir, ".out-00001.csv.gz.tmp"))
+	assert.NoFileExists(t, filepath.Join(dir, "out-00001.csv.gz"))
+	assert.NoFileExists(t, filepath.Join(dir, "out-00004.csv.gz"))
+
+	writeRecords(t, o, 3, 5)
+	require.NoError(t, o.Close())
+	require.NoError(t, o.Close())
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Len(t, matches, 3)
+	assert.Equal(t, "2\n3\n", readFile(t, filepath.Join(dir, "out-00001.csv.gz")))
+	assert.Equal(t, "4\n", readFile(t, filepath.Join(dir, "out-00002.csv.gz")))
+}
+
+func TestOutputRollSize(t *testing.T) {
+	dir := t.TempDir()
+	o, err := NewOutput(OutputConfig{Path: filepath.Join(dir, "out-%d.csv"), RollSize: 10})
+	require.NoError(t, err)
+	require.NoError(t, o.Open(nil))
+
+	writeRecords(t, o, 100, 106)
+	require.NoError(t, o.Close())
+
+	assert.Equal(t, "100\n101\n102\n", readFile(t, filepath.Join(dir, "out-0.csv")))
+	assert.Equal(t, "103\n104\n105\n", readFile(t, filepath.Join(dir, "out-1.csv")))
+	assert.Equal(t, int64(24), o.BytesWritten())
+}
+
+func TestOutputResume(t *testing.T) {
+	for _, name := range []string{"out.csv", "out.csv.gz", "out.csv.zst", "out.csv.sz", "out-%03d.csv.gz"} {
+		t.Run(strings.ReplaceAll(name, "%", ""), func(t *testing.T) {
+			dir := t.TempDir()
+			config := OutputConfig{Path: filepath.Join(dir, name)}
+			if name == "out-%03d.csv.gz" {
+				config.RollRecords = 2
+			}
+
+			// The first run flushes after three records, then writes two
+			// more and fails before the next flush
+			o, err := NewOutput(config)
+			require.NoError(t, err)
+			require.NoError(t, o.Open(nil))
+			writeRecords(t, o, 0, 3)
+			require.NoError(t, o.Flush())
+			position := roundTrip(t, o.Position())
+			writeRecords(t, o, 3, 5)
+			require.NoError(t, o.Flush())
+			require.NoError(t, o.Close())
+
+			// The resumed run drops them and writes the rest
+			o, err = NewOutput(config)
+			require.NoError(t, err)
+			require.NoError(t, o.Open(position))
+			assert.False(t, o.Empty())
+			writeRecords(t, o, 3, 6)
+			require.NoError(t, o.Close())
+
+			if config.RollRecords == 0 {
+				assert.Equal(t, "0\n1\n2\n3\n4\n5\n", readFile(t, config.Path))
+				return
+			}
+			var all string
+			for i := 0; i < 3; i++ {
+				all += readFile(t, filepath.Join(dir, fmt.Sprintf(name, i)))
+			}
+			assert.Equal(t, "0\n1\n2\n3\n4\n5\n", all)
+			assert.NoFileExists(t, filepath.Join(dir, fmt.Sprintf(name, 3)))
+		})
+	}
+}
+
+func TestOutputAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv.gz")
+	for _, lines := range []string{"a\n", "b\n"} {
+		o, err := NewOutput(OutputConfig{Path: path, Append: true})
+		require.NoError(t, err)
+		require.NoError(t, o.Open(nil))
+		_, err = io.WriteString(o, lines)
+		require.NoError(t, err)
+		require.NoError(t, o.Close())
+	}
+	assert.Equal(t, "a\nb\n", readFile(t, path))
+}
+
+func TestNewOutputErrors(t *testing.T) {
+	for _, config := range []OutputConfig{
+		{Path: "out.csv.bz2"},
+		{Path: "out.csv", Compression: "lz4"},
+		{Path: "out.csv", RollRecords: 10},
+		{Path: "out-%d-%d.csv", RollRecords: 10},
+		{Path: "out-%d.csv", RollRecords: 10, Append: true},
+	} {
+		_, err := NewOutput(config)
+		assert.Error(t, err, "%+v", config)
+	}
+}
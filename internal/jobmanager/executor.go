@@ -0,0 +1,339 @@
+package jobmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/atlanssia/fustgo/internal/checkpoint"
+	"github.com/atlanssia/fustgo/internal/config"
+	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/metrics"
+	"github.com/atlanssia/fustgo/internal/models"
+	"github.com/atlanssia/fustgo/internal/pipeline"
+	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/internal/scheduler"
+	"github.com/atlanssia/fustgo/internal/tracing"
+)
+
+// Ensure Executor satisfies the scheduler contract
+var _ scheduler.JobExecutor = (*Executor)(nil)
+
+// Executor runs jobs by building and executing their pipelines
+type Executor struct {
+	mu        sync.Mutex
+	manager   *Manager
+	registry  *plugin.Registry
+	converter *config.Converter
+	config    *ExecutorConfig
+	running   map[string]context.CancelFunc // jobID -> pipeline cancel function
+	wg        sync.WaitGroup
+	stopping  bool
+}
+
+// ExecutorConfig holds configuration for the job executor
+type ExecutorConfig struct {
+	WorkerID          string
+	MaxConcurrentJobs int
+	CheckpointConfig  *checkpoint.Config
+	LogCapture        *LogCapture // Saves the log lines of executions, when set
+}
+
+// DefaultExecutorConfig returns default executor configuration
+func DefaultExecutorConfig() *ExecutorConfig {
+	return &ExecutorConfig{
+		MaxConcurrentJobs: 5,
+		CheckpointConfig:  checkpoint.DefaultConfig(),
+	}
+}
+
+// NewExecutor creates a new job executor
+func NewExecutor(manager *Manager, registry *plugin.Registry, cfg *ExecutorConfig) *Executor {
+	if cfg == nil {
+		cfg = DefaultExecutorConfig()
+	}
+
+	return &Executor{
+		manager:   manager,
+		registry:  registry,
+		converter: config.NewConverter(registry),
+		config:    cfg,
+		running:   make(map[string]context.CancelFunc),
+	}
+}
+
+// Execute runs a job synchronously and returns when its pipeline ends.
+// It implements scheduler.JobExecutor.
+func (e *Executor) Execute(ctx context.Context, jobID string) error {
+	run, err := e.start(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	return run()
+}
+
+// Submit starts a job and runs its pipeline in the background. The trace
+// and request ID of ctx are linked to the execution; cancelling ctx does
+// not stop it.
+func (e *Executor) Submit(ctx context.Context, jobID string) error {
+	run, err := e.start(context.WithoutCancel(ctx), jobID)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := run(); err != nil {
+			logger.Error("Job %s failed: %v", jobID, err)
+		}
+	}()
+	return nil
+}
+
+// RunningJobs returns the number of pipelines currently executing
+func (e *Executor) RunningJobs() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.running)
+}
+
+// Shutdown stops all running pipelines and waits for them to save their
+// checkpoints. Interrupted jobs are left paused so they can be resumed.
+func (e *Executor) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	e.stopping = true
+	for jobID, cancel := range e.running {
+		logger.Info("Stopping running job %s", jobID)
+		cancel()
+	}
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.Info("All running jobs stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for running jobs: %w", ctx.Err())
+	}
+}
+
+// start moves the job into the running state and returns a function that
+// executes its pipeline
+func (e *Executor) start(ctx context.Context, jobID string) (func() error, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopping {
+		return nil, fmt.Errorf("executor is shutting down")
+	}
+	if _, exists := e.running[jobID]; exists {
+		return nil, fmt.Errorf("job %s is already running", jobID)
+	}
+	if e.config.MaxConcurrentJobs > 0 && len(e.running) >= e.config.MaxConcurrentJobs {
+		return nil, fmt.Errorf("maximum concurrent jobs reached (%d)", e.config.MaxConcurrentJobs)
+	}
+
+	job, err := e.manager.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Draft and finished jobs go through ready before they can run
+	switch job.Status {
+	case models.JobStatusDraft, models.JobStatusCompleted, models.JobStatusFailed:
+		job.Status = models.JobStatusReady
+		if err := e.manager.UpdateJob(job); err != nil {
+			return nil, fmt.Errorf("failed to reset job %s: %w", jobID, err)
+		}
+	}
+
+	if err := e.manager.StartJob(jobID); err != nil {
+		return nil, err
+	}
+
+	jobCtx, err := e.manager.GetJobContext(jobID)
+	if err != nil {
+		e.manager.FinishJob(jobID, models.JobStatusFailed)
+		return nil, err
+	}
+
+	// The pipeline stops when either the job or the caller is cancelled
+	runCtx, cancel := context.WithCancel(jobCtx)
+	stopAfter := context.AfterFunc(ctx, cancel)
+
+	// Executions are traced on their own, linked to the trace of the caller
+	runCtx = tracing.WithRequestID(runCtx, tracing.RequestID(ctx))
+	caller := trace.LinkFromContext(ctx)
+
+	e.running[jobID] = cancel
+	e.wg.Add(1)
+
+	return func() error {
+		defer e.wg.Done()
+		defer stopAfter()
+		defer cancel()
+
+		execErr := e.runPipeline(runCtx, job, caller)
+
+		e.mu.Lock()
+		delete(e.running, jobID)
+		stopping := e.stopping
+		e.mu.Unlock()
+
+		status := models.JobStatusCompleted
+		switch {
+		case execErr == nil:
+		case stopping:
+			status = models.JobStatusPaused
+		default:
+			status = models.JobStatusFailed
+		}
+
+		if err := e.manager.FinishJob(jobID, status); err != nil {
+			logger.Error("Failed to finish job %s: %v", jobID, err)
+		}
+		return execErr
+	}, nil
+}
+
+// runPipeline runs the job's pipeline and records the run as an execution,
+// traced in a span linked to the caller
+func (e *Executor) runPipeline(ctx context.Context, job *models.Job, caller trace.Link) error {
+	exec := &models.Execution{
+		ExecutionID: uuid.New().String(),
+		JobID:       job.JobID,
+		Status:      models.ExecutionStatusRunning,
+		StartTime:   time.Now(),
+		WorkerID:    e.config.WorkerID,
+	}
+
+	spanOptions := []trace.SpanStartOption{trace.WithAttributes(
+		attribute.String("fustgo.job.id", job.JobID),
+		attribute.String("fustgo.job.name", job.JobName),
+		attribute.String("fustgo.execution.id", exec.ExecutionID),
+		attribute.String("fustgo.worker.id", exec.WorkerID),
+	)}
+	if requestID := tracing.RequestID(ctx); requestID != "" {
+		spanOptions = append(spanOptions, trace.WithAttributes(attribute.String("fustgo.request_id", requestID)))
+	}
+	if caller.SpanContext.IsValid() {
+		spanOptions = append(spanOptions, trace.WithLinks(caller))
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "execution", spanOptions...)
+
+	if err := e.manager.CreateExecution(exec); err != nil {
+		tracing.End(span, err)
+		return err
+	}
+
+	p, execErr := e.executePipeline(ctx, job, exec.ExecutionID)
+
+	// Record the outcome of the execution
+	endTime := time.Now()
+	exec.EndTime = &endTime
+	if p != nil {
+		summary := p.GetSummary()
+		exec.RecordsRead = summary.RecordsRead
+		exec.RecordsWritten = summary.RecordsWritten
+		exec.RecordsFailed = summary.RecordsFailed
+		exec.BytesTransferred = summary.BytesWritten
+		exec.SchemaEvents = p.GetSchemaEvents()
+	}
+
+	switch {
+	case execErr == nil:
+		exec.Status = models.ExecutionStatusCompleted
+	case ctx.Err() != nil:
+		exec.Status = models.ExecutionStatusCancelled
+		exec.ErrorMessage = execErr.Error()
+	default:
+		exec.Status = models.ExecutionStatusFailed
+		exec.ErrorMessage = execErr.Error()
+	}
+
+	log := logger.With("job_id", job.JobID, "execution_id", exec.ExecutionID)
+	if execErr != nil {
+		log.Error("Execution %s of job %s failed: %v", exec.ExecutionID, job.JobID, execErr)
+	}
+	log.Info("Execution %s of job %s %s: %d read, %d written, %d failed",
+		exec.ExecutionID, job.JobID, exec.Status, exec.RecordsRead, exec.RecordsWritten, exec.RecordsFailed)
+
+	// Save the captured lines before the execution shows as ended, so that
+	// readers following the logs get all of them
+	if e.config.LogCapture != nil {
+		e.config.LogCapture.Finish(exec.ExecutionID)
+	}
+	if err := e.manager.UpdateExecution(exec); err != nil {
+		log.Error("Failed to record execution %s: %v", exec.ExecutionID, err)
+	}
+	metrics.JobExecutions.WithLabelValues(string(exec.Status)).Inc()
+	metrics.JobExecutionDuration.Observe(endTime.Sub(exec.StartTime).Seconds())
+
+	span.SetAttributes(
+		attribute.String("fustgo.execution.status", string(exec.Status)),
+		attribute.Int64("fustgo.records.read", exec.RecordsRead),
+		attribute.Int64("fustgo.records.written", exec.RecordsWritten),
+		attribute.Int64("fustgo.records.failed", exec.RecordsFailed),
+	)
+	tracing.End(span, execErr)
+	return execErr
+}
+
+// checkpointConfig returns the checkpoint configuration of an execution.
+// Database storage saves to the execution in the job manager's store unless
+// the configuration names another store.
+func (e *Executor) checkpointConfig(executionID string) *checkpoint.Config {
+	if e.config.CheckpointConfig == nil {
+		return nil
+	}
+
+	cfg := *e.config.CheckpointConfig
+	cfg.ExecutionID = executionID
+	if cfg.StorageType == "database" && cfg.Store == nil {
+		cfg.Store = e.manager.store
+	}
+	return &cfg
+}
+
+// executePipeline builds the job's pipeline from its configuration and runs
+// it. The returned pipeline is nil if it could not be built.
+func (e *Executor) executePipeline(ctx context.Context, job *models.Job, executionID string) (*pipeline.ConcurrentPipeline, error) {
+	pipelineConfig, err := e.converter.ParseYAML(job.ConfigYAML)
+	if err != nil {
+		return nil, err
+	}
+
+	log := logger.With("job_id", job.JobID, "execution_id", executionID)
+	session, err := e.registry.NewSession(executionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := session.Close(); err != nil {
+			log.Warn("Failed to release plugins for execution %s: %v", executionID, err)
+		}
+	}()
+
+	settings := pipeline.DefaultConcurrentConfig()
+	settings.JobID = job.JobID
+	settings.ExecutionID = executionID
+	settings.CheckpointConfig = e.checkpointConfig(executionID)
+
+	p, err := e.converter.BuildConcurrentPipeline(pipelineConfig, session, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Running pipeline for job %s (%s), execution %s", job.JobID, job.JobName, executionID)
+	return p, p.Execute(ctx)
+}
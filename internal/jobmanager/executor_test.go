@@ -0,0 +1,319 @@
+package jobmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/models"
+	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/pkg/types"
+	"github.com/atlanssia/fustgo/plugins/processor/transform"
+)
+
+// Mock plugins for executor tests
+
+type mockInputPlugin struct {
+	batches int
+	read    int
+	delay   time.Duration
+	drift   bool // The last batch has an extra column
+}
+
+func (m *mockInputPlugin) Name() string           { return "mock-input" }
+func (m *mockInputPlugin) Type() types.PluginType { return types.PluginTypeInput }
+func (m *mockInputPlugin) Initialize(config map[string]interface{}) error {
+	if batches, ok := config["batches"].(int); ok {
+		m.batches = batches
+	}
+	if delay, ok := config["delay"].(string); ok {
+		m.delay, _ = time.ParseDuration(delay)
+	}
+	m.drift, _ = config["drift"].(bool)
+	return nil
+}
+func (m *mockInputPlugin) Validate() error { return nil }
+func (m *mockInputPlugin) Close() error    { return nil }
+func (m *mockInputPlugin) GetMetadata() types.PluginMetadata {
+	return types.PluginMetadata{Name: "mock-input", Type: types.PluginTypeInput}
+}
+func (m *mockInputPlugin) Connect() error { return nil }
+func (m *mockInputPlugin) ReadBatch(batchSize int) (*types.DataBatch, error) {
+	time.Sleep(m.delay)
+	if m.read >= m.batches {
+		return nil, io.EOF
+	}
+	m.read++
+
+	schema := types.Schema{Columns: []types.Column{{Name: "id", DataType: types.DataTypeInt}}}
+	if m.drift && m.read == m.batches {
+		schema.Columns = append(schema.Columns, types.Column{Name: "extra", DataType: types.DataTypeString})
+	}
+	records := make([]types.Record, 10)
+	for i := range records {
+		records[i] = types.Record{Values: []interface{}{i, "x"}[:len(schema.Columns)]}
+	}
+	return &types.DataBatch{
+		Schema:  schema,
+		Records: records,
+	}, nil
+}
+func (m *mockInputPlugin) HasNext() bool                { return m.read < m.batches }
+func (m *mockInputPlugin) GetProgress() *types.Progress { return &types.Progress{} }
+
+type mockOutputPlugin struct {
+	fail  bool
+	stats types.WriteStatistics
+}
+
+func (m *mockOutputPlugin) Name() string           { return "mock-output" }
+func (m *mockOutputPlugin) Type() types.PluginType { return types.PluginTypeOutput }
+func (m *mockOutputPlugin) Initialize(config map[string]interface{}) error {
+	m.fail, _ = config["fail"].(bool)
+	return nil
+}
+func (m *mockOutputPlugin) Validate() error { return nil }
+func (m *mockOutputPlugin) Close() error    { return nil }
+func (m *mockOutputPlugin) GetMetadata() types.PluginMetadata {
+	return types.PluginMetadata{Name: "mock-output", Type: types.PluginTypeOutput}
+}
+func (m *mockOutputPlugin) Connect() error { return nil }
+func (m *mockOutputPlugin) WriteBatch(data *types.DataBatch) error {
+	if m.fail {
+		return fmt.Errorf("target unavailable")
+	}
+	m.stats.RecordsWritten += int64(data.Size())
+	m.stats.BytesWritten += int64(data.Size() * 8)
+	return nil
+}
+func (m *mockOutputPlugin) Flush() error                               { return nil }
+func (m *mockOutputPlugin) GetWriteStatistics() *types.WriteStatistics { return &m.stats }
+
+func setupTestExecutor(t *testing.T) (*Manager, *Executor) {
+	registry := plugin.NewRegistry()
+	registry.RegisterInput("mock", func() types.InputPlugin { return &mockInputPlugin{} })
+	registry.RegisterOutput("mock", func() types.OutputPlugin { return &mockOutputPlugin{} })
+	registry.RegisterProcessor("transform", func() types.ProcessorPlugin { return &transform.TransformProcessor{} })
+
+	manager := setupTestManager(t)
+	config := DefaultExecutorConfig()
+	config.WorkerID = "worker-1"
+	config.CheckpointConfig = nil
+
+	return manager, NewExecutor(manager, registry, config)
+}
+
+func createExecutorTestJob(t *testing.T, manager *Manager, configYAML string) *models.Job {
+	job := createTestJob()
+	job.Status = models.JobStatusReady
+	job.ConfigYAML = configYAML
+	require.NoError(t, manager.CreateJob(job))
+	return job
+}
+
+func TestExecutorExecute(t *testing.T) {
+	manager, executor := setupTestExecutor(t)
+	job := createExecutorTestJob(t, manager, `
+input:
+  type: mock
+  config:
+    batches: 3
+output:
+  type: mock
+`)
+
+	err := executor.Execute(context.Background(), job.JobID)
+	require.NoError(t, err)
+
+	retrieved, err := manager.GetJob(job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCompleted, retrieved.Status)
+
+	executions, err := manager.ListExecutions(job.JobID, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+
+	exec := executions[0]
+	assert.Equal(t, models.ExecutionStatusCompleted, exec.Status)
+	assert.Equal(t, int64(30), exec.RecordsRead)
+	assert.Equal(t, int64(30), exec.RecordsWritten)
+	assert.Equal(t, int64(240), exec.BytesTransferred)
+	assert.Equal(t, "worker-1", exec.WorkerID)
+	assert.Empty(t, exec.ErrorMessage)
+	assert.NotNil(t, exec.EndTime)
+	assert.Equal(t, 0, executor.RunningJobs())
+}
+
+func TestExecutorExecuteFailure(t *testing.T) {
+	manager, executor := setupTestExecutor(t)
+	job := createExecutorTestJob(t, manager, `
+input:
+  type: mock
+  config:
+    batches: 1
+output:
+  type: mock
+  config:
+    fail: true
+`)
+
+	err := executor.Execute(context.Background(), job.JobID)
+	require.Error(t, err)
+
+	retrieved, err := manager.GetJob(job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, retrieved.Status)
+
+	executions, err := manager.ListExecutions(job.JobID, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, models.ExecutionStatusFailed, executions[0].Status)
+	assert.Contains(t, executions[0].ErrorMessage, "target unavailable")
+}
+
+func TestExecutorRecordsSchemaEvents(t *testing.T) {
+	manager, executor := setupTestExecutor(t)
+	job := createExecutorTestJob(t, manager, `
+input:
+  type: mock
+  config:
+    batches: 2
+    drift: true
+output:
+  type: mock
+settings:
+  schema_policy:
+    mode: drop_unknown
+`)
+
+	require.NoError(t, executor.Execute(context.Background(), job.JobID))
+
+	executions, err := manager.ListExecutions(job.JobID, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, int64(20), executions[0].RecordsWritten)
+	require.Len(t, executions[0].SchemaEvents, 1)
+	event := executions[0].SchemaEvents[0]
+	assert.Equal(t, "column_added", event.Change)
+	assert.Equal(t, "extra", event.Column)
+	assert.Equal(t, "dropped", event.Action)
+
+	// The default policy fails the run
+	job = createExecutorTestJob(t, manager, `
+input:
+  type: mock
+  config:
+    batches: 2
+    drift: true
+output:
+  type: mock
+`)
+	require.Error(t, executor.Execute(context.Background(), job.JobID))
+	executions, err = manager.ListExecutions(job.JobID, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Contains(t, executions[0].ErrorMessage, "schema drift")
+	require.Len(t, executions[0].SchemaEvents, 1)
+	assert.Equal(t, "failed", executions[0].SchemaEvents[0].Action)
+}
+
+func TestExecutorExecuteUnknownPlugin(t *testing.T) {
+	manager, executor := setupTestExecutor(t)
+	job := createExecutorTestJob(t, manager, `
+input:
+  type: missing
+output:
+  type: mock
+`)
+
+	err := executor.Execute(context.Background(), job.JobID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "input plugin not found")
+
+	executions, err := manager.ListExecutions(job.JobID, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, models.ExecutionStatusFailed, executions[0].Status)
+}
+
+func TestExecutorInvalidProcessor(t *testing.T) {
+	manager, executor := setupTestExecutor(t)
+	job := createExecutorTestJob(t, manager, `
+input:
+  type: mock
+  config:
+    batches: 1
+processors:
+  - type: transform
+output:
+  type: mock
+`)
+
+	// A transform without operations fails before reading anything
+	err := executor.Execute(context.Background(), job.JobID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid processor plugin 'transform' configuration")
+
+	executions, err := manager.ListExecutions(job.JobID, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, models.ExecutionStatusFailed, executions[0].Status)
+	assert.Equal(t, int64(0), executions[0].RecordsRead)
+}
+
+func TestExecutorRerunsCompletedJob(t *testing.T) {
+	manager, executor := setupTestExecutor(t)
+	job := createExecutorTestJob(t, manager, `
+input:
+  type: mock
+  config:
+    batches: 1
+output:
+  type: mock
+`)
+
+	require.NoError(t, executor.Execute(context.Background(), job.JobID))
+	require.NoError(t, executor.Execute(context.Background(), job.JobID))
+
+	executions, err := manager.ListExecutions(job.JobID, 10)
+	require.NoError(t, err)
+	assert.Len(t, executions, 2)
+}
+
+func TestExecutorShutdownPausesRunningJobs(t *testing.T) {
+	manager, executor := setupTestExecutor(t)
+	job := createExecutorTestJob(t, manager, `
+input:
+  type: mock
+  config:
+    batches: 1000
+    delay: 20ms
+output:
+  type: mock
+`)
+
+	require.NoError(t, executor.Submit(context.Background(), job.JobID))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, executor.RunningJobs())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, executor.Shutdown(ctx))
+
+	retrieved, err := manager.GetJob(job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPaused, retrieved.Status)
+
+	executions, err := manager.ListExecutions(job.JobID, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, models.ExecutionStatusCancelled, executions[0].Status)
+
+	// No new runs are accepted after shutdown
+	assert.Error(t, executor.Submit(context.Background(), job.JobID))
+}
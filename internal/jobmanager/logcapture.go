@@ -0,0 +1,209 @@
+package jobmanager
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/models"
+)
+
+// maxLogBatch is the most lines saved in one transaction
+const maxLogBatch = 1000
+
+// LogCaptureConfig holds the configuration of execution log capture
+type LogCaptureConfig struct {
+	MaxLines      int           // Lines kept per execution; older lines are removed
+	MaxLineBytes  int           // Longer messages are truncated
+	FlushInterval time.Duration // Save the captured lines at least this often
+	BufferSize    int           // Lines waiting to be saved; more are dropped
+}
+
+// DefaultLogCaptureConfig returns the default log capture configuration
+func DefaultLogCaptureConfig() *LogCaptureConfig {
+	return &LogCaptureConfig{
+		MaxLines:      10000,
+		MaxLineBytes:  4096,
+		FlushInterval: time.Second,
+		BufferSize:    10000,
+	}
+}
+
+// LogCapture is a logger hook that saves the log lines of executions with
+// the execution, so they can be read back through the job manager. Lines
+// are those logged with an execution_id field, such as the pipeline, stage
+// and executor lines of a run. They are queued without blocking the logger
+// and saved in batches; lines are dropped when the queue is full.
+type LogCapture struct {
+	manager *Manager
+	config  *LogCaptureConfig
+	dropped atomic.Int64
+
+	mu      sync.RWMutex // Guards sending to lines against closing it
+	lines   chan *models.ExecutionLog
+	flushes chan flushRequest
+	closed  bool
+	done    chan struct{}
+}
+
+// flushRequest asks the capture to save its queued lines, and to forget an
+// execution that has finished
+type flushRequest struct {
+	executionID string
+	done        chan struct{}
+}
+
+// NewLogCapture creates a log capture saving lines through the job manager
+func NewLogCapture(manager *Manager, config *LogCaptureConfig) *LogCapture {
+	if config == nil {
+		config = DefaultLogCaptureConfig()
+	}
+
+	c := &LogCapture{
+		manager: manager,
+		config:  config,
+		lines:   make(chan *models.ExecutionLog, config.BufferSize),
+		flushes: make(chan flushRequest),
+		done:    make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// Fire queues the entries of executions for saving
+func (c *LogCapture) Fire(entry *logger.Entry) {
+	executionID, ok := entry.Field("execution_id").(string)
+	if !ok || executionID == "" {
+		return
+	}
+
+	fields := entry.FieldValues()
+	delete(fields, "execution_id")
+	message := entry.Message
+	if c.config.MaxLineBytes > 0 && len(message) > c.config.MaxLineBytes {
+		message = message[:c.config.MaxLineBytes] + "... (truncated)"
+	}
+	line := &models.ExecutionLog{
+		ExecutionID: executionID,
+		Time:        entry.Time,
+		Level:       entry.Level.String(),
+		Message:     message,
+		Fields:      fields,
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		c.dropped.Add(1)
+		return
+	}
+	select {
+	case c.lines <- line:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of lines dropped so far
+func (c *LogCapture) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// Finish saves the lines queued so far and forgets the line count of an
+// execution that has ended
+func (c *LogCapture) Finish(executionID string) {
+	request := flushRequest{executionID: executionID, done: make(chan struct{})}
+	select {
+	case c.flushes <- request:
+		<-request.done
+	case <-c.done:
+	}
+}
+
+// Close saves the queued lines and stops the capture. Lines logged after
+// Close are dropped.
+func (c *LogCapture) Close() error {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.lines)
+	}
+	c.mu.Unlock()
+
+	<-c.done
+	return nil
+}
+
+func (c *LogCapture) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.config.FlushInterval)
+	defer ticker.Stop()
+
+	// Lines saved per execution, to trim executions over MaxLines
+	counts := make(map[string]int)
+	var batch []*models.ExecutionLog
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		// Failures are not captured themselves, having no execution ID
+		if err := c.manager.store.SaveExecutionLogs(batch); err != nil {
+			c.dropped.Add(int64(len(batch)))
+			logger.Warn("Failed to save %d execution log lines: %v", len(batch), err)
+		} else if err := c.trim(batch, counts); err != nil {
+			logger.Warn("Failed to trim execution logs: %v", err)
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, line)
+			if len(batch) >= maxLogBatch {
+				flush()
+			}
+		case request := <-c.flushes:
+			// Take the lines queued before the request
+			for n := len(c.lines); n > 0; n-- {
+				if line, ok := <-c.lines; ok {
+					batch = append(batch, line)
+				}
+			}
+			flush()
+			delete(counts, request.executionID)
+			close(request.done)
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// trim removes the oldest lines of the executions of a saved batch that
+// went over MaxLines
+func (c *LogCapture) trim(batch []*models.ExecutionLog, counts map[string]int) error {
+	if c.config.MaxLines <= 0 {
+		return nil
+	}
+
+	over := make(map[string]bool)
+	for _, line := range batch {
+		counts[line.ExecutionID]++
+		if counts[line.ExecutionID] > c.config.MaxLines {
+			over[line.ExecutionID] = true
+		}
+	}
+	for executionID := range over {
+		if err := c.manager.store.TrimExecutionLogs(executionID, c.config.MaxLines); err != nil {
+			return err
+		}
+		counts[executionID] = c.config.MaxLines
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package jobmanager
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/database"
+	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/models"
+)
+
+// createTestExecution creates a job and one of its executions
+func createTestExecution(t *testing.T, manager *Manager) *models.Execution {
+	job := createTestJob()
+	require.NoError(t, manager.CreateJob(job))
+	exec := &models.Execution{JobID: job.JobID, Status: models.ExecutionStatusRunning}
+	require.NoError(t, manager.CreateExecution(exec))
+	return exec
+}
+
+func TestExecutorCapturesLogs(t *testing.T) {
+	manager, executor := setupTestExecutor(t)
+	capture := NewLogCapture(manager, nil)
+	defer capture.Close()
+	executor.config.LogCapture = capture
+	logger.Default().AddHook(capture)
+	defer logger.Default().RemoveHook(capture)
+
+	job := createExecutorTestJob(t, manager, `
+input:
+  type: mock
+  config:
+    batches: 1
+output:
+  type: mock
+  config:
+    fail: true
+`)
+	require.Error(t, executor.Execute(context.Background(), job.JobID))
+
+	executions, err := manager.ListExecutions(job.JobID, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	executionID := executions[0].ExecutionID
+
+	// The lines are saved when the execution ends
+	logs, err := manager.GetExecutionLogs(&database.ExecutionLogQuery{ExecutionID: executionID, Limit: 1000})
+	require.NoError(t, err)
+	require.NotEmpty(t, logs)
+	assert.Contains(t, logs[0].Message, "Running pipeline for job "+job.JobID)
+	assert.Equal(t, job.JobID, logs[0].Fields["job_id"])
+	assert.NotContains(t, logs[0].Fields, "execution_id")
+
+	stages := make(map[interface{}]bool)
+	for _, line := range logs {
+		assert.Equal(t, executionID, line.ExecutionID)
+		stages[line.Fields["stage"]] = true
+	}
+	assert.True(t, stages["input"])
+	assert.True(t, stages["output"])
+
+	// Error lines, such as the failure of the output
+	errors, err := manager.GetExecutionLogs(&database.ExecutionLogQuery{
+		ExecutionID: executionID,
+		Levels:      []string{"ERROR", "FATAL"},
+		Limit:       1000,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, errors)
+	assert.Contains(t, errors[len(errors)-1].Message, "target unavailable")
+
+	// The last line is the summary
+	tail, err := manager.GetExecutionLogs(&database.ExecutionLogQuery{ExecutionID: executionID, Limit: 1, Tail: true})
+	require.NoError(t, err)
+	require.Len(t, tail, 1)
+	assert.Equal(t, logs[len(logs)-1].ID, tail[0].ID)
+	assert.Contains(t, tail[0].Message, "failed: 10 read, 0 written")
+
+	// Lines after a known one
+	after, err := manager.GetExecutionLogs(&database.ExecutionLogQuery{ExecutionID: executionID, AfterID: logs[0].ID, Limit: 1000})
+	require.NoError(t, err)
+	assert.Len(t, after, len(logs)-1)
+}
+
+func TestLogCaptureLimits(t *testing.T) {
+	manager := setupTestManager(t)
+	exec := createTestExecution(t, manager)
+	other := createTestExecution(t, manager)
+
+	config := DefaultLogCaptureConfig()
+	config.MaxLines = 5
+	config.MaxLineBytes = 10
+	config.FlushInterval = time.Hour
+	capture := NewLogCapture(manager, config)
+	defer capture.Close()
+
+	log, err := logger.New(&logger.Config{File: t.TempDir() + "/test.log"})
+	require.NoError(t, err)
+	defer log.Close()
+	log.AddHook(capture)
+
+	// Lines without an execution ID are not captured
+	log.Info("not captured")
+	execLog := log.With("execution_id", exec.ExecutionID)
+	for i := 0; i < 12; i++ {
+		execLog.Info("line %d", i)
+	}
+	execLog.Warn("a message longer than ten bytes")
+	log.With("execution_id", other.ExecutionID).Info("other")
+	capture.Finish(exec.ExecutionID)
+
+	// Only the last lines are kept
+	logs, err := manager.GetExecutionLogs(&database.ExecutionLogQuery{ExecutionID: exec.ExecutionID, Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, logs, 5)
+	for i, line := range logs[:4] {
+		assert.Equal(t, fmt.Sprintf("line %d", i+8), line.Message)
+		assert.Equal(t, "INFO", line.Level)
+	}
+	assert.Equal(t, "a message ... (truncated)", logs[4].Message)
+	assert.Equal(t, "WARN", logs[4].Level)
+
+	logs, err = manager.GetExecutionLogs(&database.ExecutionLogQuery{ExecutionID: other.ExecutionID, Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "other", logs[0].Message)
+
+	// Lines logged after closing are dropped
+	require.NoError(t, capture.Close())
+	execLog.Info("late")
+	assert.Equal(t, int64(1), capture.Dropped())
+}
@@ -11,15 +11,29 @@ import (
 
 	"github.com/atlanssia/fustgo/internal/database"
 	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/metrics"
 	"github.com/atlanssia/fustgo/internal/models"
+	"github.com/atlanssia/fustgo/internal/scheduler"
 )
 
 // Manager handles job lifecycle management with state machine
 type Manager struct {
-	mu      sync.RWMutex
-	store   database.MetadataStore
-	jobs    map[string]*JobInstance // jobID -> instance
-	running map[string]context.CancelFunc // jobID -> cancel function
+	mu        sync.RWMutex
+	store     database.MetadataStore
+	jobs      map[string]*JobInstance // jobID -> instance
+	running   map[string]context.CancelFunc // jobID -> cancel function
+	scheduler JobScheduler
+}
+
+// JobScheduler keeps the cron schedules of jobs in step with their
+// definitions. It is implemented by scheduler.Scheduler.
+type JobScheduler interface {
+	// SyncJob schedules, reschedules or unschedules a job to match its
+	// definition
+	SyncJob(job *models.Job) error
+
+	// UnscheduleJob removes a job from the scheduler, if it is scheduled
+	UnscheduleJob(jobID string)
 }
 
 // JobInstance represents a running job instance
@@ -40,6 +54,14 @@ func NewManager(store database.MetadataStore) *Manager {
 	}
 }
 
+// SetScheduler makes the manager update the schedule of each job it
+// creates, updates or deletes
+func (m *Manager) SetScheduler(scheduler JobScheduler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scheduler = scheduler
+}
+
 // CreateJob creates a new job
 func (m *Manager) CreateJob(job *models.Job) error {
 	m.mu.Lock()
@@ -64,6 +86,9 @@ func (m *Manager) CreateJob(job *models.Job) error {
 	if err := m.validateJobConfig(job); err != nil {
 		return fmt.Errorf("invalid job configuration: %w", err)
 	}
+	if err := validateSchedule(job); err != nil {
+		return err
+	}
 
 	// Save to database
 	if err := m.store.SaveJob(job); err != nil {
@@ -77,6 +102,9 @@ func (m *Manager) CreateJob(job *models.Job) error {
 		UpdatedAt: now,
 	}
 
+	m.updateMetrics()
+	m.syncSchedule(job)
+
 	logger.Info("Created job %s (%s)", job.JobID, job.JobName)
 	return nil
 }
@@ -125,6 +153,7 @@ func (m *Manager) ListJobs(filter map[string]interface{}) ([]*models.Job, error)
 			}
 		}
 	}
+	m.updateMetrics()
 	m.mu.Unlock()
 
 	return jobs, nil
@@ -157,6 +186,11 @@ func (m *Manager) UpdateJob(job *models.Job) error {
 			return fmt.Errorf("invalid job configuration: %w", err)
 		}
 	}
+	if job.SchedulingConfig != existing.SchedulingConfig {
+		if err := validateSchedule(job); err != nil {
+			return err
+		}
+	}
 
 	// Update in database
 	if err := m.store.UpdateJob(job); err != nil {
@@ -170,6 +204,16 @@ func (m *Manager) UpdateJob(job *models.Job) error {
 		instance.UpdatedAt = job.UpdatedAt
 	}
 
+	m.updateMetrics()
+	m.syncSchedule(job)
+
+	// The stages of a replaced pipeline start new series on the next run.
+	// A running pipeline keeps its series until it is deleted or replaced
+	// again.
+	if job.ConfigYAML != existing.ConfigYAML && existing.Status != models.JobStatusRunning {
+		metrics.DeleteJob(job.JobID)
+	}
+
 	logger.Info("Updated job %s (%s) to status %s", job.JobID, job.JobName, job.Status)
 	return nil
 }
@@ -198,6 +242,12 @@ func (m *Manager) DeleteJob(jobID string) error {
 	// Remove from cache
 	delete(m.jobs, jobID)
 
+	m.updateMetrics()
+	metrics.DeleteJob(jobID)
+	if m.scheduler != nil {
+		m.scheduler.UnscheduleJob(jobID)
+	}
+
 	logger.Info("Deleted job %s (%s)", jobID, job.JobName)
 	return nil
 }
@@ -253,6 +303,8 @@ func (m *Manager) StartJob(jobID string) error {
 		}
 	}
 
+	m.updateMetrics()
+
 	logger.Info("Started job %s (%s)", jobID, job.JobName)
 	return nil
 }
@@ -294,10 +346,57 @@ func (m *Manager) StopJob(jobID string) error {
 		instance.Cancel = nil
 	}
 
+	m.updateMetrics()
+
 	logger.Info("Stopped job %s (%s)", jobID, job.JobName)
 	return nil
 }
 
+// FinishJob records the final status of a job whose execution has ended and
+// releases its execution context. Jobs that were already moved out of the
+// running state (for example by StopJob) keep their current status.
+func (m *Manager) FinishJob(jobID string, status models.JobStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.store.GetJob(jobID)
+	if err != nil {
+		return fmt.Errorf("job not found: %w", err)
+	}
+
+	// Release execution context
+	if cancel, exists := m.running[jobID]; exists {
+		cancel()
+		delete(m.running, jobID)
+	}
+
+	if job.Status == models.JobStatusRunning {
+		if err := m.validateStateTransition(job.Status, status); err != nil {
+			return err
+		}
+
+		job.Status = status
+		job.UpdatedAt = time.Now()
+		if err := m.store.UpdateJob(job); err != nil {
+			return fmt.Errorf("failed to update job status: %w", err)
+		}
+	}
+
+	// Update cache
+	if instance, exists := m.jobs[jobID]; exists {
+		instance.Job = job
+		instance.Status = job.Status
+		instance.UpdatedAt = job.UpdatedAt
+		instance.Ctx = nil
+		instance.Cancel = nil
+	}
+
+	m.updateMetrics()
+
+	logger.Info("Finished job %s (%s) with status %s", jobID, job.JobName, job.Status)
+	return nil
+}
+
 // PauseJob pauses a running job
 func (m *Manager) PauseJob(jobID string) error {
 	m.mu.Lock()
@@ -327,6 +426,8 @@ func (m *Manager) PauseJob(jobID string) error {
 		instance.UpdatedAt = job.UpdatedAt
 	}
 
+	m.updateMetrics()
+
 	logger.Info("Paused job %s (%s)", jobID, job.JobName)
 	return nil
 }
@@ -360,10 +461,71 @@ func (m *Manager) ResumeJob(jobID string) error {
 		instance.UpdatedAt = job.UpdatedAt
 	}
 
+	m.updateMetrics()
+
 	logger.Info("Resumed job %s (%s)", jobID, job.JobName)
 	return nil
 }
 
+// CreateExecution records the start of a job execution
+func (m *Manager) CreateExecution(exec *models.Execution) error {
+	if exec.ExecutionID == "" {
+		exec.ExecutionID = uuid.New().String()
+	}
+	if exec.StartTime.IsZero() {
+		exec.StartTime = time.Now()
+	}
+	if exec.Status == "" {
+		exec.Status = models.ExecutionStatusPending
+	}
+
+	if err := m.store.SaveExecution(exec); err != nil {
+		return fmt.Errorf("failed to save execution: %w", err)
+	}
+
+	logger.Info("Created execution %s for job %s", exec.ExecutionID, exec.JobID)
+	return nil
+}
+
+// UpdateExecution updates the progress or final state of an execution
+func (m *Manager) UpdateExecution(exec *models.Execution) error {
+	if err := m.store.UpdateExecution(exec); err != nil {
+		return fmt.Errorf("failed to update execution: %w", err)
+	}
+	return nil
+}
+
+// GetExecution retrieves an execution by ID
+func (m *Manager) GetExecution(executionID string) (*models.Execution, error) {
+	exec, err := m.store.GetExecution(executionID)
+	if err != nil {
+		return nil, fmt.Errorf("execution not found: %w", err)
+	}
+	return exec, nil
+}
+
+// ListExecutions returns the most recent executions of a job
+func (m *Manager) ListExecutions(jobID string, limit int) ([]*models.Execution, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	executions, err := m.store.GetExecutions(jobID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions: %w", err)
+	}
+	return executions, nil
+}
+
+// GetExecutionLogs returns the captured log lines of an execution
+func (m *Manager) GetExecutionLogs(query *database.ExecutionLogQuery) ([]*models.ExecutionLog, error) {
+	logs, err := m.store.GetExecutionLogs(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read execution logs: %w", err)
+	}
+	return logs, nil
+}
+
 // GetJobContext returns the context for a running job
 func (m *Manager) GetJobContext(jobID string) (context.Context, error) {
 	m.mu.RLock()
@@ -453,11 +615,37 @@ func (m *Manager) validateJobConfig(job *models.Job) error {
 	return nil
 }
 
+// validateSchedule checks the cron expression of a job, if any
+func validateSchedule(job *models.Job) error {
+	if job.SchedulingConfig == "" {
+		return nil
+	}
+	if err := scheduler.ValidateCronExpression(job.SchedulingConfig); err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", job.SchedulingConfig, err)
+	}
+	return nil
+}
+
+// syncSchedule updates the schedule of a job after it was saved. The
+// caller holds the lock.
+func (m *Manager) syncSchedule(job *models.Job) {
+	if m.scheduler == nil {
+		return
+	}
+	if err := m.scheduler.SyncJob(job); err != nil {
+		logger.Warn("Failed to schedule job %s: %v", job.JobID, err)
+	}
+}
+
 // GetJobStats returns statistics for all jobs
 func (m *Manager) GetJobStats() map[string]int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	return m.countJobs()
+}
 
+// countJobs counts the jobs by status. The caller holds the lock.
+func (m *Manager) countJobs() map[string]int {
 	stats := map[string]int{
 		"total":     len(m.jobs),
 		"draft":     0,
@@ -487,3 +675,13 @@ func (m *Manager) GetJobStats() map[string]int {
 
 	return stats
 }
+
+// updateMetrics sets the job gauges to the number of jobs in each status.
+// The caller holds the lock.
+func (m *Manager) updateMetrics() {
+	for status, count := range m.countJobs() {
+		if status != "total" {
+			metrics.Jobs.WithLabelValues(status).Set(float64(count))
+		}
+	}
+}
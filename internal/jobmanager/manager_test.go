@@ -8,7 +8,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/atlanssia/fustgo/internal/database"
+	"github.com/atlanssia/fustgo/internal/metrics"
 	"github.com/atlanssia/fustgo/internal/models"
+	"github.com/atlanssia/fustgo/internal/scheduler"
 )
 
 func setupTestManager(t *testing.T) *Manager {
@@ -157,12 +159,60 @@ func TestDeleteJob(t *testing.T) {
 
 	err := manager.CreateJob(job)
 	require.NoError(t, err)
+	metrics.PipelineRecords.WithLabelValues(job.JobID, "input", "csv").Add(1)
+	metrics.CheckpointSaveDuration.WithLabelValues(job.JobID).Observe(0.01)
 
 	err = manager.DeleteJob(job.JobID)
 	require.NoError(t, err)
 
 	_, err = manager.GetJob(job.JobID)
 	assert.Error(t, err)
+
+	// The metric series of the job are deleted with it
+	assert.False(t, metrics.PipelineRecords.DeleteLabelValues(job.JobID, "input", "csv"))
+	assert.False(t, metrics.CheckpointSaveDuration.DeleteLabelValues(job.JobID))
+}
+
+func TestJobSchedules(t *testing.T) {
+	manager := setupTestManager(t)
+	sched := scheduler.NewScheduler(nil, nil)
+	manager.SetScheduler(sched)
+
+	// Created jobs with a schedule are scheduled
+	job := createTestJob()
+	job.SchedulingConfig = "*/5 * * * *"
+	require.NoError(t, manager.CreateJob(job))
+	assert.Equal(t, []string{job.JobID}, sched.GetScheduledJobs())
+
+	unscheduled := createTestJob()
+	require.NoError(t, manager.CreateJob(unscheduled))
+	assert.Equal(t, 1, sched.GetJobCount())
+
+	// Updates reschedule, disable and enable jobs
+	job.SchedulingConfig = "0 * * * *"
+	require.NoError(t, manager.UpdateJob(job))
+	next, err := sched.GetNextRun(job.JobID)
+	require.NoError(t, err)
+	assert.Zero(t, next.Minute())
+
+	job.Enabled = false
+	require.NoError(t, manager.UpdateJob(job))
+	assert.Equal(t, 0, sched.GetJobCount())
+
+	job.Enabled = true
+	require.NoError(t, manager.UpdateJob(job))
+	assert.Equal(t, 1, sched.GetJobCount())
+
+	// Invalid schedules are rejected
+	job.SchedulingConfig = "every hour"
+	assert.Error(t, manager.UpdateJob(job))
+	invalid := createTestJob()
+	invalid.SchedulingConfig = "every hour"
+	assert.Error(t, manager.CreateJob(invalid))
+
+	// Deleted jobs are unscheduled
+	require.NoError(t, manager.DeleteJob(job.JobID))
+	assert.Equal(t, 0, sched.GetJobCount())
 }
 
 func TestDeleteRunningJob(t *testing.T) {
@@ -401,3 +451,30 @@ func TestGetJobContext(t *testing.T) {
 		t.Fatal("Context should be done after stopping job")
 	}
 }
+
+func TestFinishJob(t *testing.T) {
+	manager := setupTestManager(t)
+	job := createTestJob()
+	job.Status = models.JobStatusReady
+
+	err := manager.CreateJob(job)
+	require.NoError(t, err)
+
+	err = manager.StartJob(job.JobID)
+	require.NoError(t, err)
+
+	ctx, err := manager.GetJobContext(job.JobID)
+	require.NoError(t, err)
+
+	err = manager.FinishJob(job.JobID, models.JobStatusFailed)
+	require.NoError(t, err)
+
+	// Execution context is released
+	assert.Error(t, ctx.Err())
+	_, err = manager.GetJobContext(job.JobID)
+	assert.Error(t, err)
+
+	retrieved, err := manager.GetJob(job.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, retrieved.Status)
+}
@@ -1,20 +1,41 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// Logger provides structured logging capabilities
+// Logger provides structured logging capabilities. Messages are formatted
+// printf-style and carry the key/value fields of the logger, added with
+// With. Loggers derived with With share the output, level and hooks of
+// the logger they derive from, and are safe for concurrent use.
 type Logger struct {
-	level      Level
-	output     *os.File
-	prefix     string
-	enableFile bool
-	filePath   string
+	core   *core
+	prefix string
+	fields []Field
+}
+
+// core is the state shared by a logger and the loggers derived from it
+type core struct {
+	level  atomic.Int32
+	format Format
+
+	mu     sync.Mutex // Serializes writes to the output
+	output io.Writer
+	closer io.Closer // nil for stdout
+	hooks  atomic.Pointer[[]Hook]
 }
 
 // Level represents log level
@@ -46,60 +67,276 @@ func (l Level) String() string {
 	}
 }
 
-// NewLogger creates a new logger instance
-func NewLogger(prefix string, enableFile bool, filePath string) (*Logger, error) {
-	logger := &Logger{
-		level:      LevelInfo,
-		prefix:     prefix,
-		enableFile: enableFile,
-		filePath:   filePath,
-		output:     os.Stdout,
+// ParseLevel parses a level name such as "info" or "WARN"
+func ParseLevel(name string) (Level, error) {
+	for level := LevelDebug; level <= LevelFatal; level++ {
+		if strings.EqualFold(name, level.String()) {
+			return level, nil
+		}
 	}
+	return LevelInfo, fmt.Errorf("invalid log level '%s', must be 'debug', 'info', 'warn', 'error' or 'fatal'", name)
+}
+
+// Format is the format of log lines
+type Format string
+
+const (
+	FormatText Format = "text" // [time] [LEVEL] [prefix] message key=value...
+	FormatJSON Format = "json" // One JSON object per line
+)
+
+// Field is a key/value pair attached to log entries
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Entry is a log entry as passed to hooks
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Logger  string // Prefix of the logger
+	Message string
+	Fields  []Field
+}
 
-	if enableFile && filePath != "" {
-		if err := logger.initFileOutput(); err != nil {
-			return nil, err
+// Field returns the value of a field of the entry, or nil
+func (e *Entry) Field(key string) interface{} {
+	for i := len(e.Fields) - 1; i >= 0; i-- {
+		if e.Fields[i].Key == key {
+			return e.Fields[i].Value
 		}
 	}
+	return nil
+}
+
+// FieldValues returns the fields of the entry by key, with values as they
+// are encoded in JSON lines
+func (e *Entry) FieldValues() map[string]interface{} {
+	values := make(map[string]interface{}, len(e.Fields))
+	for _, field := range e.Fields {
+		values[field.Key] = fieldValue(field.Value)
+	}
+	return values
+}
 
-	return logger, nil
+// Hook receives the entries written by a logger, such as a log shipper.
+// Fire is called for every entry at or above the logger's level and must
+// not block or log.
+type Hook interface {
+	Fire(entry *Entry)
 }
 
-// initFileOutput initializes file-based logging
-func (l *Logger) initFileOutput() error {
-	dir := filepath.Dir(l.filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create log directory: %w", err)
+// Config holds logger configuration. With a file, logs are written to the
+// file instead of stdout and the file is rotated once it reaches MaxSizeMB;
+// rotated files are named after the time of rotation.
+type Config struct {
+	Prefix string
+	Level  Level
+	Format Format
+
+	File       string // Empty for stdout
+	MaxSizeMB  int    // Rotate the file at this size, 0 for 100 MB
+	MaxAgeDays int    // Remove rotated files older than this, 0 to keep them
+	MaxBackups int    // Keep at most this many rotated files, 0 for all
+	Compress   bool   // Compress rotated files with gzip
+}
+
+// DefaultConfig returns default logger configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Prefix: "fustgo",
+		Level:  LevelInfo,
+		Format: FormatText,
 	}
+}
 
-	file, err := os.OpenFile(l.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open log file: %w", err)
+// New creates a logger from a configuration
+func New(config *Config) (*Logger, error) {
+	if config == nil {
+		config = DefaultConfig()
 	}
 
-	l.output = file
-	return nil
+	c := &core{format: config.Format, output: os.Stdout}
+	if c.format == "" {
+		c.format = FormatText
+	}
+	if c.format != FormatText && c.format != FormatJSON {
+		return nil, fmt.Errorf("invalid log format '%s', must be 'text' or 'json'", config.Format)
+	}
+	c.level.Store(int32(config.Level))
+	c.hooks.Store(&[]Hook{})
+
+	if config.File != "" {
+		if err := os.MkdirAll(filepath.Dir(config.File), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
+		file := &lumberjack.Logger{
+			Filename:   config.File,
+			MaxSize:    config.MaxSizeMB,
+			MaxAge:     config.MaxAgeDays,
+			MaxBackups: config.MaxBackups,
+			Compress:   config.Compress,
+			LocalTime:  true,
+		}
+		// Open the file now so that errors surface here
+		if _, err := file.Write(nil); err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		c.output = file
+		c.closer = file
+	}
+
+	return &Logger{core: c, prefix: config.Prefix}, nil
+}
+
+// NewLogger creates a new logger instance writing text lines to stdout or,
+// when enableFile is set, to a file
+func NewLogger(prefix string, enableFile bool, filePath string) (*Logger, error) {
+	config := DefaultConfig()
+	config.Prefix = prefix
+	if enableFile {
+		config.File = filePath
+	}
+	return New(config)
 }
 
 // SetLevel sets the minimum log level
 func (l *Logger) SetLevel(level Level) {
-	l.level = level
+	l.core.level.Store(int32(level))
+}
+
+// AddHook passes the entries of the logger, and of the loggers sharing its
+// output, to a hook
+func (l *Logger) AddHook(hook Hook) {
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	hooks := append(append([]Hook(nil), *l.core.hooks.Load()...), hook)
+	l.core.hooks.Store(&hooks)
+}
+
+// RemoveHook stops passing entries to a hook
+func (l *Logger) RemoveHook(hook Hook) {
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	var hooks []Hook
+	for _, h := range *l.core.hooks.Load() {
+		if h != hook {
+			hooks = append(hooks, h)
+		}
+	}
+	l.core.hooks.Store(&hooks)
+}
+
+// With returns a logger that adds key/value pairs to its entries, as in
+// With("job_id", id, "stage", "input")
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	if len(keyvals) == 0 {
+		return l
+	}
+	fields := make([]Field, len(l.fields), len(l.fields)+(len(keyvals)+1)/2)
+	copy(fields, l.fields)
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+		var value interface{}
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		fields = append(fields, Field{Key: key, Value: value})
+	}
+	return &Logger{core: l.core, prefix: l.prefix, fields: fields}
 }
 
 // log writes a log message
 func (l *Logger) log(level Level, format string, args ...interface{}) {
-	if level < l.level {
+	if int32(level) < l.core.level.Load() {
 		return
 	}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	message := fmt.Sprintf(format, args...)
-	logLine := fmt.Sprintf("[%s] [%s] [%s] %s\n", timestamp, level, l.prefix, message)
+	entry := &Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Logger:  l.prefix,
+		Message: fmt.Sprintf(format, args...),
+		Fields:  l.fields,
+	}
 
-	if l.enableFile && l.output != os.Stdout {
-		l.output.WriteString(logLine)
+	var line []byte
+	if l.core.format == FormatJSON {
+		line = encodeJSON(entry)
 	} else {
-		fmt.Print(logLine)
+		line = encodeText(entry)
+	}
+
+	l.core.mu.Lock()
+	l.core.output.Write(line)
+	l.core.mu.Unlock()
+
+	for _, hook := range *l.core.hooks.Load() {
+		hook.Fire(entry)
+	}
+}
+
+// encodeText formats an entry as a text line
+func encodeText(entry *Entry) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "[%s] [%s] [%s] %s", entry.Time.Format("2006-01-02 15:04:05"), entry.Level, entry.Logger, entry.Message)
+	for _, field := range entry.Fields {
+		value := fmt.Sprint(field.Value)
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, " %s=%s", field.Key, value)
+	}
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+// encodeJSON formats an entry as a JSON line. Fields follow the time,
+// level, logger and message keys in the order they were added.
+func encodeJSON(entry *Entry) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"time":`)
+	writeJSON(&b, entry.Time.Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSON(&b, entry.Level.String())
+	b.WriteString(`,"logger":`)
+	writeJSON(&b, entry.Logger)
+	b.WriteString(`,"message":`)
+	writeJSON(&b, entry.Message)
+	for _, field := range entry.Fields {
+		b.WriteByte(',')
+		writeJSON(&b, field.Key)
+		b.WriteByte(':')
+		writeJSON(&b, fieldValue(field.Value))
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+func writeJSON(b *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(data)
+}
+
+// fieldValue returns the value of a field as it is encoded: errors and
+// durations as strings, other values as is
+func fieldValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
 	}
 }
 
@@ -123,53 +360,78 @@ func (l *Logger) Error(format string, args ...interface{}) {
 	l.log(LevelError, format, args...)
 }
 
-// Fatal logs a fatal message and exits
+// Fatal logs a fatal message, closes the logger and exits
 func (l *Logger) Fatal(format string, args ...interface{}) {
 	l.log(LevelFatal, format, args...)
+	l.Close()
 	os.Exit(1)
 }
 
-// Close closes the logger and releases resources
+// Close flushes and closes the hooks that can be closed, such as log
+// shippers, and the log file
 func (l *Logger) Close() error {
-	if l.output != nil && l.output != os.Stdout {
-		return l.output.Close()
+	var err error
+	for _, hook := range *l.core.hooks.Load() {
+		if closer, ok := hook.(io.Closer); ok {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
 	}
-	return nil
+
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	if l.core.closer != nil {
+		if closeErr := l.core.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
 // Global logger instance
-var defaultLogger *Logger
+var defaultLogger atomic.Pointer[Logger]
 
 func init() {
-	var err error
-	defaultLogger, err = NewLogger("fustgo", false, "")
+	logger, err := NewLogger("fustgo", false, "")
 	if err != nil {
 		log.Fatal("Failed to initialize default logger:", err)
 	}
+	defaultLogger.Store(logger)
 }
 
 // SetDefaultLogger sets the default logger
 func SetDefaultLogger(logger *Logger) {
-	defaultLogger = logger
+	defaultLogger.Store(logger)
+}
+
+// Default returns the default logger
+func Default() *Logger {
+	return defaultLogger.Load()
+}
+
+// With returns the default logger with key/value fields
+func With(keyvals ...interface{}) *Logger {
+	return Default().With(keyvals...)
 }
 
 // Convenience functions for default logger
 func Debug(format string, args ...interface{}) {
-	defaultLogger.Debug(format, args...)
+	Default().Debug(format, args...)
 }
 
 func Info(format string, args ...interface{}) {
-	defaultLogger.Info(format, args...)
+	Default().Info(format, args...)
 }
 
 func Warn(format string, args ...interface{}) {
-	defaultLogger.Warn(format, args...)
+	Default().Warn(format, args...)
 }
 
 func Error(format string, args ...interface{}) {
-	defaultLogger.Error(format, args...)
+	Default().Error(format, args...)
 }
 
 func Fatal(format string, args ...interface{}) {
-	defaultLogger.Fatal(format, args...)
+	Default().Fatal(format, args...)
 }
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBufferLogger returns a logger writing to a buffer
+func newBufferLogger(t *testing.T, format Format) (*Logger, *bytes.Buffer) {
+	config := DefaultConfig()
+	config.Prefix = "test"
+	config.Format = format
+	log, err := New(config)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	log.core.output = &buf
+	return log, &buf
+}
+
+// recorder is a hook keeping the entries it receives
+type recorder struct {
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+func (r *recorder) Fire(entry *Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+func TestJSONFormat(t *testing.T) {
+	log, buf := newBufferLogger(t, FormatJSON)
+
+	log.With("job_id", "job-1", "attempt", 2).With("stage", "output").
+		Warn("Failed to write batch: %v", errors.New("timeout"))
+	log.With("error", errors.New("closed"), "elapsed", 1500*time.Millisecond).Error("Stopped")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "WARN", entry["level"])
+	assert.Equal(t, "test", entry["logger"])
+	assert.Equal(t, "Failed to write batch: timeout", entry["message"])
+	assert.Equal(t, "job-1", entry["job_id"])
+	assert.Equal(t, float64(2), entry["attempt"])
+	assert.Equal(t, "output", entry["stage"])
+	_, err := time.Parse(time.RFC3339Nano, entry["time"].(string))
+	assert.NoError(t, err)
+
+	// Fields keep the order they were added in
+	assert.Regexp(t, `"message":.*"job_id":.*"attempt":.*"stage":`, lines[0])
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "closed", entry["error"])
+	assert.Equal(t, "1.5s", entry["elapsed"])
+}
+
+func TestTextFormat(t *testing.T) {
+	log, buf := newBufferLogger(t, FormatText)
+
+	log.With("job_id", "job-1", "path", "/tmp/my file.csv", "empty", "").Info("Read %d records", 10)
+	assert.Regexp(t, `^\[\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\] \[INFO\] \[test\] Read 10 records `+
+		`job_id=job-1 path="/tmp/my file.csv" empty=""\n$`, buf.String())
+}
+
+func TestLevelAndHooks(t *testing.T) {
+	log, buf := newBufferLogger(t, FormatText)
+	hook := &recorder{}
+	log.AddHook(hook)
+
+	// Derived loggers share the level and hooks
+	derived := log.With("execution_id", "exec-1")
+	log.SetLevel(LevelWarn)
+	derived.Info("skipped")
+	derived.Warn("kept")
+
+	assert.NotContains(t, buf.String(), "skipped")
+	assert.Contains(t, buf.String(), "kept")
+	require.Len(t, hook.entries, 1)
+	assert.Equal(t, LevelWarn, hook.entries[0].Level)
+	assert.Equal(t, "exec-1", hook.entries[0].Field("execution_id"))
+	assert.Nil(t, hook.entries[0].Field("job_id"))
+
+	log.RemoveHook(hook)
+	derived.Error("not hooked")
+	assert.Len(t, hook.entries, 1)
+
+	level, err := ParseLevel("warn")
+	require.NoError(t, err)
+	assert.Equal(t, LevelWarn, level)
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+
+	_, err = New(&Config{Format: "xml"})
+	assert.Error(t, err)
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	log, buf := newBufferLogger(t, FormatJSON)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			worker := log.With("worker", i)
+			for j := 0; j < 100; j++ {
+				worker.Info("line %d", j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Lines are not interleaved
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 800)
+	for _, line := range lines {
+		assert.True(t, json.Valid([]byte(line)), line)
+	}
+}
+
+func TestFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultConfig()
+	config.File = filepath.Join(dir, "logs", "fustgo.log")
+	config.MaxSizeMB = 1
+	config.Compress = true
+	log, err := New(config)
+	require.NoError(t, err)
+
+	message := strings.Repeat("x", 200)
+	for i := 0; i < 6000; i++ {
+		log.Info("%d %s", i, message)
+	}
+	require.NoError(t, log.Close())
+
+	// The file reached 1 MB and was rotated into a gzipped backup
+	assert.Eventually(t, func() bool {
+		backups, _ := filepath.Glob(filepath.Join(dir, "logs", "fustgo-*.log.gz"))
+		uncompressed, _ := filepath.Glob(filepath.Join(dir, "logs", "fustgo-*.log"))
+		return len(backups) == 1 && len(uncompressed) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	info, err := os.Stat(config.File)
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(1024*1024))
+}
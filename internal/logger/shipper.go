@@ -0,0 +1,192 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShipperConfig holds the configuration of an OpenObserve log shipper
+type ShipperConfig struct {
+	Endpoint     string // Base URL of OpenObserve, such as http://localhost:5080
+	Organization string
+	Stream       string
+	Username     string
+	Password     string
+
+	BatchSize     int           // Send once this many entries are buffered
+	FlushInterval time.Duration // Send the buffered entries at least this often
+	BufferSize    int           // Entries queued for sending; more are dropped
+	Timeout       time.Duration // Per request
+}
+
+// DefaultShipperConfig returns the default shipper configuration
+func DefaultShipperConfig() *ShipperConfig {
+	return &ShipperConfig{
+		Organization:  "default",
+		Stream:        "fustgo_logs",
+		BatchSize:     100,
+		FlushInterval: 5 * time.Second,
+		BufferSize:    10000,
+		Timeout:       10 * time.Second,
+	}
+}
+
+// Shipper is a hook that sends log entries to OpenObserve in batches,
+// through its JSON ingestion API. Entries are queued without blocking the
+// logger and sent once BatchSize entries are buffered or FlushInterval has
+// passed. Entries are dropped when the queue is full or a batch cannot be
+// sent, so logging never waits on OpenObserve; failures are reported on
+// stderr since the shipper cannot log through the logger it ships for.
+type Shipper struct {
+	config  *ShipperConfig
+	url     string
+	client  *http.Client
+	dropped atomic.Int64
+
+	mu      sync.RWMutex // Guards sending to entries against closing it
+	entries chan map[string]interface{}
+	closed  bool
+	done    chan struct{}
+}
+
+// NewShipper creates a shipper and starts sending
+func NewShipper(config *ShipperConfig) (*Shipper, error) {
+	if config == nil {
+		config = DefaultShipperConfig()
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid log shipping endpoint '%s'", config.Endpoint)
+	}
+	if config.Organization == "" || config.Stream == "" {
+		return nil, fmt.Errorf("log shipping requires an organization and a stream")
+	}
+	if config.BatchSize < 1 || config.FlushInterval <= 0 {
+		return nil, fmt.Errorf("invalid log shipping batch size %d or flush interval %v", config.BatchSize, config.FlushInterval)
+	}
+	bufferSize := config.BufferSize
+	if bufferSize < config.BatchSize {
+		bufferSize = config.BatchSize
+	}
+
+	s := &Shipper{
+		config:  config,
+		url:     endpoint.JoinPath("api", config.Organization, config.Stream, "_json").String(),
+		client:  &http.Client{Timeout: config.Timeout},
+		entries: make(chan map[string]interface{}, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Fire queues an entry for sending, dropping it when the queue is full
+func (s *Shipper) Fire(entry *Entry) {
+	record := entry.FieldValues()
+	record["_timestamp"] = entry.Time.UnixMicro()
+	record["level"] = entry.Level.String()
+	record["logger"] = entry.Logger
+	record["message"] = entry.Message
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		s.dropped.Add(1)
+		return
+	}
+	select {
+	case s.entries <- record:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of entries dropped so far
+func (s *Shipper) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close sends the queued entries and stops the shipper. Entries fired
+// after Close are dropped.
+func (s *Shipper) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.entries)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+	return nil
+}
+
+func (s *Shipper) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]map[string]interface{}, 0, s.config.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.send(batch); err != nil {
+			s.dropped.Add(int64(len(batch)))
+			fmt.Fprintf(os.Stderr, "Failed to ship %d log entries: %v\n", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case record, ok := <-s.entries:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= s.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// send posts a batch of entries as a JSON array
+func (s *Shipper) send(batch []map[string]interface{}) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to encode log entries: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("OpenObserve returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ingestServer is a stand-in for the OpenObserve JSON ingestion API. It
+// keeps the batches it receives.
+type ingestServer struct {
+	*httptest.Server
+	t       *testing.T
+	mu      sync.Mutex
+	batches [][]map[string]interface{}
+}
+
+func newIngestServer(t *testing.T) *ingestServer {
+	s := &ingestServer{t: t}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *ingestServer) handle(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(s.t, "/api/default/fustgo_logs/_json", r.URL.Path)
+	user, password, ok := r.BasicAuth()
+	assert.True(s.t, ok)
+	assert.Equal(s.t, "user", user)
+	assert.Equal(s.t, "secret", password)
+
+	var batch []map[string]interface{}
+	assert.NoError(s.t, json.NewDecoder(r.Body).Decode(&batch))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, batch)
+}
+
+func (s *ingestServer) received() [][]map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]map[string]interface{}(nil), s.batches...)
+}
+
+func newTestShipper(t *testing.T, endpoint string, batchSize int, flushInterval time.Duration) (*Logger, *Shipper) {
+	config := DefaultShipperConfig()
+	config.Endpoint = endpoint
+	config.Username = "user"
+	config.Password = "secret"
+	config.BatchSize = batchSize
+	config.FlushInterval = flushInterval
+	shipper, err := NewShipper(config)
+	require.NoError(t, err)
+
+	log, _ := newBufferLogger(t, FormatText)
+	log.AddHook(shipper)
+	return log, shipper
+}
+
+func TestShipperBatches(t *testing.T) {
+	server := newIngestServer(t)
+	log, shipper := newTestShipper(t, server.URL, 3, time.Hour)
+
+	job := log.With("job_id", "job-1")
+	for i := 0; i < 7; i++ {
+		job.Info("line %d", i)
+	}
+
+	// Full batches are sent right away
+	assert.Eventually(t, func() bool { return len(server.received()) == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	// The rest on close
+	require.NoError(t, log.Close())
+	batches := server.received()
+	require.Len(t, batches, 3)
+	assert.Len(t, batches[0], 3)
+	assert.Len(t, batches[2], 1)
+
+	entry := batches[0][0]
+	assert.Equal(t, "line 0", entry["message"])
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "test", entry["logger"])
+	assert.Equal(t, "job-1", entry["job_id"])
+	assert.NotZero(t, entry["_timestamp"])
+
+	// Entries after close are dropped
+	job.Info("late")
+	assert.Equal(t, int64(1), shipper.Dropped())
+}
+
+func TestShipperFlushInterval(t *testing.T) {
+	server := newIngestServer(t)
+	log, _ := newTestShipper(t, server.URL, 100, 20*time.Millisecond)
+	defer log.Close()
+
+	log.Warn("first")
+	log.Warn("second")
+
+	// A partial batch is sent once the interval passes
+	assert.Eventually(t, func() bool { return len(server.received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, server.received()[0], 2)
+
+	_, err := NewShipper(&ShipperConfig{Endpoint: "localhost:5080", Organization: "default", Stream: "logs", BatchSize: 1, FlushInterval: time.Second})
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+// Package metrics defines the Prometheus metrics of FustGo. The metrics are
+// registered on Registry and updated by the packages they describe: the
+// pipeline, the task queues, the worker pool and the job manager. Handler
+// serves them in the Prometheus text format.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "fustgo"
+
+// Registry holds the FustGo metrics and the Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+// latencyBuckets range from 1ms to about 33s
+var latencyBuckets = prometheus.ExponentialBuckets(0.001, 2, 16)
+
+// Pipeline metrics, labelled by job, stage and plugin. Processor stages are
+// named processor_<index>.
+var (
+	PipelineRecords = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "pipeline",
+		Name:      "records_total",
+		Help:      "Records read, processed or written by each pipeline stage.",
+	}, []string{"job", "stage", "plugin"})
+
+	PipelineBytes = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "pipeline",
+		Name:      "bytes_total",
+		Help:      "Estimated in-memory size of the records handled by each pipeline stage.",
+	}, []string{"job", "stage", "plugin"})
+
+	PipelineBatchDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "pipeline",
+		Name:      "batch_duration_seconds",
+		Help:      "Time each pipeline stage takes to read, process or write a batch.",
+		Buckets:   latencyBuckets,
+	}, []string{"job", "stage", "plugin"})
+
+	CheckpointSaveDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "checkpoint",
+		Name:      "save_duration_seconds",
+		Help:      "Time to flush the output and save a checkpoint.",
+		Buckets:   latencyBuckets,
+	}, []string{"job"})
+)
+
+// Task queue metrics, labelled by queue name
+var (
+	QueueDepth = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "queue",
+		Name:      "depth",
+		Help:      "Tasks waiting in each task queue.",
+	}, []string{"queue"})
+
+	QueueUtilization = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "queue",
+		Name:      "utilization_ratio",
+		Help:      "Tasks waiting in each task queue as a fraction of its maximum size.",
+	}, []string{"queue"})
+)
+
+// Worker and job metrics
+var (
+	Workers = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers",
+		Help:      "Workers in the pool by status.",
+	}, []string{"status"})
+
+	Jobs = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "jobs",
+		Help:      "Jobs by status.",
+	}, []string{"status"})
+
+	JobExecutions = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_executions_total",
+		Help:      "Job executions by final status.",
+	}, []string{"status"})
+
+	JobExecutionDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_execution_duration_seconds",
+		Help:      "Time taken by job executions.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 16),
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// DeleteJob deletes the pipeline and checkpoint series of a job, which are
+// kept across its executions. The job manager calls it when a job is deleted
+// or its pipeline replaced, so that the series of jobs and stages that no
+// longer exist do not pile up.
+func DeleteJob(job string) {
+	labels := prometheus.Labels{"job": job}
+	PipelineRecords.DeletePartialMatch(labels)
+	PipelineBytes.DeletePartialMatch(labels)
+	PipelineBatchDuration.DeletePartialMatch(labels)
+	CheckpointSaveDuration.DeleteLabelValues(job)
+}
+
+// Handler serves the metrics of Registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteJob(t *testing.T) {
+	for _, job := range []string{"job-a", "job-b"} {
+		PipelineRecords.WithLabelValues(job, "input", "csv").Add(3)
+		PipelineRecords.WithLabelValues(job, "output", "json").Add(3)
+		PipelineBytes.WithLabelValues(job, "input", "csv").Add(100)
+		PipelineBatchDuration.WithLabelValues(job, "processor_0", "filter").Observe(0.01)
+		CheckpointSaveDuration.WithLabelValues(job).Observe(0.01)
+	}
+	t.Cleanup(func() { DeleteJob("job-b") })
+
+	DeleteJob("job-a")
+
+	// Only the series of the other job are left
+	assert.Equal(t, 2, testutil.CollectAndCount(PipelineRecords))
+	assert.Equal(t, float64(3), testutil.ToFloat64(PipelineRecords.WithLabelValues("job-b", "input", "csv")))
+	assert.Equal(t, 1, testutil.CollectAndCount(PipelineBytes))
+	assert.Equal(t, 1, testutil.CollectAndCount(PipelineBatchDuration))
+	assert.Equal(t, 1, testutil.CollectAndCount(CheckpointSaveDuration))
+
+	// Deleting a job without series is a no-op
+	DeleteJob("job-c")
+	assert.Equal(t, 2, testutil.CollectAndCount(PipelineRecords))
+}
@@ -0,0 +1,312 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/atlanssia/fustgo/internal/logger"
+)
+
+// PusherConfig holds the configuration of a remote-write pusher
+type PusherConfig struct {
+	Endpoint string        // Remote-write URL
+	Interval time.Duration // Time between pushes
+	Username string        // Basic auth, when set
+	Password string
+
+	// Labels are added to every series, such as the instance pushing
+	// them. Labels of the metric take precedence.
+	Labels map[string]string
+
+	Timeout    time.Duration // Per request
+	MaxRetries int           // Retries of a failed push
+	MinBackoff time.Duration // Wait before the first retry, doubled on each retry
+	MaxBackoff time.Duration
+}
+
+// DefaultPusherConfig returns the default pusher configuration
+func DefaultPusherConfig() *PusherConfig {
+	return &PusherConfig{
+		Interval:   15 * time.Second,
+		Timeout:    10 * time.Second,
+		MaxRetries: 3,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+// Pusher periodically pushes metrics to a Prometheus remote-write endpoint,
+// such as OpenObserve, for processes that cannot be scraped. Each push
+// sends the current value of every series as a snappy-compressed protobuf
+// WriteRequest. Pushes failing with a network error, 429 or a 5xx status
+// are retried with exponential backoff; other failures wait for the next
+// interval.
+type Pusher struct {
+	config   *PusherConfig
+	gatherer prometheus.Gatherer
+	client   *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPusher creates a pusher of the metrics of gatherer
+func NewPusher(gatherer prometheus.Gatherer, config *PusherConfig) (*Pusher, error) {
+	if config == nil {
+		config = DefaultPusherConfig()
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid remote-write endpoint '%s'", config.Endpoint)
+	}
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("invalid push interval %v", config.Interval)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pusher{
+		config:   config,
+		gatherer: gatherer,
+		client:   &http.Client{Timeout: config.Timeout},
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start starts pushing metrics every interval
+func (p *Pusher) Start() {
+	go p.run()
+	logger.Info("Pushing metrics to %s every %v", p.config.Endpoint, p.config.Interval)
+}
+
+// Stop stops the pusher after a last push of the current values
+func (p *Pusher) Stop() {
+	p.cancel()
+	<-p.done
+}
+
+func (p *Pusher) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.Push(p.ctx); err != nil && p.ctx.Err() == nil {
+				logger.Warn("Failed to push metrics: %v", err)
+			}
+		case <-p.ctx.Done():
+			body, err := p.encode(time.Now())
+			if err == nil {
+				_, err = p.send(context.Background(), body)
+			}
+			if err != nil {
+				logger.Warn("Failed to push metrics on stop: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// Push gathers the metrics and sends them, retrying with backoff
+func (p *Pusher) Push(ctx context.Context) error {
+	body, err := p.encode(time.Now())
+	if err != nil {
+		return err
+	}
+
+	backoff := p.config.MinBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := p.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= p.config.MaxRetries {
+			return err
+		}
+
+		logger.Debug("Retrying metrics push in %v: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+		if backoff > p.config.MaxBackoff {
+			backoff = p.config.MaxBackoff
+		}
+	}
+}
+
+// send posts a compressed write request and reports whether a failure may
+// be retried
+func (p *Pusher) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "fustgo")
+	if p.config.Username != "" {
+		req.SetBasicAuth(p.config.Username, p.config.Password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("remote write returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5, err
+}
+
+// encode gathers the metrics and encodes them as a compressed write request
+func (p *Pusher) encode(now time.Time) ([]byte, error) {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	request := encodeWriteRequest(families, p.config.Labels, now.UnixMilli())
+	return snappy.Encode(nil, request), nil
+}
+
+type label struct {
+	name, value string
+}
+
+// encodeWriteRequest encodes metric families as a remote-write
+// WriteRequest. Histograms and summaries are split into their _bucket or
+// quantile, _sum and _count series, as in the text format.
+func encodeWriteRequest(families []*dto.MetricFamily, external map[string]string, timestamp int64) []byte {
+	var b []byte
+	for _, family := range families {
+		name := family.GetName()
+		for _, m := range family.GetMetric() {
+			ts := timestamp
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+			add := func(suffix string, value float64, extra ...label) {
+				labels := seriesLabels(name+suffix, m.GetLabel(), external, extra)
+				b = appendTimeSeries(b, labels, value, ts)
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				summary := m.GetSummary()
+				for _, q := range summary.GetQuantile() {
+					add("", q.GetValue(), label{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add("_sum", summary.GetSampleSum())
+				add("_count", float64(summary.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				histogram := m.GetHistogram()
+				infinite := false
+				for _, bucket := range histogram.GetBucket() {
+					infinite = infinite || math.IsInf(bucket.GetUpperBound(), 1)
+					add("_bucket", float64(bucket.GetCumulativeCount()), label{"le", formatFloat(bucket.GetUpperBound())})
+				}
+				if !infinite {
+					add("_bucket", float64(histogram.GetSampleCount()), label{"le", "+Inf"})
+				}
+				add("_sum", histogram.GetSampleSum())
+				add("_count", float64(histogram.GetSampleCount()))
+			}
+		}
+	}
+	return b
+}
+
+// seriesLabels returns the sorted labels of a series
+func seriesLabels(name string, pairs []*dto.LabelPair, external map[string]string, extra []label) []label {
+	merged := make(map[string]string, len(external)+len(pairs)+len(extra)+1)
+	for k, v := range external {
+		merged[k] = v
+	}
+	for _, pair := range pairs {
+		merged[pair.GetName()] = pair.GetValue()
+	}
+	for _, l := range extra {
+		merged[l.name] = l.value
+	}
+	merged["__name__"] = name
+
+	labels := make([]label, 0, len(merged))
+	for k, v := range merged {
+		labels = append(labels, label{k, v})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+	return labels
+}
+
+// appendTimeSeries appends a TimeSeries with one sample to a WriteRequest
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func appendTimeSeries(b []byte, labels []label, value float64, timestamp int64) []byte {
+	var series []byte
+	for _, l := range labels {
+		var pair []byte
+		pair = protowire.AppendTag(pair, 1, protowire.BytesType)
+		pair = protowire.AppendString(pair, l.name)
+		pair = protowire.AppendTag(pair, 2, protowire.BytesType)
+		pair = protowire.AppendString(pair, l.value)
+		series = protowire.AppendTag(series, 1, protowire.BytesType)
+		series = protowire.AppendBytes(series, pair)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(timestamp))
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	series = protowire.AppendBytes(series, sample)
+
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, series)
+}
+
+// formatFloat formats a bucket bound or quantile as the text format does
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// remoteWriteServer is a stand-in for a remote-write endpoint. It answers
+// with the queued statuses, then 204, and decodes the series received as
+// "name{labels} value" strings.
+type remoteWriteServer struct {
+	*httptest.Server
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	requests int
+	series   []string
+}
+
+func newRemoteWriteServer(t *testing.T, statuses ...int) *remoteWriteServer {
+	s := &remoteWriteServer{t: t, statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *remoteWriteServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	user, password, ok := r.BasicAuth()
+	assert.True(s.t, ok)
+	assert.Equal(s.t, "user", user)
+	assert.Equal(s.t, "secret", password)
+	assert.Equal(s.t, "snappy", r.Header.Get("Content-Encoding"))
+	assert.Equal(s.t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+	if len(s.statuses) > 0 {
+		w.WriteHeader(s.statuses[0])
+		s.statuses = s.statuses[1:]
+		return
+	}
+
+	compressed, err := io.ReadAll(r.Body)
+	require.NoError(s.t, err)
+	body, err := snappy.Decode(nil, compressed)
+	require.NoError(s.t, err)
+	s.series = decodeWriteRequest(s.t, body)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *remoteWriteServer) received() (int, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests, s.series
+}
+
+// decodeWriteRequest decodes the series of a WriteRequest
+func decodeWriteRequest(t *testing.T, b []byte) []string {
+	var series []string
+	fields(t, b, func(num protowire.Number, v []byte) {
+		var name string
+		var labels []string
+		var value float64
+		fields(t, v, func(num protowire.Number, v []byte) {
+			if num == 1 {
+				var pair [2]string
+				fields(t, v, func(num protowire.Number, v []byte) { pair[num-1] = string(v) })
+				if pair[0] == "__name__" {
+					name = pair[1]
+				} else {
+					labels = append(labels, pair[0]+"="+pair[1])
+				}
+				return
+			}
+			sample, n := protowire.ConsumeFixed64(v[1:])
+			require.Positive(t, n)
+			value = math.Float64frombits(sample)
+		})
+		series = append(series, name+"{"+strings.Join(labels, ",")+"} "+formatFloat(value))
+	})
+	sort.Strings(series)
+	return series
+}
+
+// fields calls fn with the number and contents of each length-delimited
+// field of a message; other fields are passed whole
+func fields(t *testing.T, b []byte, fn func(protowire.Number, []byte)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.Positive(t, n)
+		if typ != protowire.BytesType {
+			fn(num, b)
+			return
+		}
+		v, m := protowire.ConsumeBytes(b[n:])
+		require.Positive(t, m)
+		fn(num, v)
+		b = b[n+m:]
+	}
+}
+
+func newTestPusher(t *testing.T, endpoint string) *Pusher {
+	registry := prometheus.NewRegistry()
+	records := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "records_total", Help: "Records."}, []string{"stage"})
+	duration := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "duration_seconds", Help: "Duration.", Buckets: []float64{0.5, 1}})
+	registry.MustRegister(records, duration)
+	records.WithLabelValues("input").Add(3)
+	duration.Observe(0.7)
+
+	config := DefaultPusherConfig()
+	config.Endpoint = endpoint
+	config.Username = "user"
+	config.Password = "secret"
+	config.Labels = map[string]string{"instance": "worker-1", "stage": "none"}
+	config.MinBackoff = time.Millisecond
+	pusher, err := NewPusher(registry, config)
+	require.NoError(t, err)
+	return pusher
+}
+
+func TestPusherPush(t *testing.T) {
+	server := newRemoteWriteServer(t)
+	pusher := newTestPusher(t, server.URL)
+
+	require.NoError(t, pusher.Push(context.Background()))
+	requests, series := server.received()
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, []string{
+		"duration_seconds_bucket{instance=worker-1,le=+Inf,stage=none} 1",
+		"duration_seconds_bucket{instance=worker-1,le=0.5,stage=none} 0",
+		"duration_seconds_bucket{instance=worker-1,le=1,stage=none} 1",
+		"duration_seconds_count{instance=worker-1,stage=none} 1",
+		"duration_seconds_sum{instance=worker-1,stage=none} 0.7",
+		"records_total{instance=worker-1,stage=input} 3",
+	}, series)
+}
+
+func TestPusherRetries(t *testing.T) {
+	// Server errors and throttling are retried
+	server := newRemoteWriteServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	require.NoError(t, newTestPusher(t, server.URL).Push(context.Background()))
+	requests, series := server.received()
+	assert.Equal(t, 3, requests)
+	assert.Len(t, series, 6)
+
+	// Up to MaxRetries times
+	server = newRemoteWriteServer(t, 500, 500, 500, 500, 500)
+	err := newTestPusher(t, server.URL).Push(context.Background())
+	assert.ErrorContains(t, err, "500 Internal Server Error")
+	requests, _ = server.received()
+	assert.Equal(t, 4, requests)
+
+	// Client errors are not
+	server = newRemoteWriteServer(t, http.StatusBadRequest)
+	assert.Error(t, newTestPusher(t, server.URL).Push(context.Background()))
+	requests, _ = server.received()
+	assert.Equal(t, 1, requests)
+}
+
+func TestPusherStop(t *testing.T) {
+	server := newRemoteWriteServer(t)
+	pusher := newTestPusher(t, server.URL)
+	pusher.config.Interval = time.Hour
+
+	// Stopping pushes the last values
+	pusher.Start()
+	pusher.Stop()
+	requests, series := server.received()
+	assert.Equal(t, 1, requests)
+	assert.Len(t, series, 6)
+
+	_, err := NewPusher(prometheus.NewRegistry(), &PusherConfig{Endpoint: "localhost:5080", Interval: time.Second})
+	assert.Error(t, err)
+}
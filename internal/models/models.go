@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
 
 // JobStatus represents the status of a job
 type JobStatus string
@@ -66,6 +70,10 @@ type Execution struct {
 	ErrorMessage     string          `json:"error_message,omitempty" db:"error_message"`
 	WorkerID         string          `json:"worker_id" db:"worker_id"`
 	CheckpointData   string          `json:"checkpoint_data,omitempty" db:"checkpoint_data"`
+
+	// SchemaEvents lists the schema drift met by the run and how it was
+	// handled
+	SchemaEvents []types.SchemaEvent `json:"schema_events,omitempty" db:"schema_events"`
 }
 
 // Duration returns the execution duration
@@ -76,6 +84,16 @@ func (e *Execution) Duration() time.Duration {
 	return e.EndTime.Sub(e.StartTime)
 }
 
+// ExecutionLog is a log line captured during an execution
+type ExecutionLog struct {
+	ID          int64                  `json:"id" db:"id"`
+	ExecutionID string                 `json:"execution_id" db:"execution_id"`
+	Time        time.Time              `json:"time" db:"time"`
+	Level       string                 `json:"level" db:"level"`
+	Message     string                 `json:"message" db:"message"`
+	Fields      map[string]interface{} `json:"fields,omitempty" db:"fields"` // Such as the stage
+}
+
 // WorkerStatus represents the status of a worker node
 type WorkerStatus string
 
@@ -0,0 +1,195 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/bkaradzic/go-lz4"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is a compression codec of column chunks
+type Codec int32
+
+// Supported compression codecs, with their parquet.thrift values. LZ4 is
+// the deprecated codec of Hadoop framed blocks and is only read.
+const (
+	CodecUncompressed Codec = 0
+	CodecSnappy       Codec = 1
+	CodecGzip         Codec = 2
+	CodecBrotli       Codec = 4
+	CodecLz4          Codec = 5
+	CodecZstd         Codec = 6
+	CodecLz4Raw       Codec = 7
+)
+
+// ParseCodec parses a codec name. An empty name selects snappy, the codec
+// most readers expect by default.
+func ParseCodec(name string) (Codec, error) {
+	switch strings.ToLower(name) {
+	case "", "snappy":
+		return CodecSnappy, nil
+	case "none", "uncompressed":
+		return CodecUncompressed, nil
+	case "gzip":
+		return CodecGzip, nil
+	case "zstd":
+		return CodecZstd, nil
+	case "brotli":
+		return CodecBrotli, nil
+	case "lz4_raw":
+		return CodecLz4Raw, nil
+	default:
+		return 0, fmt.Errorf("unsupported compression: %s", name)
+	}
+}
+
+// String returns the name of the codec
+func (c Codec) String() string {
+	switch c {
+	case CodecUncompressed:
+		return "none"
+	case CodecSnappy:
+		return "snappy"
+	case CodecGzip:
+		return "gzip"
+	case CodecZstd:
+		return "zstd"
+	case CodecBrotli:
+		return "brotli"
+	case CodecLz4:
+		return "lz4"
+	case CodecLz4Raw:
+		return "lz4_raw"
+	default:
+		return fmt.Sprintf("codec(%d)", int32(c))
+	}
+}
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+)
+
+// compress compresses a page
+func compress(codec Codec, data []byte) ([]byte, error) {
+	switch codec {
+	case CodecUncompressed:
+		return data, nil
+	case CodecSnappy:
+		return s2.EncodeSnappy(nil, data), nil
+	case CodecGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CodecZstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	case CodecBrotli:
+		var buf bytes.Buffer
+		w := brotli.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CodecLz4Raw:
+		block, err := lz4.Encode(nil, data)
+		if err != nil {
+			return nil, err
+		}
+		// Drop the size prefix of go-lz4 blocks
+		return block[4:], nil
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", codec)
+	}
+}
+
+// decompress decompresses a page of the given uncompressed size
+func decompress(codec Codec, data []byte, size int) ([]byte, error) {
+	switch codec {
+	case CodecUncompressed:
+		return data, nil
+	case CodecSnappy:
+		return s2.Decode(make([]byte, 0, size), data)
+	case CodecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, 0, size)
+		buf := bytes.NewBuffer(out)
+		if _, err := io.Copy(buf, r); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CodecZstd:
+		return zstdDecoder.DecodeAll(data, make([]byte, 0, size))
+	case CodecBrotli:
+		out := make([]byte, 0, size)
+		buf := bytes.NewBuffer(out)
+		if _, err := io.Copy(buf, brotli.NewReader(bytes.NewReader(data))); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CodecLz4:
+		if out, ok := decompressHadoopLz4(data, size); ok {
+			return out, nil
+		}
+		// Some writers put a bare block under this codec
+		return decompressLz4Block(data, size)
+	case CodecLz4Raw:
+		return decompressLz4Block(data, size)
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", codec)
+	}
+}
+
+// decompressLz4Block decompresses an LZ4 block of the given uncompressed
+// size
+func decompressLz4Block(data []byte, size int) ([]byte, error) {
+	if size == 0 {
+		return []byte{}, nil
+	}
+	// go-lz4 expects blocks prefixed with their uncompressed size
+	src := make([]byte, 4, 4+len(data))
+	binary.LittleEndian.PutUint32(src, uint32(size))
+	return lz4.Decode(make([]byte, size), append(src, data...))
+}
+
+// decompressHadoopLz4 decompresses the frames of the Hadoop LZ4 codec, each
+// an LZ4 block preceded by its big-endian uncompressed and compressed
+// sizes. It reports false when the data does not consist of such frames.
+func decompressHadoopLz4(data []byte, size int) ([]byte, bool) {
+	out := make([]byte, 0, size)
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, false
+		}
+		frameSize := int(binary.BigEndian.Uint32(data))
+		blockSize := int(binary.BigEndian.Uint32(data[4:]))
+		if blockSize > len(data)-8 || frameSize > size-len(out) {
+			return nil, false
+		}
+		frame, err := decompressLz4Block(data[8:8+blockSize], frameSize)
+		if err != nil {
+			return nil, false
+		}
+		out = append(out, frame...)
+		data = data[8+blockSize:]
+	}
+	return out, len(out) == size
+}
@@ -0,0 +1,401 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// appendHybrid appends levels encoded with the RLE/bit-packing hybrid
+// encoding as runs of repeated values
+func appendHybrid(buf []byte, levels []uint8, bitWidth int) []byte {
+	width := (bitWidth + 7) / 8
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		buf = binary.AppendUvarint(buf, uint64(j-i)<<1)
+		for b := 0; b < width; b++ {
+			buf = append(buf, byte(uint32(levels[i])>>(8*b)))
+		}
+		i = j
+	}
+	return buf
+}
+
+// decodeHybrid decodes n values encoded with the RLE/bit-packing hybrid
+// encoding
+func decodeHybrid(data []byte, bitWidth, n int) ([]uint32, error) {
+	values := make([]uint32, 0, n)
+	width := (bitWidth + 7) / 8
+	pos := 0
+	for len(values) < n {
+		header, k := binary.Uvarint(data[pos:])
+		if k <= 0 {
+			return nil, fmt.Errorf("truncated hybrid encoded data")
+		}
+		pos += k
+
+		if header&1 == 0 {
+			// Run of a repeated value
+			count := int(header >> 1)
+			if pos+width > len(data) {
+				return nil, fmt.Errorf("truncated hybrid encoded data")
+			}
+			var value uint32
+			for b := 0; b < width; b++ {
+				value |= uint32(data[pos+b]) << (8 * b)
+			}
+			pos += width
+			for i := 0; i < count && len(values) < n; i++ {
+				values = append(values, value)
+			}
+			continue
+		}
+
+		// Groups of eight bit-packed values
+		count := int(header>>1) * 8
+		size := int(header>>1) * bitWidth
+		if pos+size > len(data) {
+			return nil, fmt.Errorf("truncated hybrid encoded data")
+		}
+		packed := data[pos : pos+size]
+		pos += size
+		for i := 0; i < count && len(values) < n; i++ {
+			values = append(values, uint32(unpack(packed, i, bitWidth)))
+		}
+	}
+	return values, nil
+}
+
+// unpack returns the i-th value of bitWidth bits packed from the least
+// significant bit on
+func unpack(packed []byte, i, bitWidth int) uint64 {
+	var value uint64
+	bit := i * bitWidth
+	for b := 0; b < bitWidth; b++ {
+		if packed[(bit+b)/8]&(1<<((bit+b)%8)) != 0 {
+			value |= 1 << b
+		}
+	}
+	return value
+}
+
+// decodeDeltaBinaryPacked decodes DELTA_BINARY_PACKED encoded integers and
+// returns them with the number of bytes they took
+func decodeDeltaBinaryPacked(data []byte) ([]int64, int, error) {
+	pos := 0
+	uvarint := func() (uint64, error) {
+		v, k := binary.Uvarint(data[pos:])
+		if k <= 0 {
+			return 0, fmt.Errorf("truncated delta encoded data")
+		}
+		pos += k
+		return v, nil
+	}
+	varint := func() (int64, error) {
+		v, k := binary.Varint(data[pos:])
+		if k <= 0 {
+			return 0, fmt.Errorf("truncated delta encoded data")
+		}
+		pos += k
+		return v, nil
+	}
+
+	// Header: block size, miniblocks per block, value count, first value
+	blockSize, err := uvarint()
+	if err != nil {
+		return nil, 0, err
+	}
+	miniBlocks, err := uvarint()
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := uvarint()
+	if err != nil {
+		return nil, 0, err
+	}
+	first, err := varint()
+	if err != nil {
+		return nil, 0, err
+	}
+	if miniBlocks == 0 || blockSize%miniBlocks != 0 || (blockSize/miniBlocks)%8 != 0 {
+		return nil, 0, fmt.Errorf("invalid delta encoding of %d values per block in %d miniblocks", blockSize, miniBlocks)
+	}
+	perMiniBlock := int(blockSize / miniBlocks)
+
+	var values []int64
+	if count > 0 {
+		values = append(values, first)
+	}
+	// Blocks: the minimum delta, the bit widths of the miniblocks, then the
+	// deltas less the minimum packed in the miniblocks. Miniblocks after the
+	// last value are left out.
+	for uint64(len(values)) < count {
+		minDelta, err := varint()
+		if err != nil {
+			return nil, 0, err
+		}
+		if pos+int(miniBlocks) > len(data) {
+			return nil, 0, fmt.Errorf("truncated delta encoded data")
+		}
+		widths := data[pos : pos+int(miniBlocks)]
+		pos += int(miniBlocks)
+		for _, width := range widths {
+			if uint64(len(values)) == count {
+				break
+			}
+			if width > 64 {
+				return nil, 0, fmt.Errorf("invalid delta bit width %d", width)
+			}
+			size := perMiniBlock * int(width) / 8
+			if pos+size > len(data) {
+				return nil, 0, fmt.Errorf("truncated delta encoded data")
+			}
+			packed := data[pos : pos+size]
+			pos += size
+			for i := 0; i < perMiniBlock && uint64(len(values)) < count; i++ {
+				delta := minDelta + int64(unpack(packed, i, int(width)))
+				values = append(values, values[len(values)-1]+delta)
+			}
+		}
+	}
+	return values, pos, nil
+}
+
+// decodeDeltaLengthByteArray decodes DELTA_LENGTH_BYTE_ARRAY encoded byte
+// arrays: their delta encoded lengths, then their bytes
+func decodeDeltaLengthByteArray(data []byte) ([][]byte, error) {
+	lengths, pos, err := decodeDeltaBinaryPacked(data)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(lengths))
+	for i, n := range lengths {
+		if n < 0 || int64(len(data)-pos) < n {
+			return nil, fmt.Errorf("truncated delta length encoded data")
+		}
+		values[i] = data[pos : pos+int(n) : pos+int(n)]
+		pos += int(n)
+	}
+	return values, nil
+}
+
+// decodeDeltaByteArray decodes DELTA_BYTE_ARRAY encoded byte arrays: the
+// delta encoded lengths of the prefixes they share with the previous value,
+// then their suffixes with the DELTA_LENGTH_BYTE_ARRAY encoding
+func decodeDeltaByteArray(data []byte) ([][]byte, error) {
+	prefixes, pos, err := decodeDeltaBinaryPacked(data)
+	if err != nil {
+		return nil, err
+	}
+	suffixes, err := decodeDeltaLengthByteArray(data[pos:])
+	if err != nil {
+		return nil, err
+	}
+	if len(prefixes) != len(suffixes) {
+		return nil, fmt.Errorf("%d prefix lengths for %d suffixes", len(prefixes), len(suffixes))
+	}
+
+	values := make([][]byte, len(suffixes))
+	var previous []byte
+	for i, suffix := range suffixes {
+		n := prefixes[i]
+		if n < 0 || n > int64(len(previous)) {
+			return nil, fmt.Errorf("prefix length %d exceeds the previous value", n)
+		}
+		value := make([]byte, 0, int(n)+len(suffix))
+		value = append(append(value, previous[:n]...), suffix...)
+		values[i] = value
+		previous = value
+	}
+	return values, nil
+}
+
+// decodeDelta decodes the values of a page with one of the DELTA encodings
+// as values of the physical type typ
+func decodeDelta(encoding, typ int32, data []byte) ([]interface{}, error) {
+	if encoding == encodingDeltaBinaryPacked {
+		if typ != typeInt32 && typ != typeInt64 {
+			return nil, fmt.Errorf("encoding %d of physical type %d is not supported", encoding, typ)
+		}
+		ints, _, err := decodeDeltaBinaryPacked(data)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(ints))
+		for i, v := range ints {
+			if typ == typeInt32 {
+				values[i] = int32(v)
+			} else {
+				values[i] = v
+			}
+		}
+		return values, nil
+	}
+
+	if typ != typeByteArray && typ != typeFixedLenByteArray {
+		return nil, fmt.Errorf("encoding %d of physical type %d is not supported", encoding, typ)
+	}
+	var arrays [][]byte
+	var err error
+	if encoding == encodingDeltaLengthByteArray {
+		arrays, err = decodeDeltaLengthByteArray(data)
+	} else {
+		arrays, err = decodeDeltaByteArray(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(arrays))
+	for i, v := range arrays {
+		values[i] = v
+	}
+	return values, nil
+}
+
+// decodeByteStreamSplit undoes the BYTE_STREAM_SPLIT encoding, which stores
+// the k-th bytes of all values together, of values of the given size. The
+// values are returned in the PLAIN layout.
+func decodeByteStreamSplit(data []byte, size int) ([]byte, error) {
+	if size <= 0 || len(data)%size != 0 {
+		return nil, fmt.Errorf("byte stream split data of %d bytes holds no values of %d bytes", len(data), size)
+	}
+	n := len(data) / size
+	plain := make([]byte, len(data))
+	for i := 0; i < n; i++ {
+		for b := 0; b < size; b++ {
+			plain[i*size+b] = data[b*n+i]
+		}
+	}
+	return plain, nil
+}
+
+// plainDecoder decodes PLAIN encoded values of a physical type
+type plainDecoder struct {
+	typ        int32
+	typeLength int
+	data       []byte
+	pos        int
+	bit        int // Position in the current byte of booleans
+}
+
+// next decodes the next value as bool, int32, int64, float32, float64 or
+// []byte. INT96 values are returned as their 12 bytes.
+func (d *plainDecoder) next() (interface{}, error) {
+	need := func(n int) error {
+		if d.pos+n > len(d.data) {
+			return fmt.Errorf("truncated plain encoded data")
+		}
+		return nil
+	}
+
+	switch d.typ {
+	case typeBoolean:
+		if err := need(1); err != nil {
+			return nil, err
+		}
+		v := d.data[d.pos]&(1<<d.bit) != 0
+		if d.bit++; d.bit == 8 {
+			d.bit = 0
+			d.pos++
+		}
+		return v, nil
+	case typeInt32:
+		if err := need(4); err != nil {
+			return nil, err
+		}
+		v := int32(binary.LittleEndian.Uint32(d.data[d.pos:]))
+		d.pos += 4
+		return v, nil
+	case typeInt64:
+		if err := need(8); err != nil {
+			return nil, err
+		}
+		v := int64(binary.LittleEndian.Uint64(d.data[d.pos:]))
+		d.pos += 8
+		return v, nil
+	case typeFloat:
+		if err := need(4); err != nil {
+			return nil, err
+		}
+		v := math.Float32frombits(binary.LittleEndian.Uint32(d.data[d.pos:]))
+		d.pos += 4
+		return v, nil
+	case typeDouble:
+		if err := need(8); err != nil {
+			return nil, err
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(d.data[d.pos:]))
+		d.pos += 8
+		return v, nil
+	case typeByteArray:
+		if err := need(4); err != nil {
+			return nil, err
+		}
+		n := int(binary.LittleEndian.Uint32(d.data[d.pos:]))
+		d.pos += 4
+		if err := need(n); err != nil {
+			return nil, err
+		}
+		v := d.data[d.pos : d.pos+n : d.pos+n]
+		d.pos += n
+		return v, nil
+	case typeInt96, typeFixedLenByteArray:
+		n := d.typeLength
+		if d.typ == typeInt96 {
+			n = 12
+		}
+		if err := need(n); err != nil {
+			return nil, err
+		}
+		v := d.data[d.pos : d.pos+n : d.pos+n]
+		d.pos += n
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported physical type %d", d.typ)
+	}
+}
+
+// plainEncoder encodes values of a physical type with the PLAIN encoding
+type plainEncoder struct {
+	buf  []byte
+	bits int // Booleans in the last byte
+}
+
+func (e *plainEncoder) bool(v bool) {
+	if e.bits == 0 {
+		e.buf = append(e.buf, 0)
+	}
+	if v {
+		e.buf[len(e.buf)-1] |= 1 << e.bits
+	}
+	e.bits = (e.bits + 1) % 8
+}
+
+func (e *plainEncoder) int32(v int32) {
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(v))
+}
+
+func (e *plainEncoder) int64(v int64) {
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(v))
+}
+
+func (e *plainEncoder) float(v float32) {
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, math.Float32bits(v))
+}
+
+func (e *plainEncoder) double(v float64) {
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+func (e *plainEncoder) byteArray(v []byte) {
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *plainEncoder) reset() {
+	e.buf = e.buf[:0]
+	e.bits = 0
+}
@@ -0,0 +1,355 @@
+// Package parquet reads and writes Parquet files with flat schemas, mapping
+// the column types of FustGo to Parquet logical types.
+package parquet
+
+import "fmt"
+
+// The subset of the Parquet format metadata used by the reader and writer,
+// with the field IDs of parquet.thrift.
+
+// Physical types
+const (
+	typeBoolean           int32 = 0
+	typeInt32             int32 = 1
+	typeInt64             int32 = 2
+	typeInt96             int32 = 3
+	typeFloat             int32 = 4
+	typeDouble            int32 = 5
+	typeByteArray         int32 = 6
+	typeFixedLenByteArray int32 = 7
+)
+
+// Repetition types
+const (
+	repetitionRequired int32 = 0
+	repetitionOptional int32 = 1
+	repetitionRepeated int32 = 2
+)
+
+// Converted types, the legacy annotations still read by older readers
+const (
+	convertedUTF8            int32 = 0
+	convertedEnum            int32 = 4
+	convertedDecimal         int32 = 5
+	convertedDate            int32 = 6
+	convertedTimestampMillis int32 = 9
+	convertedTimestampMicros int32 = 10
+	convertedInt32           int32 = 17
+	convertedInt64           int32 = 18
+	convertedJSON            int32 = 19
+)
+
+// Encodings
+const (
+	encodingPlain                int32 = 0
+	encodingPlainDictionary      int32 = 2
+	encodingRLE                  int32 = 3
+	encodingDeltaBinaryPacked    int32 = 5
+	encodingDeltaLengthByteArray int32 = 6
+	encodingDeltaByteArray       int32 = 7
+	encodingRLEDictionary        int32 = 8
+	encodingByteStreamSplit      int32 = 9
+)
+
+// Page types
+const (
+	pageData       int32 = 0
+	pageDictionary int32 = 2
+	pageDataV2     int32 = 3
+)
+
+// Logical type union members
+const (
+	logicalString    int16 = 1
+	logicalEnum      int16 = 4
+	logicalDecimal   int16 = 5
+	logicalDate      int16 = 6
+	logicalTimestamp int16 = 8
+	logicalInteger   int16 = 10
+	logicalJSON      int16 = 12
+)
+
+// Time units of timestamps
+const (
+	unitMillis int16 = 1
+	unitMicros int16 = 2
+	unitNanos  int16 = 3
+)
+
+// schemaElement is a node of the schema tree. The root has children and no
+// type; the columns of a flat schema are its leaves.
+type schemaElement struct {
+	Type             int32
+	HasType          bool
+	TypeLength       int32
+	Repetition       int32
+	Name             string
+	NumChildren      int32
+	ConvertedType    int32
+	HasConverted     bool
+	Scale            int32
+	Precision        int32
+	Logical          int16 // Logical type union member, 0 for none
+	TimestampUnit    int16
+	TimestampUTC     bool
+	IntegerBits      int8
+	IntegerSigned    bool
+	DecimalScale     int32
+	DecimalPrecision int32
+}
+
+type columnMetaData struct {
+	Type                  int32
+	Encodings             []int32
+	Path                  []string
+	Codec                 int32
+	NumValues             int64
+	TotalUncompressedSize int64
+	TotalCompressedSize   int64
+	DataPageOffset        int64
+	DictionaryPageOffset  int64
+	HasDictionary         bool
+}
+
+type columnChunk struct {
+	FileOffset int64
+	Meta       columnMetaData
+}
+
+type rowGroup struct {
+	Columns       []columnChunk
+	TotalByteSize int64
+	NumRows       int64
+}
+
+type fileMetaData struct {
+	Version   int32
+	Schema    []schemaElement
+	NumRows   int64
+	RowGroups []rowGroup
+	CreatedBy string
+}
+
+type pageHeader struct {
+	Type             int32
+	UncompressedSize int32
+	CompressedSize   int32
+
+	// Data pages, both versions
+	NumValues int32
+	Encoding  int32
+
+	// Data pages v2
+	NumNulls           int32
+	NumRows            int32
+	DefinitionLevelLen int32
+	RepetitionLevelLen int32
+	IsCompressed       bool
+}
+
+func (m *fileMetaData) encode() []byte {
+	w := &thriftWriter{}
+	w.beginStruct()
+	w.i32(1, m.Version)
+	w.structList(2, len(m.Schema), func(i int) { m.Schema[i].encode(w) })
+	w.i64(3, m.NumRows)
+	w.structList(4, len(m.RowGroups), func(i int) { m.RowGroups[i].encode(w) })
+	w.string(6, m.CreatedBy)
+	w.endStruct()
+	return w.buf
+}
+
+func (e *schemaElement) encode(w *thriftWriter) {
+	if e.HasType {
+		w.i32(1, e.Type)
+		if e.Type == typeFixedLenByteArray {
+			w.i32(2, e.TypeLength)
+		}
+		w.i32(3, e.Repetition)
+	}
+	w.string(4, e.Name)
+	if !e.HasType {
+		w.i32(5, e.NumChildren)
+	}
+	if e.HasConverted {
+		w.i32(6, e.ConvertedType)
+	}
+	if e.Logical != 0 {
+		w.structField(10, func() {
+			w.structField(e.Logical, func() {
+				switch e.Logical {
+				case logicalTimestamp:
+					w.bool(1, e.TimestampUTC)
+					w.structField(2, func() {
+						w.structField(e.TimestampUnit, func() {})
+					})
+				case logicalInteger:
+					w.fieldHeader(1, thriftByte)
+					w.buf = append(w.buf, byte(e.IntegerBits))
+					w.bool(2, e.IntegerSigned)
+				}
+			})
+		})
+	}
+}
+
+func (g *rowGroup) encode(w *thriftWriter) {
+	w.structList(1, len(g.Columns), func(i int) {
+		c := &g.Columns[i]
+		w.i64(2, c.FileOffset)
+		w.structField(3, func() {
+			m := &c.Meta
+			w.i32(1, m.Type)
+			w.i32List(2, m.Encodings)
+			w.stringList(3, m.Path)
+			w.i32(4, m.Codec)
+			w.i64(5, m.NumValues)
+			w.i64(6, m.TotalUncompressedSize)
+			w.i64(7, m.TotalCompressedSize)
+			w.i64(9, m.DataPageOffset)
+			if m.HasDictionary {
+				w.i64(11, m.DictionaryPageOffset)
+			}
+		})
+	})
+	w.i64(2, g.TotalByteSize)
+	w.i64(3, g.NumRows)
+}
+
+func (h *pageHeader) encode() []byte {
+	w := &thriftWriter{}
+	w.beginStruct()
+	w.i32(1, h.Type)
+	w.i32(2, h.UncompressedSize)
+	w.i32(3, h.CompressedSize)
+	w.structField(5, func() {
+		w.i32(1, h.NumValues)
+		w.i32(2, h.Encoding)
+		w.i32(3, encodingRLE)
+		w.i32(4, encodingRLE)
+	})
+	w.endStruct()
+	return w.buf
+}
+
+// decodeFileMetaData decodes the footer of a file
+func decodeFileMetaData(data []byte) (*fileMetaData, error) {
+	r := &thriftReader{buf: data}
+	s, err := r.readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("invalid file metadata: %w", err)
+	}
+
+	m := &fileMetaData{
+		Version:   s.i32(1),
+		NumRows:   s.i64(3),
+		CreatedBy: s.string(6),
+	}
+	for _, item := range s.list(2) {
+		e, _ := item.(thriftStruct)
+		m.Schema = append(m.Schema, decodeSchemaElement(e))
+	}
+	for _, item := range s.list(4) {
+		g, _ := item.(thriftStruct)
+		group := rowGroup{TotalByteSize: g.i64(2), NumRows: g.i64(3)}
+		for _, item := range g.list(1) {
+			c, _ := item.(thriftStruct)
+			meta := c.strct(3)
+			if meta == nil {
+				return nil, fmt.Errorf("column chunks in separate files are not supported")
+			}
+			chunk := columnChunk{
+				FileOffset: c.i64(2),
+				Meta: columnMetaData{
+					Type:                  meta.i32(1),
+					Codec:                 meta.i32(4),
+					NumValues:             meta.i64(5),
+					TotalUncompressedSize: meta.i64(6),
+					TotalCompressedSize:   meta.i64(7),
+					DataPageOffset:        meta.i64(9),
+					DictionaryPageOffset:  meta.i64(11),
+					HasDictionary:         meta.has(11),
+				},
+			}
+			for _, item := range meta.list(3) {
+				name, _ := item.([]byte)
+				chunk.Meta.Path = append(chunk.Meta.Path, string(name))
+			}
+			group.Columns = append(group.Columns, chunk)
+		}
+		m.RowGroups = append(m.RowGroups, group)
+	}
+	return m, nil
+}
+
+func decodeSchemaElement(s thriftStruct) schemaElement {
+	e := schemaElement{
+		Type:          s.i32(1),
+		HasType:       s.has(1),
+		TypeLength:    s.i32(2),
+		Repetition:    s.i32(3),
+		Name:          s.string(4),
+		NumChildren:   s.i32(5),
+		ConvertedType: s.i32(6),
+		HasConverted:  s.has(6),
+		Scale:         s.i32(7),
+		Precision:     s.i32(8),
+	}
+
+	for member, value := range s.strct(10) {
+		e.Logical = member
+		body, _ := value.(thriftStruct)
+		switch member {
+		case logicalTimestamp:
+			e.TimestampUTC = body.bool(1, false)
+			for unit := range body.strct(2) {
+				e.TimestampUnit = unit
+			}
+		case logicalInteger:
+			e.IntegerBits = int8(body.i64(1))
+			e.IntegerSigned = body.bool(2, false)
+		case logicalDecimal:
+			e.DecimalScale = body.i32(1)
+			e.DecimalPrecision = body.i32(2)
+		}
+	}
+	return e
+}
+
+// decodePageHeader decodes a page header and returns its encoded size
+func decodePageHeader(data []byte) (*pageHeader, int, error) {
+	r := &thriftReader{buf: data}
+	s, err := r.readStruct()
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid page header: %w", err)
+	}
+
+	h := &pageHeader{
+		Type:             s.i32(1),
+		UncompressedSize: s.i32(2),
+		CompressedSize:   s.i32(3),
+	}
+	switch h.Type {
+	case pageData:
+		d := s.strct(5)
+		h.NumValues = d.i32(1)
+		h.Encoding = d.i32(2)
+	case pageDictionary:
+		d := s.strct(7)
+		h.NumValues = d.i32(1)
+		h.Encoding = d.i32(2)
+	case pageDataV2:
+		d := s.strct(8)
+		h.NumValues = d.i32(1)
+		h.NumNulls = d.i32(2)
+		h.NumRows = d.i32(3)
+		h.Encoding = d.i32(4)
+		h.DefinitionLevelLen = d.i32(5)
+		h.RepetitionLevelLen = d.i32(6)
+		h.IsCompressed = d.bool(7, true)
+	}
+	if h.CompressedSize < 0 || h.UncompressedSize < 0 {
+		return nil, 0, fmt.Errorf("invalid page sizes")
+	}
+	return h, r.pos, nil
+}
@@ -0,0 +1,592 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+var testSchema = types.Schema{Columns: []types.Column{
+	{Name: "id", DataType: types.DataTypeBigInt},
+	{Name: "name", DataType: types.DataTypeString, Nullable: true},
+	{Name: "age", DataType: types.DataTypeInt, Nullable: true},
+	{Name: "score", DataType: types.DataTypeDouble, Nullable: true},
+	{Name: "ratio", DataType: types.DataTypeFloat, Nullable: true},
+	{Name: "active", DataType: types.DataTypeBool, Nullable: true},
+	{Name: "born", DataType: types.DataTypeDate, Nullable: true},
+	{Name: "seen", DataType: types.DataTypeTimestamp, Nullable: true},
+	{Name: "raw", DataType: types.DataTypeBytes, Nullable: true},
+	{Name: "attrs", DataType: types.DataTypeJSON, Nullable: true},
+}}
+
+// testRow returns a row of testSchema, with nulls in every third row
+func testRow(i int) []interface{} {
+	if i%3 == 2 {
+		return []interface{}{int64(i), nil, nil, nil, nil, nil, nil, nil, nil, nil}
+	}
+	return []interface{}{
+		int64(i),
+		"name " + string(rune('a'+i%26)),
+		int64(20 + i),
+		float64(i) / 4,
+		float64(i) / 2,
+		i%2 == 0,
+		time.Date(2024, 1, 1+i%28, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 12, 0, 0, i*1000, time.UTC),
+		[]byte{byte(i), 0, 255},
+		`{"n":1}`,
+	}
+}
+
+// writeFile writes rows of testSchema and returns the file
+func writeFile(t *testing.T, config WriterConfig, rows int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testSchema, config)
+	require.NoError(t, err)
+	for i := 0; i < rows; i++ {
+		require.NoError(t, w.Write(testRow(i)))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// readFile returns all rows of the given columns of a file
+func readFile(t *testing.T, data []byte, columns []int) [][]interface{} {
+	t.Helper()
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	var rows [][]interface{}
+	for i := 0; i < r.NumRowGroups(); i++ {
+		group, err := r.ReadRowGroup(i, columns)
+		require.NoError(t, err)
+		rows = append(rows, group...)
+	}
+	return rows
+}
+
+func allColumns() []int {
+	columns := make([]int, len(testSchema.Columns))
+	for i := range columns {
+		columns[i] = i
+	}
+	return columns
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{CodecUncompressed, CodecSnappy, CodecGzip, CodecZstd, CodecBrotli, CodecLz4Raw} {
+		t.Run(codec.String(), func(t *testing.T) {
+			data := writeFile(t, WriterConfig{Compression: codec, RowGroupRows: 40, PageSize: 64}, 100)
+
+			r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+			require.NoError(t, err)
+			assert.Equal(t, testSchema, r.Schema())
+			assert.Equal(t, int64(100), r.NumRows())
+			assert.Equal(t, 3, r.NumRowGroups())
+
+			rows := readFile(t, data, allColumns())
+			require.Len(t, rows, 100)
+			for i, row := range rows {
+				assert.Equal(t, testRow(i), row, "row %d", i)
+			}
+		})
+	}
+}
+
+func TestProjection(t *testing.T) {
+	data := writeFile(t, WriterConfig{}, 5)
+	rows := readFile(t, data, []int{7, 0})
+	assert.Equal(t, []interface{}{time.Date(2024, 3, 1, 12, 0, 0, 1000, time.UTC), int64(1)}, rows[1])
+	assert.Equal(t, []interface{}{nil, int64(2)}, rows[2])
+}
+
+func TestWriteConvertsValues(t *testing.T) {
+	schema := types.Schema{Columns: []types.Column{
+		{Name: "n", DataType: types.DataTypeInt},
+		{Name: "d", DataType: types.DataTypeDate},
+		{Name: "s", DataType: types.DataTypeUnknown},
+	}}
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, schema, WriterConfig{})
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]interface{}{"42", "2024-05-06", 7}))
+
+	// Bad rows are rejected whole
+	assert.ErrorIs(t, w.Write([]interface{}{int64(1) << 40, "2024-05-06", "x"}), ErrInvalidRow)
+	assert.Error(t, w.Write([]interface{}{1, "not a date", "x"}))
+	assert.Error(t, w.Write([]interface{}{nil, "2024-05-06", "x"}))
+	assert.Error(t, w.Write([]interface{}{1}))
+	require.NoError(t, w.Close())
+
+	rows := readFile(t, buf.Bytes(), []int{0, 1, 2})
+	assert.Equal(t, [][]interface{}{{int64(42), time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), "7"}}, rows)
+}
+
+func TestResume(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testSchema, WriterConfig{Compression: CodecSnappy, PageSize: 64})
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, w.Write(testRow(i)))
+	}
+	require.NoError(t, w.Flush())
+	for i := 10; i < 15; i++ {
+		require.NoError(t, w.Write(testRow(i)))
+	}
+	require.NoError(t, w.Flush())
+	offset, rowGroups := w.Offset(), w.RowGroups()
+	assert.Equal(t, []int64{10, 5}, rowGroups)
+
+	// A row group written after the position is dropped
+	require.NoError(t, w.Write(testRow(15)))
+	require.NoError(t, w.Flush())
+
+	data := buf.Bytes()[:offset]
+	resumed := bytes.NewBuffer(data)
+	w, err = NewWriter(resumed, testSchema, WriterConfig{Compression: CodecSnappy})
+	require.NoError(t, err)
+	require.NoError(t, w.Resume(bytes.NewReader(data), offset, rowGroups))
+	for i := 15; i < 20; i++ {
+		require.NoError(t, w.Write(testRow(i)))
+	}
+	require.NoError(t, w.Close())
+
+	rows := readFile(t, resumed.Bytes(), allColumns())
+	require.Len(t, rows, 20)
+	for i, row := range rows {
+		assert.Equal(t, testRow(i), row, "row %d", i)
+	}
+
+	// The row counts must match the pages
+	w, err = NewWriter(&bytes.Buffer{}, testSchema, WriterConfig{})
+	require.NoError(t, err)
+	assert.Error(t, w.Resume(bytes.NewReader(data), offset, []int64{10, 4}))
+}
+
+func TestReadDictionaryPages(t *testing.T) {
+	// A column of strings written the way most writers do: a dictionary
+	// page, then a data page v2 of dictionary indices
+	var dict plainEncoder
+	dict.byteArray([]byte("red"))
+	dict.byteArray([]byte("green"))
+
+	levels := appendHybrid(nil, []uint8{1, 0, 1, 1}, 1)
+	indices := append([]byte{1}, appendHybrid(nil, []uint8{1, 0, 0}, 1)...)
+
+	w := &thriftWriter{}
+	w.beginStruct()
+	w.i32(1, pageDictionary)
+	w.i32(2, int32(len(dict.buf)))
+	w.i32(3, int32(len(dict.buf)))
+	w.structField(7, func() {
+		w.i32(1, 2)
+		w.i32(2, encodingPlainDictionary)
+	})
+	w.endStruct()
+	chunk := append(w.buf, dict.buf...)
+	dataOffset := len(magic) + len(chunk)
+
+	w = &thriftWriter{}
+	w.beginStruct()
+	w.i32(1, pageDataV2)
+	w.i32(2, int32(len(levels)+len(indices)))
+	w.i32(3, int32(len(levels)+len(indices)))
+	w.structField(8, func() {
+		w.i32(1, 4)
+		w.i32(2, 1)
+		w.i32(3, 4)
+		w.i32(4, encodingRLEDictionary)
+		w.i32(5, int32(len(levels)))
+		w.i32(6, 0)
+		w.bool(7, false)
+	})
+	w.endStruct()
+	chunk = append(chunk, w.buf...)
+	chunk = append(chunk, levels...)
+	chunk = append(chunk, indices...)
+
+	column := newColumn(types.Column{Name: "color", DataType: types.DataTypeString, Nullable: true})
+	meta := fileMetaData{
+		Version: 1,
+		Schema:  []schemaElement{{Name: "schema", NumChildren: 1}, column.element},
+		NumRows: 4,
+		RowGroups: []rowGroup{{NumRows: 4, Columns: []columnChunk{{
+			FileOffset: int64(len(magic)),
+			Meta: columnMetaData{
+				Type:                  typeByteArray,
+				Encodings:             []int32{encodingPlainDictionary, encodingRLEDictionary, encodingRLE},
+				Path:                  []string{"color"},
+				NumValues:             4,
+				TotalUncompressedSize: int64(len(chunk)),
+				TotalCompressedSize:   int64(len(chunk)),
+				DataPageOffset:        int64(dataOffset),
+				DictionaryPageOffset:  int64(len(magic)),
+				HasDictionary:         true,
+			},
+		}}}},
+	}
+	footer := meta.encode()
+
+	data := append([]byte(magic), chunk...)
+	data = append(data, footer...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(footer)))
+	data = append(data, magic...)
+
+	rows := readFile(t, data, []int{0})
+	assert.Equal(t, [][]interface{}{{"green"}, {nil}, {"red"}, {"red"}}, rows)
+}
+
+// The expected bytes of the encoding tests are the examples of the Parquet
+// encodings specification, which uses blocks of 8 values in 1 miniblock for
+// brevity, laid out byte by byte.
+
+func TestDecodeDeltaBinaryPacked(t *testing.T) {
+	// 1, 2, 3, 4, 5: a minimum delta of 1 and no bits per delta
+	values, n, err := decodeDeltaBinaryPacked([]byte{0x08, 0x01, 0x05, 0x02, 0x02, 0x00, 0xff})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, values)
+	assert.Equal(t, 6, n)
+
+	// 7, 5, 3, 1, 2, 3, 4, 5: a minimum delta of -2 and 2 bits per delta
+	values, _, err = decodeDeltaBinaryPacked([]byte{0x08, 0x01, 0x08, 0x0e, 0x03, 0x02, 0xc0, 0x3f})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{7, 5, 3, 1, 2, 3, 4, 5}, values)
+
+	// Two blocks of 16 values in 2 miniblocks of 21 and 22 bits, then of 6
+	// bits and a miniblock after the last value, which is left out
+	values, n, err = decodeDeltaBinaryPacked([]byte{
+		0x10, 0x02, 0x14, 0x09,
+		0xe7, 0x91, 0xf4, 0x01, 0x15, 0x16,
+		0x76, 0x84, 0x7e, 0x9b, 0xd0, 0xcf, 0x11, 0xfa, 0xfc, 0x43, 0x4f, 0xca, 0xe7, 0xb9, 0x10, 0xfd, 0x1e, 0xa1, 0xa7, 0x23, 0xf4,
+		0x74, 0x84, 0x5e, 0x1d, 0xa1, 0x57, 0x47, 0xe8, 0xd5, 0x11, 0x7a, 0xea, 0x08, 0x3d, 0x00, 0x00, 0x50, 0x47, 0xe8, 0xd5, 0x11, 0x7a,
+		0x39, 0x06, 0x00,
+		0x00, 0xef, 0x01, 0x00, 0x00, 0x00,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{-5, -3, 100, 99, 1000, -1000, 0, 7, 7, 7, 8, 9, 10, 2000000, 12, 13, 14, -15, 16, 17}, values)
+	assert.Equal(t, 62, n)
+
+	for _, data := range [][]byte{
+		{0x08, 0x01, 0x08, 0x0e, 0x03, 0x02, 0xc0},
+		{0x08, 0x03, 0x05, 0x02},
+		{0x08},
+	} {
+		_, _, err := decodeDeltaBinaryPacked(data)
+		assert.Error(t, err, "%x", data)
+	}
+}
+
+func TestDecodeDeltaByteArrays(t *testing.T) {
+	// "Hello", "World", "Foobar", "ABCDEF": lengths 5, 5, 6, 6
+	data := append([]byte{0x08, 0x01, 0x04, 0x0a, 0x00, 0x01, 0x02}, "HelloWorldFoobarABCDEF"...)
+	values, err := decodeDeltaLengthByteArray(data)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("Hello"), []byte("World"), []byte("Foobar"), []byte("ABCDEF")}, values)
+	_, err = decodeDeltaLengthByteArray(data[:len(data)-1])
+	assert.Error(t, err)
+
+	// "axis", "axle", "babble", "babyhood": prefix lengths 0, 2, 0, 3 and
+	// suffix lengths 4, 2, 6, 5
+	data = []byte{0x08, 0x01, 0x04, 0x00, 0x03, 0x03, 0x44, 0x01, 0x00}
+	data = append(data, 0x08, 0x01, 0x04, 0x08, 0x03, 0x03, 0x70, 0x00, 0x00)
+	data = append(data, "axislebabbleyhood"...)
+	values, err = decodeDeltaByteArray(data)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("axis"), []byte("axle"), []byte("babble"), []byte("babyhood")}, values)
+
+	// A prefix longer than the previous value
+	_, err = decodeDeltaByteArray([]byte{0x08, 0x01, 0x01, 0x02, 0x08, 0x01, 0x01, 0x02, 'a'})
+	assert.Error(t, err)
+}
+
+func TestDecodeByteStreamSplit(t *testing.T) {
+	data, err := decodeByteStreamSplit([]byte{
+		0xaa, 0x00, 0xa3, 0xbb, 0x11, 0xb4, 0xcc, 0x22, 0xc5, 0xdd, 0x33, 0xd6,
+	}, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xaa, 0xbb, 0xcc, 0xdd, 0x00, 0x11, 0x22, 0x33, 0xa3, 0xb4, 0xc5, 0xd6}, data)
+	_, err = decodeByteStreamSplit(make([]byte, 10), 4)
+	assert.Error(t, err)
+}
+
+func TestDecompressLz4(t *testing.T) {
+	// Blocks of literals, and of literals then a match of 4 bytes at an
+	// offset of 3 and the last literals
+	literals := []byte("\x70abcabca")
+	matched := []byte("\x30abc\x03\x00\x50bcabc")
+	for _, block := range [][]byte{matched, []byte("\xc0abcabcabcabc")} {
+		out, err := decompress(CodecLz4Raw, block, 12)
+		require.NoError(t, err)
+		assert.Equal(t, "abcabcabcabc", string(out))
+	}
+
+	// Hadoop frames of the deprecated LZ4 codec, and a bare block under it
+	var frames []byte
+	frames = binary.BigEndian.AppendUint32(frames, 7)
+	frames = binary.BigEndian.AppendUint32(frames, uint32(len(literals)))
+	frames = append(frames, literals...)
+	frames = binary.BigEndian.AppendUint32(frames, 5)
+	frames = binary.BigEndian.AppendUint32(frames, 6)
+	frames = append(frames, "\x50bcabc"...)
+	for _, data := range [][]byte{frames, matched} {
+		out, err := decompress(CodecLz4, data, 12)
+		require.NoError(t, err)
+		assert.Equal(t, "abcabcabcabc", string(out))
+	}
+
+	_, err := decompress(CodecLz4Raw, []byte("\x30abc\x09\x00"), 12)
+	assert.Error(t, err)
+}
+
+// testPage is a data page v1 of numValues values and nulls
+type testPage struct {
+	encoding  int32
+	numValues int
+	levels    []uint8 // Definition levels of an optional column
+	values    []byte  // Encoded values
+}
+
+// columnFile returns a file of a single column and row group holding the
+// given pages
+func columnFile(t *testing.T, c column, codec Codec, pages ...testPage) []byte {
+	t.Helper()
+	data := []byte(magic)
+	rows := 0
+	for _, p := range pages {
+		var page []byte
+		if c.optional() {
+			levels := appendHybrid(nil, p.levels, 1)
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+			page = append(page, levels...)
+		}
+		page = append(page, p.values...)
+		compressed, err := compress(codec, page)
+		require.NoError(t, err)
+
+		header := &pageHeader{
+			Type:             pageData,
+			UncompressedSize: int32(len(page)),
+			CompressedSize:   int32(len(compressed)),
+			NumValues:        int32(p.numValues),
+			Encoding:         p.encoding,
+		}
+		data = append(data, header.encode()...)
+		data = append(data, compressed...)
+		rows += p.numValues
+	}
+
+	size := int64(len(data) - len(magic))
+	meta := fileMetaData{
+		Version: 1,
+		Schema:  []schemaElement{{Name: "schema", NumChildren: 1}, c.element},
+		NumRows: int64(rows),
+		RowGroups: []rowGroup{{NumRows: int64(rows), Columns: []columnChunk{{
+			FileOffset: int64(len(magic)),
+			Meta: columnMetaData{
+				Type:                  c.element.Type,
+				Encodings:             []int32{pages[0].encoding},
+				Path:                  []string{c.element.Name},
+				Codec:                 int32(codec),
+				NumValues:             int64(rows),
+				TotalUncompressedSize: size,
+				TotalCompressedSize:   size,
+				DataPageOffset:        int64(len(magic)),
+			},
+		}}}},
+	}
+	footer := meta.encode()
+	data = append(data, footer...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(footer)))
+	return append(data, magic...)
+}
+
+func TestReadEncodings(t *testing.T) {
+	// The examples of the specification as pages of files, with values
+	// following nulls in the optional columns
+	id := newColumn(types.Column{Name: "id", DataType: types.DataTypeBigInt})
+	data := columnFile(t, id, CodecLz4Raw, testPage{
+		encoding:  encodingDeltaBinaryPacked,
+		numValues: 8,
+		values:    []byte{0x08, 0x01, 0x08, 0x0e, 0x03, 0x02, 0xc0, 0x3f},
+	}, testPage{
+		encoding:  encodingDeltaBinaryPacked,
+		numValues: 5,
+		values:    []byte{0x08, 0x01, 0x05, 0x02, 0x02, 0x00},
+	})
+	assert.Equal(t, [][]interface{}{{int64(7)}, {int64(5)}, {int64(3)}, {int64(1)}, {int64(2)}, {int64(3)}, {int64(4)}, {int64(5)},
+		{int64(1)}, {int64(2)}, {int64(3)}, {int64(4)}, {int64(5)}}, readFile(t, data, []int{0}))
+
+	name := newColumn(types.Column{Name: "name", DataType: types.DataTypeString, Nullable: true})
+	prefixed := []byte{0x08, 0x01, 0x04, 0x00, 0x03, 0x03, 0x44, 0x01, 0x00, 0x08, 0x01, 0x04, 0x08, 0x03, 0x03, 0x70, 0x00, 0x00}
+	data = columnFile(t, name, CodecBrotli, testPage{
+		encoding:  encodingDeltaByteArray,
+		numValues: 5,
+		levels:    []uint8{1, 0, 1, 1, 1},
+		values:    append(prefixed, "axislebabbleyhood"...),
+	}, testPage{
+		encoding:  encodingDeltaLengthByteArray,
+		numValues: 4,
+		levels:    []uint8{1, 1, 1, 1},
+		values:    append([]byte{0x08, 0x01, 0x04, 0x0a, 0x00, 0x01, 0x02}, "HelloWorldFoobarABCDEF"...),
+	})
+	assert.Equal(t, [][]interface{}{{"axis"}, {nil}, {"axle"}, {"babble"}, {"babyhood"},
+		{"Hello"}, {"World"}, {"Foobar"}, {"ABCDEF"}}, readFile(t, data, []int{0}))
+
+	ratio := newColumn(types.Column{Name: "ratio", DataType: types.DataTypeFloat, Nullable: true})
+	data = columnFile(t, ratio, CodecSnappy, testPage{
+		encoding:  encodingByteStreamSplit,
+		numValues: 4,
+		levels:    []uint8{1, 1, 0, 1},
+		values:    []byte{0xaa, 0x00, 0xa3, 0xbb, 0x11, 0xb4, 0xcc, 0x22, 0xc5, 0xdd, 0x33, 0xd6},
+	})
+	assert.Equal(t, [][]interface{}{
+		{float64(math.Float32frombits(0xddccbbaa))},
+		{float64(math.Float32frombits(0x33221100))},
+		{nil},
+		{float64(math.Float32frombits(0xd6c5b4a3))},
+	}, readFile(t, data, []int{0}))
+
+	// Pages holding fewer values than their levels, and encodings the
+	// physical type cannot have
+	for _, page := range []testPage{
+		{encoding: encodingDeltaLengthByteArray, numValues: 5, levels: []uint8{1, 1, 1, 1, 1}, values: append([]byte{0x08, 0x01, 0x04, 0x0a, 0x00, 0x01, 0x02}, "HelloWorldFoobarABCDEF"...)},
+		{encoding: encodingDeltaBinaryPacked, numValues: 5, levels: []uint8{1, 1, 1, 1, 1}, values: []byte{0x08, 0x01, 0x05, 0x02, 0x02, 0x00}},
+	} {
+		data := columnFile(t, name, CodecUncompressed, page)
+		r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		_, err = r.ReadRowGroup(0, []int{0})
+		assert.Error(t, err)
+	}
+}
+
+func TestNewReaderErrors(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		[]byte("PAR1PAR1"),
+		[]byte("PAR1\xff\xff\x00\x00PAR1"),
+		[]byte("not a parquet file"),
+	} {
+		_, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		assert.Error(t, err, "%q", data)
+	}
+}
+
+func TestParseCodec(t *testing.T) {
+	codec, err := ParseCodec("")
+	require.NoError(t, err)
+	assert.Equal(t, CodecSnappy, codec)
+	codec, err = ParseCodec("ZSTD")
+	require.NoError(t, err)
+	assert.Equal(t, CodecZstd, codec)
+	codec, err = ParseCodec("lz4_raw")
+	require.NoError(t, err)
+	assert.Equal(t, CodecLz4Raw, codec)
+	_, err = ParseCodec("lz4")
+	assert.Error(t, err)
+}
+
+// TestReadFixtures reads the files that testdata/generate.py writes with
+// pyarrow and compares them with the schema and the rows recorded next to
+// each of them
+func TestReadFixtures(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.parquet"))
+	require.NoError(t, err)
+	if len(paths) == 0 {
+		t.Skip("no fixtures in testdata, run testdata/generate.py to write them")
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			expected, err := os.ReadFile(strings.TrimSuffix(path, ".parquet") + ".json")
+			require.NoError(t, err)
+			var want struct {
+				Columns []struct {
+					Name     string
+					Type     string
+					Nullable bool
+				}
+				Rows  [][]json.RawMessage
+				Error string
+			}
+			require.NoError(t, json.Unmarshal(expected, &want))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+			if want.Error != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), want.Error)
+				return
+			}
+			require.NoError(t, err)
+
+			schema := r.Schema()
+			require.Len(t, schema.Columns, len(want.Columns))
+			columns := make([]int, len(schema.Columns))
+			for i, col := range schema.Columns {
+				columns[i] = i
+				assert.Equal(t, want.Columns[i].Name, col.Name)
+				assert.Equal(t, want.Columns[i].Type, col.DataType.String(), col.Name)
+				assert.Equal(t, want.Columns[i].Nullable, col.Nullable, col.Name)
+			}
+
+			var rows [][]interface{}
+			for i := 0; i < r.NumRowGroups(); i++ {
+				group, err := r.ReadRowGroup(i, columns)
+				require.NoError(t, err)
+				rows = append(rows, group...)
+			}
+			require.Len(t, rows, len(want.Rows))
+			for i, row := range want.Rows {
+				for j, raw := range row {
+					assert.Equal(t, fixtureValue(t, schema.Columns[j].DataType, raw), rows[i][j], "row %d column %s", i, schema.Columns[j].Name)
+				}
+			}
+		})
+	}
+}
+
+// fixtureValue decodes a value recorded by testdata/generate.py to the
+// value the reader returns for a column of the given type
+func fixtureValue(t *testing.T, dataType types.DataType, raw json.RawMessage) interface{} {
+	t.Helper()
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&v))
+	if v == nil {
+		return nil
+	}
+
+	var value interface{}
+	var err error
+	switch dataType {
+	case types.DataTypeInt, types.DataTypeBigInt:
+		value, err = v.(json.Number).Int64()
+	case types.DataTypeFloat, types.DataTypeDouble:
+		value, err = v.(json.Number).Float64()
+	case types.DataTypeBytes:
+		value, err = base64.StdEncoding.DecodeString(v.(string))
+	case types.DataTypeDate:
+		value, err = time.Parse(time.DateOnly, v.(string))
+	case types.DataTypeTimestamp:
+		value, err = time.Parse(time.RFC3339Nano, v.(string))
+	default:
+		value = v
+	}
+	require.NoError(t, err)
+	return value
+}
@@ -0,0 +1,319 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// Reader reads the row groups of a Parquet file with a flat schema
+type Reader struct {
+	r       io.ReaderAt
+	meta    *fileMetaData
+	columns []column
+}
+
+// NewReader reads the footer of a file of the given size
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	if size < int64(2*len(magic)+4) {
+		return nil, fmt.Errorf("not a parquet file")
+	}
+	tail := make([]byte, 4+len(magic))
+	if _, err := r.ReadAt(tail, size-int64(len(tail))); err != nil {
+		return nil, fmt.Errorf("failed to read footer: %w", err)
+	}
+	if string(tail[4:]) != magic {
+		return nil, fmt.Errorf("not a parquet file")
+	}
+	length := int64(binary.LittleEndian.Uint32(tail))
+	if length > size-int64(len(tail)+len(magic)) {
+		return nil, fmt.Errorf("invalid footer length %d", length)
+	}
+
+	footer := make([]byte, length)
+	if _, err := r.ReadAt(footer, size-int64(len(tail))-length); err != nil {
+		return nil, fmt.Errorf("failed to read footer: %w", err)
+	}
+	meta, err := decodeFileMetaData(footer)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &Reader{r: r, meta: meta}
+	if len(meta.Schema) == 0 {
+		return nil, fmt.Errorf("file has no schema")
+	}
+	for _, e := range meta.Schema[1:] {
+		if !e.HasType {
+			return nil, fmt.Errorf("nested column %s is not supported", e.Name)
+		}
+		c, err := readColumn(e)
+		if err != nil {
+			return nil, err
+		}
+		reader.columns = append(reader.columns, c)
+	}
+	for i, group := range meta.RowGroups {
+		if len(group.Columns) != len(reader.columns) {
+			return nil, fmt.Errorf("row group %d has %d columns, schema has %d", i, len(group.Columns), len(reader.columns))
+		}
+	}
+	return reader, nil
+}
+
+// Schema returns the schema of the file
+func (r *Reader) Schema() types.Schema {
+	var schema types.Schema
+	for _, c := range r.columns {
+		schema.Columns = append(schema.Columns, types.Column{
+			Name:     c.element.Name,
+			DataType: c.dataType,
+			Nullable: c.optional(),
+		})
+	}
+	return schema
+}
+
+// NumRows returns the number of rows of the file
+func (r *Reader) NumRows() int64 {
+	return r.meta.NumRows
+}
+
+// NumRowGroups returns the number of row groups of the file
+func (r *Reader) NumRowGroups() int {
+	return len(r.meta.RowGroups)
+}
+
+// RowGroupRows returns the number of rows of a row group
+func (r *Reader) RowGroupRows(index int) int64 {
+	return r.meta.RowGroups[index].NumRows
+}
+
+// ReadRowGroup reads the given columns, by index in the schema, of a row
+// group and returns its rows
+func (r *Reader) ReadRowGroup(index int, columns []int) ([][]interface{}, error) {
+	if index < 0 || index >= len(r.meta.RowGroups) {
+		return nil, fmt.Errorf("row group %d does not exist", index)
+	}
+	group := &r.meta.RowGroups[index]
+
+	rows := make([][]interface{}, group.NumRows)
+	for i := range rows {
+		rows[i] = make([]interface{}, len(columns))
+	}
+	for j, col := range columns {
+		if col < 0 || col >= len(r.columns) {
+			return nil, fmt.Errorf("column %d does not exist", col)
+		}
+		c := &r.columns[col]
+		values, err := r.readChunk(c, &group.Columns[col].Meta, group.NumRows)
+		if err != nil {
+			return nil, fmt.Errorf("column %s of row group %d: %w", c.element.Name, index, err)
+		}
+		for i, v := range values {
+			rows[i][j] = v
+		}
+	}
+	return rows, nil
+}
+
+// readChunk reads and decodes the values of a column chunk
+func (r *Reader) readChunk(c *column, meta *columnMetaData, numRows int64) ([]interface{}, error) {
+	start := meta.DataPageOffset
+	if meta.HasDictionary && meta.DictionaryPageOffset > 0 && meta.DictionaryPageOffset < start {
+		start = meta.DictionaryPageOffset
+	}
+	data := make([]byte, meta.TotalCompressedSize)
+	if _, err := r.r.ReadAt(data, start); err != nil {
+		return nil, fmt.Errorf("read error: %w", err)
+	}
+
+	codec := Codec(meta.Codec)
+	values := make([]interface{}, 0, numRows)
+	var dictionary []interface{}
+	for pos := 0; int64(len(values)) < numRows; {
+		if pos >= len(data) {
+			return nil, fmt.Errorf("column chunk holds %d values, not %d", len(values), numRows)
+		}
+		h, size, err := decodePageHeader(data[pos:])
+		if err != nil {
+			return nil, err
+		}
+		pos += size
+		if pos+int(h.CompressedSize) > len(data) {
+			return nil, fmt.Errorf("truncated page")
+		}
+		page := data[pos : pos+int(h.CompressedSize)]
+		pos += int(h.CompressedSize)
+
+		switch h.Type {
+		case pageDictionary:
+			page, err = decompress(codec, page, int(h.UncompressedSize))
+			if err != nil {
+				return nil, fmt.Errorf("decompression error: %w", err)
+			}
+			d := &plainDecoder{typ: c.element.Type, typeLength: int(c.element.TypeLength), data: page}
+			dictionary = make([]interface{}, h.NumValues)
+			for i := range dictionary {
+				v, err := d.next()
+				if err != nil {
+					return nil, fmt.Errorf("dictionary page: %w", err)
+				}
+				dictionary[i] = c.decode(v)
+			}
+		case pageData, pageDataV2:
+			if values, err = c.readPage(values, h, page, codec, dictionary); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return values, nil
+}
+
+// readPage decodes a data page and appends its values
+func (c *column) readPage(values []interface{}, h *pageHeader, page []byte, codec Codec, dictionary []interface{}) ([]interface{}, error) {
+	n := int(h.NumValues)
+	var levels []byte
+	var err error
+
+	if h.Type == pageDataV2 {
+		levelsLen := int(h.RepetitionLevelLen + h.DefinitionLevelLen)
+		if levelsLen > len(page) {
+			return nil, fmt.Errorf("truncated page")
+		}
+		levels = page[h.RepetitionLevelLen:levelsLen]
+		page = page[levelsLen:]
+		if h.IsCompressed {
+			if page, err = decompress(codec, page, int(h.UncompressedSize)-levelsLen); err != nil {
+				return nil, fmt.Errorf("decompression error: %w", err)
+			}
+		}
+	} else {
+		if page, err = decompress(codec, page, int(h.UncompressedSize)); err != nil {
+			return nil, fmt.Errorf("decompression error: %w", err)
+		}
+		if c.optional() {
+			if len(page) < 4 {
+				return nil, fmt.Errorf("truncated page")
+			}
+			size := int(binary.LittleEndian.Uint32(page))
+			if 4+size > len(page) {
+				return nil, fmt.Errorf("truncated page")
+			}
+			levels, page = page[4:4+size], page[4+size:]
+		}
+	}
+
+	// Definition levels tell nulls from values
+	defined := n
+	var definitions []uint32
+	if c.optional() {
+		if definitions, err = decodeHybrid(levels, 1, n); err != nil {
+			return nil, fmt.Errorf("definition levels: %w", err)
+		}
+		defined = 0
+		for _, level := range definitions {
+			defined += int(level)
+		}
+	}
+
+	plain := func(data []byte) func() (interface{}, error) {
+		d := &plainDecoder{typ: c.element.Type, typeLength: int(c.element.TypeLength), data: data}
+		return func() (interface{}, error) {
+			v, err := d.next()
+			if err != nil {
+				return nil, err
+			}
+			return c.decode(v), nil
+		}
+	}
+
+	var next func() (interface{}, error)
+	switch h.Encoding {
+	case encodingPlain:
+		next = plain(page)
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if dictionary == nil {
+			return nil, fmt.Errorf("dictionary page is missing")
+		}
+		if len(page) == 0 {
+			return nil, fmt.Errorf("truncated page")
+		}
+		indices, err := decodeHybrid(page[1:], int(page[0]), defined)
+		if err != nil {
+			return nil, fmt.Errorf("dictionary indices: %w", err)
+		}
+		next = func() (interface{}, error) {
+			i := indices[0]
+			indices = indices[1:]
+			if int(i) >= len(dictionary) {
+				return nil, fmt.Errorf("dictionary index %d is out of range", i)
+			}
+			return dictionary[i], nil
+		}
+	case encodingRLE:
+		if c.element.Type != typeBoolean {
+			return nil, fmt.Errorf("unsupported encoding %d", h.Encoding)
+		}
+		if len(page) < 4 {
+			return nil, fmt.Errorf("truncated page")
+		}
+		page = page[4:]
+		bools, err := decodeHybrid(page, 1, defined)
+		if err != nil {
+			return nil, err
+		}
+		next = func() (interface{}, error) {
+			v := bools[0] == 1
+			bools = bools[1:]
+			return v, nil
+		}
+	case encodingDeltaBinaryPacked, encodingDeltaLengthByteArray, encodingDeltaByteArray:
+		decoded, err := decodeDelta(h.Encoding, c.element.Type, page)
+		if err != nil {
+			return nil, err
+		}
+		next = func() (interface{}, error) {
+			if len(decoded) == 0 {
+				return nil, fmt.Errorf("page holds fewer values than its definition levels")
+			}
+			v := decoded[0]
+			decoded = decoded[1:]
+			return c.decode(v), nil
+		}
+	case encodingByteStreamSplit:
+		var size int
+		switch c.element.Type {
+		case typeInt32, typeFloat:
+			size = 4
+		case typeInt64, typeDouble:
+			size = 8
+		case typeFixedLenByteArray:
+			size = int(c.element.TypeLength)
+		default:
+			return nil, fmt.Errorf("unsupported encoding %d", h.Encoding)
+		}
+		data, err := decodeByteStreamSplit(page, size)
+		if err != nil {
+			return nil, err
+		}
+		next = plain(data)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %d", h.Encoding)
+	}
+
+	for i := 0; i < n; i++ {
+		if definitions != nil && definitions[i] == 0 {
+			values = append(values, nil)
+			continue
+		}
+		v, err := next()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
@@ -0,0 +1,262 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/atlanssia/fustgo/internal/expr"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// julianEpoch is the Julian day of the Unix epoch, used by INT96 timestamps
+const julianEpoch = 2440588
+
+// column describes a leaf column of a file
+type column struct {
+	element  schemaElement
+	dataType types.DataType
+}
+
+// optional reports whether the column can hold nulls
+func (c *column) optional() bool {
+	return c.element.Repetition == repetitionOptional
+}
+
+// newColumn maps a column of a schema to a Parquet column
+func newColumn(col types.Column) column {
+	e := schemaElement{
+		HasType:    true,
+		Name:       col.Name,
+		Repetition: repetitionRequired,
+	}
+	if col.Nullable {
+		e.Repetition = repetitionOptional
+	}
+
+	dataType := col.DataType
+	switch dataType {
+	case types.DataTypeInt:
+		e.Type = typeInt32
+		e.HasConverted, e.ConvertedType = true, convertedInt32
+		e.Logical, e.IntegerBits, e.IntegerSigned = logicalInteger, 32, true
+	case types.DataTypeBigInt:
+		e.Type = typeInt64
+		e.HasConverted, e.ConvertedType = true, convertedInt64
+		e.Logical, e.IntegerBits, e.IntegerSigned = logicalInteger, 64, true
+	case types.DataTypeFloat:
+		e.Type = typeFloat
+	case types.DataTypeDouble:
+		e.Type = typeDouble
+	case types.DataTypeBool:
+		e.Type = typeBoolean
+	case types.DataTypeDate:
+		e.Type = typeInt32
+		e.HasConverted, e.ConvertedType = true, convertedDate
+		e.Logical = logicalDate
+	case types.DataTypeTimestamp:
+		e.Type = typeInt64
+		e.HasConverted, e.ConvertedType = true, convertedTimestampMicros
+		e.Logical, e.TimestampUTC, e.TimestampUnit = logicalTimestamp, true, unitMicros
+	case types.DataTypeBytes:
+		e.Type = typeByteArray
+	case types.DataTypeJSON:
+		e.Type = typeByteArray
+		e.HasConverted, e.ConvertedType = true, convertedJSON
+		e.Logical = logicalJSON
+	default:
+		// Strings, and values of unknown type written as strings
+		dataType = types.DataTypeString
+		e.Type = typeByteArray
+		e.HasConverted, e.ConvertedType = true, convertedUTF8
+		e.Logical = logicalString
+	}
+	return column{element: e, dataType: dataType}
+}
+
+// readColumn maps a leaf of the schema of a file to a column
+func readColumn(e schemaElement) (column, error) {
+	if e.Repetition == repetitionRepeated {
+		return column{}, fmt.Errorf("repeated column %s is not supported", e.Name)
+	}
+
+	c := column{element: e}
+	switch e.Type {
+	case typeBoolean:
+		c.dataType = types.DataTypeBool
+	case typeInt32:
+		switch {
+		case c.isDate():
+			c.dataType = types.DataTypeDate
+		case c.isDecimal():
+			c.dataType = types.DataTypeDouble
+		case e.Logical == logicalInteger && !e.IntegerSigned:
+			c.dataType = types.DataTypeBigInt
+		default:
+			c.dataType = types.DataTypeInt
+		}
+	case typeInt64:
+		switch {
+		case c.timeUnit() != 0:
+			c.dataType = types.DataTypeTimestamp
+		case c.isDecimal():
+			c.dataType = types.DataTypeDouble
+		default:
+			c.dataType = types.DataTypeBigInt
+		}
+	case typeInt96:
+		c.dataType = types.DataTypeTimestamp
+	case typeFloat:
+		c.dataType = types.DataTypeFloat
+	case typeDouble:
+		c.dataType = types.DataTypeDouble
+	case typeByteArray, typeFixedLenByteArray:
+		switch {
+		case c.isDecimal():
+			c.dataType = types.DataTypeDouble
+		case e.Logical == logicalJSON || (e.HasConverted && e.ConvertedType == convertedJSON):
+			c.dataType = types.DataTypeJSON
+		case e.Logical == logicalString || e.Logical == logicalEnum ||
+			(e.HasConverted && (e.ConvertedType == convertedUTF8 || e.ConvertedType == convertedEnum)):
+			c.dataType = types.DataTypeString
+		default:
+			c.dataType = types.DataTypeBytes
+		}
+	default:
+		return column{}, fmt.Errorf("column %s has unsupported physical type %d", e.Name, e.Type)
+	}
+	return c, nil
+}
+
+func (c *column) isDate() bool {
+	return c.element.Logical == logicalDate || (c.element.HasConverted && c.element.ConvertedType == convertedDate)
+}
+
+func (c *column) isDecimal() bool {
+	return c.element.Logical == logicalDecimal || (c.element.HasConverted && c.element.ConvertedType == convertedDecimal)
+}
+
+// scale returns the scale of a decimal column
+func (c *column) scale() int32 {
+	if c.element.Logical == logicalDecimal {
+		return c.element.DecimalScale
+	}
+	return c.element.Scale
+}
+
+// timeUnit returns the unit of an INT64 timestamp column, or 0
+func (c *column) timeUnit() int16 {
+	e := &c.element
+	switch {
+	case e.Logical == logicalTimestamp:
+		return e.TimestampUnit
+	case e.HasConverted && e.ConvertedType == convertedTimestampMillis:
+		return unitMillis
+	case e.HasConverted && e.ConvertedType == convertedTimestampMicros:
+		return unitMicros
+	default:
+		return 0
+	}
+}
+
+// encode casts a value to the data type of the column and appends it
+func (c *column) encode(e *plainEncoder, v interface{}) error {
+	v, err := expr.Cast(v, c.dataType)
+	if err != nil {
+		return err
+	}
+
+	switch c.dataType {
+	case types.DataTypeInt:
+		n := v.(int64)
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return fmt.Errorf("value %d is out of the range of INT", n)
+		}
+		e.int32(int32(n))
+	case types.DataTypeBigInt:
+		e.int64(v.(int64))
+	case types.DataTypeFloat:
+		e.float(float32(v.(float64)))
+	case types.DataTypeDouble:
+		e.double(v.(float64))
+	case types.DataTypeBool:
+		e.bool(v.(bool))
+	case types.DataTypeDate:
+		t := v.(time.Time)
+		days := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		e.int32(int32(days))
+	case types.DataTypeTimestamp:
+		e.int64(v.(time.Time).UnixMicro())
+	case types.DataTypeBytes:
+		e.byteArray(v.([]byte))
+	default:
+		e.byteArray([]byte(v.(string)))
+	}
+	return nil
+}
+
+// decode converts a PLAIN decoded physical value to the value of the column
+func (c *column) decode(v interface{}) interface{} {
+	switch c.dataType {
+	case types.DataTypeInt:
+		return int64(v.(int32))
+	case types.DataTypeBigInt:
+		if n, ok := v.(int32); ok {
+			// Unsigned 32-bit integers
+			return int64(uint32(n))
+		}
+		return v
+	case types.DataTypeFloat:
+		return float64(v.(float32))
+	case types.DataTypeDate:
+		return time.Unix(int64(v.(int32))*86400, 0).UTC()
+	case types.DataTypeTimestamp:
+		if b, ok := v.([]byte); ok {
+			// INT96: nanoseconds of the day, then the Julian day
+			nanos := int64(binary.LittleEndian.Uint64(b))
+			days := int64(binary.LittleEndian.Uint32(b[8:])) - julianEpoch
+			return time.Unix(days*86400, nanos).UTC()
+		}
+		n := v.(int64)
+		switch c.timeUnit() {
+		case unitMillis:
+			return time.UnixMilli(n).UTC()
+		case unitMicros:
+			return time.UnixMicro(n).UTC()
+		default:
+			return time.Unix(0, n).UTC()
+		}
+	case types.DataTypeDouble:
+		if !c.isDecimal() {
+			return v
+		}
+		var unscaled *big.Float
+		switch n := v.(type) {
+		case int32:
+			unscaled = new(big.Float).SetInt64(int64(n))
+		case int64:
+			unscaled = new(big.Float).SetInt64(n)
+		case []byte:
+			unscaled = new(big.Float).SetInt(bigEndianSigned(n))
+		}
+		f, _ := unscaled.Quo(unscaled, big.NewFloat(math.Pow10(int(c.scale())))).Float64()
+		return f
+	case types.DataTypeBytes:
+		return append([]byte(nil), v.([]byte)...)
+	case types.DataTypeString, types.DataTypeJSON:
+		return string(v.([]byte))
+	default:
+		return v
+	}
+}
+
+// bigEndianSigned decodes a two's complement big-endian integer
+func bigEndianSigned(b []byte) *big.Int {
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b))*8))
+	}
+	return n
+}
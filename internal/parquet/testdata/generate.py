@@ -0,0 +1,173 @@
+#!/usr/bin/env python3
+"""Writes Parquet files with pyarrow for the interoperability tests of the
+Parquet reader (TestReadFixtures), each next to a JSON file holding the
+schema the reader must return and the rows as pyarrow reads them back.
+
+    pip install pyarrow
+    python3 internal/parquet/testdata/generate.py
+
+Commit the .parquet and .json files it writes.
+"""
+
+import base64
+import datetime
+import decimal
+import json
+import os
+
+import pyarrow as pa
+import pyarrow.parquet as pq
+
+HERE = os.path.dirname(os.path.abspath(__file__))
+ROWS = 300
+
+SCHEMA = pa.schema([
+    pa.field("id", pa.int64(), nullable=False),
+    pa.field("i32", pa.int32()),
+    pa.field("i64", pa.int64()),
+    pa.field("u32", pa.uint32()),
+    pa.field("f32", pa.float32()),
+    pa.field("f64", pa.float64()),
+    pa.field("flag", pa.bool_()),
+    pa.field("s", pa.string()),
+    pa.field("b", pa.binary()),
+    pa.field("d", pa.date32()),
+    pa.field("ts", pa.timestamp("us", tz="UTC")),
+    pa.field("ts_ms", pa.timestamp("ms")),
+    pa.field("dec", pa.decimal128(9, 2)),
+])
+
+# Type and nullability the reader maps each column to
+COLUMNS = [
+    {"name": "id", "type": "BIGINT", "nullable": False},
+    {"name": "i32", "type": "INT", "nullable": True},
+    {"name": "i64", "type": "BIGINT", "nullable": True},
+    {"name": "u32", "type": "BIGINT", "nullable": True},
+    {"name": "f32", "type": "FLOAT", "nullable": True},
+    {"name": "f64", "type": "DOUBLE", "nullable": True},
+    {"name": "flag", "type": "BOOL", "nullable": True},
+    {"name": "s", "type": "STRING", "nullable": True},
+    {"name": "b", "type": "BYTES", "nullable": True},
+    {"name": "d", "type": "DATE", "nullable": True},
+    {"name": "ts", "type": "TIMESTAMP", "nullable": True},
+    {"name": "ts_ms", "type": "TIMESTAMP", "nullable": True},
+    {"name": "dec", "type": "DOUBLE", "nullable": True},
+]
+
+
+def row(i):
+    """Returns row i, with nulls in the optional columns of every 7th row"""
+    if i % 7 == 3:
+        return [i] + [None] * (len(SCHEMA) - 1)
+    return [
+        i,
+        (i * 7919) % 200001 - 100000,
+        (-1) ** i * i * 30011 * 1000003 + (2**62 if i == 1 else 0),
+        2**32 - 1 - i,
+        i / 4,
+        i / 8 - 10,
+        i % 3 == 0,
+        "name-%d" % (i % 17) + ("x" * (i % 5)),
+        bytes([i % 256, 0, 255]),
+        datetime.date(2024, 1, 1) + datetime.timedelta(days=i),
+        datetime.datetime(2024, 3, 1, 12, tzinfo=datetime.timezone.utc) + datetime.timedelta(microseconds=i * 1001),
+        datetime.datetime(2024, 3, 1, 12) + datetime.timedelta(milliseconds=i * 37),
+        decimal.Decimal(i * 125) / 100,
+    ]
+
+
+def table():
+    rows = [row(i) for i in range(ROWS)]
+    return pa.table([pa.array([r[j] for r in rows], type=f.type) for j, f in enumerate(SCHEMA)], schema=SCHEMA)
+
+
+def to_json(v):
+    """Encodes a value the way the Go test encodes the values of the reader"""
+    if v is None or isinstance(v, (bool, int, str)):
+        return v
+    if isinstance(v, float):
+        return v
+    if isinstance(v, decimal.Decimal):
+        return float(v)
+    if isinstance(v, bytes):
+        return base64.b64encode(v).decode()
+    if isinstance(v, datetime.datetime):
+        if v.tzinfo is not None:
+            v = v.astimezone(datetime.timezone.utc).replace(tzinfo=None)
+        text = v.strftime("%Y-%m-%dT%H:%M:%S")
+        if v.microsecond:
+            text += ("." + "%06d" % v.microsecond).rstrip("0")
+        return text + "Z"
+    if isinstance(v, datetime.date):
+        return v.isoformat()
+    raise TypeError("unexpected value %r" % (v,))
+
+
+def write(name, data, columns=COLUMNS, error=None, **options):
+    path = os.path.join(HERE, name + ".parquet")
+    options.setdefault("row_group_size", 128)
+    options.setdefault("data_page_size", 512)
+    pq.write_table(data, path, **options)
+
+    expected = {"writer": "pyarrow " + pa.__version__, "options": {k: str(v) for k, v in options.items()}}
+    if error:
+        expected["error"] = error
+    else:
+        read = pq.read_table(path)
+        expected["columns"] = columns
+        expected["rows"] = [[to_json(v) for v in r.values()] for r in read.to_pylist()]
+    with open(os.path.join(HERE, name + ".json"), "w") as f:
+        json.dump(expected, f, indent=1)
+        f.write("\n")
+
+
+def main():
+    data = table()
+
+    # PLAIN pages with each codec, and data pages v2
+    for codec in ["none", "snappy", "gzip", "zstd", "brotli", "lz4"]:
+        write("codec_" + codec, data, compression=codec, use_dictionary=False)
+    write("page_v2", data, compression="zstd", use_dictionary=False, data_page_version="2.0")
+    write("page_v2_uncompressed", data, compression="none", use_dictionary=False, data_page_version="2.0")
+
+    # Dictionary pages, with fallback to PLAIN when the dictionary grows
+    write("dictionary", data, compression="snappy", use_dictionary=True)
+    write("dictionary_fallback", data, compression="snappy", use_dictionary=True, dictionary_pagesize_limit=64)
+
+    # DELTA_* and BYTE_STREAM_SPLIT encodings
+    write("delta", data, compression="none", use_dictionary=False, column_encoding={
+        "id": "DELTA_BINARY_PACKED",
+        "i32": "DELTA_BINARY_PACKED",
+        "i64": "DELTA_BINARY_PACKED",
+        "u32": "DELTA_BINARY_PACKED",
+        "s": "DELTA_BYTE_ARRAY",
+        "b": "DELTA_LENGTH_BYTE_ARRAY",
+    })
+    write("delta_v2", data, compression="snappy", use_dictionary=False, data_page_version="2.0", column_encoding={
+        "i64": "DELTA_BINARY_PACKED",
+        "s": "DELTA_LENGTH_BYTE_ARRAY",
+        "b": "DELTA_BYTE_ARRAY",
+    })
+    write("byte_stream_split", data, compression="none", use_dictionary=False, column_encoding={
+        "f32": "BYTE_STREAM_SPLIT",
+        "f64": "BYTE_STREAM_SPLIT",
+    })
+
+    # Legacy INT96 timestamps
+    write("int96", data, compression="snappy", use_deprecated_int96_timestamps=True)
+
+    # Nested columns are rejected with an error
+    nested = pa.table({
+        "id": pa.array([1, 2], type=pa.int64()),
+        "address": pa.array([{"city": "Oslo"}, None], type=pa.struct([("city", pa.string())])),
+    })
+    write("nested_struct", nested, error="nested column address is not supported")
+    lists = pa.table({
+        "id": pa.array([1, 2], type=pa.int64()),
+        "tags": pa.array([["a"], []], type=pa.list_(pa.string())),
+    })
+    write("nested_list", lists, error="nested column tags is not supported")
+
+
+if __name__ == "__main__":
+    main()
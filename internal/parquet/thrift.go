@@ -0,0 +1,323 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Parquet metadata is serialized with the Thrift compact protocol. Only the
+// parts of the protocol used by the Parquet format are implemented.
+
+// Thrift compact protocol types
+const (
+	thriftBoolTrue   = 1
+	thriftBoolFalse  = 2
+	thriftByte       = 3
+	thriftI16        = 4
+	thriftI32        = 5
+	thriftI64        = 6
+	thriftDouble     = 7
+	thriftBinary     = 8
+	thriftList       = 9
+	thriftSet        = 10
+	thriftMap        = 11
+	thriftStructType = 12
+)
+
+var errTruncated = errors.New("truncated thrift data")
+
+// thriftWriter encodes Thrift structs
+type thriftWriter struct {
+	buf     []byte
+	lastIDs []int16 // Last field ID of each enclosing struct
+	lastID  int16
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - w.lastID; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|typ)
+	} else {
+		w.buf = append(w.buf, typ)
+		w.zigzag(int64(id))
+	}
+	w.lastID = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.fieldHeader(id, thriftI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.fieldHeader(id, thriftI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) bool(id int16, v bool) {
+	if v {
+		w.fieldHeader(id, thriftBoolTrue)
+	} else {
+		w.fieldHeader(id, thriftBoolFalse)
+	}
+}
+
+func (w *thriftWriter) binary(id int16, v []byte) {
+	w.fieldHeader(id, thriftBinary)
+	w.varint(uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+func (w *thriftWriter) string(id int16, v string) {
+	w.binary(id, []byte(v))
+}
+
+// structField writes a struct field whose fields are written by body
+func (w *thriftWriter) structField(id int16, body func()) {
+	w.fieldHeader(id, thriftStructType)
+	w.beginStruct()
+	body()
+	w.endStruct()
+}
+
+func (w *thriftWriter) beginStruct() {
+	w.lastIDs = append(w.lastIDs, w.lastID)
+	w.lastID = 0
+}
+
+func (w *thriftWriter) endStruct() {
+	w.buf = append(w.buf, 0)
+	w.lastID = w.lastIDs[len(w.lastIDs)-1]
+	w.lastIDs = w.lastIDs[:len(w.lastIDs)-1]
+}
+
+func (w *thriftWriter) listHeader(id int16, elemType byte, n int) {
+	w.fieldHeader(id, thriftList)
+	if n < 15 {
+		w.buf = append(w.buf, byte(n)<<4|elemType)
+	} else {
+		w.buf = append(w.buf, 0xF0|elemType)
+		w.varint(uint64(n))
+	}
+}
+
+// structList writes a list of n structs, the fields of each written by body
+func (w *thriftWriter) structList(id int16, n int, body func(i int)) {
+	w.listHeader(id, thriftStructType, n)
+	for i := 0; i < n; i++ {
+		w.beginStruct()
+		body(i)
+		w.endStruct()
+	}
+}
+
+func (w *thriftWriter) i32List(id int16, values []int32) {
+	w.listHeader(id, thriftI32, len(values))
+	for _, v := range values {
+		w.zigzag(int64(v))
+	}
+}
+
+func (w *thriftWriter) stringList(id int16, values []string) {
+	w.listHeader(id, thriftBinary, len(values))
+	for _, v := range values {
+		w.varint(uint64(len(v)))
+		w.buf = append(w.buf, v...)
+	}
+}
+
+// thriftStruct is a decoded Thrift struct: its field values by field ID.
+// Integers are int64, binaries []byte, lists []interface{} and structs
+// thriftStruct.
+type thriftStruct map[int16]interface{}
+
+func (s thriftStruct) i64(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStruct) i32(id int16) int32 {
+	return int32(s.i64(id))
+}
+
+func (s thriftStruct) has(id int16) bool {
+	_, ok := s[id]
+	return ok
+}
+
+func (s thriftStruct) bool(id int16, def bool) bool {
+	if v, ok := s[id].(bool); ok {
+		return v
+	}
+	return def
+}
+
+func (s thriftStruct) string(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+func (s thriftStruct) strct(id int16) thriftStruct {
+	v, _ := s[id].(thriftStruct)
+	return v
+}
+
+func (s thriftStruct) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+// thriftReader decodes Thrift structs
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errTruncated
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *thriftReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *thriftReader) zigzag() (int64, error) {
+	v, err := r.varint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+// readStruct decodes a struct up to its stop field
+func (r *thriftReader) readStruct() (thriftStruct, error) {
+	s := make(thriftStruct)
+	var lastID int16
+	for {
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if header == 0 {
+			return s, nil
+		}
+
+		typ := header & 0x0F
+		id := lastID + int16(header>>4)
+		if header>>4 == 0 {
+			v, err := r.zigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		lastID = id
+
+		var value interface{}
+		switch typ {
+		case thriftBoolTrue:
+			value = true
+		case thriftBoolFalse:
+			value = false
+		default:
+			value, err = r.readValue(typ)
+			if err != nil {
+				return nil, err
+			}
+		}
+		s[id] = value
+	}
+}
+
+// readValue decodes a value of the given type
+func (r *thriftReader) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case thriftBoolTrue, thriftBoolFalse:
+		// Booleans in lists are a byte of their own
+		b, err := r.byte()
+		return b == thriftBoolTrue, err
+	case thriftByte:
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftDouble:
+		if r.pos+8 > len(r.buf) {
+			return nil, errTruncated
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.buf[r.pos:]))
+		r.pos += 8
+		return v, nil
+	case thriftBinary:
+		n, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(r.buf)-r.pos) < n {
+			return nil, errTruncated
+		}
+		v := r.buf[r.pos : r.pos+int(n)]
+		r.pos += int(n)
+		return v, nil
+	case thriftList, thriftSet:
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(header >> 4)
+		if n == 15 {
+			if n, err = r.varint(); err != nil {
+				return nil, err
+			}
+		}
+		if n > uint64(len(r.buf)-r.pos) {
+			return nil, errTruncated
+		}
+		elemType := header & 0x0F
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = r.readValue(elemType); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case thriftMap:
+		n, err := r.varint()
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		types, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < n; i++ {
+			if _, err := r.readValue(types >> 4); err != nil {
+				return nil, err
+			}
+			if _, err := r.readValue(types & 0x0F); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case thriftStructType:
+		return r.readStruct()
+	default:
+		return nil, fmt.Errorf("unknown thrift type %d", typ)
+	}
+}
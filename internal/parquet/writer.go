@@ -0,0 +1,383 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+const magic = "PAR1"
+
+// ErrInvalidRow is returned by Write for rows that do not fit the schema.
+// Such rows are dropped whole and the writer remains usable.
+var ErrInvalidRow = errors.New("invalid row")
+
+const (
+	DefaultRowGroupSize = 64 << 20
+	DefaultPageSize     = 1 << 20
+)
+
+// WriterConfig configures a Writer
+type WriterConfig struct {
+	// Compression is the codec of the column chunks
+	Compression Codec
+
+	// RowGroupSize and RowGroupRows complete a row group once it holds that
+	// many bytes, before compression, or rows. Zero selects the default
+	// size and no row limit.
+	RowGroupSize int64
+	RowGroupRows int64
+
+	// PageSize is the size of the data pages before compression. Zero
+	// selects the default.
+	PageSize int
+}
+
+// Writer writes records to a Parquet file. Rows are buffered in memory,
+// column by column, and written as a row group when the group is full or
+// on Flush; the footer describing the row groups is written on Close.
+type Writer struct {
+	w         io.Writer
+	config    WriterConfig
+	columns   []*columnWriter
+	rowGroups []rowGroup
+	rows      int64 // Buffered rows
+	offset    int64 // Bytes written to the file
+	closed    bool
+}
+
+// columnWriter buffers the pages of a column chunk
+type columnWriter struct {
+	column
+	values    plainEncoder
+	levels    []uint8 // Definition levels of the page
+	numValues int     // Values and nulls in the page
+	pages     []byte  // Completed pages, with their headers
+	rawSize   int64   // Size of the completed pages before compression
+}
+
+// NewWriter creates a writer of records of the given schema
+func NewWriter(w io.Writer, schema types.Schema, config WriterConfig) (*Writer, error) {
+	if len(schema.Columns) == 0 {
+		return nil, fmt.Errorf("schema has no columns")
+	}
+	if config.RowGroupSize <= 0 {
+		config.RowGroupSize = DefaultRowGroupSize
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = DefaultPageSize
+	}
+	if _, err := compress(config.Compression, nil); err != nil {
+		return nil, err
+	}
+
+	writer := &Writer{w: w, config: config}
+	for _, col := range schema.Columns {
+		writer.columns = append(writer.columns, &columnWriter{column: newColumn(col)})
+	}
+	return writer, nil
+}
+
+// Resume continues a file that was cut after its last complete row group,
+// at offset. The row groups are rebuilt from the pages read back through r,
+// given the number of rows of each.
+func (w *Writer) Resume(r io.ReaderAt, offset int64, rowGroups []int64) error {
+	if w.offset > 0 || w.rows > 0 {
+		return fmt.Errorf("writer has already written data")
+	}
+	if offset == 0 {
+		return nil
+	}
+
+	head := make([]byte, len(magic))
+	if _, err := r.ReadAt(head, 0); err != nil || string(head) != magic {
+		return fmt.Errorf("not a parquet file")
+	}
+
+	pos := int64(len(magic))
+	for _, rows := range rowGroups {
+		group := rowGroup{NumRows: rows}
+		for _, c := range w.columns {
+			chunk, err := scanChunk(r, pos, rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan row group %d: %w", len(w.rowGroups), err)
+			}
+			c.describe(&chunk.Meta)
+			chunk.Meta.Codec = int32(w.config.Compression)
+			group.Columns = append(group.Columns, chunk)
+			group.TotalByteSize += chunk.Meta.TotalUncompressedSize
+			pos += chunk.Meta.TotalCompressedSize
+		}
+		w.rowGroups = append(w.rowGroups, group)
+	}
+	if pos != offset {
+		return fmt.Errorf("row groups end at %d, not at %d", pos, offset)
+	}
+	w.offset = offset
+	return nil
+}
+
+// scanChunk reads the page headers of the column chunk at offset, until
+// they hold the given number of rows
+func scanChunk(r io.ReaderAt, offset, rows int64) (columnChunk, error) {
+	chunk := columnChunk{FileOffset: offset}
+	meta := &chunk.Meta
+	meta.DataPageOffset = -1
+
+	pos := offset
+	for meta.NumValues < rows {
+		h, size, err := readPageHeader(r, pos)
+		if err != nil {
+			return chunk, err
+		}
+		switch h.Type {
+		case pageData:
+			if meta.DataPageOffset < 0 {
+				meta.DataPageOffset = pos
+			}
+			meta.NumValues += int64(h.NumValues)
+		case pageDictionary:
+			meta.HasDictionary, meta.DictionaryPageOffset = true, pos
+		default:
+			return chunk, fmt.Errorf("unexpected page type %d", h.Type)
+		}
+		meta.TotalUncompressedSize += int64(size) + int64(h.UncompressedSize)
+		meta.TotalCompressedSize += int64(size) + int64(h.CompressedSize)
+		pos += int64(size) + int64(h.CompressedSize)
+	}
+	if meta.NumValues != rows {
+		return chunk, fmt.Errorf("column chunk holds %d values, not %d", meta.NumValues, rows)
+	}
+	return chunk, nil
+}
+
+// readPageHeader reads the page header at offset and returns its size
+func readPageHeader(r io.ReaderAt, offset int64) (*pageHeader, int, error) {
+	buf := make([]byte, 256)
+	for {
+		n, err := r.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		h, size, decodeErr := decodePageHeader(buf[:n])
+		if decodeErr == nil {
+			return h, size, nil
+		}
+		if n < len(buf) || !errors.Is(decodeErr, errTruncated) {
+			return nil, 0, decodeErr
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+// Write buffers a row, whose values are in the order of the schema
+func (w *Writer) Write(values []interface{}) error {
+	if w.closed {
+		return fmt.Errorf("writer is closed")
+	}
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("%w: row has %d values, schema has %d columns", ErrInvalidRow, len(values), len(w.columns))
+	}
+
+	marks := make([][3]int, len(w.columns))
+	for i, c := range w.columns {
+		marks[i] = c.mark()
+		if err := c.write(values[i]); err != nil {
+			// Drop the rest of the row to keep the columns aligned
+			for j, written := range w.columns[:i] {
+				written.reset(marks[j])
+			}
+			return fmt.Errorf("%w: column %s: %v", ErrInvalidRow, c.element.Name, err)
+		}
+	}
+	w.rows++
+
+	var size int64
+	for _, c := range w.columns {
+		if len(c.values.buf) >= w.config.PageSize {
+			if err := c.completePage(w.config.Compression); err != nil {
+				return err
+			}
+		}
+		size += c.rawSize + int64(len(c.values.buf))
+	}
+	if size >= w.config.RowGroupSize || (w.config.RowGroupRows > 0 && w.rows >= w.config.RowGroupRows) {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered rows as a row group
+func (w *Writer) Flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	if w.offset == 0 {
+		if err := w.write([]byte(magic)); err != nil {
+			return err
+		}
+	}
+
+	group := rowGroup{NumRows: w.rows}
+	for _, c := range w.columns {
+		if err := c.completePage(w.config.Compression); err != nil {
+			return err
+		}
+		chunk := columnChunk{FileOffset: w.offset}
+		chunk.Meta = columnMetaData{
+			Codec:                 int32(w.config.Compression),
+			NumValues:             w.rows,
+			TotalUncompressedSize: c.rawSize,
+			TotalCompressedSize:   int64(len(c.pages)),
+			DataPageOffset:        w.offset,
+		}
+		c.describe(&chunk.Meta)
+		if err := w.write(c.pages); err != nil {
+			return err
+		}
+		group.Columns = append(group.Columns, chunk)
+		group.TotalByteSize += c.rawSize
+		c.pages, c.rawSize = c.pages[:0], 0
+	}
+	w.rowGroups = append(w.rowGroups, group)
+	w.rows = 0
+	return nil
+}
+
+// RowGroups returns the number of rows of each row group written
+func (w *Writer) RowGroups() []int64 {
+	rows := make([]int64, len(w.rowGroups))
+	for i, group := range w.rowGroups {
+		rows[i] = group.NumRows
+	}
+	return rows
+}
+
+// Offset returns the number of bytes written to the file
+func (w *Writer) Offset() int64 {
+	return w.offset
+}
+
+// Close flushes the buffered rows and writes the footer. It does not
+// close the underlying writer. Calling Close more than once is a no-op.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	w.closed = true
+
+	if w.offset == 0 {
+		if err := w.write([]byte(magic)); err != nil {
+			return err
+		}
+	}
+	meta := fileMetaData{
+		Version:   1,
+		Schema:    []schemaElement{{Name: "schema", NumChildren: int32(len(w.columns))}},
+		RowGroups: w.rowGroups,
+		CreatedBy: "fustgo",
+	}
+	for _, c := range w.columns {
+		meta.Schema = append(meta.Schema, c.element)
+	}
+	for _, group := range w.rowGroups {
+		meta.NumRows += group.NumRows
+	}
+
+	footer := meta.encode()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, magic...)
+	return w.write(footer)
+}
+
+func (w *Writer) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	if err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// describe fills in the metadata shared by all chunks of the column
+func (c *columnWriter) describe(meta *columnMetaData) {
+	meta.Type = c.element.Type
+	meta.Encodings = []int32{encodingPlain, encodingRLE}
+	meta.Path = []string{c.element.Name}
+}
+
+// write buffers a value
+func (c *columnWriter) write(v interface{}) error {
+	if v == nil {
+		if !c.optional() {
+			return fmt.Errorf("null value in a column that is not nullable")
+		}
+		c.levels = append(c.levels, 0)
+		c.numValues++
+		return nil
+	}
+
+	if err := c.encode(&c.values, v); err != nil {
+		return err
+	}
+	c.levels = append(c.levels, 1)
+	c.numValues++
+	return nil
+}
+
+// mark returns the state of the page buffer, for reset to go back to
+func (c *columnWriter) mark() [3]int {
+	return [3]int{len(c.values.buf), c.values.bits, len(c.levels)}
+}
+
+// reset drops the values buffered since mark was called
+func (c *columnWriter) reset(mark [3]int) {
+	c.values.buf, c.values.bits = c.values.buf[:mark[0]], mark[1]
+	if c.values.bits > 0 {
+		c.values.buf[len(c.values.buf)-1] &= 1<<c.values.bits - 1
+	}
+	c.levels = c.levels[:mark[2]]
+	c.numValues = len(c.levels)
+}
+
+// completePage compresses the buffered values into a data page
+func (c *columnWriter) completePage(codec Codec) error {
+	if c.numValues == 0 {
+		return nil
+	}
+
+	var data []byte
+	if c.optional() {
+		levels := appendHybrid(nil, c.levels, 1)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(levels)))
+		data = append(data, levels...)
+	}
+	data = append(data, c.values.buf...)
+
+	compressed, err := compress(codec, data)
+	if err != nil {
+		return fmt.Errorf("compression error: %w", err)
+	}
+	header := &pageHeader{
+		Type:             pageData,
+		UncompressedSize: int32(len(data)),
+		CompressedSize:   int32(len(compressed)),
+		NumValues:        int32(c.numValues),
+		Encoding:         encodingPlain,
+	}
+	encoded := header.encode()
+	c.pages = append(c.pages, encoded...)
+	c.pages = append(c.pages, compressed...)
+	c.rawSize += int64(len(encoded) + len(data))
+
+	c.values.reset()
+	c.levels = c.levels[:0]
+	c.numValues = 0
+	return nil
+}
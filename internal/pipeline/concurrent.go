@@ -2,41 +2,71 @@ package pipeline
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/atlanssia/fustgo/internal/checkpoint"
 	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/metrics"
+	"github.com/atlanssia/fustgo/internal/tracing"
 	"github.com/atlanssia/fustgo/pkg/types"
 )
 
-// ConcurrentPipeline represents a high-performance pipeline with buffered channels and backpressure
+// ConcurrentPipeline represents a high-performance pipeline with bounded queues and backpressure.
+// A stage blocks when the queue of the next stage is full, so the slowest
+// stage sets the pace and memory use stays bounded.
 type ConcurrentPipeline struct {
 	input       types.InputPlugin
 	processors  []types.ProcessorPlugin
 	output      types.OutputPlugin
 	batchSize   int
 	
-	// Channel configuration
+	// Queue configuration
 	inputBufferSize      int
 	processorBufferSize  int
 	outputBufferSize     int
+	inputBufferBytes     int64
+	processorBufferBytes int64
+	outputBufferBytes    int64
+	
+	// Flow metrics by stage: input, processors, output
+	stages []*stageMetrics
+	
+	// Shards of a split input, nil when the input is read by one reader
+	inputShards int
+	shards      *shardSet
 	
-	// Backpressure thresholds
-	backpressureThreshold float64 // 0.0 to 1.0, triggers when buffer is this full
+	// Parallel processor stages by processor index, and the processor
+	// instances of their workers
+	parallelism map[int]*ProcessorParallelism
+	workers     map[int][]types.ProcessorPlugin
 	
 	// Checkpoint management
-	checkpointManager *checkpoint.Manager
+	checkpointManager  *checkpoint.Manager
+	checkpointInterval time.Duration // Commit output checkpoints this often, 0 after every batch
+	checkpointDuration prometheus.Observer
 	
 	// Error handling
 	errorChan chan error
+	errors    *errorHandler
+	
+	// Schema drift handling
+	schemas *schemaTracker
+	
+	// Logs carry the job and execution IDs, and stage logs the stage
+	log *logger.Logger
 	
 	// Metrics
 	mu                sync.RWMutex
 	totalBatches      int64
 	totalRecords      int64
+	recordsRead       int64
 	failedRecords     int64
 	startTime         time.Time
 	endTime           time.Time
@@ -44,23 +74,38 @@ type ConcurrentPipeline struct {
 
 // ConcurrentPipelineConfig holds configuration for concurrent pipeline
 type ConcurrentPipelineConfig struct {
-	BatchSize             int
-	InputBufferSize       int
-	ProcessorBufferSize   int
-	OutputBufferSize      int
-	BackpressureThreshold float64
-	JobID                 string
-	CheckpointConfig      *checkpoint.Config
+	BatchSize           int
+	InputBufferSize     int // Batches queued after the input
+	ProcessorBufferSize int // Batches queued between processors
+	OutputBufferSize    int // Batches queued before the output
+	JobID               string
+	ExecutionID         string // Added to the logs of the pipeline
+	CheckpointConfig    *checkpoint.Config
+	ErrorPolicy         *ErrorPolicy // Fail-fast when nil
+	SchemaPolicy        *SchemaPolicy // Fail on schema changes when nil
+	
+	// Optional limits on the estimated memory of the records in each
+	// queue, in bytes; 0 limits queues by batch count only
+	InputBufferBytes     int64
+	ProcessorBufferBytes int64
+	OutputBufferBytes    int64
+	
+	// Parallelism runs processor stages on several workers, by processor
+	// index. Stages not listed run on a single goroutine.
+	Parallelism map[int]*ProcessorParallelism
+	
+	// InputShards splits a SplittableInput into up to this many shards
+	// read concurrently. Other inputs are read by a single reader.
+	InputShards int
 }
 
 // DefaultConcurrentConfig returns default configuration
 func DefaultConcurrentConfig() *ConcurrentPipelineConfig {
 	return &ConcurrentPipelineConfig{
-		BatchSize:             1000,
-		InputBufferSize:       10,   // Buffer 10 batches
-		ProcessorBufferSize:   10,
-		OutputBufferSize:      5,
-		BackpressureThreshold: 0.8,  // Trigger backpressure at 80% full
+		BatchSize:           1000,
+		InputBufferSize:     10, // Buffer 10 batches
+		ProcessorBufferSize: 10,
+		OutputBufferSize:    5,
 	}
 }
 
@@ -83,10 +128,37 @@ func NewConcurrentPipeline(
 		inputBufferSize:       config.InputBufferSize,
 		processorBufferSize:   config.ProcessorBufferSize,
 		outputBufferSize:      config.OutputBufferSize,
-		backpressureThreshold: config.BackpressureThreshold,
+		inputBufferBytes:      config.InputBufferBytes,
+		processorBufferBytes:  config.ProcessorBufferBytes,
+		outputBufferBytes:     config.OutputBufferBytes,
+		parallelism:           config.Parallelism,
+		inputShards:           config.InputShards,
+		workers:               make(map[int][]types.ProcessorPlugin),
 		errorChan:             make(chan error, 10),
 	}
 	
+	pipeline.log = logger.Default()
+	if config.JobID != "" {
+		pipeline.log = pipeline.log.With("job_id", config.JobID)
+	}
+	if config.ExecutionID != "" {
+		pipeline.log = pipeline.log.With("execution_id", config.ExecutionID)
+	}
+	
+	pipeline.stages = append(pipeline.stages, newStageMetrics(pipeline.log, config.JobID, StageInput, 0, input.Name()))
+	for i, processor := range processors {
+		pipeline.stages = append(pipeline.stages, newStageMetrics(pipeline.log, config.JobID, StageProcessor, i, processor.Name()))
+	}
+	pipeline.stages = append(pipeline.stages, newStageMetrics(pipeline.log, config.JobID, StageOutput, 0, output.Name()))
+	pipeline.checkpointDuration = metrics.CheckpointSaveDuration.WithLabelValues(config.JobID)
+	
+	policy := config.ErrorPolicy
+	if policy == nil {
+		policy = DefaultErrorPolicy()
+	}
+	pipeline.errors = &errorHandler{policy: policy}
+	pipeline.schemas = newSchemaTracker(config.SchemaPolicy, pipeline.stages[len(pipeline.stages)-1].log)
+	
 	// Initialize checkpoint manager if configured
 	if config.CheckpointConfig != nil && config.CheckpointConfig.Enabled {
 		if config.JobID == "" {
@@ -95,10 +167,11 @@ func NewConcurrentPipeline(
 		
 		manager, err := checkpoint.NewManager(config.JobID, config.CheckpointConfig)
 		if err != nil {
-			logger.Warn("Failed to create checkpoint manager: %v", err)
+			pipeline.log.Warn("Failed to create checkpoint manager: %v", err)
 		} else {
 			pipeline.checkpointManager = manager
-			logger.Info("Checkpoint manager enabled for job %s", config.JobID)
+			pipeline.checkpointInterval = config.CheckpointConfig.Interval
+			pipeline.log.Info("Checkpoint manager enabled for job %s", config.JobID)
 		}
 	}
 	
@@ -108,7 +181,26 @@ func NewConcurrentPipeline(
 // Execute executes the pipeline with concurrent processing
 func (p *ConcurrentPipeline) Execute(ctx context.Context) error {
 	p.startTime = time.Now()
-	logger.Info("Starting concurrent pipeline execution")
+	p.log.Info("Starting concurrent pipeline execution")
+	
+	if err := p.errors.policy.Validate(); err != nil {
+		return fmt.Errorf("invalid error policy: %w", err)
+	}
+	if err := p.schemas.policy.Validate(); err != nil {
+		return fmt.Errorf("invalid schema policy: %w", err)
+	}
+	
+	// Persist the latest checkpoints however the run ends
+	if p.checkpointManager != nil {
+		defer func() {
+			_, span := tracing.Tracer().Start(ctx, "checkpoint.persist")
+			err := p.checkpointManager.Close()
+			tracing.End(span, err)
+			if err != nil {
+				p.log.Warn("Failed to persist checkpoints: %v", err)
+			}
+		}()
+	}
 	
 	// Connect input
 	if err := p.input.Connect(); err != nil {
@@ -116,20 +208,44 @@ func (p *ConcurrentPipeline) Execute(ctx context.Context) error {
 	}
 	defer p.input.Close()
 	
+	// Resume from the last committed position of a previous run, splitting
+	// the input first so that each shard resumes from its own position
+	checkpoint, err := p.resumeCheckpoint()
+	if err != nil {
+		return err
+	}
+	if err := p.splitInput(checkpoint); err != nil {
+		return err
+	}
+	if p.shards != nil {
+		defer p.shards.close(p.log)
+	}
+	if err := p.resume(checkpoint); err != nil {
+		return err
+	}
+	
 	// Connect output
 	if err := p.output.Connect(); err != nil {
 		return fmt.Errorf("failed to connect output: %w", err)
 	}
 	defer p.output.Close()
 	
-	// Create buffered channels
-	inputChan := make(chan *types.DataBatch, p.inputBufferSize)
-	processorChans := make([]chan *types.DataBatch, len(p.processors)+1)
-	processorChans[0] = inputChan
-	for i := 0; i < len(p.processors); i++ {
-		processorChans[i+1] = make(chan *types.DataBatch, p.processorBufferSize)
+	// Connect dead-letter output
+	if deadLetter := p.deadLetter(); deadLetter != nil {
+		if err := deadLetter.Connect(); err != nil {
+			return fmt.Errorf("failed to connect dead-letter output: %w", err)
+		}
+		defer deadLetter.Close()
+	}
+	
+	// Create the workers of parallel processor stages
+	if err := p.createWorkers(); err != nil {
+		return err
 	}
-	outputChan := processorChans[len(p.processors)]
+	
+	// Create the queues between stages: queues[i] feeds processor i, and
+	// the last one the output
+	queues := p.createQueues()
 	
 	// WaitGroup for goroutines
 	var wg sync.WaitGroup
@@ -140,17 +256,21 @@ func (p *ConcurrentPipeline) Execute(ctx context.Context) error {
 	
 	// Start input reader
 	wg.Add(1)
-	go p.runInputReader(pipelineCtx, inputChan, &wg)
+	go p.runInputReader(pipelineCtx, queues[0], &wg)
 	
 	// Start processors
 	for i, processor := range p.processors {
 		wg.Add(1)
-		go p.runProcessor(pipelineCtx, processor, processorChans[i], processorChans[i+1], i, &wg)
+		if workers := p.workers[i]; len(workers) > 1 {
+			go p.runParallelProcessor(pipelineCtx, workers, p.parallelism[i].Unordered, queues[i], queues[i+1], i, &wg)
+			continue
+		}
+		go p.runProcessor(pipelineCtx, processor, queues[i], queues[i+1], i, &wg)
 	}
 	
 	// Start output writer
 	wg.Add(1)
-	go p.runOutputWriter(pipelineCtx, outputChan, &wg)
+	go p.runOutputWriter(pipelineCtx, queues[len(p.processors)], &wg)
 	
 	// Wait for completion or error
 	done := make(chan struct{})
@@ -161,7 +281,13 @@ func (p *ConcurrentPipeline) Execute(ctx context.Context) error {
 	
 	select {
 	case <-done:
-		logger.Info("Pipeline completed successfully")
+		// A stage may have failed on its last batch
+		select {
+		case err := <-p.errorChan:
+			return fmt.Errorf("pipeline error: %w", err)
+		default:
+		}
+		p.log.Info("Pipeline completed successfully")
 	case err := <-p.errorChan:
 		cancel()
 		wg.Wait()
@@ -169,6 +295,15 @@ func (p *ConcurrentPipeline) Execute(ctx context.Context) error {
 	case <-ctx.Done():
 		cancel()
 		wg.Wait()
+		
+		// Persist what has already been written so the run can resume
+		// from its last checkpoint
+		if err := p.output.Flush(); err != nil {
+			p.log.Warn("Failed to flush output after cancellation: %v", err)
+		}
+		if err := p.flushDeadLetter(); err != nil {
+			p.log.Warn("Failed to flush dead-letter output after cancellation: %v", err)
+		}
 		return fmt.Errorf("pipeline cancelled: %w", ctx.Err())
 	}
 	
@@ -176,6 +311,10 @@ func (p *ConcurrentPipeline) Execute(ctx context.Context) error {
 	if err := p.output.Flush(); err != nil {
 		return fmt.Errorf("failed to flush output: %w", err)
 	}
+	if err := p.flushDeadLetter(); err != nil {
+		return err
+	}
+	p.completeCheckpoint()
 	
 	p.endTime = time.Now()
 	p.logStatistics()
@@ -183,179 +322,540 @@ func (p *ConcurrentPipeline) Execute(ctx context.Context) error {
 	return nil
 }
 
-// runInputReader reads batches from input and sends to channel
-func (p *ConcurrentPipeline) runInputReader(ctx context.Context, outputChan chan<- *types.DataBatch, wg *sync.WaitGroup) {
+// resumeCheckpoint returns the last checkpoint committed by the output,
+// or nil if there is none or the input cannot resume from it
+func (p *ConcurrentPipeline) resumeCheckpoint() (*types.Checkpoint, error) {
+	if _, ok := p.input.(types.ResumableInput); !ok || p.checkpointManager == nil {
+		return nil, nil
+	}
+	
+	checkpoint, err := p.checkpointManager.LoadCheckpoint("output")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// resume seeks a resumable input to the last checkpoint committed by the
+// output; the shards of a split input have already been positioned. If
+// the run that saved it did not complete, a resumable output also
+// continues from the position stored with it.
+func (p *ConcurrentPipeline) resume(checkpoint *types.Checkpoint) error {
+	if checkpoint == nil {
+		return nil
+	}
+	
+	if p.shards == nil {
+		if err := p.input.(types.ResumableInput).Seek(checkpoint); err != nil {
+			return fmt.Errorf("failed to resume input from checkpoint: %w", err)
+		}
+	}
+	if checkpoint.IsCompleted() {
+		p.log.Info("Previous run completed at %v, starting a new run", checkpoint.Timestamp)
+		return nil
+	}
+	p.log.Info("Resumed input from checkpoint saved at %v", checkpoint.Timestamp)
+	
+	output, ok := p.output.(types.ResumableOutput)
+	if !ok {
+		p.log.Warn("Output %s cannot resume, records written after the checkpoint will be written again", p.output.Name())
+		return nil
+	}
+	encoded, found := checkpoint.Metadata[types.CheckpointOutputPosition]
+	if !found {
+		return nil
+	}
+	
+	var position interface{}
+	if err := json.Unmarshal([]byte(encoded), &position); err != nil {
+		return fmt.Errorf("invalid output position in checkpoint: %w", err)
+	}
+	if err := output.Resume(position); err != nil {
+		return fmt.Errorf("failed to resume output from checkpoint: %w", err)
+	}
+	p.log.Info("Resumed output at the position of the checkpoint")
+	return nil
+}
+
+// commitCheckpoint flushes the output and the dead-letter output and then
+// saves and persists the checkpoint of the batches written so far, so a
+// saved checkpoint never gets ahead of the data and rejected records that
+// reached their targets
+func (p *ConcurrentPipeline) commitCheckpoint(ctx context.Context, checkpoint *types.Checkpoint) (err error) {
+	start := time.Now()
+	_, span := tracing.Tracer().Start(ctx, "checkpoint.save")
+	defer func() {
+		p.checkpointDuration.Observe(time.Since(start).Seconds())
+		tracing.End(span, err)
+	}()
+	
+	if err := p.output.Flush(); err != nil {
+		return fmt.Errorf("failed to flush output: %w", err)
+	}
+	if err := p.flushDeadLetter(); err != nil {
+		return err
+	}
+	
+	committed := &types.Checkpoint{
+		Position: checkpoint.Position,
+		Metadata: make(map[string]string, len(checkpoint.Metadata)+1),
+	}
+	for k, v := range checkpoint.Metadata {
+		committed.Metadata[k] = v
+	}
+	
+	if output, ok := p.output.(types.ResumableOutput); ok {
+		position, err := output.Position()
+		if err != nil {
+			return fmt.Errorf("failed to get output position: %w", err)
+		}
+		encoded, err := json.Marshal(position)
+		if err != nil {
+			return fmt.Errorf("failed to encode output position: %w", err)
+		}
+		committed.Metadata[types.CheckpointOutputPosition] = string(encoded)
+	}
+	
+	if err := p.checkpointManager.SaveCheckpoint("output", committed); err != nil {
+		return fmt.Errorf("failed to save output checkpoint: %w", err)
+	}
+	if err := p.checkpointManager.Flush(); err != nil {
+		return fmt.Errorf("failed to persist output checkpoint: %w", err)
+	}
+	return nil
+}
+
+// completeCheckpoint marks the last committed checkpoint as the end of a
+// completed run
+func (p *ConcurrentPipeline) completeCheckpoint() {
+	if p.checkpointManager == nil {
+		return
+	}
+	
+	checkpoint, err := p.checkpointManager.LoadCheckpoint("output")
+	if err != nil || checkpoint == nil || checkpoint.IsCompleted() {
+		return
+	}
+	
+	completed := &types.Checkpoint{
+		Position: checkpoint.Position,
+		Metadata: map[string]string{types.CheckpointCompleted: "true"},
+	}
+	if err := p.checkpointManager.SaveCheckpoint("output", completed); err != nil {
+		p.log.Warn("Failed to save output checkpoint: %v", err)
+	}
+}
+
+// runInputReader reads batches from input and queues them for the next
+// stage. The shards of a split input are read by one reader each.
+func (p *ConcurrentPipeline) runInputReader(ctx context.Context, out *queue, wg *sync.WaitGroup) {
 	defer wg.Done()
-	defer close(outputChan)
+	defer out.close()
+	
+	if p.shards != nil {
+		p.runShardReaders(ctx, out)
+		return
+	}
 	
-	logger.Info("Input reader started")
+	p.stages[0].log.Info("Input reader started")
+	send := func(batch *types.DataBatch) bool {
+		return p.sendInputBatch(ctx, batch, out)
+	}
+	if _, err := p.readInput(ctx, p.input, "Input reader", send); err != nil {
+		p.fail(ctx, err)
+	}
+}
+
+// readInput reads batches from an input and hands them to send, which
+// returns false when cancelled. It returns true when the input was read to
+// the end.
+func (p *ConcurrentPipeline) readInput(
+	ctx context.Context,
+	input types.InputPlugin,
+	name string,
+	send func(*types.DataBatch) bool,
+) (bool, error) {
+	metrics := p.stages[0]
+	log := metrics.log
 	batchCount := 0
 	
 	for {
-		select {
-		case <-ctx.Done():
-			logger.Info("Input reader cancelled")
-			return
-		default:
-			// Check backpressure
-			if p.isBackpressure(outputChan, p.inputBufferSize) {
-				logger.Debug("Backpressure detected in input reader, slowing down")
-				time.Sleep(100 * time.Millisecond)
-				continue
-			}
-			
-			// Read batch
-			batch, err := p.input.ReadBatch(p.batchSize)
-			if err == io.EOF {
-				logger.Info("Input reader reached end of input")
-				return
-			}
+		if ctx.Err() != nil {
+			log.Info("%s cancelled", name)
+			return false, nil
+		}
+		
+		// Read batch
+		start := time.Now()
+		_, span := metrics.startSpan(ctx)
+		batch, err := input.ReadBatch(p.batchSize)
+		elapsed := metrics.addBusy(start)
+		endSpan(span, batch, err)
+		if err == io.EOF {
+			log.Info("%s reached end of input", name)
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read batch: %w", err)
+		}
+		
+		if batch == nil || (batch.IsEmpty() && len(batch.Rejected) == 0) {
+			log.Info("%s received empty batch, stopping", name)
+			return true, nil
+		}
+		
+		// Rejected records count as read for the error rate
+		p.incrementRead(int64(batch.Size() + len(batch.Rejected)))
+		
+		if len(batch.Rejected) > 0 {
+			err := p.reject(StageInput, input.Name(), batch.Schema, batch.Rejected)
+			batch.Rejected = nil
 			if err != nil {
-				p.errorChan <- fmt.Errorf("failed to read batch: %w", err)
-				return
-			}
-			
-			if batch == nil || batch.IsEmpty() {
-				logger.Info("Input reader received empty batch, stopping")
-				return
-			}
-			
-			batchCount++
-			logger.Debug("Input reader produced batch %d with %d records", batchCount, batch.Size())
-			
-			// Save checkpoint if enabled
-			if p.checkpointManager != nil && batch.Checkpoint != nil {
-				if err := p.checkpointManager.SaveCheckpoint("input", batch.Checkpoint); err != nil {
-					logger.Warn("Failed to save input checkpoint: %v", err)
-				}
-			}
-			
-			// Send to channel
-			select {
-			case outputChan <- batch:
-				p.incrementBatches()
-			case <-ctx.Done():
-				logger.Info("Input reader cancelled while sending batch")
-				return
+				return false, err
 			}
 		}
+		
+		batchCount++
+		metrics.addBatch(recordCount(batch), batch, elapsed)
+		log.Debug("%s produced batch %d with %d records", name, batchCount, batch.Size())
+		
+		if !send(batch) {
+			log.Info("%s cancelled while sending batch", name)
+			return false, nil
+		}
+	}
+}
+
+// sendInputBatch saves the input checkpoint of a batch and queues the batch
+// for the next stage, blocking while it is full. It returns false when
+// cancelled.
+func (p *ConcurrentPipeline) sendInputBatch(ctx context.Context, batch *types.DataBatch, out *queue) bool {
+	// Save checkpoint if enabled
+	if p.checkpointManager != nil && batch.Checkpoint != nil {
+		if err := p.checkpointManager.SaveCheckpoint("input", batch.Checkpoint); err != nil {
+			p.stages[0].log.Warn("Failed to save input checkpoint: %v", err)
+		}
+	}
+	
+	if batch.IsEmpty() && batch.Checkpoint == nil {
+		return true
+	}
+	
+	if !out.send(ctx, batch, p.stages[0]) {
+		return false
 	}
+	p.incrementBatches()
+	return true
 }
 
-// runProcessor processes batches from input channel and sends to output channel
+// runProcessor processes batches from the input queue and queues them for
+// the next stage
 func (p *ConcurrentPipeline) runProcessor(
 	ctx context.Context,
 	processor types.ProcessorPlugin,
-	inputChan <-chan *types.DataBatch,
-	outputChan chan<- *types.DataBatch,
+	in *queue,
+	out *queue,
 	index int,
 	wg *sync.WaitGroup,
 ) {
 	defer wg.Done()
-	defer close(outputChan)
+	defer out.close()
 	
-	logger.Info("Processor %d (%s) started", index, processor.Name())
+	metrics := p.stages[index+1]
+	log := metrics.log
+	log.Info("Processor %d (%s) started", index, processor.Name())
 	processedCount := 0
 	
 	for {
-		select {
-		case <-ctx.Done():
-			logger.Info("Processor %d cancelled", index)
-			return
-		case batch, ok := <-inputChan:
-			if !ok {
-				logger.Info("Processor %d input channel closed", index)
-				return
-			}
-			
-			// Check backpressure
-			if p.isBackpressure(outputChan, p.processorBufferSize) {
-				logger.Debug("Backpressure detected in processor %d, slowing down", index)
-				time.Sleep(50 * time.Millisecond)
-			}
-			
-			// Process batch
-			processed, err := processor.Process(batch)
-			if err != nil {
-				p.errorChan <- fmt.Errorf("processor %d (%s) failed: %w", index, processor.Name(), err)
-				return
-			}
-			
-			processedCount++
-			
-			if processed.IsEmpty() {
-				logger.Debug("Processor %d filtered out all records in batch %d", index, processedCount)
-				continue
-			}
-			
-			logger.Debug("Processor %d processed batch %d: %d records", index, processedCount, processed.Size())
-			
-			// Send to next stage
-			select {
-			case outputChan <- processed:
-				// Success
-			case <-ctx.Done():
-				logger.Info("Processor %d cancelled while sending batch", index)
-				return
+		batch, ok := in.receive(ctx, metrics)
+		if !ok {
+			if ctx.Err() != nil {
+				log.Info("Processor %d cancelled", index)
+			} else {
+				log.Info("Processor %d input channel closed", index)
 			}
+			return
+		}
+		
+		// Process batch
+		start := time.Now()
+		_, span := metrics.startSpan(ctx)
+		processed, err := p.processBatch(processor, batch, index)
+		elapsed := metrics.addBusy(start)
+		endSpan(span, processed, err)
+		if err != nil {
+			p.fail(ctx, err)
+			return
+		}
+		
+		processedCount++
+		metrics.addBatch(recordCount(processed), processed, elapsed)
+		
+		// Fully filtered batches still carry their checkpoint downstream,
+		// so the read position advances past them
+		if processed == nil || (processed.IsEmpty() && processed.Checkpoint == nil) {
+			log.Debug("Processor %d filtered out all records in batch %d", index, processedCount)
+			continue
+		}
+		
+		log.Debug("Processor %d processed batch %d: %d records", index, processedCount, processed.Size())
+		
+		// Send to next stage
+		if !out.send(ctx, processed, metrics) {
+			log.Info("Processor %d cancelled while sending batch", index)
+			return
+		}
+	}
+}
+
+// createQueues creates the queues between stages and attaches each to the
+// metrics of the stage reading it
+func (p *ConcurrentPipeline) createQueues() []*queue {
+	queues := make([]*queue, len(p.processors)+1)
+	for i := range queues {
+		switch {
+		case i == 0:
+			queues[i] = newQueue(p.inputBufferSize, p.inputBufferBytes)
+		case i == len(p.processors):
+			queues[i] = newQueue(p.outputBufferSize, p.outputBufferBytes)
+		default:
+			queues[i] = newQueue(p.processorBufferSize, p.processorBufferBytes)
 		}
 	}
+	
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, q := range queues {
+		p.stages[i+1].input = q
+	}
+	for i := range p.processors {
+		if workers := p.workers[i]; len(workers) > 1 {
+			p.stages[i+1].workers = len(workers)
+		}
+	}
+	return queues
 }
 
-// runOutputWriter writes batches from channel to output
-func (p *ConcurrentPipeline) runOutputWriter(ctx context.Context, inputChan <-chan *types.DataBatch, wg *sync.WaitGroup) {
+// processBatch processes a batch and applies the error policy to the
+// records the processor rejected
+func (p *ConcurrentPipeline) processBatch(processor types.ProcessorPlugin, batch *types.DataBatch, index int) (*types.DataBatch, error) {
+	processed, err := processor.Process(batch)
+	if err != nil {
+		return nil, fmt.Errorf("processor %d (%s) failed: %w", index, processor.Name(), err)
+	}
+	
+	if processed != nil && len(processed.Rejected) > 0 {
+		err := p.reject(StageProcessor, processor.Name(), batch.Schema, processed.Rejected)
+		processed.Rejected = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+	
+	return processed, nil
+}
+
+// runOutputWriter writes batches from the queue to output. The checkpoints
+// of the batches written are committed every checkpoint interval, or after
+// every batch without an interval, and when the writer stops unless the
+// output failed: it may then hold part of the failed batch.
+func (p *ConcurrentPipeline) runOutputWriter(ctx context.Context, in *queue, wg *sync.WaitGroup) {
 	defer wg.Done()
 	
-	logger.Info("Output writer started")
+	metrics := p.stages[len(p.stages)-1]
+	log := metrics.log
+	log.Info("Output writer started")
 	batchCount := 0
 	
+	var pending *types.Checkpoint
+	var tick <-chan time.Time
+	if p.checkpointManager != nil && p.checkpointInterval > 0 {
+		ticker := time.NewTicker(p.checkpointInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	
 	for {
-		select {
-		case <-ctx.Done():
-			logger.Info("Output writer cancelled")
-			return
-		case batch, ok := <-inputChan:
-			if !ok {
-				logger.Info("Output writer input channel closed")
-				return
+		batch, ok, ticked := in.receiveOrTick(ctx, metrics, tick)
+		if ticked {
+			if pending != nil {
+				if err := p.commitCheckpoint(ctx, pending); err != nil {
+					p.fail(ctx, err)
+					return
+				}
+				pending = nil
+			}
+			continue
+		}
+		if !ok {
+			if ctx.Err() != nil {
+				log.Info("Output writer cancelled")
+			} else {
+				log.Info("Output writer input channel closed")
 			}
-			
-			// Write batch
-			if err := p.output.WriteBatch(batch); err != nil {
-				p.errorChan <- fmt.Errorf("failed to write batch: %w", err)
-				return
+			// Commit what was written, so a cancelled or failed run
+			// resumes after it
+			if pending != nil {
+				if err := p.commitCheckpoint(ctx, pending); err != nil {
+					log.Warn("Failed to commit checkpoint: %v", err)
+				}
 			}
-			
-			batchCount++
-			p.incrementRecords(int64(batch.Size()))
-			logger.Debug("Output writer wrote batch %d with %d records", batchCount, batch.Size())
-			
-			// Save checkpoint if enabled
-			if p.checkpointManager != nil && batch.Checkpoint != nil {
-				if err := p.checkpointManager.SaveCheckpoint("output", batch.Checkpoint); err != nil {
-					logger.Warn("Failed to save output checkpoint: %v", err)
+			return
+		}
+		
+		// Conform the batch to the established schema and write it
+		start := time.Now()
+		spanCtx, span := metrics.startSpan(ctx)
+		batch, err := p.conformBatch(batch)
+		if err != nil {
+			metrics.addBusy(start)
+			endSpan(span, nil, err)
+			p.fail(ctx, err)
+			return
+		}
+		written, err := p.writeBatch(batch)
+		if err != nil {
+			metrics.addBusy(start)
+			endSpan(span, nil, err)
+			p.fail(ctx, err)
+			return
+		}
+		
+		batchCount++
+		p.incrementRecords(written)
+		log.Debug("Output writer wrote batch %d with %d records", batchCount, batch.Size())
+		
+		// Commit checkpoint if enabled
+		if p.checkpointManager != nil && batch.Checkpoint != nil {
+			pending = batch.Checkpoint
+			if tick == nil {
+				if err := p.commitCheckpoint(spanCtx, pending); err != nil {
+					metrics.addBusy(start)
+					endSpan(span, batch, err)
+					p.fail(ctx, err)
+					return
 				}
+				pending = nil
 			}
 		}
+		metrics.addBatch(written, batch, metrics.addBusy(start))
+		endSpan(span, batch, nil)
 	}
 }
 
-// isBackpressure checks if backpressure should be applied
-func (p *ConcurrentPipeline) isBackpressure(ch interface{}, capacity int) bool {
-	// Use reflection to get channel length
-	var length int
-	switch c := ch.(type) {
-	case chan *types.DataBatch:
-		length = len(c)
-	case chan<- *types.DataBatch:
-		// Can't check send-only channel length directly
-		return false
-	default:
-		return false
+// conformBatch applies the schema policy to a batch about to be written.
+// Records with values that cannot be converted to the established types
+// are rejected.
+func (p *ConcurrentPipeline) conformBatch(batch *types.DataBatch) (*types.DataBatch, error) {
+	if batch.IsEmpty() {
+		return batch, nil
+	}
+	
+	conformed, rejected, err := p.schemas.conform(batch, p.output)
+	if err != nil {
+		p.incrementFailed(int64(batch.Size()))
+		return nil, err
+	}
+	if err := p.reject(StageOutput, p.output.Name(), batch.Schema, rejected); err != nil {
+		return nil, err
+	}
+	return conformed, nil
+}
+
+// writeBatch writes a batch to the output and returns the number of records
+// written. Records rejected by the output go through the error policy.
+// Unless the policy is fail-fast, a failed batch of a transactional output
+// is retried record by record so that only the bad records are rejected;
+// other outputs may have written part of the batch, so it is not retried.
+func (p *ConcurrentPipeline) writeBatch(batch *types.DataBatch) (int64, error) {
+	if batch.IsEmpty() {
+		return 0, nil
+	}
+	
+	err := p.output.WriteBatch(batch)
+	if err == nil {
+		return int64(batch.Size()), nil
+	}
+	
+	var recordErr *types.RecordWriteError
+	if errors.As(err, &recordErr) {
+		written := int64(batch.Size() - len(recordErr.Rejected))
+		return written, p.reject(StageOutput, p.output.Name(), batch.Schema, recordErr.Rejected)
 	}
 	
-	ratio := float64(length) / float64(capacity)
-	return ratio >= p.backpressureThreshold
+	if p.errors.policy.Mode == ErrorModeFail || !isTransactional(p.output) {
+		p.incrementFailed(int64(batch.Size()))
+		return 0, fmt.Errorf("failed to write batch: %w", err)
+	}
+	
+	p.stages[len(p.stages)-1].log.Warn("Failed to write batch of %d records, retrying records one by one: %v", batch.Size(), err)
+	
+	var written int64
+	var rejected []types.RejectedRecord
+	for _, record := range batch.Records {
+		single := &types.DataBatch{
+			Schema:   batch.Schema,
+			Records:  []types.Record{record},
+			Metadata: batch.Metadata,
+		}
+		if err := p.output.WriteBatch(single); err != nil {
+			rejected = append(rejected, types.RejectedRecord{Record: record, Error: err.Error()})
+			continue
+		}
+		written++
+	}
+	
+	return written, p.reject(StageOutput, p.output.Name(), batch.Schema, rejected)
+}
+
+// isTransactional reports whether a failed batch of the output wrote nothing
+func isTransactional(output types.OutputPlugin) bool {
+	transactional, ok := output.(types.TransactionalOutput)
+	return ok && transactional.Transactional()
+}
+
+// reject counts records rejected by a stage and applies the error policy
+// to them. It returns an error when the run must abort.
+func (p *ConcurrentPipeline) reject(stage, plugin string, schema types.Schema, rejected []types.RejectedRecord) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+	
+	p.incrementFailed(int64(len(rejected)))
+	if err := p.errors.handle(stage, plugin, schema, rejected); err != nil {
+		return err
+	}
+	p.log.Warn("%s %s rejected %d records: %s", stage, plugin, len(rejected), rejected[0].Error)
+	
+	p.mu.RLock()
+	failed, read := p.failedRecords, p.recordsRead
+	p.mu.RUnlock()
+	return p.errors.checkRate(failed, read)
+}
+
+// deadLetter returns the dead-letter output, or nil if bad records are not
+// routed to one
+func (p *ConcurrentPipeline) deadLetter() types.OutputPlugin {
+	if p.errors.policy.Mode != ErrorModeDeadLetter {
+		return nil
+	}
+	return p.errors.policy.DeadLetter
+}
+
+// flushDeadLetter flushes the dead-letter output, if any. Writes of other
+// stages wait for the flush.
+func (p *ConcurrentPipeline) flushDeadLetter() error {
+	deadLetter := p.deadLetter()
+	if deadLetter == nil {
+		return nil
+	}
+	
+	p.errors.mu.Lock()
+	defer p.errors.mu.Unlock()
+	if err := deadLetter.Flush(); err != nil {
+		return fmt.Errorf("failed to flush dead-letter output: %w", err)
+	}
+	return nil
 }
 
 // incrementBatches increments batch counter
@@ -365,6 +865,20 @@ func (p *ConcurrentPipeline) incrementBatches() {
 	p.totalBatches++
 }
 
+// incrementRead increments the counter of records read from input
+func (p *ConcurrentPipeline) incrementRead(count int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recordsRead += count
+}
+
+// incrementFailed increments the counter of failed records
+func (p *ConcurrentPipeline) incrementFailed(count int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failedRecords += count
+}
+
 // incrementRecords increments record counter
 func (p *ConcurrentPipeline) incrementRecords(count int64) {
 	p.mu.Lock()
@@ -382,6 +896,7 @@ func (p *ConcurrentPipeline) GetStatistics() map[string]interface{} {
 	// Pipeline stats
 	stats["total_batches"] = p.totalBatches
 	stats["total_records"] = p.totalRecords
+	stats["records_read"] = p.recordsRead
 	stats["failed_records"] = p.failedRecords
 	
 	// Timing
@@ -395,11 +910,19 @@ func (p *ConcurrentPipeline) GetStatistics() map[string]interface{} {
 	}
 	
 	// Input progress
-	stats["input_progress"] = p.input.GetProgress()
+	if p.shards != nil {
+		stats["input_progress"] = p.shards.progress()
+	} else {
+		stats["input_progress"] = p.input.GetProgress()
+	}
 	
 	// Processor statistics
 	processorStats := make([]interface{}, len(p.processors))
 	for i, proc := range p.processors {
+		if workers := p.workers[i]; len(workers) > 1 {
+			processorStats[i] = workerStatistics(workers)
+			continue
+		}
 		processorStats[i] = proc.GetStatistics()
 	}
 	stats["processors"] = processorStats
@@ -407,9 +930,58 @@ func (p *ConcurrentPipeline) GetStatistics() map[string]interface{} {
 	// Output statistics
 	stats["output"] = p.output.GetWriteStatistics()
 	
+	// Flow metrics
+	stats["stages"] = p.stageSnapshots()
+	
 	return stats
 }
 
+// GetStageMetrics returns the flow metrics of the input, each processor
+// and the output, in pipeline order
+func (p *ConcurrentPipeline) GetStageMetrics() []StageMetrics {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.stageSnapshots()
+}
+
+// stageSnapshots returns the flow metrics of all stages. The caller holds
+// the lock.
+func (p *ConcurrentPipeline) stageSnapshots() []StageMetrics {
+	stages := make([]StageMetrics, len(p.stages))
+	for i, stage := range p.stages {
+		stages[i] = stage.snapshot()
+	}
+	return stages
+}
+
+// Summary holds the record counters of a pipeline run
+type Summary struct {
+	RecordsRead    int64
+	RecordsWritten int64
+	RecordsFailed  int64
+	BytesWritten   int64
+}
+
+// GetSummary returns the record counters of the pipeline run
+func (p *ConcurrentPipeline) GetSummary() *Summary {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	summary := &Summary{
+		RecordsRead:    p.recordsRead,
+		RecordsWritten: p.totalRecords,
+		RecordsFailed:  p.failedRecords,
+	}
+
+	// Failed writes are already counted as failed records, and the output
+	// also counts the batch attempts retried record by record
+	if stats := p.output.GetWriteStatistics(); stats != nil {
+		summary.BytesWritten = stats.BytesWritten
+	}
+
+	return summary
+}
+
 // logStatistics logs pipeline statistics
 func (p *ConcurrentPipeline) logStatistics() {
 	p.mu.RLock()
@@ -418,12 +990,23 @@ func (p *ConcurrentPipeline) logStatistics() {
 	duration := p.endTime.Sub(p.startTime)
 	throughput := float64(p.totalRecords) / duration.Seconds()
 	
-	logger.Info("Pipeline Statistics:")
-	logger.Info("  Total Batches: %d", p.totalBatches)
-	logger.Info("  Total Records: %d", p.totalRecords)
-	logger.Info("  Failed Records: %d", p.failedRecords)
-	logger.Info("  Duration: %.2f seconds", duration.Seconds())
-	logger.Info("  Throughput: %.2f records/second", throughput)
+	p.log.Info("Pipeline Statistics:")
+	p.log.Info("  Total Batches: %d", p.totalBatches)
+	p.log.Info("  Total Records: %d", p.totalRecords)
+	p.log.Info("  Failed Records: %d", p.failedRecords)
+	p.log.Info("  Duration: %.2f seconds", duration.Seconds())
+	p.log.Info("  Throughput: %.2f records/second", throughput)
+	for _, stage := range p.stageSnapshots() {
+		p.log.Info("  Stage %s %s: %d batches, busy %v, blocked %v, waiting %v",
+			stage.Stage, stage.Plugin, stage.Batches,
+			stage.BusyTime.Round(time.Millisecond), stage.BlockedTime.Round(time.Millisecond), stage.WaitTime.Round(time.Millisecond))
+	}
+}
+
+// GetSchemaEvents returns the schema changes met by the output writer and
+// how they were handled
+func (p *ConcurrentPipeline) GetSchemaEvents() []types.SchemaEvent {
+	return p.schemas.recorded()
 }
 
 // GetCheckpointManager returns the checkpoint manager
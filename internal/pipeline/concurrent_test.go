@@ -162,7 +162,7 @@ func TestNewConcurrentPipeline(t *testing.T) {
 	assert.Equal(t, 10, pipeline.inputBufferSize)
 	assert.Equal(t, 10, pipeline.processorBufferSize)
 	assert.Equal(t, 5, pipeline.outputBufferSize)
-	assert.Equal(t, 0.8, pipeline.backpressureThreshold)
+	assert.Equal(t, int64(0), pipeline.processorBufferBytes)
 }
 
 func TestNewConcurrentPipelineWithCustomConfig(t *testing.T) {
@@ -175,7 +175,7 @@ func TestNewConcurrentPipelineWithCustomConfig(t *testing.T) {
 		InputBufferSize:       5,
 		ProcessorBufferSize:   5,
 		OutputBufferSize:      3,
-		BackpressureThreshold: 0.7,
+		ProcessorBufferBytes:  1 << 20,
 	}
 	
 	pipeline := NewConcurrentPipeline(input, processors, output, config)
@@ -183,7 +183,7 @@ func TestNewConcurrentPipelineWithCustomConfig(t *testing.T) {
 	assert.NotNil(t, pipeline)
 	assert.Equal(t, 500, pipeline.batchSize)
 	assert.Equal(t, 5, pipeline.inputBufferSize)
-	assert.Equal(t, 0.7, pipeline.backpressureThreshold)
+	assert.Equal(t, int64(1<<20), pipeline.processorBufferBytes)
 }
 
 func TestConcurrentPipelineExecute(t *testing.T) {
@@ -296,7 +296,7 @@ func TestDefaultConcurrentConfig(t *testing.T) {
 	assert.Equal(t, 10, config.InputBufferSize)
 	assert.Equal(t, 10, config.ProcessorBufferSize)
 	assert.Equal(t, 5, config.OutputBufferSize)
-	assert.Equal(t, 0.8, config.BackpressureThreshold)
+	assert.Equal(t, int64(0), config.ProcessorBufferBytes)
 }
 
 func TestConcurrentPipelineBackpressure(t *testing.T) {
@@ -317,7 +317,6 @@ func TestConcurrentPipelineBackpressure(t *testing.T) {
 		InputBufferSize:       3, // Small buffer to trigger backpressure
 		ProcessorBufferSize:   3,
 		OutputBufferSize:      2,
-		BackpressureThreshold: 0.5,
 	}
 	
 	pipeline := NewConcurrentPipeline(input, processors, output, config)
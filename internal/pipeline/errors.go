@@ -0,0 +1,177 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// Error policy modes
+const (
+	ErrorModeFail       = "fail"        // Abort the run on the first bad record
+	ErrorModeSkip       = "skip"        // Drop bad records and continue
+	ErrorModeDeadLetter = "dead_letter" // Write bad records to a dead-letter output and continue
+)
+
+// Pipeline stages reported with bad records
+const (
+	StageInput     = "input"
+	StageProcessor = "processor"
+	StageOutput    = "output"
+)
+
+// ErrorPolicy decides what happens to records that fail in a stage
+type ErrorPolicy struct {
+	Mode string
+
+	// MaxErrorRate aborts the run when the share of failed records among
+	// those read exceeds it; 0 disables the check
+	MaxErrorRate float64
+
+	// MinRecords is the number of records read before MaxErrorRate applies,
+	// so that a bad first record does not abort the run
+	MinRecords int64
+
+	// DeadLetter receives bad records in dead_letter mode
+	DeadLetter types.OutputPlugin
+}
+
+// DefaultErrorPolicy returns the fail-fast policy
+func DefaultErrorPolicy() *ErrorPolicy {
+	return &ErrorPolicy{
+		Mode:       ErrorModeFail,
+		MinRecords: 100,
+	}
+}
+
+// Validate validates the policy
+func (ep *ErrorPolicy) Validate() error {
+	switch ep.Mode {
+	case ErrorModeFail, ErrorModeSkip:
+	case ErrorModeDeadLetter:
+		if ep.DeadLetter == nil {
+			return fmt.Errorf("dead_letter error mode requires a dead-letter output")
+		}
+	default:
+		return fmt.Errorf("invalid error mode '%s', must be 'fail', 'skip' or 'dead_letter'", ep.Mode)
+	}
+	if ep.MaxErrorRate < 0 || ep.MaxErrorRate > 1 {
+		return fmt.Errorf("max_error_rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// deadLetterSchema is the layout of records written to the dead-letter output
+var deadLetterSchema = types.Schema{
+	Columns: []types.Column{
+		{Name: "failed_at", DataType: types.DataTypeTimestamp},
+		{Name: "stage", DataType: types.DataTypeString},
+		{Name: "plugin", DataType: types.DataTypeString},
+		{Name: "error", DataType: types.DataTypeString},
+		{Name: "record", DataType: types.DataTypeJSON, Nullable: true},
+		{Name: "metadata", DataType: types.DataTypeJSON, Nullable: true},
+	},
+}
+
+// errorHandler applies the error policy to bad records from all stages
+type errorHandler struct {
+	policy *ErrorPolicy
+	mu     sync.Mutex // Serializes dead-letter writes
+}
+
+// handle applies the policy to records rejected by a stage. schema is the
+// layout of the rejected records. It returns an error when the run must
+// abort.
+func (h *errorHandler) handle(stage, plugin string, schema types.Schema, rejected []types.RejectedRecord) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	if h.policy.Mode == ErrorModeFail {
+		first := rejected[0]
+		return fmt.Errorf("%s %s rejected record%s: %s", stage, plugin, describeRow(first.Record), first.Error)
+	}
+
+	if h.policy.Mode == ErrorModeDeadLetter {
+		batch := &types.DataBatch{
+			Schema:  deadLetterSchema,
+			Records: make([]types.Record, 0, len(rejected)),
+		}
+		now := time.Now()
+		for _, r := range rejected {
+			batch.Records = append(batch.Records, types.Record{
+				Values: []interface{}{now, stage, plugin, r.Error, encodeRecord(schema, r.Record), encodeMetadata(r.Record.Metadata)},
+			})
+		}
+
+		h.mu.Lock()
+		err := h.policy.DeadLetter.WriteBatch(batch)
+		h.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to write %d records to dead-letter output: %w", len(rejected), err)
+		}
+	}
+
+	return nil
+}
+
+// checkRate returns an error when the error rate exceeds the threshold
+func (h *errorHandler) checkRate(failed, read int64) error {
+	if h.policy.MaxErrorRate <= 0 || read == 0 || read < h.policy.MinRecords {
+		return nil
+	}
+	rate := float64(failed) / float64(read)
+	if rate > h.policy.MaxErrorRate {
+		return fmt.Errorf("error rate %.2f%% (%d of %d records) exceeds threshold of %.2f%%",
+			rate*100, failed, read, h.policy.MaxErrorRate*100)
+	}
+	return nil
+}
+
+// encodeRecord encodes a record as a JSON object keyed by column names.
+// Values beyond the schema are kept under positional keys.
+func encodeRecord(schema types.Schema, record types.Record) interface{} {
+	if record.Values == nil {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(record.Values))
+	for i, v := range record.Values {
+		name := fmt.Sprintf("_%d", i)
+		if i < len(schema.Columns) {
+			name = schema.Columns[i].Name
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		fields[name] = v
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Sprintf("%v", record.Values)
+	}
+	return string(data)
+}
+
+func encodeMetadata(metadata map[string]string) interface{} {
+	if len(metadata) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
+
+// describeRow returns a " at row N" suffix when the record has a row number
+func describeRow(record types.Record) string {
+	if row, ok := record.Metadata["row_number"]; ok {
+		return " at row " + row
+	}
+	return ""
+}
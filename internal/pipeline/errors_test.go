@@ -0,0 +1,221 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// rejectingProcessor rejects records whose id is divisible by every
+type rejectingProcessor struct {
+	mockProcessorPlugin
+	every int
+}
+
+func (m *rejectingProcessor) Process(input *types.DataBatch) (*types.DataBatch, error) {
+	output := &types.DataBatch{Schema: input.Schema, Checkpoint: input.Checkpoint}
+	for _, record := range input.Records {
+		if record.Values[0].(int)%m.every == 0 {
+			output.Rejected = append(output.Rejected, types.RejectedRecord{
+				Record: record,
+				Error:  "bad id",
+			})
+			continue
+		}
+		output.Records = append(output.Records, record)
+	}
+	return output, nil
+}
+
+// failingOutput fails every batch that contains the id bad, writing none of
+// its records
+type failingOutput struct {
+	mockOutputPlugin
+	bad int
+}
+
+func (m *failingOutput) Transactional() bool { return true }
+
+func (m *failingOutput) WriteBatch(data *types.DataBatch) error {
+	for _, record := range data.Records {
+		if record.Values[0].(int) == m.bad {
+			return fmt.Errorf("constraint violation")
+		}
+	}
+	return m.mockOutputPlugin.WriteBatch(data)
+}
+
+// partialOutput writes records one by one, like a file, and stops at the id
+// bad keeping the records before it. With reject, it skips the bad record
+// and reports it instead.
+type partialOutput struct {
+	mockOutputPlugin
+	bad     int
+	reject  bool
+	written []types.Record
+}
+
+func (m *partialOutput) WriteBatch(data *types.DataBatch) error {
+	var rejected []types.RejectedRecord
+	for _, record := range data.Records {
+		if record.Values[0].(int) == m.bad {
+			if !m.reject {
+				return fmt.Errorf("invalid value")
+			}
+			rejected = append(rejected, types.RejectedRecord{Record: record, Error: "invalid value"})
+			continue
+		}
+		m.written = append(m.written, record)
+	}
+	if len(rejected) > 0 {
+		return &types.RecordWriteError{Rejected: rejected}
+	}
+	return nil
+}
+
+func runWithPolicy(processors []types.ProcessorPlugin, output types.OutputPlugin, policy *ErrorPolicy) (*ConcurrentPipeline, error) {
+	input := &mockInputPlugin{batches: []*types.DataBatch{createTestBatch(10), createTestBatch(10)}}
+	config := DefaultConcurrentConfig()
+	config.ErrorPolicy = policy
+
+	p := NewConcurrentPipeline(input, processors, output, config)
+	return p, p.Execute(context.Background())
+}
+
+func TestErrorPolicyFailFast(t *testing.T) {
+	processors := []types.ProcessorPlugin{&rejectingProcessor{mockProcessorPlugin{name: "reject"}, 5}}
+
+	p, err := runWithPolicy(processors, &mockOutputPlugin{}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "processor reject rejected record: bad id")
+	assert.Greater(t, p.GetSummary().RecordsFailed, int64(0))
+}
+
+func TestErrorPolicySkip(t *testing.T) {
+	processors := []types.ProcessorPlugin{&rejectingProcessor{mockProcessorPlugin{name: "reject"}, 5}}
+	output := &mockOutputPlugin{}
+
+	p, err := runWithPolicy(processors, output, &ErrorPolicy{Mode: ErrorModeSkip})
+	require.NoError(t, err)
+
+	summary := p.GetSummary()
+	assert.Equal(t, int64(20), summary.RecordsRead)
+	assert.Equal(t, int64(16), summary.RecordsWritten) // ids 0 and 5 of each batch
+	assert.Equal(t, int64(4), summary.RecordsFailed)
+}
+
+func TestErrorPolicyDeadLetter(t *testing.T) {
+	output := &failingOutput{bad: 3}
+	deadLetter := &mockOutputPlugin{}
+
+	p, err := runWithPolicy(nil, output, &ErrorPolicy{Mode: ErrorModeDeadLetter, DeadLetter: deadLetter})
+	require.NoError(t, err)
+
+	summary := p.GetSummary()
+	assert.Equal(t, int64(18), summary.RecordsWritten)
+	assert.Equal(t, int64(2), summary.RecordsFailed)
+
+	// Bad records are written with the error, stage and record
+	require.Len(t, deadLetter.batches, 2)
+	batch := deadLetter.batches[0]
+	assert.Equal(t, deadLetterSchema, batch.Schema)
+	values := batch.Records[0].Values
+	assert.Equal(t, StageOutput, values[1])
+	assert.Equal(t, "mock-output", values[2])
+	assert.Equal(t, "constraint violation", values[3])
+	assert.JSONEq(t, `{"id": 3, "name": "test"}`, values[4].(string))
+}
+
+func TestErrorPolicyPartialWrite(t *testing.T) {
+	// A failed batch of a non-transactional output is not retried, as its
+	// first records were already written
+	output := &partialOutput{bad: 3}
+	p, err := runWithPolicy(nil, output, &ErrorPolicy{Mode: ErrorModeSkip})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid value")
+	require.Len(t, output.written, 3)
+	for i, record := range output.written {
+		assert.Equal(t, i, record.Values[0])
+	}
+	assert.Equal(t, int64(0), p.GetSummary().RecordsWritten)
+
+	// Records the output rejects go through the policy, the others count
+	// as written
+	output = &partialOutput{bad: 3, reject: true}
+	deadLetter := &mockOutputPlugin{}
+	p, err = runWithPolicy(nil, output, &ErrorPolicy{Mode: ErrorModeDeadLetter, DeadLetter: deadLetter})
+	require.NoError(t, err)
+	assert.Len(t, output.written, 18)
+
+	summary := p.GetSummary()
+	assert.Equal(t, int64(18), summary.RecordsWritten)
+	assert.Equal(t, int64(2), summary.RecordsFailed)
+	require.Len(t, deadLetter.batches, 2)
+	assert.Equal(t, "invalid value", deadLetter.batches[0].Records[0].Values[3])
+
+	// Under fail-fast the first rejected record aborts the run
+	_, err = runWithPolicy(nil, &partialOutput{bad: 3, reject: true}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output mock-output rejected record: invalid value")
+}
+
+func TestErrorPolicyMaxErrorRate(t *testing.T) {
+	processors := []types.ProcessorPlugin{&rejectingProcessor{mockProcessorPlugin{name: "reject"}, 2}}
+	policy := &ErrorPolicy{Mode: ErrorModeSkip, MaxErrorRate: 0.1, MinRecords: 5}
+
+	_, err := runWithPolicy(processors, &mockOutputPlugin{}, policy)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds threshold of 10.00%")
+
+	// Below the minimum number of records the rate is not checked
+	policy.MinRecords = 1000
+	_, err = runWithPolicy(processors, &mockOutputPlugin{}, policy)
+	assert.NoError(t, err)
+}
+
+func TestErrorPolicyValidate(t *testing.T) {
+	assert.NoError(t, DefaultErrorPolicy().Validate())
+	assert.Error(t, (&ErrorPolicy{Mode: "retry"}).Validate())
+	assert.Error(t, (&ErrorPolicy{Mode: ErrorModeDeadLetter}).Validate())
+	assert.Error(t, (&ErrorPolicy{Mode: ErrorModeSkip, MaxErrorRate: 2}).Validate())
+}
+
+func TestStageFailureAfterAbort(t *testing.T) {
+	p := NewConcurrentPipeline(&mockInputPlugin{}, nil, &failingOutput{bad: 0}, nil)
+	queues := p.createQueues()
+
+	// The run was already aborted by other failures, and no longer reads
+	// errors
+	for len(p.errorChan) < cap(p.errorChan) {
+		p.errorChan <- fmt.Errorf("earlier failure")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	done := make(chan struct{})
+	go func() {
+		p.runOutputWriter(ctx, queues[0], &wg)
+		close(done)
+	}()
+	require.True(t, queues[0].send(ctx, createTestBatch(1), p.stages[0]))
+	assert.Eventually(t, func() bool {
+		n, _ := queues[0].depth()
+		return n == 0
+	}, 5*time.Second, time.Millisecond)
+
+	// The failing output writer returns once the run is cancelled
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("output writer blocked on the error channel")
+	}
+}
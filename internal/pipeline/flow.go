@@ -0,0 +1,315 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/metrics"
+	"github.com/atlanssia/fustgo/internal/tracing"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// queue is a bounded queue of batches between two stages. Sending blocks
+// while the queue holds its capacity in batches or, with a byte limit,
+// while the batches in it would exceed the limit, so memory stays bounded
+// however wide the rows are. A batch larger than the limit is let through
+// when the queue is empty, so that it cannot stall the pipeline.
+type queue struct {
+	items    chan queueItem
+	maxBytes int64 // 0 for no byte limit
+
+	mu    sync.Mutex
+	bytes int64
+	space chan struct{} // Signalled when bytes are released
+}
+
+type queueItem struct {
+	batch *types.DataBatch
+	size  int64
+}
+
+// newQueue creates a queue holding up to capacity batches and, when
+// maxBytes is positive, up to maxBytes of records
+func newQueue(capacity int, maxBytes int64) *queue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &queue{
+		items:    make(chan queueItem, capacity),
+		maxBytes: maxBytes,
+		space:    make(chan struct{}, 1),
+	}
+}
+
+// send queues a batch, blocking while the queue is full, and adds the time
+// spent blocked to the sending stage. It returns false when cancelled.
+func (q *queue) send(ctx context.Context, batch *types.DataBatch, sender *stageMetrics) bool {
+	item := queueItem{batch: batch}
+	var start time.Time
+
+	if q.maxBytes > 0 {
+		item.size = batch.MemorySize()
+		for !q.reserve(item.size) {
+			if start.IsZero() {
+				start = time.Now()
+			}
+			select {
+			case <-q.space:
+			case <-ctx.Done():
+				sender.addBlocked(start)
+				return false
+			}
+		}
+	}
+
+	select {
+	case q.items <- item:
+		sender.addBlocked(start)
+		return true
+	default:
+	}
+
+	if start.IsZero() {
+		start = time.Now()
+	}
+	select {
+	case q.items <- item:
+		sender.addBlocked(start)
+		return true
+	case <-ctx.Done():
+		q.release(item.size)
+		sender.addBlocked(start)
+		return false
+	}
+}
+
+// receive takes the next batch, blocking while the queue is empty, and adds
+// the time spent waiting to the receiving stage. It returns false when the
+// queue is closed and drained, or when cancelled.
+func (q *queue) receive(ctx context.Context, receiver *stageMetrics) (*types.DataBatch, bool) {
+	batch, ok, _ := q.receiveOrTick(ctx, receiver, nil)
+	return batch, ok
+}
+
+// receiveOrTick is receive that also returns, with ticked set, when tick
+// fires while waiting. A nil tick never fires.
+func (q *queue) receiveOrTick(ctx context.Context, receiver *stageMetrics, tick <-chan time.Time) (batch *types.DataBatch, ok, ticked bool) {
+	start := time.Now()
+	select {
+	case item, ok := <-q.items:
+		receiver.addWait(start)
+		if !ok {
+			return nil, false, false
+		}
+		q.release(item.size)
+		return item.batch, true, false
+	case <-tick:
+		receiver.addWait(start)
+		return nil, false, true
+	case <-ctx.Done():
+		receiver.addWait(start)
+		return nil, false, false
+	}
+}
+
+// close closes the queue once the sender is done
+func (q *queue) close() {
+	close(q.items)
+}
+
+// reserve takes room for size bytes, if there is room
+func (q *queue) reserve(size int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.bytes > 0 && q.bytes+size > q.maxBytes {
+		return false
+	}
+	q.bytes += size
+	return true
+}
+
+// release frees the room of a batch taken off the queue
+func (q *queue) release(size int64) {
+	if size == 0 {
+		return
+	}
+	q.mu.Lock()
+	q.bytes -= size
+	q.mu.Unlock()
+
+	select {
+	case q.space <- struct{}{}:
+	default:
+	}
+}
+
+// depth returns the number of batches and bytes in the queue
+func (q *queue) depth() (int, int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items), q.bytes
+}
+
+// StageMetrics holds the flow metrics of a pipeline stage. A stage whose
+// busy time dominates is the bottleneck; a stage blocked for long waits on
+// a slower stage downstream.
+type StageMetrics struct {
+	Stage   string `json:"stage"` // "input", "processor" or "output"
+	Index   int    `json:"index"` // Processor index, 0 for input and output
+	Plugin  string `json:"plugin"`
+	Workers int    `json:"workers"`
+	Batches int64  `json:"batches"`
+
+	// Batches and bytes waiting in the stage's input queue. Bytes are only
+	// tracked when the queue has a byte limit.
+	QueueDepth    int   `json:"queue_depth"`
+	QueueCapacity int   `json:"queue_capacity"`
+	QueueBytes    int64 `json:"queue_bytes"`
+	QueueMaxBytes int64 `json:"queue_max_bytes,omitempty"`
+
+	// BusyTime is the time spent reading, processing or writing, summed
+	// over the workers of the stage
+	BusyTime time.Duration `json:"busy_time"`
+
+	// BlockedTime is the time spent waiting for room in the next queue
+	BlockedTime time.Duration `json:"blocked_time"`
+
+	// WaitTime is the time spent waiting for batches from the previous stage
+	WaitTime time.Duration `json:"wait_time"`
+}
+
+// stageMetrics collects the flow metrics of a stage. Times are kept in
+// nanoseconds and updated atomically. Batches are also counted in the
+// Prometheus metrics of the stage and traced in spans.
+type stageMetrics struct {
+	stage   string
+	index   int
+	name    string // input, processor_<index> or output
+	plugin  string
+	workers int
+	log     *logger.Logger
+	span    string // Name of the span of each batch
+
+	input   *queue // nil for the input stage
+	batches int64
+	busy    int64
+	blocked int64
+	wait    int64
+
+	records  prometheus.Counter
+	bytes    prometheus.Counter
+	duration prometheus.Observer
+}
+
+// newStageMetrics creates the metrics of a stage of a job's pipeline, and
+// the logger of the stage
+func newStageMetrics(log *logger.Logger, job, stage string, index int, plugin string) *stageMetrics {
+	name := stage
+	if stage == StageProcessor {
+		name = fmt.Sprintf("%s_%d", stage, index)
+	}
+	span := "pipeline.read"
+	switch stage {
+	case StageProcessor:
+		span = "pipeline.process"
+	case StageOutput:
+		span = "pipeline.write"
+	}
+	return &stageMetrics{
+		stage:    stage,
+		index:    index,
+		name:     name,
+		plugin:   plugin,
+		workers:  1,
+		log:      log.With("stage", name),
+		span:     span,
+		records:  metrics.PipelineRecords.WithLabelValues(job, name, plugin),
+		bytes:    metrics.PipelineBytes.WithLabelValues(job, name, plugin),
+		duration: metrics.PipelineBatchDuration.WithLabelValues(job, name, plugin),
+	}
+}
+
+// addBatch counts a batch that resulted in records, taking elapsed to read,
+// process or write
+func (m *stageMetrics) addBatch(records int64, batch *types.DataBatch, elapsed time.Duration) {
+	atomic.AddInt64(&m.batches, 1)
+	m.records.Add(float64(records))
+	if batch != nil {
+		m.bytes.Add(float64(batch.MemorySize()))
+	}
+	m.duration.Observe(elapsed.Seconds())
+}
+
+// startSpan starts the span of a batch read, processed or written by the
+// stage
+func (m *stageMetrics) startSpan(ctx context.Context) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, m.span, trace.WithAttributes(
+		attribute.String("fustgo.stage", m.name),
+		attribute.String("fustgo.plugin", m.plugin),
+	))
+}
+
+// endSpan ends the span of a batch with the number of records that
+// resulted and the error, if any. The end of the input is not an error.
+func endSpan(span trace.Span, batch *types.DataBatch, err error) {
+	span.SetAttributes(attribute.Int64("fustgo.records", recordCount(batch)))
+	if err == io.EOF {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+// recordCount returns the number of records of a batch, 0 for nil
+func recordCount(batch *types.DataBatch) int64 {
+	if batch == nil {
+		return 0
+	}
+	return int64(batch.Size())
+}
+
+// addBusy adds the time since start to the busy time and returns it
+func (m *stageMetrics) addBusy(start time.Time) time.Duration {
+	elapsed := time.Since(start)
+	atomic.AddInt64(&m.busy, int64(elapsed))
+	return elapsed
+}
+
+// addBlocked adds the time since start to the blocked time, if start is set
+func (m *stageMetrics) addBlocked(start time.Time) {
+	if !start.IsZero() {
+		atomic.AddInt64(&m.blocked, int64(time.Since(start)))
+	}
+}
+
+func (m *stageMetrics) addWait(start time.Time) {
+	atomic.AddInt64(&m.wait, int64(time.Since(start)))
+}
+
+// snapshot returns the current metrics
+func (m *stageMetrics) snapshot() StageMetrics {
+	s := StageMetrics{
+		Stage:       m.stage,
+		Index:       m.index,
+		Plugin:      m.plugin,
+		Workers:     m.workers,
+		Batches:     atomic.LoadInt64(&m.batches),
+		BusyTime:    time.Duration(atomic.LoadInt64(&m.busy)),
+		BlockedTime: time.Duration(atomic.LoadInt64(&m.blocked)),
+		WaitTime:    time.Duration(atomic.LoadInt64(&m.wait)),
+	}
+	if m.input != nil {
+		s.QueueDepth, s.QueueBytes = m.input.depth()
+		s.QueueCapacity = cap(m.input.items)
+		s.QueueMaxBytes = m.input.maxBytes
+	}
+	return s
+}
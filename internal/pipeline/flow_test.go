@@ -0,0 +1,213 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/atlanssia/fustgo/internal/checkpoint"
+	"github.com/atlanssia/fustgo/internal/metrics"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func TestQueueBlocksWhenFull(t *testing.T) {
+	q := newQueue(1, 0)
+	sender := &stageMetrics{}
+	receiver := &stageMetrics{}
+	ctx := context.Background()
+
+	require.True(t, q.send(ctx, createTestBatch(1), sender))
+
+	sent := make(chan bool)
+	go func() {
+		sent <- q.send(ctx, createTestBatch(2), sender)
+	}()
+
+	select {
+	case <-sent:
+		t.Fatal("send should block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	batch, ok := q.receive(ctx, receiver)
+	require.True(t, ok)
+	assert.Equal(t, 1, batch.Size())
+	assert.True(t, <-sent)
+	assert.Greater(t, sender.snapshot().BlockedTime, 10*time.Millisecond)
+
+	batch, ok = q.receive(ctx, receiver)
+	require.True(t, ok)
+	assert.Equal(t, 2, batch.Size())
+
+	q.close()
+	_, ok = q.receive(ctx, receiver)
+	assert.False(t, ok)
+}
+
+func TestQueueByteLimit(t *testing.T) {
+	batch := createTestBatch(10)
+	size := batch.MemorySize()
+
+	// Room for two batches by count, one by size
+	q := newQueue(2, size+size/2)
+	sender := &stageMetrics{input: q}
+	ctx := context.Background()
+
+	// A batch over the limit still passes an empty queue
+	big := createTestBatch(100)
+	require.True(t, q.send(ctx, big, sender))
+	_, ok := q.receive(ctx, sender)
+	require.True(t, ok)
+
+	require.True(t, q.send(ctx, batch, sender))
+	depth, bytes := q.depth()
+	assert.Equal(t, 1, depth)
+	assert.Equal(t, size, bytes)
+
+	cancelled, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.False(t, q.send(cancelled, createTestBatch(10), sender), "send should block on the byte limit")
+
+	_, ok = q.receive(ctx, sender)
+	require.True(t, ok)
+	_, bytes = q.depth()
+	assert.Equal(t, int64(0), bytes)
+	assert.True(t, q.send(ctx, createTestBatch(10), sender))
+}
+
+func TestQueueSendCancelled(t *testing.T) {
+	q := newQueue(1, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.True(t, q.send(ctx, createTestBatch(1), &stageMetrics{}))
+
+	cancel()
+	assert.False(t, q.send(ctx, createTestBatch(1), &stageMetrics{}))
+	depth, _ := q.depth()
+	assert.Equal(t, 1, depth)
+}
+
+func TestConcurrentPipelineStageMetrics(t *testing.T) {
+	batches := make([]*types.DataBatch, 20)
+	for i := range batches {
+		batches[i] = createTestBatch(10)
+	}
+
+	input := &mockInputPlugin{batches: batches}
+	processors := []types.ProcessorPlugin{&mockProcessorPlugin{name: "fast-proc"}}
+	output := &mockOutputPlugin{delay: 5 * time.Millisecond}
+
+	config := DefaultConcurrentConfig()
+	config.InputBufferSize = 1
+	config.OutputBufferSize = 1
+	config.OutputBufferBytes = 1 << 20
+
+	p := NewConcurrentPipeline(input, processors, output, config)
+	require.NoError(t, p.Execute(context.Background()))
+
+	stages := p.GetStageMetrics()
+	require.Len(t, stages, 3)
+
+	assert.Equal(t, StageInput, stages[0].Stage)
+	assert.Equal(t, "mock-input", stages[0].Plugin)
+	assert.Equal(t, int64(20), stages[0].Batches)
+	assert.Equal(t, 0, stages[0].QueueCapacity, "the input has no input queue")
+
+	assert.Equal(t, StageProcessor, stages[1].Stage)
+	assert.Equal(t, "fast-proc", stages[1].Plugin)
+	assert.Equal(t, int64(20), stages[1].Batches)
+	assert.Equal(t, 1, stages[1].QueueCapacity)
+
+	assert.Equal(t, StageOutput, stages[2].Stage)
+	assert.Equal(t, int64(20), stages[2].Batches)
+	assert.Equal(t, int64(1<<20), stages[2].QueueMaxBytes)
+	assert.Equal(t, 0, stages[2].QueueDepth)
+
+	// The slow output is the bottleneck: it is busy, and the stages before
+	// it are blocked on it
+	assert.GreaterOrEqual(t, stages[2].BusyTime, 100*time.Millisecond)
+	assert.Greater(t, stages[1].BlockedTime, stages[1].BusyTime)
+	assert.Greater(t, stages[1].BlockedTime, 50*time.Millisecond)
+
+	stats := p.GetStatistics()
+	assert.Len(t, stats["stages"], 3)
+}
+
+// observations returns the number of observations of a histogram
+func observations(t *testing.T, observer prometheus.Observer) uint64 {
+	var m dto.Metric
+	require.NoError(t, observer.(prometheus.Histogram).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestConcurrentPipelinePrometheusMetrics(t *testing.T) {
+	input := &resumableInput{mockInputPlugin: mockInputPlugin{batches: testBatches()}}
+	processors := []types.ProcessorPlugin{&mockProcessorPlugin{name: "proc"}}
+
+	config := DefaultConcurrentConfig()
+	// Counters are process-wide, so each run counts under its own job
+	config.JobID = fmt.Sprintf("metrics-job-%d", time.Now().UnixNano())
+	config.CheckpointConfig = &checkpoint.Config{Enabled: true, StorageType: "file", StoragePath: t.TempDir()}
+	p := NewConcurrentPipeline(input, processors, &mockOutputPlugin{}, config)
+	require.NoError(t, p.Execute(context.Background()))
+
+	for _, stage := range [][]string{{"input", "mock-input"}, {"processor_0", "proc"}, {"output", "mock-output"}} {
+		labels := []string{config.JobID, stage[0], stage[1]}
+		assert.Equal(t, float64(12), testutil.ToFloat64(metrics.PipelineRecords.WithLabelValues(labels...)), stage[0])
+		assert.Positive(t, testutil.ToFloat64(metrics.PipelineBytes.WithLabelValues(labels...)), stage[0])
+		assert.Equal(t, uint64(4), observations(t, metrics.PipelineBatchDuration.WithLabelValues(labels...)), stage[0])
+	}
+	assert.Equal(t, uint64(4), observations(t, metrics.CheckpointSaveDuration.WithLabelValues(config.JobID)))
+}
+
+func TestConcurrentPipelineSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	input := &resumableInput{mockInputPlugin: mockInputPlugin{batches: testBatches()}}
+	processors := []types.ProcessorPlugin{&mockProcessorPlugin{name: "proc"}}
+	config := DefaultConcurrentConfig()
+	config.CheckpointConfig = &checkpoint.Config{Enabled: true, StorageType: "file", StoragePath: t.TempDir()}
+	p := NewConcurrentPipeline(input, processors, &mockOutputPlugin{}, config)
+
+	ctx, execution := otel.Tracer("test").Start(context.Background(), "execution")
+	require.NoError(t, p.Execute(ctx))
+	execution.End()
+
+	// Every batch is traced in each stage, under the span of the execution
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+		if span.Name() != "execution" {
+			assert.Equal(t, execution.SpanContext().TraceID(), span.SpanContext().TraceID(), span.Name())
+		}
+	}
+	assert.Len(t, spans["pipeline.read"], 5) // The last read reaches the end
+	assert.Len(t, spans["pipeline.process"], 4)
+	assert.Len(t, spans["pipeline.write"], 4)
+	assert.Len(t, spans["checkpoint.persist"], 1)
+
+	// Checkpoints are saved within the write of their batch
+	require.Len(t, spans["checkpoint.save"], 4)
+	writes := make(map[string]bool)
+	for _, span := range spans["pipeline.write"] {
+		writes[span.SpanContext().SpanID().String()] = true
+		assert.Contains(t, span.Attributes(), attribute.String("fustgo.stage", "output"))
+		assert.Contains(t, span.Attributes(), attribute.String("fustgo.plugin", "mock-output"))
+	}
+	for _, span := range spans["checkpoint.save"] {
+		assert.True(t, writes[span.Parent().SpanID().String()])
+	}
+}
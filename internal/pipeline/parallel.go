@@ -0,0 +1,291 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// ProcessorParallelism configures a processor stage that fans batches out
+// to several workers and merges them back.
+//
+// By default the stage keeps the order of its input: finished batches wait
+// in a reorder buffer until all earlier batches have left the stage. An
+// unordered stage passes batches on as soon as they finish, and holds back
+// checkpoints until no batch after them has been passed on, so the output
+// never commits a checkpoint while holding records beyond it.
+type ProcessorParallelism struct {
+	// Workers is the number of goroutines processing batches; 1 or less
+	// runs the stage on a single goroutine
+	Workers int
+
+	// Unordered lets batches leave the stage in the order they finish
+	Unordered bool
+
+	// NewInstance creates an initialized processor instance for each
+	// worker beyond the first, which uses the stage's processor. When nil,
+	// the stage runs on a single goroutine, as no instance is ever given
+	// batches by several workers.
+	NewInstance func() (types.ProcessorPlugin, error)
+}
+
+// sequencedBatch is a batch tagged with its position in the stage input
+type sequencedBatch struct {
+	seq   int64
+	batch *types.DataBatch
+}
+
+// createWorkers creates the processor instances of parallel stages
+func (p *ConcurrentPipeline) createWorkers() error {
+	for i, processor := range p.processors {
+		parallelism := p.parallelism[i]
+		if parallelism == nil || parallelism.Workers <= 1 {
+			continue
+		}
+		if parallelism.NewInstance == nil {
+			p.stages[i+1].log.Warn("Processor %d (%s) runs on one worker, as it has no way to create more instances", i, processor.Name())
+			continue
+		}
+
+		workers := make([]types.ProcessorPlugin, parallelism.Workers)
+		workers[0] = processor
+		for w := 1; w < len(workers); w++ {
+			instance, err := parallelism.NewInstance()
+			if err != nil {
+				return fmt.Errorf("failed to create worker %d of processor %d (%s): %w", w, i, processor.Name(), err)
+			}
+			workers[w] = instance
+		}
+
+		p.mu.Lock()
+		p.workers[i] = workers
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// runParallelProcessor processes batches from the input queue on one
+// worker per processor instance and merges the results into the output
+// queue
+func (p *ConcurrentPipeline) runParallelProcessor(
+	ctx context.Context,
+	workers []types.ProcessorPlugin,
+	unordered bool,
+	in *queue,
+	out *queue,
+	index int,
+	wg *sync.WaitGroup,
+) {
+	defer wg.Done()
+	defer out.close()
+
+	metrics := p.stages[index+1]
+	metrics.log.Info("Processor %d (%s) started with %d workers", index, workers[0].Name(), len(workers))
+
+	// The window bounds the batches in flight in the stage, including
+	// those waiting in the reorder buffer
+	window := make(chan struct{}, 2*len(workers))
+	jobs := make(chan sequencedBatch)
+	results := make(chan sequencedBatch, len(workers))
+
+	// Dispatch batches in input order
+	go func() {
+		defer close(jobs)
+		var seq int64
+		for {
+			batch, ok := in.receive(ctx, metrics)
+			if !ok {
+				return
+			}
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- sequencedBatch{seq: seq, batch: batch}:
+				seq++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var workerWg sync.WaitGroup
+	for _, processor := range workers {
+		workerWg.Add(1)
+		go func(processor types.ProcessorPlugin) {
+			defer workerWg.Done()
+			for job := range jobs {
+				start := time.Now()
+				_, span := metrics.startSpan(ctx)
+				processed, err := p.processBatch(processor, job.batch, index)
+				elapsed := metrics.addBusy(start)
+				endSpan(span, processed, err)
+				if err != nil {
+					p.fail(ctx, err)
+					return
+				}
+				metrics.addBatch(recordCount(processed), processed, elapsed)
+				select {
+				case results <- sequencedBatch{seq: job.seq, batch: processed}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(processor)
+	}
+	go func() {
+		workerWg.Wait()
+		close(results)
+	}()
+
+	// Merge results. Reading goes on until all workers have stopped, so
+	// that none is left running when the stage returns.
+	merge := p.mergeOrdered
+	if unordered {
+		merge = p.mergeUnordered
+	}
+	if merge(ctx, results, window, out, metrics) {
+		metrics.log.Info("Processor %d input channel closed", index)
+	} else {
+		metrics.log.Info("Processor %d cancelled", index)
+	}
+}
+
+// mergeOrdered passes results on in sequence order. It returns false when
+// cancelled.
+func (p *ConcurrentPipeline) mergeOrdered(
+	ctx context.Context,
+	results <-chan sequencedBatch,
+	window <-chan struct{},
+	out *queue,
+	metrics *stageMetrics,
+) bool {
+	buffer := make(map[int64]*types.DataBatch)
+	var next int64
+	cancelled := false
+
+	for result := range results {
+		buffer[result.seq] = result.batch
+		for {
+			batch, ok := buffer[next]
+			if !ok {
+				break
+			}
+			delete(buffer, next)
+			next++
+			<-window
+
+			if !cancelled && !emit(ctx, batch, out, metrics) {
+				cancelled = true
+			}
+		}
+	}
+
+	return !cancelled && ctx.Err() == nil
+}
+
+// mergeUnordered passes results on as they arrive. A checkpoint leaves the
+// stage once all batches before it have finished, on the first batch passed
+// on while no later batch has been passed on yet, so that the output never
+// holds records beyond a checkpoint it commits. It returns false when
+// cancelled.
+func (p *ConcurrentPipeline) mergeUnordered(
+	ctx context.Context,
+	results <-chan sequencedBatch,
+	window <-chan struct{},
+	out *queue,
+	metrics *stageMetrics,
+) bool {
+	finished := make(map[int64]*types.Checkpoint)
+	var next int64
+	var checkpoint *types.Checkpoint
+	last := int64(-1) // Highest sequence number passed on
+	cancelled := false
+
+	for result := range results {
+		<-window
+
+		var batchCheckpoint *types.Checkpoint
+		if result.batch != nil {
+			batchCheckpoint = result.batch.Checkpoint
+		}
+		finished[result.seq] = batchCheckpoint
+
+		// Advance over the run of finished batches from the start
+		for {
+			cp, ok := finished[next]
+			if !ok {
+				break
+			}
+			delete(finished, next)
+			next++
+			if cp != nil {
+				checkpoint = cp
+			}
+		}
+
+		batch := result.batch
+		if batch == nil {
+			batch = &types.DataBatch{}
+		}
+		if !batch.IsEmpty() && result.seq > last {
+			last = result.seq
+		}
+
+		// Hold the checkpoint while a batch after it has been passed on
+		batch.Checkpoint = nil
+		if checkpoint != nil && last < next {
+			batch.Checkpoint = checkpoint
+			checkpoint = nil
+		}
+
+		if !cancelled && !emit(ctx, batch, out, metrics) {
+			cancelled = true
+		}
+	}
+
+	return !cancelled && ctx.Err() == nil
+}
+
+// emit queues a processed batch for the next stage, dropping batches left
+// with neither records nor a checkpoint. It returns false when cancelled.
+func emit(ctx context.Context, batch *types.DataBatch, out *queue, metrics *stageMetrics) bool {
+	if batch == nil || (batch.IsEmpty() && batch.Checkpoint == nil) {
+		return true
+	}
+	return out.send(ctx, batch, metrics)
+}
+
+// fail reports an error that aborts the run, unless the run is already
+// being cancelled
+func (p *ConcurrentPipeline) fail(ctx context.Context, err error) {
+	select {
+	case p.errorChan <- err:
+	case <-ctx.Done():
+	}
+}
+
+// workerStatistics sums the statistics of the workers of a stage. Duration
+// is the longest of the workers.
+func workerStatistics(workers []types.ProcessorPlugin) *types.ProcessStatistics {
+	total := &types.ProcessStatistics{}
+	for _, worker := range workers {
+		stats := worker.GetStatistics()
+		if stats == nil {
+			continue
+		}
+		total.RecordsIn += stats.RecordsIn
+		total.RecordsOut += stats.RecordsOut
+		total.Filtered += stats.Filtered
+		total.Errors += stats.Errors
+		if stats.Duration > total.Duration {
+			total.Duration = stats.Duration
+		}
+	}
+	return total
+}
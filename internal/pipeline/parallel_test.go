@@ -0,0 +1,268 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/checkpoint"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// sequencedInput produces batches whose single record and checkpoint hold
+// the batch number
+type sequencedInput struct {
+	mockInputPlugin
+	count int
+}
+
+func (m *sequencedInput) ReadBatch(batchSize int) (*types.DataBatch, error) {
+	if m.index >= m.count {
+		return nil, io.EOF
+	}
+	n := m.index
+	m.index++
+	batch := createTestBatch(1)
+	batch.Records[0].Values[0] = n
+	batch.Checkpoint = &types.Checkpoint{Position: n}
+	return batch, nil
+}
+
+func (m *sequencedInput) HasNext() bool {
+	return m.index < m.count
+}
+
+// slowProcessor takes longer on earlier batches, so that later batches
+// finish first, and drops every batch number divisible by dropEvery. It
+// records the most calls it ever ran at once.
+type slowProcessor struct {
+	mockProcessorPlugin
+	dropEvery int
+	calls     int64
+	active    int64
+	maxActive int64
+	fail      bool
+}
+
+func (m *slowProcessor) Process(input *types.DataBatch) (*types.DataBatch, error) {
+	atomic.AddInt64(&m.calls, 1)
+	active := atomic.AddInt64(&m.active, 1)
+	defer atomic.AddInt64(&m.active, -1)
+	for {
+		max := atomic.LoadInt64(&m.maxActive)
+		if active <= max || atomic.CompareAndSwapInt64(&m.maxActive, max, active) {
+			break
+		}
+	}
+	if m.fail {
+		return nil, fmt.Errorf("boom")
+	}
+	n := input.Records[0].Values[0].(int)
+	time.Sleep(time.Duration(10-n%10) * time.Millisecond)
+	if m.dropEvery > 0 && n%m.dropEvery == 0 {
+		return &types.DataBatch{Schema: input.Schema, Checkpoint: input.Checkpoint}, nil
+	}
+	return input, nil
+}
+
+// writtenBatches returns the batch numbers and checkpoints written
+func writtenBatches(output *mockOutputPlugin) ([]int, []int) {
+	var numbers, checkpoints []int
+	for _, batch := range output.batches {
+		for _, record := range batch.Records {
+			numbers = append(numbers, record.Values[0].(int))
+		}
+		if batch.Checkpoint != nil {
+			checkpoints = append(checkpoints, batch.Checkpoint.Position.(int))
+		}
+	}
+	return numbers, checkpoints
+}
+
+func runParallel(t *testing.T, count int, parallelism *ProcessorParallelism, processor types.ProcessorPlugin) (*mockOutputPlugin, error) {
+	t.Helper()
+	input := &sequencedInput{count: count}
+	output := &mockOutputPlugin{}
+	config := DefaultConcurrentConfig()
+	config.Parallelism = map[int]*ProcessorParallelism{0: parallelism}
+
+	p := NewConcurrentPipeline(input, []types.ProcessorPlugin{processor}, output, config)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return output, p.Execute(ctx)
+}
+
+func TestParallelProcessorOrdered(t *testing.T) {
+	var instances []*slowProcessor
+	parallelism := &ProcessorParallelism{
+		Workers: 4,
+		NewInstance: func() (types.ProcessorPlugin, error) {
+			instance := &slowProcessor{dropEvery: 7}
+			instances = append(instances, instance)
+			return instance, nil
+		},
+	}
+	first := &slowProcessor{dropEvery: 7}
+
+	output, err := runParallel(t, 50, parallelism, first)
+	require.NoError(t, err)
+	require.Len(t, instances, 3, "one instance per additional worker")
+
+	numbers, checkpoints := writtenBatches(output)
+	var expected []int
+	for n := 0; n < 50; n++ {
+		if n%7 != 0 {
+			expected = append(expected, n)
+		}
+	}
+	assert.Equal(t, expected, numbers)
+
+	// Checkpoints arrive in order, including those of dropped batches
+	require.NotEmpty(t, checkpoints)
+	for i := 1; i < len(checkpoints); i++ {
+		assert.Greater(t, checkpoints[i], checkpoints[i-1])
+	}
+	// Batch 49 is dropped, so its checkpoint travels in an empty batch that
+	// is committed without being written
+	assert.Equal(t, 48, checkpoints[len(checkpoints)-1])
+
+	calls := atomic.LoadInt64(&first.calls)
+	for _, instance := range instances {
+		calls += atomic.LoadInt64(&instance.calls)
+	}
+	assert.Equal(t, int64(50), calls)
+}
+
+func TestParallelProcessorUnordered(t *testing.T) {
+	parallelism := &ProcessorParallelism{
+		Workers:   4,
+		Unordered: true,
+		NewInstance: func() (types.ProcessorPlugin, error) {
+			return &slowProcessor{}, nil
+		},
+	}
+
+	output, err := runParallel(t, 50, parallelism, &slowProcessor{})
+	require.NoError(t, err)
+
+	// All batches arrive
+	numbers, _ := writtenBatches(output)
+	assert.ElementsMatch(t, func() []int {
+		all := make([]int, 50)
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}(), numbers)
+
+	// A checkpoint only covers batches written with or before it
+	written := make(map[int]bool)
+	last := -1
+	for _, batch := range output.batches {
+		for _, record := range batch.Records {
+			written[record.Values[0].(int)] = true
+		}
+		if batch.Checkpoint == nil {
+			continue
+		}
+		position := batch.Checkpoint.Position.(int)
+		assert.Greater(t, position, last)
+		for n := 0; n <= position; n++ {
+			assert.True(t, written[n], "batch %d not written before checkpoint %d", n, position)
+		}
+		last = position
+	}
+	assert.Equal(t, 49, last)
+}
+
+func TestParallelProcessorUnorderedWithCheckpoints(t *testing.T) {
+	parallelism := &ProcessorParallelism{
+		Workers:   4,
+		Unordered: true,
+		NewInstance: func() (types.ProcessorPlugin, error) {
+			return &slowProcessor{}, nil
+		},
+	}
+	output := &mockOutputPlugin{}
+	config := DefaultConcurrentConfig()
+	config.Parallelism = map[int]*ProcessorParallelism{0: parallelism}
+	config.CheckpointConfig = &checkpoint.Config{Enabled: true, StorageType: "file", StoragePath: t.TempDir()}
+
+	p := NewConcurrentPipeline(&sequencedInput{count: 30}, []types.ProcessorPlugin{&slowProcessor{}}, output, config)
+	require.NoError(t, p.Execute(context.Background()))
+
+	// Batches leave the stage as they finish
+	numbers, _ := writtenBatches(output)
+	ordered := make([]int, 30)
+	for i := range ordered {
+		ordered[i] = i
+	}
+	assert.ElementsMatch(t, ordered, numbers)
+	assert.NotEqual(t, ordered, numbers)
+
+	// The output holds exactly the batches a checkpoint covers when it
+	// commits it, so a resumed run neither skips nor repeats batches
+	written := make(map[int]bool)
+	last := -1
+	for _, batch := range output.batches {
+		for _, record := range batch.Records {
+			written[record.Values[0].(int)] = true
+		}
+		if batch.Checkpoint == nil {
+			continue
+		}
+		position := batch.Checkpoint.Position.(int)
+		assert.Greater(t, position, last)
+		assert.Len(t, written, position+1, "batches written at checkpoint %d", position)
+		for n := 0; n <= position; n++ {
+			assert.True(t, written[n], "batch %d not written before checkpoint %d", n, position)
+		}
+		last = position
+	}
+	assert.Equal(t, 29, last)
+}
+
+func TestParallelProcessorWithoutNewInstance(t *testing.T) {
+	processor := &slowProcessor{}
+
+	// Without a way to create instances, the stage runs on one worker
+	output, err := runParallel(t, 20, &ProcessorParallelism{Workers: 3, Unordered: true}, processor)
+	require.NoError(t, err)
+
+	numbers, _ := writtenBatches(output)
+	assert.Len(t, numbers, 20)
+	assert.Equal(t, int64(20), atomic.LoadInt64(&processor.calls))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&processor.maxActive))
+}
+
+func TestParallelProcessorError(t *testing.T) {
+	parallelism := &ProcessorParallelism{
+		Workers: 4,
+		NewInstance: func() (types.ProcessorPlugin, error) {
+			return &slowProcessor{fail: true}, nil
+		},
+	}
+
+	_, err := runParallel(t, 50, parallelism, &slowProcessor{fail: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
+
+func TestParallelProcessorInstanceError(t *testing.T) {
+	parallelism := &ProcessorParallelism{
+		Workers: 2,
+		NewInstance: func() (types.ProcessorPlugin, error) {
+			return nil, fmt.Errorf("no instance")
+		},
+	}
+
+	_, err := runParallel(t, 5, parallelism, &slowProcessor{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no instance")
+}
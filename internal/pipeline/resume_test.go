@@ -0,0 +1,252 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/checkpoint"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// resumableInput produces numbered batches with their index as checkpoint
+type resumableInput struct {
+	mockInputPlugin
+	seeked *types.Checkpoint
+}
+
+func (m *resumableInput) ReadBatch(batchSize int) (*types.DataBatch, error) {
+	batch, err := m.mockInputPlugin.ReadBatch(batchSize)
+	if err != nil {
+		return nil, err
+	}
+	return &types.DataBatch{
+		Schema:     batch.Schema,
+		Records:    batch.Records,
+		Checkpoint: &types.Checkpoint{Position: map[string]interface{}{"index": m.index}},
+	}, nil
+}
+
+func (m *resumableInput) Seek(cp *types.Checkpoint) error {
+	m.seeked = cp
+	if cp.IsCompleted() {
+		return nil
+	}
+	m.index = int(cp.Position.(map[string]interface{})["index"].(float64))
+	return nil
+}
+
+// resumableOutput keeps the records flushed so far, like a file, and can
+// fail on a given batch to simulate a crash
+type resumableOutput struct {
+	mockOutputPlugin
+	flushed  []types.Record
+	pending  []types.Record
+	failOn   int
+	writes   int
+	flushes  int
+	resumeAt int
+}
+
+func (m *resumableOutput) Connect() error {
+	m.flushed = m.flushed[:m.resumeAt]
+	return nil
+}
+
+func (m *resumableOutput) WriteBatch(data *types.DataBatch) error {
+	m.writes++
+	if m.writes == m.failOn {
+		return fmt.Errorf("crash")
+	}
+	m.pending = append(m.pending, data.Records...)
+	return nil
+}
+
+func (m *resumableOutput) Flush() error {
+	m.flushes++
+	m.flushed = append(m.flushed, m.pending...)
+	m.pending = nil
+	return nil
+}
+
+func (m *resumableOutput) Position() (interface{}, error) {
+	return map[string]interface{}{"records": len(m.flushed)}, nil
+}
+
+func (m *resumableOutput) Resume(position interface{}) error {
+	m.resumeAt = int(position.(map[string]interface{})["records"].(float64))
+	return nil
+}
+
+func newResumablePipeline(t *testing.T, dir string, input types.InputPlugin, output types.OutputPlugin) *ConcurrentPipeline {
+	config := DefaultConcurrentConfig()
+	config.JobID = "resume-job"
+	config.CheckpointConfig = &checkpoint.Config{Enabled: true, StorageType: "file", StoragePath: dir}
+	p := NewConcurrentPipeline(input, nil, output, config)
+	require.NotNil(t, p.GetCheckpointManager())
+	return p
+}
+
+func testBatches() []*types.DataBatch {
+	return []*types.DataBatch{createTestBatch(3), createTestBatch(3), createTestBatch(3), createTestBatch(3)}
+}
+
+func TestConcurrentPipelineResume(t *testing.T) {
+	dir := t.TempDir()
+	output := &resumableOutput{failOn: 3}
+
+	// The first run crashes while writing the third batch
+	first := newResumablePipeline(t, dir, &resumableInput{mockInputPlugin: mockInputPlugin{batches: testBatches()}}, output)
+	require.Error(t, first.Execute(context.Background()))
+	assert.Len(t, output.flushed, 6)
+
+	// The restarted run continues after the second batch
+	input := &resumableInput{mockInputPlugin: mockInputPlugin{batches: testBatches()}}
+	output.failOn = 0
+	second := newResumablePipeline(t, dir, input, output)
+	require.NoError(t, second.Execute(context.Background()))
+
+	require.NotNil(t, input.seeked)
+	assert.Equal(t, 6, output.resumeAt)
+	assert.Len(t, output.flushed, 12)
+	assert.Equal(t, int64(6), second.GetSummary().RecordsRead)
+
+	// After a completed run, the next one starts over
+	input = &resumableInput{mockInputPlugin: mockInputPlugin{batches: testBatches()}}
+	fresh := &resumableOutput{}
+	third := newResumablePipeline(t, dir, input, fresh)
+	require.NoError(t, third.Execute(context.Background()))
+
+	assert.True(t, input.seeked.IsCompleted())
+	assert.Equal(t, 0, fresh.resumeAt)
+	assert.Len(t, fresh.flushed, 12)
+}
+
+// failingProcessor fails on batch failOn, once the batches before it had
+// time to reach the output
+type failingProcessor struct {
+	mockProcessorPlugin
+	failOn int
+	calls  int
+}
+
+func (m *failingProcessor) Process(input *types.DataBatch) (*types.DataBatch, error) {
+	m.calls++
+	if m.calls == m.failOn {
+		time.Sleep(50 * time.Millisecond)
+		return nil, fmt.Errorf("processor crash")
+	}
+	return input, nil
+}
+
+func manyBatches(n int) []*types.DataBatch {
+	batches := make([]*types.DataBatch, n)
+	for i := range batches {
+		batches[i] = createTestBatch(3)
+	}
+	return batches
+}
+
+func newIntervalPipeline(t *testing.T, interval time.Duration, input types.InputPlugin, processors []types.ProcessorPlugin, output types.OutputPlugin) *ConcurrentPipeline {
+	config := DefaultConcurrentConfig()
+	config.JobID = "interval-job"
+	config.CheckpointConfig = &checkpoint.Config{Enabled: true, StorageType: "file", StoragePath: t.TempDir(), Interval: interval}
+	return NewConcurrentPipeline(input, processors, output, config)
+}
+
+// committedIndex returns the input index of the committed output checkpoint
+func committedIndex(t *testing.T, p *ConcurrentPipeline) int {
+	cp, err := p.LoadCheckpoint("output")
+	require.NoError(t, err)
+	if cp == nil {
+		return 0
+	}
+	return cp.Position.(map[string]interface{})["index"].(int)
+}
+
+func TestCheckpointInterval(t *testing.T) {
+	// The output is flushed with the checkpoint when the run ends rather
+	// than after every batch
+	output := &resumableOutput{}
+	input := &resumableInput{mockInputPlugin: mockInputPlugin{batches: manyBatches(50)}}
+	p := newIntervalPipeline(t, time.Hour, input, nil, output)
+	require.NoError(t, p.Execute(context.Background()))
+	assert.LessOrEqual(t, output.flushes, 2)
+	assert.Len(t, output.flushed, 150)
+	assert.Equal(t, 50, committedIndex(t, p))
+
+	// Without an interval, after every batch
+	output = &resumableOutput{}
+	input = &resumableInput{mockInputPlugin: mockInputPlugin{batches: manyBatches(50)}}
+	p = newIntervalPipeline(t, 0, input, nil, output)
+	require.NoError(t, p.Execute(context.Background()))
+	assert.GreaterOrEqual(t, output.flushes, 50)
+
+	// On every tick while the run goes on
+	output = &resumableOutput{}
+	input = &resumableInput{mockInputPlugin: mockInputPlugin{batches: manyBatches(40), delay: 5 * time.Millisecond}}
+	p = newIntervalPipeline(t, 20*time.Millisecond, input, nil, output)
+	require.NoError(t, p.Execute(context.Background()))
+	assert.Greater(t, output.flushes, 2)
+	assert.Less(t, output.flushes, 40)
+}
+
+func TestCheckpointIntervalFailure(t *testing.T) {
+	// A failed output commits nothing, as it may hold part of the batch
+	// that failed
+	output := &resumableOutput{failOn: 3}
+	input := &resumableInput{mockInputPlugin: mockInputPlugin{batches: manyBatches(10)}}
+	p := newIntervalPipeline(t, time.Hour, input, nil, output)
+	require.Error(t, p.Execute(context.Background()))
+	assert.Empty(t, output.flushed)
+	assert.Equal(t, 0, committedIndex(t, p))
+
+	// When another stage fails, the batches written are committed
+	output = &resumableOutput{}
+	input = &resumableInput{mockInputPlugin: mockInputPlugin{batches: manyBatches(10)}}
+	processors := []types.ProcessorPlugin{&failingProcessor{mockProcessorPlugin: mockProcessorPlugin{name: "proc"}, failOn: 6}}
+	p = newIntervalPipeline(t, time.Hour, input, processors, output)
+	require.Error(t, p.Execute(context.Background()))
+	assert.Equal(t, 1, output.flushes)
+	assert.Equal(t, 5, committedIndex(t, p))
+	assert.Len(t, output.flushed, 15)
+}
+
+func TestCheckpointFlushesDeadLetter(t *testing.T) {
+	// Records rejected before a committed checkpoint reach the dead-letter
+	// target with it, as the resumed run reads past them
+	deadLetter := &resumableOutput{}
+	output := &resumableOutput{failOn: 3}
+	input := &resumableInput{mockInputPlugin: mockInputPlugin{batches: testBatches()}}
+	processors := []types.ProcessorPlugin{&rejectingProcessor{mockProcessorPlugin{name: "reject"}, 2}}
+
+	config := DefaultConcurrentConfig()
+	config.JobID = "dead-letter-job"
+	config.CheckpointConfig = &checkpoint.Config{Enabled: true, StorageType: "file", StoragePath: t.TempDir()}
+	config.ErrorPolicy = &ErrorPolicy{Mode: ErrorModeDeadLetter, DeadLetter: deadLetter}
+	p := NewConcurrentPipeline(input, processors, output, config)
+	require.Error(t, p.Execute(context.Background()))
+
+	assert.Equal(t, 2, committedIndex(t, p))
+	assert.Len(t, output.flushed, 2)
+	assert.GreaterOrEqual(t, len(deadLetter.flushed), 4)
+}
+
+func TestCheckpointSaveFailure(t *testing.T) {
+	// A checkpoint that cannot be saved fails the run instead of being
+	// taken as committed
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "resume-job"), nil, 0644))
+
+	output := &resumableOutput{}
+	p := newResumablePipeline(t, dir, &resumableInput{mockInputPlugin: mockInputPlugin{batches: testBatches()}}, output)
+	err := p.Execute(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to save output checkpoint")
+}
@@ -0,0 +1,362 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atlanssia/fustgo/internal/expr"
+	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// Schema policy modes
+const (
+	SchemaModeFail        = "fail"         // Abort the run when a batch schema differs
+	SchemaModeAddColumns  = "add_columns"  // Add new columns to the schema and the output
+	SchemaModeDropUnknown = "drop_unknown" // Drop new columns and convert values to the established types
+	SchemaModeWiden       = "widen"        // Add new columns and widen column types to hold both types
+)
+
+// Schema changes reported in schema events
+const (
+	SchemaChangeColumnAdded   = "column_added"   // The batch has a column the established schema lacks
+	SchemaChangeColumnMissing = "column_missing" // The batch lacks a column of the established schema
+	SchemaChangeTypeChanged   = "type_changed"   // A column has another data type in the batch
+)
+
+// Actions taken on schema changes
+const (
+	SchemaActionFailed    = "failed"    // The run aborted
+	SchemaActionAdded     = "added"     // The column was added to the schema and the output
+	SchemaActionDropped   = "dropped"   // The values of the column were dropped
+	SchemaActionFilled    = "filled"    // The column was filled with nulls
+	SchemaActionConverted = "converted" // The values were converted to the established type
+	SchemaActionWidened   = "widened"   // The column type was widened to hold both types
+)
+
+// SchemaPolicy decides what happens when the schema of a batch reaching the
+// output differs from the schema established by the first batch written.
+// Columns are matched by name, so a batch with the same columns in another
+// order is reordered without a schema change. In every mode but fail,
+// columns missing from a batch are filled with nulls.
+type SchemaPolicy struct {
+	Mode string
+}
+
+// DefaultSchemaPolicy returns the policy that fails on any schema change
+func DefaultSchemaPolicy() *SchemaPolicy {
+	return &SchemaPolicy{Mode: SchemaModeFail}
+}
+
+// Validate validates the policy
+func (sp *SchemaPolicy) Validate() error {
+	switch sp.Mode {
+	case SchemaModeFail, SchemaModeAddColumns, SchemaModeDropUnknown, SchemaModeWiden:
+		return nil
+	default:
+		return fmt.Errorf("invalid schema mode '%s', must be 'fail', 'add_columns', 'drop_unknown' or 'widen'", sp.Mode)
+	}
+}
+
+// schemaTracker compares the schema of each batch written to the
+// established schema and conforms batches to it according to the policy.
+// conform is only called by the output writer.
+type schemaTracker struct {
+	policy      *SchemaPolicy
+	established *types.Schema
+	plans       map[string]*schemaPlan // By batch schema signature
+
+	mu     sync.Mutex
+	events []types.SchemaEvent
+	log    *logger.Logger
+}
+
+// schemaPlan maps the columns of a batch schema to the established schema
+type schemaPlan struct {
+	schema   types.Schema     // Established schema the plan was built for
+	identity bool             // The batch already has the established layout
+	sources  []int            // Batch column of each established column, -1 for nulls
+	casts    []types.DataType // Type each value is converted to, unknown for none
+}
+
+func newSchemaTracker(policy *SchemaPolicy, log *logger.Logger) *schemaTracker {
+	if policy == nil {
+		policy = DefaultSchemaPolicy()
+	}
+	return &schemaTracker{policy: policy, plans: make(map[string]*schemaPlan), log: log}
+}
+
+// conform returns the batch in the established schema, evolving the schema
+// and the output first when the policy allows it, along with the records
+// whose values could not be converted. The first batch with columns
+// establishes the schema.
+func (t *schemaTracker) conform(batch *types.DataBatch, output types.OutputPlugin) (*types.DataBatch, []types.RejectedRecord, error) {
+	if len(batch.Schema.Columns) == 0 {
+		return batch, nil, nil
+	}
+	if t.established == nil {
+		schema := copySchema(batch.Schema)
+		t.established = &schema
+		return batch, nil, nil
+	}
+
+	key := schemaSignature(batch.Schema)
+	plan, ok := t.plans[key]
+	if !ok {
+		var err error
+		if plan, err = t.plan(batch.Schema, output); err != nil {
+			return nil, nil, err
+		}
+		t.plans[key] = plan
+	}
+	if plan.identity {
+		return batch, nil, nil
+	}
+
+	conformed, rejected := plan.apply(batch)
+	return conformed, rejected, nil
+}
+
+// plan compares a batch schema to the established schema, records the
+// changes and applies the policy to them
+func (t *schemaTracker) plan(schema types.Schema, output types.OutputPlugin) (*schemaPlan, error) {
+	established := t.established
+	evolved := copySchema(*established)
+	mode := t.policy.Mode
+	now := time.Now()
+
+	var events []types.SchemaEvent
+	var added, widened []types.Column
+
+	// Changed and missing columns
+	for i, col := range established.Columns {
+		j := schema.ColumnIndex(col.Name)
+		if j < 0 {
+			action := SchemaActionFilled
+			if mode == SchemaModeFail {
+				action = SchemaActionFailed
+			}
+			events = append(events, types.SchemaEvent{Time: now, Change: SchemaChangeColumnMissing, Column: col.Name, Action: action})
+			continue
+		}
+
+		other := schema.Columns[j].DataType
+		if !typeChanged(col.DataType, other) {
+			continue
+		}
+		action := SchemaActionConverted
+		switch mode {
+		case SchemaModeFail, SchemaModeAddColumns:
+			action = SchemaActionFailed
+		case SchemaModeWiden:
+			if wider := widenType(col.DataType, other); wider != col.DataType {
+				action = SchemaActionWidened
+				evolved.Columns[i].DataType = wider
+				widened = append(widened, evolved.Columns[i])
+			}
+		}
+		events = append(events, types.SchemaEvent{
+			Time: now, Change: SchemaChangeTypeChanged, Column: col.Name,
+			From: col.DataType.String(), To: other.String(), Action: action,
+		})
+	}
+
+	// New columns
+	for _, col := range schema.Columns {
+		if established.ColumnIndex(col.Name) >= 0 {
+			continue
+		}
+		action := SchemaActionDropped
+		switch mode {
+		case SchemaModeFail:
+			action = SchemaActionFailed
+		case SchemaModeAddColumns, SchemaModeWiden:
+			action = SchemaActionAdded
+			// Records written earlier have no value for it
+			col.Nullable = true
+			evolved.Columns = append(evolved.Columns, col)
+			added = append(added, col)
+		}
+		events = append(events, types.SchemaEvent{Time: now, Change: SchemaChangeColumnAdded, Column: col.Name, To: col.DataType.String(), Action: action})
+	}
+
+	t.record(events)
+	for _, event := range events {
+		if event.Action == SchemaActionFailed {
+			return nil, fmt.Errorf("schema drift: %s", describeSchemaEvent(event))
+		}
+	}
+
+	if len(added) > 0 || len(widened) > 0 {
+		if err := evolveOutput(output, added, widened); err != nil {
+			return nil, err
+		}
+		t.established = &evolved
+		t.plans = make(map[string]*schemaPlan)
+	}
+
+	return newSchemaPlan(schema, *t.established), nil
+}
+
+// record keeps schema events and logs them
+func (t *schemaTracker) record(events []types.SchemaEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	t.events = append(t.events, events...)
+	t.mu.Unlock()
+
+	for _, event := range events {
+		t.log.Warn("Schema drift: %s, %s", describeSchemaEvent(event), event.Action)
+	}
+}
+
+// recorded returns the schema events recorded so far
+func (t *schemaTracker) recorded() []types.SchemaEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]types.SchemaEvent(nil), t.events...)
+}
+
+// evolveOutput adds and widens columns of an output that can change the
+// schema of its target. Other outputs receive batches in the evolved
+// schema and decide whether they can write them.
+func evolveOutput(output types.OutputPlugin, added, widened []types.Column) error {
+	evolver, ok := output.(types.SchemaEvolver)
+	if !ok {
+		return nil
+	}
+	if len(added) > 0 {
+		if err := evolver.AddColumns(added); err != nil {
+			return fmt.Errorf("failed to add columns to output: %w", err)
+		}
+	}
+	if len(widened) > 0 {
+		if err := evolver.AlterColumns(widened); err != nil {
+			return fmt.Errorf("failed to widen columns of output: %w", err)
+		}
+	}
+	return nil
+}
+
+// newSchemaPlan maps the columns of a batch schema to a target schema
+func newSchemaPlan(schema, target types.Schema) *schemaPlan {
+	plan := &schemaPlan{
+		schema:   target,
+		identity: len(schema.Columns) == len(target.Columns),
+		sources:  make([]int, len(target.Columns)),
+		casts:    make([]types.DataType, len(target.Columns)),
+	}
+	for i, col := range target.Columns {
+		j := schema.ColumnIndex(col.Name)
+		plan.sources[i] = j
+		if j != i {
+			plan.identity = false
+		}
+		if j >= 0 && typeChanged(col.DataType, schema.Columns[j].DataType) {
+			plan.casts[i] = col.DataType
+			plan.identity = false
+		}
+	}
+	return plan
+}
+
+// apply returns a batch with the records in the target schema, and the
+// records with values that could not be converted
+func (pl *schemaPlan) apply(batch *types.DataBatch) (*types.DataBatch, []types.RejectedRecord) {
+	conformed := &types.DataBatch{
+		Schema:     pl.schema,
+		Records:    make([]types.Record, 0, len(batch.Records)),
+		Metadata:   batch.Metadata,
+		Checkpoint: batch.Checkpoint,
+	}
+
+	var rejected []types.RejectedRecord
+	for _, record := range batch.Records {
+		values := make([]interface{}, len(pl.sources))
+		var err error
+		for i, j := range pl.sources {
+			if j < 0 || j >= len(record.Values) {
+				continue
+			}
+			v := record.Values[j]
+			if cast := pl.casts[i]; cast != types.DataTypeUnknown && v != nil {
+				if v, err = expr.Cast(v, cast); err != nil {
+					err = fmt.Errorf("column %s: %w", pl.schema.Columns[i].Name, err)
+					break
+				}
+			}
+			values[i] = v
+		}
+		if err != nil {
+			rejected = append(rejected, types.RejectedRecord{Record: record, Error: err.Error()})
+			continue
+		}
+		conformed.Records = append(conformed.Records, types.Record{Values: values, Metadata: record.Metadata})
+	}
+	return conformed, rejected
+}
+
+// typeChanged reports whether a column of the established type has another
+// type in a batch. Unknown types match any type.
+func typeChanged(established, other types.DataType) bool {
+	return established != other && established != types.DataTypeUnknown && other != types.DataTypeUnknown
+}
+
+// widenType returns a type that holds the values of both types
+func widenType(a, b types.DataType) types.DataType {
+	isInteger := func(dt types.DataType) bool {
+		return dt == types.DataTypeInt || dt == types.DataTypeBigInt
+	}
+	isNumber := func(dt types.DataType) bool {
+		return isInteger(dt) || dt == types.DataTypeFloat || dt == types.DataTypeDouble
+	}
+	isTime := func(dt types.DataType) bool {
+		return dt == types.DataTypeDate || dt == types.DataTypeTimestamp
+	}
+
+	switch {
+	case a == b:
+		return a
+	case isInteger(a) && isInteger(b):
+		return types.DataTypeBigInt
+	case isNumber(a) && isNumber(b):
+		return types.DataTypeDouble
+	case isTime(a) && isTime(b):
+		return types.DataTypeTimestamp
+	default:
+		return types.DataTypeString
+	}
+}
+
+// schemaSignature identifies the column names and types of a schema
+func schemaSignature(schema types.Schema) string {
+	var sb strings.Builder
+	for _, col := range schema.Columns {
+		fmt.Fprintf(&sb, "%q:%d,", col.Name, col.DataType)
+	}
+	return sb.String()
+}
+
+func copySchema(schema types.Schema) types.Schema {
+	return types.Schema{
+		Columns:     append([]types.Column(nil), schema.Columns...),
+		PrimaryKeys: append([]string(nil), schema.PrimaryKeys...),
+	}
+}
+
+// describeSchemaEvent describes the change of a schema event
+func describeSchemaEvent(event types.SchemaEvent) string {
+	switch event.Change {
+	case SchemaChangeColumnAdded:
+		return fmt.Sprintf("batch has new column %s", event.Column)
+	case SchemaChangeColumnMissing:
+		return fmt.Sprintf("batch lacks column %s", event.Column)
+	default:
+		return fmt.Sprintf("column %s changed from %s to %s", event.Column, event.From, event.To)
+	}
+}
@@ -0,0 +1,182 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// evolvingOutput records the schema changes asked of it
+type evolvingOutput struct {
+	mockOutputPlugin
+	added   []types.Column
+	altered []types.Column
+}
+
+func (m *evolvingOutput) AddColumns(columns []types.Column) error {
+	m.added = append(m.added, columns...)
+	return nil
+}
+
+func (m *evolvingOutput) AlterColumns(columns []types.Column) error {
+	m.altered = append(m.altered, columns...)
+	return nil
+}
+
+var (
+	idColumn    = types.Column{Name: "id", DataType: types.DataTypeInt}
+	nameColumn  = types.Column{Name: "name", DataType: types.DataTypeString, Nullable: true}
+	extraColumn = types.Column{Name: "extra", DataType: types.DataTypeBool}
+)
+
+func schemaBatch(columns []types.Column, rows ...[]interface{}) *types.DataBatch {
+	batch := &types.DataBatch{Schema: types.Schema{Columns: columns}}
+	for _, row := range rows {
+		batch.Records = append(batch.Records, types.Record{Values: row})
+	}
+	return batch
+}
+
+func runWithSchemaPolicy(output types.OutputPlugin, mode string, batches ...*types.DataBatch) (*ConcurrentPipeline, error) {
+	config := DefaultConcurrentConfig()
+	config.SchemaPolicy = &SchemaPolicy{Mode: mode}
+	config.ErrorPolicy = &ErrorPolicy{Mode: ErrorModeSkip}
+
+	p := NewConcurrentPipeline(&mockInputPlugin{batches: batches}, nil, output, config)
+	return p, p.Execute(context.Background())
+}
+
+// eventSummaries returns the change, column and action of each event
+func eventSummaries(events []types.SchemaEvent) []string {
+	summaries := []string{}
+	for _, event := range events {
+		summaries = append(summaries, event.Change+" "+event.Column+" "+event.Action)
+	}
+	return summaries
+}
+
+func TestSchemaPolicyFail(t *testing.T) {
+	output := &mockOutputPlugin{}
+	p, err := runWithSchemaPolicy(output, SchemaModeFail,
+		schemaBatch([]types.Column{idColumn, nameColumn}, []interface{}{1, "a"}),
+		schemaBatch([]types.Column{idColumn, nameColumn, extraColumn}, []interface{}{2, "b", true}),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "schema drift: batch has new column extra")
+	assert.Len(t, output.batches, 1)
+	assert.Equal(t, []string{"column_added extra failed"}, eventSummaries(p.GetSchemaEvents()))
+}
+
+func TestSchemaPolicyAddColumns(t *testing.T) {
+	output := &evolvingOutput{}
+	p, err := runWithSchemaPolicy(output, SchemaModeAddColumns,
+		schemaBatch([]types.Column{idColumn, nameColumn}, []interface{}{1, "a"}),
+		schemaBatch([]types.Column{idColumn, nameColumn, extraColumn}, []interface{}{2, "b", true}),
+		schemaBatch([]types.Column{extraColumn, idColumn}, []interface{}{false, 3}),
+		schemaBatch([]types.Column{extraColumn, idColumn}, []interface{}{true, 4}),
+	)
+	require.NoError(t, err)
+
+	added := extraColumn
+	added.Nullable = true
+	assert.Equal(t, []types.Column{added}, output.added)
+
+	// Later batches are written in the evolved schema, with nulls for
+	// missing columns
+	require.Len(t, output.batches, 4)
+	assert.Equal(t, []types.Column{idColumn, nameColumn, added}, output.batches[3].Schema.Columns)
+	assert.Equal(t, []interface{}{3, nil, false}, output.batches[2].Records[0].Values)
+	assert.Equal(t, []interface{}{4, nil, true}, output.batches[3].Records[0].Values)
+
+	// Events are recorded once per batch schema
+	assert.Equal(t, []string{"column_added extra added", "column_missing name filled"}, eventSummaries(p.GetSchemaEvents()))
+
+	// Type changes fail
+	_, err = runWithSchemaPolicy(&evolvingOutput{}, SchemaModeAddColumns,
+		schemaBatch([]types.Column{idColumn}, []interface{}{1}),
+		schemaBatch([]types.Column{{Name: "id", DataType: types.DataTypeString}}, []interface{}{"2"}),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "column id changed from INT to STRING")
+}
+
+func TestSchemaPolicyDropUnknown(t *testing.T) {
+	output := &evolvingOutput{}
+	p, err := runWithSchemaPolicy(output, SchemaModeDropUnknown,
+		schemaBatch([]types.Column{idColumn, nameColumn}, []interface{}{1, "a"}),
+		schemaBatch([]types.Column{{Name: "id", DataType: types.DataTypeString}, nameColumn, extraColumn},
+			[]interface{}{"2", "b", true},
+			[]interface{}{"x", "c", false},
+		),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, output.added)
+
+	// Values are converted to the established type, and records that
+	// cannot be are rejected
+	require.Len(t, output.batches, 2)
+	assert.Equal(t, []types.Column{idColumn, nameColumn}, output.batches[1].Schema.Columns)
+	assert.Equal(t, []types.Record{{Values: []interface{}{int64(2), "b"}}}, output.batches[1].Records)
+	assert.Equal(t, int64(1), p.GetSummary().RecordsFailed)
+
+	assert.Equal(t, []string{"type_changed id converted", "column_added extra dropped"}, eventSummaries(p.GetSchemaEvents()))
+	event := p.GetSchemaEvents()[0]
+	assert.Equal(t, "INT", event.From)
+	assert.Equal(t, "STRING", event.To)
+	assert.False(t, event.Time.IsZero())
+}
+
+func TestSchemaPolicyWiden(t *testing.T) {
+	output := &evolvingOutput{}
+	p, err := runWithSchemaPolicy(output, SchemaModeWiden,
+		schemaBatch([]types.Column{idColumn, nameColumn}, []interface{}{1, "a"}),
+		schemaBatch([]types.Column{nameColumn, idColumn}, []interface{}{"b", 2}),
+		schemaBatch([]types.Column{{Name: "id", DataType: types.DataTypeDouble}, nameColumn}, []interface{}{2.5, "c"}),
+		schemaBatch([]types.Column{idColumn, nameColumn}, []interface{}{4, "d"}),
+	)
+	require.NoError(t, err)
+
+	widened := idColumn
+	widened.DataType = types.DataTypeDouble
+	assert.Equal(t, []types.Column{widened}, output.altered)
+
+	// Reordered columns are not a schema change
+	require.Len(t, output.batches, 4)
+	assert.Equal(t, []interface{}{2, "b"}, output.batches[1].Records[0].Values)
+	assert.Equal(t, []interface{}{2.5, "c"}, output.batches[2].Records[0].Values)
+	assert.Equal(t, []interface{}{float64(4), "d"}, output.batches[3].Records[0].Values)
+	assert.Equal(t, []types.Column{widened, nameColumn}, output.batches[3].Schema.Columns)
+
+	assert.Equal(t, []string{"type_changed id widened", "type_changed id converted"}, eventSummaries(p.GetSchemaEvents()))
+}
+
+func TestWidenType(t *testing.T) {
+	tests := []struct {
+		a, b, want types.DataType
+	}{
+		{types.DataTypeInt, types.DataTypeInt, types.DataTypeInt},
+		{types.DataTypeInt, types.DataTypeBigInt, types.DataTypeBigInt},
+		{types.DataTypeBigInt, types.DataTypeFloat, types.DataTypeDouble},
+		{types.DataTypeFloat, types.DataTypeDouble, types.DataTypeDouble},
+		{types.DataTypeDate, types.DataTypeTimestamp, types.DataTypeTimestamp},
+		{types.DataTypeBool, types.DataTypeInt, types.DataTypeString},
+		{types.DataTypeJSON, types.DataTypeString, types.DataTypeString},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, widenType(tt.a, tt.b), "%v %v", tt.a, tt.b)
+		assert.Equal(t, tt.want, widenType(tt.b, tt.a), "%v %v", tt.b, tt.a)
+	}
+}
+
+func TestSchemaPolicyValidate(t *testing.T) {
+	assert.NoError(t, DefaultSchemaPolicy().Validate())
+	assert.NoError(t, (&SchemaPolicy{Mode: SchemaModeWiden}).Validate())
+	assert.Error(t, (&SchemaPolicy{Mode: "merge"}).Validate())
+
+	_, err := runWithSchemaPolicy(&mockOutputPlugin{}, "merge", createTestBatch(1))
+	assert.ErrorContains(t, err, "invalid schema policy")
+}
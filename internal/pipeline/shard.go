@@ -0,0 +1,313 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// inputShard is a shard of a split input
+type inputShard struct {
+	id    string
+	input types.InputPlugin
+
+	last  *types.Checkpoint // Last checkpoint of the shard, nil if none yet
+	done  bool              // Read to the end, possibly by an earlier run
+	state string            // JSON-encoded checkpoint of the shard
+}
+
+// update records the latest checkpoint of the shard and whether it has
+// been read to the end
+func (s *inputShard) update(checkpoint *types.Checkpoint, done bool) error {
+	if checkpoint != nil {
+		s.last = checkpoint
+	}
+	s.done = s.done || done
+
+	state := s.last
+	if s.done {
+		state = &types.Checkpoint{Metadata: map[string]string{types.CheckpointCompleted: "true"}}
+		if s.last != nil {
+			state.Position = s.last.Position
+			state.Timestamp = s.last.Timestamp
+			for k, v := range s.last.Metadata {
+				state.Metadata[k] = v
+			}
+			state.Metadata[types.CheckpointCompleted] = "true"
+		}
+	}
+
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint of input shard %s: %w", s.id, err)
+	}
+	s.state = string(encoded)
+	return nil
+}
+
+// shardSet holds the shards of a split input. The batches of all shards
+// are queued through sendShardBatch, which gives each a checkpoint holding
+// the positions of every shard. As the queue keeps the order of sending,
+// the checkpoint of a batch covers all batches queued before it, whichever
+// shard they came from.
+type shardSet struct {
+	mu     sync.Mutex
+	shards []*inputShard
+
+	// queued is closed once the batch given the latest checkpoint is
+	// queued, nil before the first batch
+	queued chan struct{}
+}
+
+// checkpoint returns the checkpoint of all shards. The caller holds mu.
+func (s *shardSet) checkpoint() *types.Checkpoint {
+	checkpoint := &types.Checkpoint{
+		Position: map[string]interface{}{"shards": len(s.shards)},
+		Metadata: make(map[string]string, len(s.shards)),
+	}
+	for _, shard := range s.shards {
+		checkpoint.Metadata[types.CheckpointShardPrefix+shard.id] = shard.state
+	}
+	return checkpoint
+}
+
+// pending returns the shards not yet read to the end
+func (s *shardSet) pending() []*inputShard {
+	var shards []*inputShard
+	for _, shard := range s.shards {
+		if !shard.done {
+			shards = append(shards, shard)
+		}
+	}
+	return shards
+}
+
+// close closes the shard inputs
+func (s *shardSet) close(log *logger.Logger) {
+	for _, shard := range s.shards {
+		if err := shard.input.Close(); err != nil {
+			log.Warn("Failed to close input shard %s: %v", shard.id, err)
+		}
+	}
+}
+
+// progress sums the progress of the shard inputs
+func (s *shardSet) progress() *types.Progress {
+	total := &types.Progress{}
+	for _, shard := range s.shards {
+		progress := shard.input.GetProgress()
+		if progress == nil {
+			continue
+		}
+		total.TotalRecords += progress.TotalRecords
+		total.ProcessedRecords += progress.ProcessedRecords
+		total.FailedRecords += progress.FailedRecords
+		total.BytesTransferred += progress.BytesTransferred
+		if total.StartTime.IsZero() || (!progress.StartTime.IsZero() && progress.StartTime.Before(total.StartTime)) {
+			total.StartTime = progress.StartTime
+		}
+		if progress.LastUpdateTime.After(total.LastUpdateTime) {
+			total.LastUpdateTime = progress.LastUpdateTime
+		}
+	}
+	return total
+}
+
+// shardCheckpoints returns the checkpoints of the shards stored in the
+// checkpoint of an interrupted sharded run, by shard ID. A shard that had
+// not produced a checkpoint yet maps to nil.
+func shardCheckpoints(checkpoint *types.Checkpoint) (map[string]*types.Checkpoint, error) {
+	if checkpoint == nil || checkpoint.IsCompleted() {
+		return nil, nil
+	}
+
+	var shards map[string]*types.Checkpoint
+	for key, encoded := range checkpoint.Metadata {
+		id, ok := strings.CutPrefix(key, types.CheckpointShardPrefix)
+		if !ok {
+			continue
+		}
+		var shardCheckpoint *types.Checkpoint
+		if err := json.Unmarshal([]byte(encoded), &shardCheckpoint); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint of input shard %s: %w", id, err)
+		}
+		if shards == nil {
+			shards = make(map[string]*types.Checkpoint)
+		}
+		shards[id] = shardCheckpoint
+	}
+	return shards, nil
+}
+
+// splitInput splits a splittable input into shards that are read
+// concurrently. An interrupted sharded run is resumed with the same number
+// of shards whatever the configured number, each shard continuing from its
+// own checkpoint and shards read to the end being skipped.
+func (p *ConcurrentPipeline) splitInput(checkpoint *types.Checkpoint) error {
+	previous, err := shardCheckpoints(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	n := p.inputShards
+	if len(previous) > 0 {
+		n = len(previous)
+	}
+	if n <= 1 {
+		return nil
+	}
+	if len(previous) == 0 && checkpoint != nil && !checkpoint.IsCompleted() {
+		p.log.Info("Finishing the interrupted run of input %s with a single reader", p.input.Name())
+		return nil
+	}
+
+	splittable, ok := p.input.(types.SplittableInput)
+	if !ok {
+		if len(previous) > 0 {
+			return fmt.Errorf("checkpoint holds %d input shards but input %s cannot be split", len(previous), p.input.Name())
+		}
+		p.log.Warn("Input %s cannot be split, reading it with a single reader", p.input.Name())
+		return nil
+	}
+
+	shards, err := splittable.Split(n)
+	if err != nil {
+		return fmt.Errorf("failed to split input: %w", err)
+	}
+	if len(previous) > 0 && len(shards) != len(previous) {
+		return fmt.Errorf("input split into %d shards, but the checkpoint holds %d", len(shards), len(previous))
+	}
+
+	set := &shardSet{}
+	for _, shard := range shards {
+		s := &inputShard{id: shard.ID, input: shard.Input}
+		set.shards = append(set.shards, s)
+		if err := p.openShard(s, shard.Checkpoint, previous); err != nil {
+			set.close(p.log)
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	p.shards = set
+	p.stages[0].workers = len(set.shards)
+	p.mu.Unlock()
+
+	p.log.Info("Input %s split into %d shards, %d left to read", p.input.Name(), len(set.shards), len(set.pending()))
+	return nil
+}
+
+// openShard connects a shard input and positions it at its checkpoint in
+// an interrupted run, or else at the start of the shard
+func (p *ConcurrentPipeline) openShard(shard *inputShard, start *types.Checkpoint, previous map[string]*types.Checkpoint) error {
+	if err := shard.input.Connect(); err != nil {
+		return fmt.Errorf("failed to connect input shard %s: %w", shard.id, err)
+	}
+
+	if previous == nil {
+		return shard.update(start, false)
+	}
+
+	checkpoint, found := previous[shard.id]
+	if !found {
+		return fmt.Errorf("checkpoint holds no position for input shard %s", shard.id)
+	}
+	if checkpoint == nil {
+		return shard.update(start, false)
+	}
+	if checkpoint.IsCompleted() {
+		return shard.update(checkpoint, true)
+	}
+
+	resumable, ok := shard.input.(types.ResumableInput)
+	if !ok {
+		return fmt.Errorf("input shard %s cannot resume from its checkpoint", shard.id)
+	}
+	if err := resumable.Seek(checkpoint); err != nil {
+		return fmt.Errorf("failed to resume input shard %s from checkpoint: %w", shard.id, err)
+	}
+	return shard.update(checkpoint, false)
+}
+
+// runShardReaders reads the shards that are left concurrently, one reader
+// per shard, and queues their batches for the next stage
+func (p *ConcurrentPipeline) runShardReaders(ctx context.Context, out *queue) {
+	var wg sync.WaitGroup
+	for _, shard := range p.shards.pending() {
+		wg.Add(1)
+		go func(shard *inputShard) {
+			defer wg.Done()
+
+			name := fmt.Sprintf("Input shard %s reader", shard.id)
+			p.stages[0].log.Info("%s started", name)
+			send := func(batch *types.DataBatch) bool {
+				return p.sendShardBatch(ctx, shard, batch, false, out)
+			}
+
+			done, err := p.readInput(ctx, shard.input, name, send)
+			if err != nil {
+				p.fail(ctx, fmt.Errorf("input shard %s: %w", shard.id, err))
+				return
+			}
+			if done {
+				p.sendShardBatch(ctx, shard, nil, true, out)
+			}
+		}(shard)
+	}
+	wg.Wait()
+}
+
+// sendShardBatch gives a batch of a shard the checkpoint of all shards and
+// queues it. A shard read to the end passes a nil batch, for which an
+// empty batch carries the checkpoint marking the shard as done. It returns
+// false when cancelled.
+//
+// The lock on the shards is released before queueing, which may block. To
+// queue batches in the order of their checkpoints, a batch given a
+// checkpoint waits until the batch given the previous one is queued.
+func (p *ConcurrentPipeline) sendShardBatch(ctx context.Context, shard *inputShard, batch *types.DataBatch, done bool, out *queue) bool {
+	set := p.shards
+	set.mu.Lock()
+
+	var checkpoint *types.Checkpoint
+	if batch != nil {
+		checkpoint = batch.Checkpoint
+	}
+	if err := shard.update(checkpoint, done); err != nil {
+		set.mu.Unlock()
+		p.fail(ctx, err)
+		return false
+	}
+
+	// Only batches that advance a checkpointed shard carry a checkpoint
+	if checkpoint != nil || (done && shard.last != nil) {
+		if batch == nil {
+			batch = &types.DataBatch{}
+		}
+		batch.Checkpoint = set.checkpoint()
+	}
+	if batch == nil || batch.Checkpoint == nil {
+		set.mu.Unlock()
+		return batch == nil || p.sendInputBatch(ctx, batch, out)
+	}
+
+	previous := set.queued
+	queued := make(chan struct{})
+	set.queued = queued
+	set.mu.Unlock()
+	defer close(queued)
+
+	if previous != nil {
+		select {
+		case <-previous:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return p.sendInputBatch(ctx, batch, out)
+}
@@ -0,0 +1,228 @@
+package pipeline
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/checkpoint"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// splittableInput splits into shards of numbered single-record batches
+type splittableInput struct {
+	resumableInput
+	perShard int
+	shards   []*shardInput
+}
+
+func (m *splittableInput) Split(n int) ([]types.InputShard, error) {
+	var shards []types.InputShard
+	for i := 0; i < n; i++ {
+		shard := &shardInput{shard: i, count: m.perShard}
+		m.shards = append(m.shards, shard)
+		shards = append(shards, types.InputShard{ID: strconv.Itoa(i), Input: shard})
+	}
+	return shards, nil
+}
+
+// shardInput produces the values shard*1000+n with n as checkpoint
+type shardInput struct {
+	mockInputPlugin
+	shard, count int
+	connected    bool
+	closed       bool
+	seeked       *types.Checkpoint
+}
+
+func (m *shardInput) Connect() error {
+	m.connected = true
+	return nil
+}
+
+func (m *shardInput) Close() error {
+	m.closed = true
+	return nil
+}
+
+func (m *shardInput) ReadBatch(batchSize int) (*types.DataBatch, error) {
+	if m.index >= m.count {
+		return nil, io.EOF
+	}
+	batch := createTestBatch(1)
+	batch.Records[0].Values[0] = m.shard*1000 + m.index
+	m.index++
+	batch.Checkpoint = &types.Checkpoint{Position: map[string]interface{}{"index": m.index}}
+	time.Sleep(time.Millisecond)
+	return batch, nil
+}
+
+func (m *shardInput) Seek(cp *types.Checkpoint) error {
+	m.seeked = cp
+	m.index = int(cp.Position.(map[string]interface{})["index"].(float64))
+	return nil
+}
+
+func newShardedPipeline(t *testing.T, dir string, shards int, input types.InputPlugin, output types.OutputPlugin) *ConcurrentPipeline {
+	config := DefaultConcurrentConfig()
+	config.JobID = "shard-job"
+	config.InputShards = shards
+	config.CheckpointConfig = &checkpoint.Config{Enabled: true, StorageType: "file", StoragePath: dir}
+	return NewConcurrentPipeline(input, nil, output, config)
+}
+
+func allShardValues(shards, perShard int) []int {
+	var values []int
+	for s := 0; s < shards; s++ {
+		for n := 0; n < perShard; n++ {
+			values = append(values, s*1000+n)
+		}
+	}
+	return values
+}
+
+func recordValues(records []types.Record) []int {
+	values := make([]int, len(records))
+	for i, record := range records {
+		values[i] = record.Values[0].(int)
+	}
+	sort.Ints(values)
+	return values
+}
+
+func TestConcurrentPipelineShardedInput(t *testing.T) {
+	input := &splittableInput{perShard: 5}
+	output := &resumableOutput{}
+
+	p := newShardedPipeline(t, t.TempDir(), 3, input, output)
+	require.NoError(t, p.Execute(context.Background()))
+
+	require.Len(t, input.shards, 3)
+	for _, shard := range input.shards {
+		assert.True(t, shard.connected)
+		assert.True(t, shard.closed)
+		assert.Nil(t, shard.seeked)
+	}
+	assert.Equal(t, allShardValues(3, 5), recordValues(output.flushed))
+	assert.Equal(t, int64(15), p.GetSummary().RecordsRead)
+	assert.Equal(t, 3, p.GetStageMetrics()[0].Workers)
+
+	// The last input checkpoint holds every shard, read to the end
+	cp, err := p.LoadCheckpoint("input")
+	require.NoError(t, err)
+	shards, err := shardCheckpoints(cp)
+	require.NoError(t, err)
+	require.Len(t, shards, 3)
+	for id, shard := range shards {
+		assert.True(t, shard.IsCompleted(), "shard %s", id)
+	}
+}
+
+func TestConcurrentPipelineShardedResume(t *testing.T) {
+	dir := t.TempDir()
+	output := &resumableOutput{failOn: 7}
+
+	// The first run crashes while writing the seventh batch
+	first := newShardedPipeline(t, dir, 3, &splittableInput{perShard: 5}, output)
+	require.Error(t, first.Execute(context.Background()))
+	assert.Len(t, output.flushed, 6)
+
+	// The restarted run keeps the three shards although configured for
+	// one, and each continues from its own checkpoint
+	input := &splittableInput{perShard: 5}
+	output.failOn = 0
+	second := newShardedPipeline(t, dir, 1, input, output)
+	require.NoError(t, second.Execute(context.Background()))
+
+	require.Len(t, input.shards, 3)
+	assert.Equal(t, 6, output.resumeAt)
+	assert.Equal(t, allShardValues(3, 5), recordValues(output.flushed), "no record lost or duplicated")
+	assert.Equal(t, int64(9), second.GetSummary().RecordsRead)
+
+	// After a completed run, the next one starts over with fresh shards
+	input = &splittableInput{perShard: 5}
+	fresh := &resumableOutput{}
+	third := newShardedPipeline(t, dir, 2, input, fresh)
+	require.NoError(t, third.Execute(context.Background()))
+
+	require.Len(t, input.shards, 2)
+	assert.Equal(t, allShardValues(2, 5), recordValues(fresh.flushed))
+}
+
+func TestConcurrentPipelineUnsplittableInput(t *testing.T) {
+	input := &mockInputPlugin{batches: testBatches()}
+	output := &mockOutputPlugin{}
+
+	p := newShardedPipeline(t, t.TempDir(), 4, input, output)
+	require.NoError(t, p.Execute(context.Background()))
+
+	assert.Len(t, output.batches, 4)
+	assert.Equal(t, 1, p.GetStageMetrics()[0].Workers)
+}
+
+func TestSendShardBatchBlocked(t *testing.T) {
+	p := NewConcurrentPipeline(&mockInputPlugin{}, nil, &mockOutputPlugin{}, DefaultConcurrentConfig())
+	a, b := &inputShard{id: "a"}, &inputShard{id: "b"}
+	require.NoError(t, a.update(nil, false))
+	require.NoError(t, b.update(nil, false))
+	p.shards = &shardSet{shards: []*inputShard{a, b}}
+	out := newQueue(1, 0)
+	ctx := context.Background()
+
+	batch := func(index int) *types.DataBatch {
+		batch := createTestBatch(1)
+		batch.Checkpoint = &types.Checkpoint{Position: map[string]interface{}{"index": index}}
+		return batch
+	}
+	send := func(shard *inputShard, index int) chan bool {
+		sent := make(chan bool, 1)
+		go func() { sent <- p.sendShardBatch(ctx, shard, batch(index), false, out) }()
+		return sent
+	}
+
+	// The first batch fills the queue, so the second blocks, without
+	// holding the lock on the shards
+	require.True(t, <-send(a, 1))
+	second := send(a, 2)
+	require.Eventually(t, func() bool {
+		p.shards.mu.Lock()
+		defer p.shards.mu.Unlock()
+		return a.last.Position.(map[string]interface{})["index"] == 2
+	}, time.Second, time.Millisecond)
+
+	// A batch of another shard takes its checkpoint and waits for its turn
+	third := send(b, 1)
+	require.Eventually(t, func() bool {
+		p.shards.mu.Lock()
+		defer p.shards.mu.Unlock()
+		return b.last != nil
+	}, time.Second, time.Millisecond)
+
+	// The batches are queued in the order of their checkpoints
+	var states []string
+	for i := 0; i < 3; i++ {
+		received, ok := out.receive(ctx, p.stages[1])
+		require.True(t, ok)
+		shards, err := shardCheckpoints(received.Checkpoint)
+		require.NoError(t, err)
+		var state []string
+		for _, id := range []string{"a", "b"} {
+			if shards[id] == nil {
+				state = append(state, id+"=-")
+				continue
+			}
+			state = append(state, id+"="+strconv.Itoa(int(shards[id].Position.(map[string]interface{})["index"].(float64))))
+		}
+		states = append(states, strings.Join(state, ","))
+	}
+	assert.Equal(t, []string{"a=1,b=-", "a=2,b=-", "a=2,b=1"}, states)
+	assert.True(t, <-second)
+	assert.True(t, <-third)
+}
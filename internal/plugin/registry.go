@@ -1,108 +1,137 @@
 package plugin
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/atlanssia/fustgo/pkg/types"
 )
 
-// Registry manages all registered plugins
+// InputFactory constructs a new input plugin instance
+type InputFactory func() types.InputPlugin
+
+// ProcessorFactory constructs a new processor plugin instance
+type ProcessorFactory func() types.ProcessorPlugin
+
+// OutputFactory constructs a new output plugin instance
+type OutputFactory func() types.OutputPlugin
+
+// Registry manages all registered plugin factories
 type Registry struct {
 	mu         sync.RWMutex
-	inputs     map[string]types.InputPlugin
-	processors map[string]types.ProcessorPlugin
-	outputs    map[string]types.OutputPlugin
+	inputs     map[string]InputFactory
+	processors map[string]ProcessorFactory
+	outputs    map[string]OutputFactory
+	sessions   map[string]*Session // session ID -> active session
 }
 
-// Global registry instance
-var globalRegistry = &Registry{
-	inputs:     make(map[string]types.InputPlugin),
-	processors: make(map[string]types.ProcessorPlugin),
-	outputs:    make(map[string]types.OutputPlugin),
+// NewRegistry creates an empty plugin registry
+func NewRegistry() *Registry {
+	return &Registry{
+		inputs:     make(map[string]InputFactory),
+		processors: make(map[string]ProcessorFactory),
+		outputs:    make(map[string]OutputFactory),
+		sessions:   make(map[string]*Session),
+	}
 }
 
+// Global registry instance
+var globalRegistry = NewRegistry()
+
 // GetRegistry returns the global plugin registry
 func GetRegistry() *Registry {
 	return globalRegistry
 }
 
-// RegisterInput registers an input plugin
-func (r *Registry) RegisterInput(name string, plugin types.InputPlugin) error {
+// RegisterInput registers an input plugin factory
+func (r *Registry) RegisterInput(name string, factory InputFactory) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if factory == nil {
+		return fmt.Errorf("input plugin factory is nil: %s", name)
+	}
 	if _, exists := r.inputs[name]; exists {
 		return fmt.Errorf("input plugin already registered: %s", name)
 	}
 
-	r.inputs[name] = plugin
+	r.inputs[name] = factory
 	return nil
 }
 
-// RegisterProcessor registers a processor plugin
-func (r *Registry) RegisterProcessor(name string, plugin types.ProcessorPlugin) error {
+// RegisterProcessor registers a processor plugin factory
+func (r *Registry) RegisterProcessor(name string, factory ProcessorFactory) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if factory == nil {
+		return fmt.Errorf("processor plugin factory is nil: %s", name)
+	}
 	if _, exists := r.processors[name]; exists {
 		return fmt.Errorf("processor plugin already registered: %s", name)
 	}
 
-	r.processors[name] = plugin
+	r.processors[name] = factory
 	return nil
 }
 
-// RegisterOutput registers an output plugin
-func (r *Registry) RegisterOutput(name string, plugin types.OutputPlugin) error {
+// RegisterOutput registers an output plugin factory
+func (r *Registry) RegisterOutput(name string, factory OutputFactory) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if factory == nil {
+		return fmt.Errorf("output plugin factory is nil: %s", name)
+	}
 	if _, exists := r.outputs[name]; exists {
 		return fmt.Errorf("output plugin already registered: %s", name)
 	}
 
-	r.outputs[name] = plugin
+	r.outputs[name] = factory
 	return nil
 }
 
-// GetInput retrieves an input plugin by name
+// GetInput creates a new input plugin instance by name.
+// Every call returns a fresh instance that is owned by the caller.
 func (r *Registry) GetInput(name string) (types.InputPlugin, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	plugin, exists := r.inputs[name]
+	factory, exists := r.inputs[name]
 	if !exists {
 		return nil, fmt.Errorf("input plugin not found: %s", name)
 	}
 
-	return plugin, nil
+	return factory(), nil
 }
 
-// GetProcessor retrieves a processor plugin by name
+// GetProcessor creates a new processor plugin instance by name.
+// Every call returns a fresh instance that is owned by the caller.
 func (r *Registry) GetProcessor(name string) (types.ProcessorPlugin, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	plugin, exists := r.processors[name]
+	factory, exists := r.processors[name]
 	if !exists {
 		return nil, fmt.Errorf("processor plugin not found: %s", name)
 	}
 
-	return plugin, nil
+	return factory(), nil
 }
 
-// GetOutput retrieves an output plugin by name
+// GetOutput creates a new output plugin instance by name.
+// Every call returns a fresh instance that is owned by the caller.
 func (r *Registry) GetOutput(name string) (types.OutputPlugin, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	plugin, exists := r.outputs[name]
+	factory, exists := r.outputs[name]
 	if !exists {
 		return nil, fmt.Errorf("output plugin not found: %s", name)
 	}
 
-	return plugin, nil
+	return factory(), nil
 }
 
 // ListInputs returns all registered input plugins
@@ -148,32 +177,168 @@ func (r *Registry) GetPluginMetadata() []types.PluginMetadata {
 
 	var metadata []types.PluginMetadata
 
-	for _, plugin := range r.inputs {
-		metadata = append(metadata, plugin.GetMetadata())
+	for _, factory := range r.inputs {
+		metadata = append(metadata, factory().GetMetadata())
 	}
 
-	for _, plugin := range r.processors {
-		metadata = append(metadata, plugin.GetMetadata())
+	for _, factory := range r.processors {
+		metadata = append(metadata, factory().GetMetadata())
 	}
 
-	for _, plugin := range r.outputs {
-		metadata = append(metadata, plugin.GetMetadata())
+	for _, factory := range r.outputs {
+		metadata = append(metadata, factory().GetMetadata())
 	}
 
 	return metadata
 }
 
+// NewSession opens a session that owns the plugin instances of one execution.
+// The session must be closed when the execution ends. Session IDs are unique
+// among open sessions.
+func (r *Registry) NewSession(id string) (*Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.sessions[id]; exists {
+		return nil, fmt.Errorf("plugin session already open: %s", id)
+	}
+
+	session := &Session{
+		id:       id,
+		registry: r,
+	}
+	r.sessions[id] = session
+	return session, nil
+}
+
+// ActiveSessions returns the IDs of all sessions that have not been closed
+func (r *Registry) ActiveSessions() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.sessions))
+	for id := range r.sessions {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// removeSession forgets a closed session
+func (r *Registry) removeSession(session *Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, exists := r.sessions[session.id]; exists && current == session {
+		delete(r.sessions, session.id)
+	}
+}
+
+// Session tracks the plugin instances created for a single execution so
+// they can be closed together when the execution ends.
+type Session struct {
+	mu        sync.Mutex
+	id        string
+	registry  *Registry
+	instances []types.Plugin
+	closed    bool
+}
+
+// ID returns the session identifier
+func (s *Session) ID() string {
+	return s.id
+}
+
+// NewInput creates an input plugin instance owned by the session
+func (s *Session) NewInput(name string) (types.InputPlugin, error) {
+	input, err := s.registry.GetInput(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.track(input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// NewProcessor creates a processor plugin instance owned by the session
+func (s *Session) NewProcessor(name string) (types.ProcessorPlugin, error) {
+	processor, err := s.registry.GetProcessor(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.track(processor); err != nil {
+		return nil, err
+	}
+	return processor, nil
+}
+
+// NewOutput creates an output plugin instance owned by the session
+func (s *Session) NewOutput(name string) (types.OutputPlugin, error) {
+	output, err := s.registry.GetOutput(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.track(output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// Instances returns the number of plugin instances owned by the session
+func (s *Session) Instances() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.instances)
+}
+
+// track registers a plugin instance with the session
+func (s *Session) track(p types.Plugin) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("plugin session %s is closed", s.id)
+	}
+
+	s.instances = append(s.instances, p)
+	return nil
+}
+
+// Close closes every plugin instance owned by the session in reverse
+// creation order. It is safe to call Close more than once.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	instances := s.instances
+	s.instances = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for i := len(instances) - 1; i >= 0; i-- {
+		if err := instances[i].Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close plugin %s: %w", instances[i].Name(), err))
+		}
+	}
+
+	s.registry.removeSession(s)
+	return errors.Join(errs...)
+}
+
 // Convenience functions for global registry
-func RegisterInput(name string, plugin types.InputPlugin) error {
-	return globalRegistry.RegisterInput(name, plugin)
+func RegisterInput(name string, factory InputFactory) error {
+	return globalRegistry.RegisterInput(name, factory)
 }
 
-func RegisterProcessor(name string, plugin types.ProcessorPlugin) error {
-	return globalRegistry.RegisterProcessor(name, plugin)
+func RegisterProcessor(name string, factory ProcessorFactory) error {
+	return globalRegistry.RegisterProcessor(name, factory)
 }
 
-func RegisterOutput(name string, plugin types.OutputPlugin) error {
-	return globalRegistry.RegisterOutput(name, plugin)
+func RegisterOutput(name string, factory OutputFactory) error {
+	return globalRegistry.RegisterOutput(name, factory)
 }
 
 func GetInput(name string) (types.InputPlugin, error) {
@@ -1,10 +1,13 @@
 package plugin
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/atlanssia/fustgo/pkg/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock plugins for testing
@@ -36,13 +39,22 @@ func (m *mockProcessorPlugin) GetMetadata() types.PluginMetadata {
 func (m *mockProcessorPlugin) Process(input *types.DataBatch) (*types.DataBatch, error) { return input, nil }
 func (m *mockProcessorPlugin) GetStatistics() *types.ProcessStatistics                   { return nil }
 
-type mockOutputPlugin struct{}
+type mockOutputPlugin struct {
+	path   string
+	closed int
+}
 
-func (m *mockOutputPlugin) Name() string                                  { return "mock-output" }
-func (m *mockOutputPlugin) Type() types.PluginType                        { return types.PluginTypeOutput }
-func (m *mockOutputPlugin) Initialize(config map[string]interface{}) error { return nil }
-func (m *mockOutputPlugin) Validate() error                               { return nil }
-func (m *mockOutputPlugin) Close() error                                  { return nil }
+func (m *mockOutputPlugin) Name() string           { return "mock-output" }
+func (m *mockOutputPlugin) Type() types.PluginType { return types.PluginTypeOutput }
+func (m *mockOutputPlugin) Initialize(config map[string]interface{}) error {
+	m.path, _ = config["path"].(string)
+	return nil
+}
+func (m *mockOutputPlugin) Validate() error { return nil }
+func (m *mockOutputPlugin) Close() error {
+	m.closed++
+	return nil
+}
 func (m *mockOutputPlugin) GetMetadata() types.PluginMetadata {
 	return types.PluginMetadata{Name: "mock-output", Type: types.PluginTypeOutput}
 }
@@ -52,62 +64,45 @@ func (m *mockOutputPlugin) Flush() error                                   { ret
 func (m *mockOutputPlugin) GetWriteStatistics() *types.WriteStatistics     { return nil }
 
 func TestRegistry_RegisterInput(t *testing.T) {
-	registry := &Registry{
-		inputs:     make(map[string]types.InputPlugin),
-		processors: make(map[string]types.ProcessorPlugin),
-		outputs:    make(map[string]types.OutputPlugin),
-	}
+	registry := NewRegistry()
 
-	plugin := &mockInputPlugin{}
-	err := registry.RegisterInput("test-input", plugin)
+	factory := func() types.InputPlugin { return &mockInputPlugin{} }
+	err := registry.RegisterInput("test-input", factory)
 	assert.NoError(t, err)
 
 	// Try to register again - should error
-	err = registry.RegisterInput("test-input", plugin)
+	err = registry.RegisterInput("test-input", factory)
 	assert.Error(t, err)
 }
 
 func TestRegistry_RegisterProcessor(t *testing.T) {
-	registry := &Registry{
-		inputs:     make(map[string]types.InputPlugin),
-		processors: make(map[string]types.ProcessorPlugin),
-		outputs:    make(map[string]types.OutputPlugin),
-	}
+	registry := NewRegistry()
 
-	plugin := &mockProcessorPlugin{}
-	err := registry.RegisterProcessor("test-processor", plugin)
+	factory := func() types.ProcessorPlugin { return &mockProcessorPlugin{} }
+	err := registry.RegisterProcessor("test-processor", factory)
 	assert.NoError(t, err)
 
 	// Try to register again - should error
-	err = registry.RegisterProcessor("test-processor", plugin)
+	err = registry.RegisterProcessor("test-processor", factory)
 	assert.Error(t, err)
 }
 
 func TestRegistry_RegisterOutput(t *testing.T) {
-	registry := &Registry{
-		inputs:     make(map[string]types.InputPlugin),
-		processors: make(map[string]types.ProcessorPlugin),
-		outputs:    make(map[string]types.OutputPlugin),
-	}
+	registry := NewRegistry()
 
-	plugin := &mockOutputPlugin{}
-	err := registry.RegisterOutput("test-output", plugin)
+	factory := func() types.OutputPlugin { return &mockOutputPlugin{} }
+	err := registry.RegisterOutput("test-output", factory)
 	assert.NoError(t, err)
 
 	// Try to register again - should error
-	err = registry.RegisterOutput("test-output", plugin)
+	err = registry.RegisterOutput("test-output", factory)
 	assert.Error(t, err)
 }
 
 func TestRegistry_GetInput(t *testing.T) {
-	registry := &Registry{
-		inputs:     make(map[string]types.InputPlugin),
-		processors: make(map[string]types.ProcessorPlugin),
-		outputs:    make(map[string]types.OutputPlugin),
-	}
+	registry := NewRegistry()
 
-	plugin := &mockInputPlugin{}
-	registry.RegisterInput("test-input", plugin)
+	registry.RegisterInput("test-input", func() types.InputPlugin { return &mockInputPlugin{} })
 
 	// Test successful retrieval
 	retrieved, err := registry.GetInput("test-input")
@@ -120,14 +115,10 @@ func TestRegistry_GetInput(t *testing.T) {
 }
 
 func TestRegistry_ListInputs(t *testing.T) {
-	registry := &Registry{
-		inputs:     make(map[string]types.InputPlugin),
-		processors: make(map[string]types.ProcessorPlugin),
-		outputs:    make(map[string]types.OutputPlugin),
-	}
+	registry := NewRegistry()
 
-	registry.RegisterInput("input1", &mockInputPlugin{})
-	registry.RegisterInput("input2", &mockInputPlugin{})
+	registry.RegisterInput("input1", func() types.InputPlugin { return &mockInputPlugin{} })
+	registry.RegisterInput("input2", func() types.InputPlugin { return &mockInputPlugin{} })
 
 	names := registry.ListInputs()
 	assert.Len(t, names, 2)
@@ -136,14 +127,10 @@ func TestRegistry_ListInputs(t *testing.T) {
 }
 
 func TestRegistry_ListProcessors(t *testing.T) {
-	registry := &Registry{
-		inputs:     make(map[string]types.InputPlugin),
-		processors: make(map[string]types.ProcessorPlugin),
-		outputs:    make(map[string]types.OutputPlugin),
-	}
+	registry := NewRegistry()
 
-	registry.RegisterProcessor("proc1", &mockProcessorPlugin{})
-	registry.RegisterProcessor("proc2", &mockProcessorPlugin{})
+	registry.RegisterProcessor("proc1", func() types.ProcessorPlugin { return &mockProcessorPlugin{} })
+	registry.RegisterProcessor("proc2", func() types.ProcessorPlugin { return &mockProcessorPlugin{} })
 
 	names := registry.ListProcessors()
 	assert.Len(t, names, 2)
@@ -152,17 +139,97 @@ func TestRegistry_ListProcessors(t *testing.T) {
 }
 
 func TestRegistry_ListOutputs(t *testing.T) {
-	registry := &Registry{
-		inputs:     make(map[string]types.InputPlugin),
-		processors: make(map[string]types.ProcessorPlugin),
-		outputs:    make(map[string]types.OutputPlugin),
-	}
+	registry := NewRegistry()
 
-	registry.RegisterOutput("output1", &mockOutputPlugin{})
-	registry.RegisterOutput("output2", &mockOutputPlugin{})
+	registry.RegisterOutput("output1", func() types.OutputPlugin { return &mockOutputPlugin{} })
+	registry.RegisterOutput("output2", func() types.OutputPlugin { return &mockOutputPlugin{} })
 
 	names := registry.ListOutputs()
 	assert.Len(t, names, 2)
 	assert.Contains(t, names, "output1")
 	assert.Contains(t, names, "output2")
 }
+
+func TestRegistry_GetOutputReturnsFreshInstances(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterOutput("output", func() types.OutputPlugin { return &mockOutputPlugin{} })
+
+	first, err := registry.GetOutput("output")
+	assert.NoError(t, err)
+	second, err := registry.GetOutput("output")
+	assert.NoError(t, err)
+
+	assert.NoError(t, first.Initialize(map[string]interface{}{"path": "/tmp/a.csv"}))
+	assert.NoError(t, second.Initialize(map[string]interface{}{"path": "/tmp/b.csv"}))
+
+	assert.NotSame(t, first, second)
+	assert.Equal(t, "/tmp/a.csv", first.(*mockOutputPlugin).path)
+	assert.Equal(t, "/tmp/b.csv", second.(*mockOutputPlugin).path)
+}
+
+func TestSession_CloseReleasesInstances(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterInput("input", func() types.InputPlugin { return &mockInputPlugin{} })
+	registry.RegisterOutput("output", func() types.OutputPlugin { return &mockOutputPlugin{} })
+
+	session, err := registry.NewSession("exec-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec-1"}, registry.ActiveSessions())
+
+	// An open session cannot be replaced, which would leak its instances
+	_, err = registry.NewSession("exec-1")
+	assert.Error(t, err)
+
+	_, err = session.NewInput("input")
+	assert.NoError(t, err)
+	output, err := session.NewOutput("output")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, session.Instances())
+
+	_, err = session.NewProcessor("missing")
+	assert.Error(t, err)
+
+	assert.NoError(t, session.Close())
+	assert.NoError(t, session.Close()) // idempotent
+	assert.Equal(t, 1, output.(*mockOutputPlugin).closed)
+	assert.Empty(t, registry.ActiveSessions())
+
+	_, err = session.NewInput("input")
+	assert.Error(t, err)
+
+	// The ID can be used again once the session is closed
+	session, err = registry.NewSession("exec-1")
+	require.NoError(t, err)
+	assert.NoError(t, session.Close())
+}
+
+func TestSession_ConcurrentSessionsAreIsolated(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterOutput("output", func() types.OutputPlugin { return &mockOutputPlugin{} })
+
+	var wg sync.WaitGroup
+	outputs := make([]types.OutputPlugin, 10)
+	for i := 0; i < len(outputs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			session, err := registry.NewSession(fmt.Sprintf("exec-%d", i))
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer session.Close()
+
+			output, err := session.NewOutput("output")
+			assert.NoError(t, err)
+			output.Initialize(map[string]interface{}{"path": fmt.Sprintf("/tmp/%d.csv", i)})
+			outputs[i] = output
+		}(i)
+	}
+	wg.Wait()
+
+	for i, output := range outputs {
+		assert.Equal(t, fmt.Sprintf("/tmp/%d.csv", i), output.(*mockOutputPlugin).path)
+		assert.Equal(t, 1, output.(*mockOutputPlugin).closed)
+	}
+	assert.Empty(t, registry.ActiveSessions())
+}
@@ -0,0 +1,163 @@
+// Package plugintest provides the helpers and checks shared by the tests of
+// input and output plugins
+package plugintest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// StartInput initializes, validates and connects an input, which is closed
+// when the test ends
+func StartInput(t *testing.T, p types.InputPlugin, config map[string]interface{}) {
+	t.Helper()
+	require.NoError(t, p.Initialize(config))
+	require.NoError(t, p.Validate())
+	require.NoError(t, p.Connect())
+	t.Cleanup(func() { p.Close() })
+}
+
+// ReadAll reads all batches of an input and returns the records
+func ReadAll(t *testing.T, p types.InputPlugin, batchSize int) []types.Record {
+	t.Helper()
+	var records []types.Record
+	for p.HasNext() {
+		batch, err := p.ReadBatch(batchSize)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		records = append(records, batch.Records...)
+	}
+	return records
+}
+
+// RoundTrip encodes and decodes a checkpoint the way checkpoint storage does
+func RoundTrip(t *testing.T, checkpoint *types.Checkpoint) *types.Checkpoint {
+	t.Helper()
+	data, err := json.Marshal(checkpoint)
+	require.NoError(t, err)
+	var decoded types.Checkpoint
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return &decoded
+}
+
+// WriteFiles creates files with the given contents below dir
+func WriteFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+// StartOutput initializes an output, resumes it at a position unless the
+// position is nil, and connects it. The output is closed when the test ends.
+func StartOutput(t *testing.T, p types.ResumableOutput, config map[string]interface{}, position interface{}) {
+	t.Helper()
+	require.NoError(t, p.Initialize(config))
+	if position != nil {
+		require.NoError(t, p.Resume(position))
+	}
+	require.NoError(t, p.Connect())
+	t.Cleanup(func() { p.Close() })
+}
+
+// Position flushes an output and returns its position the way checkpoint
+// storage returns it
+func Position(t *testing.T, p types.ResumableOutput) interface{} {
+	t.Helper()
+	require.NoError(t, p.Flush())
+	pos, err := p.Position()
+	require.NoError(t, err)
+	data, err := json.Marshal(pos)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return decoded
+}
+
+// Batch returns a batch with a BIGINT id column and a nullable STRING name
+// column, holding a record with the name "n<id>" for each id
+func Batch(ids ...int64) *types.DataBatch {
+	batch := &types.DataBatch{
+		Schema: types.Schema{Columns: []types.Column{
+			{Name: "id", DataType: types.DataTypeBigInt},
+			{Name: "name", DataType: types.DataTypeString, Nullable: true},
+		}},
+	}
+	for _, id := range ids {
+		batch.Records = append(batch.Records, types.Record{Values: []interface{}{id, fmt.Sprintf("n%d", id)}})
+	}
+	return batch
+}
+
+// ReadIDs returns the ids of the records of a file written by an output
+type ReadIDs func(t *testing.T, path string) []int64
+
+// CheckRolling checks that an output writing to a path with a verb starts a
+// new file every two records. The config gets the path and roll_records.
+func CheckRolling(t *testing.T, newOutput func() types.ResumableOutput, config map[string]interface{}, name string, read ReadIDs) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	config["path"] = path
+	config["roll_records"] = 2
+
+	p := newOutput()
+	StartOutput(t, p, config, nil)
+	require.NoError(t, p.WriteBatch(Batch(1, 2, 3)))
+	require.NoError(t, p.Close())
+
+	assert.Equal(t, []int64{1, 2}, read(t, fmt.Sprintf(path, 0)))
+	assert.Equal(t, []int64{3}, read(t, fmt.Sprintf(path, 1)))
+	assert.NoFileExists(t, fmt.Sprintf(path, 2))
+	assert.Equal(t, int64(3), p.GetWriteStatistics().RecordsWritten)
+	assert.Positive(t, p.GetWriteStatistics().BytesWritten)
+}
+
+// CheckResume checks that an output resumed at a position drops the records
+// written after it and continues there. A name with a verb rolls the output
+// every two records. The config gets the path.
+func CheckResume(t *testing.T, newOutput func() types.ResumableOutput, config map[string]interface{}, name string, read ReadIDs) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	config["path"] = path
+	rolling := strings.Contains(name, "%")
+	if rolling {
+		config["roll_records"] = 2
+	}
+
+	// The first run writes 1 to 3, checkpoints, then writes 4 and 5 before
+	// failing
+	first := newOutput()
+	StartOutput(t, first, config, nil)
+	require.NoError(t, first.WriteBatch(Batch(1, 2, 3)))
+	pos := Position(t, first)
+	require.NoError(t, first.WriteBatch(Batch(4, 5)))
+	require.NoError(t, first.Flush())
+	require.NoError(t, first.Close())
+
+	second := newOutput()
+	StartOutput(t, second, config, pos)
+	require.NoError(t, second.WriteBatch(Batch(4)))
+	require.NoError(t, second.Close())
+
+	if !rolling {
+		assert.Equal(t, []int64{1, 2, 3, 4}, read(t, path))
+		return
+	}
+	assert.Equal(t, []int64{1, 2}, read(t, fmt.Sprintf(path, 0)))
+	assert.Equal(t, []int64{3, 4}, read(t, fmt.Sprintf(path, 1)))
+	assert.NoFileExists(t, fmt.Sprintf(path, 2))
+}
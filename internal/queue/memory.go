@@ -7,7 +7,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/metrics"
 )
 
 // Task represents a task in the queue
@@ -42,17 +45,23 @@ type MemoryQueue struct {
 	enqueued   int64
 	dequeued   int64
 	failed     int64
+	
+	// Depth and utilization gauges of the queue
+	depth       prometheus.Gauge
+	utilization prometheus.Gauge
 }
 
 // Config holds memory queue configuration
 type Config struct {
 	MaxSize int
+	Name    string // Labels the metrics of the queue
 }
 
 // DefaultConfig returns default queue configuration
 func DefaultConfig() *Config {
 	return &Config{
 		MaxSize: 10000,
+		Name:    "default",
 	}
 }
 
@@ -62,11 +71,19 @@ func NewMemoryQueue(config *Config) *MemoryQueue {
 		config = DefaultConfig()
 	}
 
+	name := config.Name
+	if name == "" {
+		name = "default"
+	}
+	
 	q := &MemoryQueue{
-		tasks:   list.New(),
-		maxSize: config.MaxSize,
+		tasks:       list.New(),
+		maxSize:     config.MaxSize,
+		depth:       metrics.QueueDepth.WithLabelValues(name),
+		utilization: metrics.QueueUtilization.WithLabelValues(name),
 	}
 	q.notEmpty = sync.NewCond(&q.mu)
+	q.updateMetrics()
 
 	logger.Info("Created memory queue with max size: %d", config.MaxSize)
 	return q
@@ -101,6 +118,7 @@ func (q *MemoryQueue) Enqueue(task *Task) error {
 	}
 
 	q.enqueued++
+	q.updateMetrics()
 	q.notEmpty.Signal()
 
 	logger.Debug("Enqueued task %s (job: %s, priority: %d)", task.ID, task.JobID, task.Priority)
@@ -131,6 +149,7 @@ func (q *MemoryQueue) Dequeue(ctx context.Context) (*Task, error) {
 			task := element.Value.(*Task)
 			q.tasks.Remove(element)
 			q.dequeued++
+			q.updateMetrics()
 			q.mu.Unlock()
 
 			logger.Debug("Dequeued task %s (job: %s)", task.ID, task.JobID)
@@ -161,6 +180,7 @@ func (q *MemoryQueue) Clear() error {
 
 	count := q.tasks.Len()
 	q.tasks.Init()
+	q.updateMetrics()
 	
 	logger.Info("Cleared %d tasks from queue", count)
 	return nil
@@ -204,6 +224,16 @@ func (q *MemoryQueue) GetStatistics() map[string]interface{} {
 	}
 }
 
+// updateMetrics sets the depth and utilization gauges of the queue. The
+// caller holds the lock.
+func (q *MemoryQueue) updateMetrics() {
+	size := q.tasks.Len()
+	q.depth.Set(float64(size))
+	if q.maxSize > 0 {
+		q.utilization.Set(float64(size) / float64(q.maxSize))
+	}
+}
+
 // Peek returns the next task without removing it
 func (q *MemoryQueue) Peek() (*Task, error) {
 	q.mu.RLock()
@@ -246,6 +276,7 @@ func (q *MemoryQueue) RemoveTask(taskID string) error {
 		task := e.Value.(*Task)
 		if task.ID == taskID {
 			q.tasks.Remove(e)
+			q.updateMetrics()
 			logger.Debug("Removed task %s from queue", taskID)
 			return nil
 		}
@@ -260,14 +291,24 @@ type PriorityQueue struct {
 	mu     sync.RWMutex
 }
 
-// NewPriorityQueue creates a new priority-based queue system
+// NewPriorityQueue creates a new priority-based queue system. The queue of
+// each priority is named after the configured name and the priority.
 func NewPriorityQueue(priorities []int, config *Config) *PriorityQueue {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
 	pq := &PriorityQueue{
 		queues: make(map[int]*MemoryQueue),
 	}
 
 	for _, priority := range priorities {
-		pq.queues[priority] = NewMemoryQueue(config)
+		queueConfig := *config
+		if queueConfig.Name == "" {
+			queueConfig.Name = "default"
+		}
+		queueConfig.Name = fmt.Sprintf("%s-%d", queueConfig.Name, priority)
+		pq.queues[priority] = NewMemoryQueue(&queueConfig)
 	}
 
 	logger.Info("Created priority queue with %d priority levels", len(priorities))
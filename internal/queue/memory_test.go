@@ -5,8 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/metrics"
 )
 
 func createTestTask(id string, jobID string, priority int) *Task {
@@ -256,6 +259,25 @@ func TestGetStatistics(t *testing.T) {
 	assert.Equal(t, int64(1), stats["dequeued"])
 }
 
+func TestQueueMetrics(t *testing.T) {
+	queue := NewMemoryQueue(&Config{MaxSize: 4, Name: "metrics-test"})
+	depth := metrics.QueueDepth.WithLabelValues("metrics-test")
+	utilization := metrics.QueueUtilization.WithLabelValues("metrics-test")
+
+	require.NoError(t, queue.Enqueue(createTestTask("task-1", "job-1", 1)))
+	require.NoError(t, queue.Enqueue(createTestTask("task-2", "job-1", 1)))
+	assert.Equal(t, float64(2), testutil.ToFloat64(depth))
+	assert.Equal(t, 0.5, testutil.ToFloat64(utilization))
+
+	_, err := queue.Dequeue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(depth))
+
+	require.NoError(t, queue.Clear())
+	assert.Equal(t, float64(0), testutil.ToFloat64(depth))
+	assert.Equal(t, float64(0), testutil.ToFloat64(utilization))
+}
+
 func TestConcurrentEnqueueDequeue(t *testing.T) {
 	queue := NewMemoryQueue(nil)
 	done := make(chan bool)
@@ -22,6 +22,7 @@ type Scheduler struct {
 	mu         sync.RWMutex
 	cron       *cron.Cron
 	jobs       map[string]cron.EntryID // jobID -> cron entry ID
+	exprs      map[string]string       // jobID -> cron expression
 	executor   JobExecutor
 	running    bool
 	ctx        context.Context
@@ -59,6 +60,7 @@ func NewScheduler(executor JobExecutor, config *Config) *Scheduler {
 	return &Scheduler{
 		cron:     cron.New(opts...),
 		jobs:     make(map[string]cron.EntryID),
+		exprs:    make(map[string]string),
 		executor: executor,
 	}
 }
@@ -83,20 +85,22 @@ func (s *Scheduler) Start() error {
 // Stop stops the scheduler
 func (s *Scheduler) Stop() error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if !s.running {
+		s.mu.Unlock()
 		return fmt.Errorf("scheduler is not running")
 	}
-
+	s.running = false
 	ctx := s.cron.Stop()
-	<-ctx.Done() // Wait for all running jobs to complete
+	s.mu.Unlock()
+
+	// Wait for all running jobs to complete. The lock is released, as
+	// they may change the schedules of jobs.
+	<-ctx.Done()
 
 	if s.cancel != nil {
 		s.cancel()
 	}
 
-	s.running = false
 	logger.Info("Scheduler stopped")
 	return nil
 }
@@ -105,7 +109,11 @@ func (s *Scheduler) Stop() error {
 func (s *Scheduler) AddJob(jobID string, cronExpr string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.addJob(jobID, cronExpr)
+}
 
+// addJob adds a job to the scheduler. The caller holds the lock.
+func (s *Scheduler) addJob(jobID string, cronExpr string) error {
 	// Check if job is already scheduled
 	if _, exists := s.jobs[jobID]; exists {
 		return fmt.Errorf("job %s is already scheduled", jobID)
@@ -125,6 +133,7 @@ func (s *Scheduler) AddJob(jobID string, cronExpr string) error {
 	}
 
 	s.jobs[jobID] = entryID
+	s.exprs[jobID] = cronExpr
 	logger.Info("Scheduled job %s with cron expression: %s", jobID, cronExpr)
 	return nil
 }
@@ -134,16 +143,59 @@ func (s *Scheduler) RemoveJob(jobID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	entryID, exists := s.jobs[jobID]
-	if !exists {
+	if _, exists := s.jobs[jobID]; !exists {
 		return fmt.Errorf("job %s is not scheduled", jobID)
 	}
 
-	s.cron.Remove(entryID)
+	s.removeJob(jobID)
+	return nil
+}
+
+// removeJob removes a scheduled job. The caller holds the lock.
+func (s *Scheduler) removeJob(jobID string) {
+	s.cron.Remove(s.jobs[jobID])
 	delete(s.jobs, jobID)
+	delete(s.exprs, jobID)
 
 	logger.Info("Removed job %s from scheduler", jobID)
-	return nil
+}
+
+// SyncJob makes the schedule of a job match its definition: an enabled job
+// with a cron expression is scheduled, or rescheduled when its expression
+// changed, and any other job is removed from the scheduler
+func (s *Scheduler) SyncJob(job *models.Job) error {
+	var cronExpr string
+	if job.Enabled && job.SchedulingConfig != "" {
+		schedule, err := ParseSchedulingConfig(job)
+		if err != nil {
+			return err
+		}
+		cronExpr = schedule.CronExpr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.JobID]; exists {
+		if s.exprs[job.JobID] == cronExpr {
+			return nil
+		}
+		s.removeJob(job.JobID)
+	}
+	if cronExpr == "" {
+		return nil
+	}
+	return s.addJob(job.JobID, cronExpr)
+}
+
+// UnscheduleJob removes a job from the scheduler, if it is scheduled
+func (s *Scheduler) UnscheduleJob(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[jobID]; exists {
+		s.removeJob(jobID)
+	}
 }
 
 // UpdateJob updates a job's schedule
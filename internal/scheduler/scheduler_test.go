@@ -8,6 +8,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/models"
 )
 
 // MockExecutor is a mock job executor for testing
@@ -250,6 +252,47 @@ func TestUpdateJobNotScheduled(t *testing.T) {
 	assert.Equal(t, 1, len(jobs))
 }
 
+func TestSyncJob(t *testing.T) {
+	scheduler := NewScheduler(&MockExecutor{}, nil)
+	job := &models.Job{JobID: "job-1", Enabled: true, SchedulingConfig: "*/5 * * * *"}
+
+	require.NoError(t, scheduler.SyncJob(job))
+	assert.Equal(t, []string{"job-1"}, scheduler.GetScheduledJobs())
+	entry := scheduler.jobs["job-1"]
+
+	// An unchanged schedule keeps its entry
+	require.NoError(t, scheduler.SyncJob(job))
+	assert.Equal(t, entry, scheduler.jobs["job-1"])
+
+	// A changed schedule replaces it
+	job.SchedulingConfig = "0 * * * *"
+	require.NoError(t, scheduler.SyncJob(job))
+	assert.NotEqual(t, entry, scheduler.jobs["job-1"])
+	assert.Equal(t, "0 * * * *", scheduler.exprs["job-1"])
+	assert.Equal(t, 1, scheduler.GetJobCount())
+
+	// Disabled jobs and jobs without a schedule are removed
+	job.Enabled = false
+	require.NoError(t, scheduler.SyncJob(job))
+	assert.Equal(t, 0, scheduler.GetJobCount())
+
+	job.Enabled = true
+	require.NoError(t, scheduler.SyncJob(job))
+	job.SchedulingConfig = ""
+	require.NoError(t, scheduler.SyncJob(job))
+	assert.Equal(t, 0, scheduler.GetJobCount())
+
+	job.SchedulingConfig = "not cron"
+	assert.Error(t, scheduler.SyncJob(job))
+	assert.Equal(t, 0, scheduler.GetJobCount())
+
+	// Unscheduling ignores jobs that are not scheduled
+	scheduler.UnscheduleJob("job-1")
+	require.NoError(t, scheduler.AddJob("job-2", "* * * * *"))
+	scheduler.UnscheduleJob("job-2")
+	assert.Equal(t, 0, scheduler.GetJobCount())
+}
+
 func TestGetNextRun(t *testing.T) {
 	executor := &MockExecutor{}
 	scheduler := NewScheduler(executor, nil)
@@ -0,0 +1,489 @@
+// Package sqldb provides the connection handling, SQL dialects and type
+// mapping shared by the SQL input and output plugins.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// Config holds the connection settings of a SQL plugin
+type Config struct {
+	Dialect  string // "mysql", "postgresql" or "sqlite"
+	DSN      string // Driver-specific DSN, overrides the fields below
+	Host     string
+	Port     int
+	Database string // Database name, or file path for SQLite
+	User     string
+	Password string
+	Params   map[string]string // Extra DSN parameters, e.g. sslmode
+
+	MaxOpenConns int
+	ConnTimeout  time.Duration
+}
+
+// ParseConfig reads connection settings from a plugin configuration. The
+// settings may be nested under "connection" or given at the top level.
+// dialect is used when the configuration has no "dialect" key.
+func ParseConfig(config map[string]interface{}, dialect string) (*Config, error) {
+	conn, ok := config["connection"].(map[string]interface{})
+	if !ok {
+		conn = config
+	}
+
+	cfg := &Config{
+		Dialect:      dialect,
+		MaxOpenConns: 4,
+		ConnTimeout:  10 * time.Second,
+		Params:       make(map[string]string),
+	}
+
+	if d := stringValue(config, "dialect"); d != "" {
+		cfg.Dialect = d
+	}
+	if d := stringValue(conn, "dialect"); d != "" {
+		cfg.Dialect = d
+	}
+	cfg.DSN = stringValue(conn, "dsn")
+	cfg.Host = stringValue(conn, "host")
+	cfg.Database = stringValue(conn, "database")
+	if cfg.Database == "" {
+		cfg.Database = stringValue(conn, "path")
+	}
+	cfg.User = stringValue(conn, "user")
+	if cfg.User == "" {
+		cfg.User = stringValue(conn, "username")
+	}
+	cfg.Password = stringValue(conn, "password")
+
+	if port, ok := conn["port"]; ok {
+		p, err := strconv.Atoi(fmt.Sprintf("%v", port))
+		if err != nil {
+			return nil, fmt.Errorf("invalid port: %v", port)
+		}
+		cfg.Port = p
+	}
+
+	if params, ok := conn["params"].(map[string]interface{}); ok {
+		for k, v := range params {
+			cfg.Params[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	if sslmode := stringValue(conn, "sslmode"); sslmode != "" {
+		cfg.Params["sslmode"] = sslmode
+	}
+
+	if n, ok := conn["max_open_conns"].(int); ok && n > 0 {
+		cfg.MaxOpenConns = n
+	}
+
+	if _, err := GetDialect(cfg.Dialect); err != nil {
+		return nil, err
+	}
+	if cfg.DSN == "" && cfg.Database == "" {
+		return nil, fmt.Errorf("database is required")
+	}
+
+	return cfg, nil
+}
+
+// Open opens and verifies a database connection
+func Open(cfg *Config) (*sql.DB, Dialect, error) {
+	dialect, err := GetDialect(cfg.Dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dsn := cfg.DSN
+	if dsn == "" {
+		dsn = dialect.DSN(cfg)
+	}
+
+	db, err := sql.Open(dialect.DriverName(), dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s database: %w", dialect.Name(), err)
+	}
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to connect to %s database: %w", dialect.Name(), err)
+	}
+
+	return db, dialect, nil
+}
+
+// TableColumns returns the column names of a table
+func TableColumns(db *sql.DB, d Dialect, table string) ([]string, error) {
+	rows, err := db.Query("SELECT * FROM " + d.QuoteIdentifier(table) + " WHERE 1 = 0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
+
+// TableExists reports whether a table can be queried
+func TableExists(db *sql.DB, d Dialect, table string) bool {
+	rows, err := db.Query("SELECT 1 FROM " + d.QuoteIdentifier(table) + " WHERE 1 = 0")
+	if err != nil {
+		return false
+	}
+	rows.Close()
+	return true
+}
+
+// Dialect abstracts the SQL differences between databases
+type Dialect interface {
+	// Name returns the dialect name
+	Name() string
+
+	// DriverName returns the database/sql driver name
+	DriverName() string
+
+	// DSN builds a driver DSN from connection settings
+	DSN(cfg *Config) string
+
+	// QuoteIdentifier quotes a possibly schema-qualified identifier
+	QuoteIdentifier(name string) string
+
+	// Placeholder returns the bind parameter for the n-th argument (1-based)
+	Placeholder(n int) string
+
+	// MaxParams returns the maximum number of bind parameters per statement
+	MaxParams() int
+
+	// ColumnType returns the column type used to create a column of the
+	// given data type. Key columns need types that can be indexed.
+	ColumnType(dataType types.DataType, key bool) string
+
+	// UpsertClause returns the clause appended to an INSERT so that rows
+	// conflicting on the key columns update the other columns instead
+	UpsertClause(columns, keys []string) string
+
+	// TruncateSQL returns the statement that removes all rows of a table
+	TruncateSQL(table string) string
+
+	// AlterColumnTypeSQL returns the statement that changes the type of a
+	// column, or "" if the database needs none
+	AlterColumnTypeSQL(table, column, columnType string, notNull bool) string
+}
+
+// GetDialect returns the dialect with the given name
+func GetDialect(name string) (Dialect, error) {
+	switch strings.ToLower(name) {
+	case "mysql", "mariadb":
+		return mysqlDialect{}, nil
+	case "postgresql", "postgres", "pgsql":
+		return postgresDialect{}, nil
+	case "sqlite", "sqlite3":
+		return sqliteDialect{}, nil
+	case "":
+		return nil, fmt.Errorf("dialect is required")
+	default:
+		return nil, fmt.Errorf("unsupported dialect: %s", name)
+	}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string       { return "mysql" }
+func (mysqlDialect) DriverName() string { return "mysql" }
+
+func (mysqlDialect) DSN(cfg *Config) string {
+	host := cfg.Host
+	if host == "" {
+		host = "localhost"
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 3306
+	}
+
+	params := url.Values{}
+	params.Set("parseTime", "true")
+	for k, v := range cfg.Params {
+		params.Set(k, v)
+	}
+
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", cfg.User, cfg.Password, host, port, cfg.Database, params.Encode())
+}
+
+func (mysqlDialect) QuoteIdentifier(name string) string {
+	return quoteParts(name, "`")
+}
+
+func (mysqlDialect) Placeholder(n int) string { return "?" }
+func (mysqlDialect) MaxParams() int           { return 65535 }
+
+func (mysqlDialect) ColumnType(dataType types.DataType, key bool) string {
+	switch dataType {
+	case types.DataTypeInt:
+		return "INT"
+	case types.DataTypeBigInt:
+		return "BIGINT"
+	case types.DataTypeFloat:
+		return "FLOAT"
+	case types.DataTypeDouble:
+		return "DOUBLE"
+	case types.DataTypeBool:
+		return "BOOLEAN"
+	case types.DataTypeDate:
+		return "DATE"
+	case types.DataTypeTimestamp:
+		return "DATETIME(6)"
+	case types.DataTypeBytes:
+		if key {
+			return "VARBINARY(255)"
+		}
+		return "LONGBLOB"
+	case types.DataTypeJSON:
+		return "JSON"
+	default:
+		// TEXT columns cannot be primary keys without a prefix length
+		if key {
+			return "VARCHAR(255)"
+		}
+		return "TEXT"
+	}
+}
+
+func (d mysqlDialect) UpsertClause(columns, keys []string) string {
+	updates := make([]string, 0, len(columns))
+	for _, col := range nonKeyColumns(columns, keys) {
+		quoted := d.QuoteIdentifier(col)
+		updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", quoted, quoted))
+	}
+	if len(updates) == 0 {
+		// Nothing to update, keep the existing row
+		quoted := d.QuoteIdentifier(keys[0])
+		updates = append(updates, fmt.Sprintf("%s = %s", quoted, quoted))
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+}
+
+func (d mysqlDialect) TruncateSQL(table string) string {
+	return "TRUNCATE TABLE " + d.QuoteIdentifier(table)
+}
+
+// AlterColumnTypeSQL returns a MODIFY COLUMN, which restates the whole
+// column definition
+func (d mysqlDialect) AlterColumnTypeSQL(table, column, columnType string, notNull bool) string {
+	definition := d.QuoteIdentifier(column) + " " + columnType
+	if notNull {
+		definition += " NOT NULL"
+	}
+	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s", d.QuoteIdentifier(table), definition)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string       { return "postgresql" }
+func (postgresDialect) DriverName() string { return "postgres" }
+
+func (postgresDialect) DSN(cfg *Config) string {
+	host := cfg.Host
+	if host == "" {
+		host = "localhost"
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 5432
+	}
+
+	params := url.Values{}
+	params.Set("sslmode", "disable")
+	for k, v := range cfg.Params {
+		params.Set(k, v)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     fmt.Sprintf("%s:%d", host, port),
+		Path:     "/" + cfg.Database,
+		RawQuery: params.Encode(),
+	}
+	return u.String()
+}
+
+func (postgresDialect) QuoteIdentifier(name string) string {
+	return quoteParts(name, `"`)
+}
+
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+func (postgresDialect) MaxParams() int           { return 65535 }
+
+func (postgresDialect) ColumnType(dataType types.DataType, key bool) string {
+	switch dataType {
+	case types.DataTypeInt:
+		return "INTEGER"
+	case types.DataTypeBigInt:
+		return "BIGINT"
+	case types.DataTypeFloat:
+		return "REAL"
+	case types.DataTypeDouble:
+		return "DOUBLE PRECISION"
+	case types.DataTypeBool:
+		return "BOOLEAN"
+	case types.DataTypeDate:
+		return "DATE"
+	case types.DataTypeTimestamp:
+		return "TIMESTAMP"
+	case types.DataTypeBytes:
+		return "BYTEA"
+	case types.DataTypeJSON:
+		return "JSONB"
+	default:
+		return "TEXT"
+	}
+}
+
+func (d postgresDialect) UpsertClause(columns, keys []string) string {
+	return onConflictClause(d, columns, keys)
+}
+
+func (d postgresDialect) TruncateSQL(table string) string {
+	return "TRUNCATE TABLE " + d.QuoteIdentifier(table)
+}
+
+func (d postgresDialect) AlterColumnTypeSQL(table, column, columnType string, notNull bool) string {
+	quoted := d.QuoteIdentifier(column)
+	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s",
+		d.QuoteIdentifier(table), quoted, columnType, quoted, columnType)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string       { return "sqlite" }
+func (sqliteDialect) DriverName() string { return "sqlite3" }
+
+func (sqliteDialect) DSN(cfg *Config) string {
+	if len(cfg.Params) == 0 {
+		return cfg.Database
+	}
+	params := url.Values{}
+	for k, v := range cfg.Params {
+		params.Set(k, v)
+	}
+	return "file:" + cfg.Database + "?" + params.Encode()
+}
+
+func (sqliteDialect) QuoteIdentifier(name string) string {
+	return quoteParts(name, `"`)
+}
+
+func (sqliteDialect) Placeholder(n int) string { return "?" }
+func (sqliteDialect) MaxParams() int           { return 32766 }
+
+// ColumnType returns type names that SQLite maps to the right affinity and
+// that read back as the same data type
+func (sqliteDialect) ColumnType(dataType types.DataType, key bool) string {
+	switch dataType {
+	case types.DataTypeInt:
+		return "INTEGER"
+	case types.DataTypeBigInt:
+		return "BIGINT"
+	case types.DataTypeFloat:
+		return "REAL"
+	case types.DataTypeDouble:
+		return "DOUBLE"
+	case types.DataTypeBool:
+		return "BOOLEAN"
+	case types.DataTypeDate:
+		return "DATE"
+	case types.DataTypeTimestamp:
+		return "TIMESTAMP"
+	case types.DataTypeBytes:
+		return "BLOB"
+	case types.DataTypeJSON:
+		return "JSON"
+	default:
+		return "TEXT"
+	}
+}
+
+func (d sqliteDialect) UpsertClause(columns, keys []string) string {
+	return onConflictClause(d, columns, keys)
+}
+
+// TruncateSQL returns a DELETE, as SQLite has no TRUNCATE statement
+func (d sqliteDialect) TruncateSQL(table string) string {
+	return "DELETE FROM " + d.QuoteIdentifier(table)
+}
+
+// AlterColumnTypeSQL returns "", as SQLite cannot change column types and
+// stores values of any type in any column
+func (d sqliteDialect) AlterColumnTypeSQL(table, column, columnType string, notNull bool) string {
+	return ""
+}
+
+// onConflictClause builds the upsert clause shared by PostgreSQL and SQLite
+func onConflictClause(d Dialect, columns, keys []string) string {
+	quotedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		quotedKeys[i] = d.QuoteIdentifier(key)
+	}
+
+	updates := make([]string, 0, len(columns))
+	for _, col := range nonKeyColumns(columns, keys) {
+		quoted := d.QuoteIdentifier(col)
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quoted, quoted))
+	}
+
+	clause := fmt.Sprintf("ON CONFLICT (%s) ", strings.Join(quotedKeys, ", "))
+	if len(updates) == 0 {
+		return clause + "DO NOTHING"
+	}
+	return clause + "DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+// nonKeyColumns returns the columns that are not keys
+func nonKeyColumns(columns, keys []string) []string {
+	var result []string
+	for _, col := range columns {
+		isKey := false
+		for _, key := range keys {
+			if col == key {
+				isKey = true
+				break
+			}
+		}
+		if !isKey {
+			result = append(result, col)
+		}
+	}
+	return result
+}
+
+// quoteParts quotes each dot-separated part of an identifier, doubling
+// embedded quote characters
+func quoteParts(name, quote string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+func stringValue(m map[string]interface{}, key string) string {
+	if v, ok := m[key].(string); ok {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package sqldb
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig(map[string]interface{}{
+		"connection": map[string]interface{}{
+			"host":     "db.local",
+			"port":     5433,
+			"database": "sales",
+			"username": "etl",
+			"password": "secret",
+			"sslmode":  "require",
+		},
+	}, "postgresql")
+	require.NoError(t, err)
+
+	assert.Equal(t, "postgresql", cfg.Dialect)
+	assert.Equal(t, "db.local", cfg.Host)
+	assert.Equal(t, 5433, cfg.Port)
+	assert.Equal(t, "etl", cfg.User)
+	assert.Equal(t, "require", cfg.Params["sslmode"])
+
+	// Top-level settings and an explicit dialect
+	cfg, err = ParseConfig(map[string]interface{}{
+		"dialect": "sqlite",
+		"path":    "/tmp/test.db",
+	}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", cfg.Dialect)
+	assert.Equal(t, "/tmp/test.db", cfg.Database)
+
+	_, err = ParseConfig(map[string]interface{}{"database": "x"}, "")
+	assert.Error(t, err)
+
+	_, err = ParseConfig(map[string]interface{}{"database": "x"}, "oracle")
+	assert.Error(t, err)
+
+	_, err = ParseConfig(map[string]interface{}{}, "mysql")
+	assert.Error(t, err)
+}
+
+func TestDialects(t *testing.T) {
+	cfg := &Config{
+		Host:     "db",
+		Database: "sales",
+		User:     "etl",
+		Password: "secret",
+		Params:   map[string]string{},
+	}
+
+	mysql, err := GetDialect("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, "etl:secret@tcp(db:3306)/sales?parseTime=true", mysql.DSN(cfg))
+	assert.Equal(t, "`shop`.`order``s`", mysql.QuoteIdentifier("shop.order`s"))
+	assert.Equal(t, "?", mysql.Placeholder(3))
+
+	postgres, err := GetDialect("postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://etl:secret@db:5432/sales?sslmode=disable", postgres.DSN(cfg))
+	assert.Equal(t, `"public"."orders"`, postgres.QuoteIdentifier("public.orders"))
+	assert.Equal(t, "$3", postgres.Placeholder(3))
+
+	sqlite, err := GetDialect("sqlite3")
+	require.NoError(t, err)
+	assert.Equal(t, "sales", sqlite.DSN(cfg))
+	assert.Equal(t, "sqlite3", sqlite.DriverName())
+}
+
+func TestDataTypeOf(t *testing.T) {
+	tests := map[string]types.DataType{
+		"INT":               types.DataTypeInt,
+		"int4":              types.DataTypeInt,
+		"UNSIGNED BIGINT":   types.DataTypeBigInt,
+		"INT8":              types.DataTypeBigInt,
+		"DECIMAL(10,2)":     types.DataTypeDouble,
+		"FLOAT4":            types.DataTypeFloat,
+		"BOOLEAN":           types.DataTypeBool,
+		"DATE":              types.DataTypeDate,
+		"TIMESTAMPTZ":       types.DataTypeTimestamp,
+		"DATETIME":          types.DataTypeTimestamp,
+		"BYTEA":             types.DataTypeBytes,
+		"JSONB":             types.DataTypeJSON,
+		"VARCHAR":           types.DataTypeString,
+		"CHARACTER VARYING": types.DataTypeString,
+		"":                  types.DataTypeUnknown,
+	}
+
+	for name, expected := range tests {
+		assert.Equal(t, expected, DataTypeOf(name), name)
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	assert.Equal(t, int64(42), ConvertValue([]byte("42"), types.DataTypeInt))
+	assert.Equal(t, 1.5, ConvertValue("1.5", types.DataTypeDouble))
+	assert.Equal(t, true, ConvertValue([]byte{1}, types.DataTypeBool))
+	assert.Equal(t, "abc", ConvertValue([]byte("abc"), types.DataTypeString))
+	assert.Equal(t, []byte("abc"), ConvertValue([]byte("abc"), types.DataTypeBytes))
+	assert.Equal(t, "n/a", ConvertValue("n/a", types.DataTypeInt))
+	assert.Nil(t, ConvertValue(nil, types.DataTypeInt))
+}
+
+func TestOpenSQLite(t *testing.T) {
+	cfg, err := ParseConfig(map[string]interface{}{
+		"path": filepath.Join(t.TempDir(), "test.db"),
+	}, "sqlite")
+	require.NoError(t, err)
+
+	db, dialect, err := Open(cfg)
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, "sqlite", dialect.Name())
+	_, err = db.Exec("CREATE TABLE t (id INTEGER)")
+	assert.NoError(t, err)
+}
+
+func TestInsertSQL(t *testing.T) {
+	mysql, _ := GetDialect("mysql")
+	assert.Equal(t,
+		"INSERT INTO `t` (`id`, `name`) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)",
+		InsertSQL(mysql, "t", []string{"id", "name"}, 2, []string{"id"}))
+
+	postgres, _ := GetDialect("postgresql")
+	assert.Equal(t,
+		`INSERT INTO "t" ("id", "name") VALUES ($1, $2), ($3, $4) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		InsertSQL(postgres, "t", []string{"id", "name"}, 2, []string{"id"}))
+	assert.Equal(t,
+		`INSERT INTO "t" ("id") VALUES ($1) ON CONFLICT ("id") DO NOTHING`,
+		InsertSQL(postgres, "t", []string{"id"}, 1, []string{"id"}))
+
+	sqlite, _ := GetDialect("sqlite")
+	assert.Equal(t, `INSERT INTO "t" ("id") VALUES (?)`, InsertSQL(sqlite, "t", []string{"id"}, 1, nil))
+	assert.Equal(t, `DELETE FROM "t"`, sqlite.TruncateSQL("t"))
+}
+
+func TestCreateTableSQL(t *testing.T) {
+	schema := &types.Schema{
+		Columns: []types.Column{
+			{Name: "id", DataType: types.DataTypeString},
+			{Name: "amount", DataType: types.DataTypeDouble, Nullable: true},
+			{Name: "doc", DataType: types.DataTypeJSON, Nullable: true},
+		},
+		PrimaryKeys: []string{"id"},
+	}
+
+	mysql, _ := GetDialect("mysql")
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS `orders` (\n"+
+		"  `id` VARCHAR(255) NOT NULL,\n"+
+		"  `amount` DOUBLE,\n"+
+		"  `doc` JSON,\n"+
+		"  PRIMARY KEY (`id`)\n)", CreateTableSQL(mysql, "orders", schema))
+
+	postgres, _ := GetDialect("postgresql")
+	assert.Contains(t, CreateTableSQL(postgres, "orders", schema), `"doc" JSONB`)
+}
+
+func TestAlterTableSQL(t *testing.T) {
+	col := types.Column{Name: "amount", DataType: types.DataTypeDouble, Nullable: true}
+
+	mysql, _ := GetDialect("mysql")
+	assert.Equal(t, "ALTER TABLE `orders` ADD COLUMN `amount` DOUBLE", AddColumnSQL(mysql, "orders", col))
+	assert.Equal(t, "ALTER TABLE `orders` MODIFY COLUMN `amount` DOUBLE", AlterColumnSQL(mysql, "orders", col, false))
+	assert.Equal(t, "ALTER TABLE `orders` MODIFY COLUMN `id` VARCHAR(255) NOT NULL",
+		AlterColumnSQL(mysql, "orders", types.Column{Name: "id", DataType: types.DataTypeString}, true))
+
+	postgres, _ := GetDialect("postgresql")
+	assert.Equal(t, `ALTER TABLE "orders" ALTER COLUMN "amount" TYPE DOUBLE PRECISION USING "amount"::DOUBLE PRECISION`,
+		AlterColumnSQL(postgres, "orders", col, false))
+
+	sqlite, _ := GetDialect("sqlite")
+	assert.Equal(t, `ALTER TABLE "orders" ADD COLUMN "amount" DOUBLE`, AddColumnSQL(sqlite, "orders", col))
+	assert.Empty(t, AlterColumnSQL(sqlite, "orders", col, false))
+}
@@ -0,0 +1,88 @@
+package sqldb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// CreateTableSQL builds a CREATE TABLE IF NOT EXISTS statement for a schema.
+// The schema primary keys become the table primary key.
+func CreateTableSQL(d Dialect, table string, schema *types.Schema) string {
+	isKey := make(map[string]bool, len(schema.PrimaryKeys))
+	for _, key := range schema.PrimaryKeys {
+		isKey[key] = true
+	}
+
+	definitions := make([]string, 0, len(schema.Columns)+1)
+	for _, col := range schema.Columns {
+		definition := d.QuoteIdentifier(col.Name) + " " + d.ColumnType(col.DataType, isKey[col.Name])
+		if !col.Nullable || isKey[col.Name] {
+			definition += " NOT NULL"
+		}
+		definitions = append(definitions, definition)
+	}
+
+	if len(schema.PrimaryKeys) > 0 {
+		keys := make([]string, len(schema.PrimaryKeys))
+		for i, key := range schema.PrimaryKeys {
+			keys[i] = d.QuoteIdentifier(key)
+		}
+		definitions = append(definitions, "PRIMARY KEY ("+strings.Join(keys, ", ")+")")
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n  %s\n)",
+		d.QuoteIdentifier(table), strings.Join(definitions, ",\n  "))
+}
+
+// AddColumnSQL builds an ALTER TABLE statement that adds a column
+func AddColumnSQL(d Dialect, table string, col types.Column) string {
+	definition := d.QuoteIdentifier(col.Name) + " " + d.ColumnType(col.DataType, false)
+	if !col.Nullable {
+		definition += " NOT NULL"
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", d.QuoteIdentifier(table), definition)
+}
+
+// AlterColumnSQL builds the statement that changes a column to the type of
+// col, or returns "" if the dialect needs none. Key columns need types
+// that can be indexed.
+func AlterColumnSQL(d Dialect, table string, col types.Column, key bool) string {
+	return d.AlterColumnTypeSQL(table, col.Name, d.ColumnType(col.DataType, key), !col.Nullable || key)
+}
+
+// InsertSQL builds a multi-row INSERT statement for the given number of
+// rows. With upsert keys, conflicting rows update the existing ones.
+func InsertSQL(d Dialect, table string, columns []string, rows int, upsertKeys []string) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = d.QuoteIdentifier(col)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "INSERT INTO %s (%s) VALUES ", d.QuoteIdentifier(table), strings.Join(quoted, ", "))
+
+	n := 1
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteByte('(')
+		for c := range columns {
+			if c > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(d.Placeholder(n))
+			n++
+		}
+		sb.WriteByte(')')
+	}
+
+	if len(upsertKeys) > 0 {
+		sb.WriteByte(' ')
+		sb.WriteString(d.UpsertClause(columns, upsertKeys))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,79 @@
+package sqldb
+
+import (
+	"strings"
+
+	"github.com/atlanssia/fustgo/internal/expr"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// DataTypeOf maps a database column type name, as reported by
+// sql.ColumnType.DatabaseTypeName, to a DataType. Unrecognised and empty
+// type names map to DataTypeUnknown.
+func DataTypeOf(databaseType string) types.DataType {
+	name := strings.ToUpper(strings.TrimSpace(databaseType))
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	name = strings.TrimPrefix(name, "UNSIGNED ")
+	name = strings.TrimSuffix(name, " UNSIGNED")
+
+	switch name {
+	case "":
+		return types.DataTypeUnknown
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "INT2", "INT4", "SERIAL", "SMALLSERIAL", "YEAR":
+		return types.DataTypeInt
+	case "BIGINT", "INT8", "BIGSERIAL":
+		return types.DataTypeBigInt
+	case "FLOAT", "REAL", "FLOAT4":
+		return types.DataTypeFloat
+	case "DOUBLE", "DOUBLE PRECISION", "FLOAT8", "DECIMAL", "NUMERIC", "NUMBER", "MONEY":
+		return types.DataTypeDouble
+	case "BOOL", "BOOLEAN", "BIT":
+		return types.DataTypeBool
+	case "DATE":
+		return types.DataTypeDate
+	case "DATETIME", "TIMESTAMP", "TIMESTAMPTZ", "TIMESTAMP WITH TIME ZONE", "TIMESTAMP WITHOUT TIME ZONE":
+		return types.DataTypeTimestamp
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "BYTEA":
+		return types.DataTypeBytes
+	case "JSON", "JSONB":
+		return types.DataTypeJSON
+	default:
+		return types.DataTypeString
+	}
+}
+
+// ConvertValue converts a scanned driver value, or a record value about to
+// be bound, to the canonical Go type of the column data type. Values that
+// cannot be converted are returned unchanged, with byte slices turned into
+// strings.
+func ConvertValue(v interface{}, dataType types.DataType) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	if b, ok := v.([]byte); ok {
+		// Drivers reuse scan buffers
+		if dataType == types.DataTypeBytes {
+			return append([]byte(nil), b...)
+		}
+		v = string(b)
+	}
+
+	if dataType == types.DataTypeUnknown || dataType == types.DataTypeBytes {
+		return v
+	}
+	if dataType == types.DataTypeBool {
+		// MySQL BIT(1) arrives as a single raw byte
+		if s, ok := v.(string); ok && len(s) == 1 && s[0] <= 1 {
+			return s[0] == 1
+		}
+	}
+
+	converted, err := expr.Cast(v, dataType)
+	if err != nil {
+		return v
+	}
+	return converted
+}
@@ -0,0 +1,139 @@
+// Package tracing traces executions with OpenTelemetry. Spans are created
+// through Tracer, which uses the global tracer provider: they are dropped
+// until NewProvider installs a provider that exports them over OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of FustGo spans
+const instrumentationName = "github.com/atlanssia/fustgo"
+
+// Config holds the configuration of trace export
+type Config struct {
+	Endpoint string // OTLP/HTTP traces URL, such as http://localhost:5080/api/default/v1/traces
+	Username string // Basic auth, when set
+	Password string
+
+	// SampleRatio is the fraction of traces recorded, from 0 to 1. Spans
+	// whose parent was sampled, such as spans of a request sent by another
+	// FustGo process, are always recorded.
+	SampleRatio float64
+
+	ServiceName    string
+	ServiceVersion string
+	Attributes     map[string]string // Added to the resource, such as host.name
+
+	Timeout time.Duration // Per export
+}
+
+// DefaultConfig returns the default tracing configuration
+func DefaultConfig() *Config {
+	return &Config{
+		SampleRatio: 1,
+		ServiceName: "fustgo",
+		Timeout:     10 * time.Second,
+	}
+}
+
+// Provider exports the spans of the process
+type Provider struct {
+	provider *sdktrace.TracerProvider
+}
+
+// NewProvider creates a provider exporting spans in batches to the
+// configured endpoint, and installs it as the global tracer provider along
+// with the W3C trace context propagator
+func NewProvider(config *Config) (*Provider, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid traces endpoint '%s'", config.Endpoint)
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid trace sample ratio %v, must be between 0 and 1", config.SampleRatio)
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(config.Endpoint)}
+	if config.Username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(config.Username + ":" + config.Password))
+		options = append(options, otlptracehttp.WithHeaders(map[string]string{"Authorization": "Basic " + credentials}))
+	}
+	if config.Timeout > 0 {
+		options = append(options, otlptracehttp.WithTimeout(config.Timeout))
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	attributes := []attribute.KeyValue{attribute.String("service.name", config.ServiceName)}
+	if config.ServiceVersion != "" {
+		attributes = append(attributes, attribute.String("service.version", config.ServiceVersion))
+	}
+	for k, v := range config.Attributes {
+		attributes = append(attributes, attribute.String(k, v))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attributes...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return &Provider{provider: provider}, nil
+}
+
+// Shutdown exports the remaining spans and stops the provider
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.provider.Shutdown(ctx)
+}
+
+// Tracer returns the tracer of FustGo spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// End ends a span, recording err as its error when set
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the API request it
+// serves
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the ID of the API request of a context, or ""
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
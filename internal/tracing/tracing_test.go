@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is a stand-in for an OTLP/HTTP traces endpoint. It keeps the
+// spans it receives and the resource attributes they came with.
+type collector struct {
+	*httptest.Server
+	t          *testing.T
+	mu         sync.Mutex
+	spans      []*tracepb.Span
+	attributes map[string]string
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{t: t, attributes: make(map[string]string)}
+	c.Server = httptest.NewServer(http.HandlerFunc(c.handle))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func (c *collector) handle(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(c.t, "/api/default/v1/traces", r.URL.Path)
+	assert.Equal(c.t, "application/x-protobuf", r.Header.Get("Content-Type"))
+	user, password, ok := r.BasicAuth()
+	assert.True(c.t, ok)
+	assert.Equal(c.t, "user", user)
+	assert.Equal(c.t, "secret", password)
+
+	body, err := io.ReadAll(r.Body)
+	require.NoError(c.t, err)
+	request := &coltracepb.ExportTraceServiceRequest{}
+	require.NoError(c.t, proto.Unmarshal(body, request))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, resourceSpans := range request.ResourceSpans {
+		for _, kv := range resourceSpans.Resource.Attributes {
+			c.attributes[kv.Key] = kv.Value.GetStringValue()
+		}
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			c.spans = append(c.spans, scopeSpans.Spans...)
+		}
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *collector) received() ([]*tracepb.Span, map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spans, c.attributes
+}
+
+func newTestProvider(t *testing.T, endpoint string, ratio float64) *Provider {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	config := DefaultConfig()
+	config.Endpoint = endpoint + "/api/default/v1/traces"
+	config.Username = "user"
+	config.Password = "secret"
+	config.SampleRatio = ratio
+	config.ServiceVersion = "1.2.3"
+	config.Attributes = map[string]string{"host.name": "worker-1"}
+	provider, err := NewProvider(config)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestProviderExports(t *testing.T) {
+	collector := newCollector(t)
+	provider := newTestProvider(t, collector.URL, 1)
+
+	ctx := WithRequestID(context.Background(), "request-1")
+	assert.Equal(t, "request-1", RequestID(ctx))
+	assert.Empty(t, RequestID(context.Background()))
+
+	ctx, parent := Tracer().Start(ctx, "execution")
+	_, child := Tracer().Start(ctx, "pipeline.write")
+	End(child, errors.New("target unavailable"))
+	End(parent, nil)
+
+	// Shutting down exports the remaining spans
+	require.NoError(t, provider.Shutdown(context.Background()))
+	spans, attributes := collector.received()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "fustgo", attributes["service.name"])
+	assert.Equal(t, "1.2.3", attributes["service.version"])
+	assert.Equal(t, "worker-1", attributes["host.name"])
+
+	write, execution := spans[0], spans[1]
+	assert.Equal(t, "pipeline.write", write.Name)
+	assert.Equal(t, execution.SpanId, write.ParentSpanId)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, write.Status.Code)
+	assert.Equal(t, "target unavailable", write.Status.Message)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_UNSET, execution.Status.Code)
+}
+
+func TestProviderSampling(t *testing.T) {
+	collector := newCollector(t)
+	provider := newTestProvider(t, collector.URL, 0)
+
+	// New traces are not sampled
+	_, span := Tracer().Start(context.Background(), "execution")
+	assert.False(t, span.SpanContext().IsSampled())
+	span.End()
+
+	// Traces continued from a sampled caller are
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+	_, span = Tracer().Start(ctx, "GET /api/v1/jobs", trace.WithSpanKind(trace.SpanKindServer))
+	assert.True(t, span.SpanContext().IsSampled())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	span.End()
+
+	require.NoError(t, provider.Shutdown(context.Background()))
+	spans, _ := collector.received()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /api/v1/jobs", spans[0].Name)
+
+	_, err := NewProvider(&Config{Endpoint: "localhost:5080/v1/traces", SampleRatio: 1})
+	assert.Error(t, err)
+	_, err = NewProvider(&Config{Endpoint: collector.URL, SampleRatio: 2})
+	assert.Error(t, err)
+}
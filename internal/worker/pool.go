@@ -12,6 +12,7 @@ import (
 
 	"github.com/atlanssia/fustgo/internal/database"
 	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/metrics"
 	"github.com/atlanssia/fustgo/internal/models"
 )
 
@@ -87,6 +88,7 @@ func (p *Pool) RegisterWorker(hostname string, port int) (*models.Worker, error)
 
 	// Add to in-memory map
 	p.workers[worker.WorkerID] = worker
+	p.updateMetrics()
 
 	logger.Info("Registered worker %s (%s:%d) with %d CPU cores, %d MB memory",
 		worker.WorkerID, worker.IPAddress, worker.Port, worker.CPUCores, worker.MemoryMB)
@@ -110,6 +112,7 @@ func (p *Pool) UnregisterWorker(workerID string) error {
 
 	// Remove from memory
 	delete(p.workers, workerID)
+	p.updateMetrics()
 
 	logger.Info("Unregistered worker %s", workerID)
 	return nil
@@ -129,6 +132,7 @@ func (p *Pool) UpdateHeartbeat(workerID string) error {
 		}
 		worker = dbWorker
 		p.workers[workerID] = worker
+		p.updateMetrics()
 	}
 
 	// Update heartbeat
@@ -173,6 +177,7 @@ func (p *Pool) ListWorkers() ([]*models.Worker, error) {
 	for _, worker := range workers {
 		p.workers[worker.WorkerID] = worker
 	}
+	p.updateMetrics()
 	p.mu.Unlock()
 
 	return workers, nil
@@ -217,6 +222,7 @@ func (p *Pool) Start() error {
 		for _, worker := range workers {
 			p.workers[worker.WorkerID] = worker
 		}
+		p.updateMetrics()
 		logger.Info("Loaded %d existing workers", len(workers))
 	}
 
@@ -248,6 +254,30 @@ func (p *Pool) Stop() error {
 	return nil
 }
 
+// KeepAlive periodically refreshes the heartbeat of a worker hosted by this
+// process until the pool is stopped
+func (p *Pool) KeepAlive(workerID string) {
+	p.mu.RLock()
+	stopChan := p.stopChan
+	p.mu.RUnlock()
+
+	go func() {
+		ticker := time.NewTicker(p.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.UpdateHeartbeat(workerID); err != nil {
+					logger.Warn("Failed to send heartbeat for worker %s: %v", workerID, err)
+				}
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+}
+
 // IsRunning returns whether the pool is running
 func (p *Pool) IsRunning() bool {
 	p.mu.RLock()
@@ -294,11 +324,29 @@ func (p *Pool) checkWorkerHealth() {
 		}
 	}
 
+	p.updateMetrics()
+
 	if offlineCount > 0 {
 		logger.Info("Health check: %d workers marked offline", offlineCount)
 	}
 }
 
+// updateMetrics sets the worker gauges to the number of workers in each
+// status. The caller holds the lock.
+func (p *Pool) updateMetrics() {
+	counts := map[models.WorkerStatus]int{
+		models.WorkerStatusOnline:  0,
+		models.WorkerStatusOffline: 0,
+		models.WorkerStatusBusy:    0,
+	}
+	for _, worker := range p.workers {
+		counts[worker.Status]++
+	}
+	for status, count := range counts {
+		metrics.Workers.WithLabelValues(string(status)).Set(float64(count))
+	}
+}
+
 // GetPoolStats returns statistics about the worker pool
 func (p *Pool) GetPoolStats() map[string]interface{} {
 	p.mu.RLock()
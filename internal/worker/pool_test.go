@@ -4,10 +4,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/atlanssia/fustgo/internal/database"
+	"github.com/atlanssia/fustgo/internal/metrics"
 	"github.com/atlanssia/fustgo/internal/models"
 )
 
@@ -258,6 +260,27 @@ func TestMonitorWorkers(t *testing.T) {
 	assert.Equal(t, models.WorkerStatusOffline, status)
 }
 
+func TestPoolMetrics(t *testing.T) {
+	pool := setupTestPool(t)
+
+	stale, err := pool.RegisterWorker("worker-1", 8080)
+	require.NoError(t, err)
+	_, err = pool.RegisterWorker("worker-2", 8081)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.Workers.WithLabelValues("online")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.Workers.WithLabelValues("offline")))
+
+	pool.mu.Lock()
+	pool.workers[stale.WorkerID].LastHeartbeat = time.Now().Add(-time.Hour)
+	pool.mu.Unlock()
+	pool.checkWorkerHealth()
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.Workers.WithLabelValues("online")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.Workers.WithLabelValues("offline")))
+
+	require.NoError(t, pool.UnregisterWorker(stale.WorkerID))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.Workers.WithLabelValues("offline")))
+}
+
 func TestGetPoolStats(t *testing.T) {
 	pool := setupTestPool(t)
 
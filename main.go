@@ -1,21 +1,43 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/gin-gonic/gin"
+
+	"github.com/atlanssia/fustgo/internal/api"
+	"github.com/atlanssia/fustgo/internal/checkpoint"
 	"github.com/atlanssia/fustgo/internal/config"
 	"github.com/atlanssia/fustgo/internal/database"
+	"github.com/atlanssia/fustgo/internal/jobmanager"
 	"github.com/atlanssia/fustgo/internal/logger"
+	"github.com/atlanssia/fustgo/internal/metrics"
+	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/internal/scheduler"
+	"github.com/atlanssia/fustgo/internal/tracing"
+	"github.com/atlanssia/fustgo/internal/worker"
+
+	// Register all built-in plugins
+	_ "github.com/atlanssia/fustgo/plugins"
 )
 
 var (
-	version   = "0.1.0"
-	configFile string
+	version     = "0.1.0"
+	configFile  string
 	showVersion bool
 )
 
+// shutdownTimeout bounds how long running pipelines get to stop and save
+// their checkpoints
+const shutdownTimeout = 30 * time.Second
+
 func init() {
 	flag.StringVar(&configFile, "config", "configs/default.yaml", "Path to configuration file")
 	flag.BoolVar(&showVersion, "version", false, "Show version information")
@@ -29,10 +51,10 @@ func main() {
 		os.Exit(0)
 	}
 
-	fmt.Println("=", "="*50)
+	fmt.Println(strings.Repeat("=", 52))
 	fmt.Println("  FustGo DataX - ETL/ELT Data Synchronization System")
 	fmt.Printf("  Version: %s\n", version)
-	fmt.Println("=", "="*50)
+	fmt.Println(strings.Repeat("=", 52))
 
 	// Load configuration
 	cfg, err := config.LoadConfig(configFile)
@@ -47,11 +69,7 @@ func main() {
 	}
 
 	// Initialize logger
-	log, err := logger.NewLogger(
-		"fustgo",
-		cfg.Observability.Logs.Local.Enabled,
-		cfg.Observability.Logs.Local.Path+"/fustgo.log",
-	)
+	log, err := newLogger(&cfg.Observability.Logs)
 	if err != nil {
 		fmt.Printf("Failed to initialize logger: %v\n", err)
 		os.Exit(1)
@@ -79,13 +97,238 @@ func main() {
 
 	log.Info("Metadata store initialized successfully")
 
-	// TODO: Start web server
-	// TODO: Start worker pool
-	// TODO: Start scheduler
+	registry := plugin.GetRegistry()
+	log.Info("Loaded plugins: %d input, %d processor, %d output",
+		len(registry.ListInputs()), len(registry.ListProcessors()), len(registry.ListOutputs()))
+
+	jobManager := jobmanager.NewManager(metaStore)
+
+	// Start worker pool and register this node as a worker
+	heartbeatInterval := parseDuration(cfg.Worker.HeartbeatInterval, 10*time.Second)
+	workerPool := worker.NewPool(metaStore, &worker.Config{
+		HeartbeatInterval: heartbeatInterval,
+		HeartbeatTimeout:  3 * heartbeatInterval,
+	})
+	if err := workerPool.Start(); err != nil {
+		log.Fatal("Failed to start worker pool: %v", err)
+	}
+	localWorker, err := workerPool.RegisterWorker(worker.GetWorkerHostname(), cfg.Server.Port)
+	if err != nil {
+		log.Fatal("Failed to register local worker: %v", err)
+	}
+	workerPool.KeepAlive(localWorker.WorkerID)
+
+	// Push metrics to a remote-write endpoint, as workers behind NAT
+	// cannot be scraped
+	var pusher *metrics.Pusher
+	if pushConfig := cfg.Observability.Metrics.OpenObserve; pushConfig.Enabled {
+		pusherConfig := metrics.DefaultPusherConfig()
+		pusherConfig.Endpoint = pushConfig.Endpoint
+		pusherConfig.Interval = parseDuration(pushConfig.PushInterval, pusherConfig.Interval)
+		pusherConfig.Username = pushConfig.Username
+		pusherConfig.Password = pushConfig.Password
+		pusherConfig.Labels = map[string]string{"instance": worker.GetWorkerHostname()}
+		pusher, err = metrics.NewPusher(metrics.Registry, pusherConfig)
+		if err != nil {
+			log.Fatal("Failed to create metrics pusher: %v", err)
+		}
+		pusher.Start()
+	}
+
+	// Export traces of executions and API requests over OTLP/HTTP
+	var tracer *tracing.Provider
+	if traceConfig := cfg.Observability.Traces.OpenObserve; traceConfig.Enabled {
+		providerConfig := tracing.DefaultConfig()
+		providerConfig.Endpoint = strings.TrimSuffix(traceConfig.Endpoint, "/") + "/api/" + traceConfig.Organization + "/v1/traces"
+		providerConfig.Username = traceConfig.Username
+		providerConfig.Password = traceConfig.Password
+		providerConfig.SampleRatio = traceConfig.SampleRatio
+		providerConfig.ServiceVersion = version
+		providerConfig.Attributes = map[string]string{
+			"host.name":              worker.GetWorkerHostname(),
+			"fustgo.deployment.mode": cfg.Deployment.Mode,
+		}
+		tracer, err = tracing.NewProvider(providerConfig)
+		if err != nil {
+			log.Fatal("Failed to create trace provider: %v", err)
+		}
+		log.Info("Exporting traces to %s, sampling %.0f%%", providerConfig.Endpoint, 100*providerConfig.SampleRatio)
+	}
+
+	// Create job executor
+	checkpointConfig := checkpoint.DefaultConfig()
+	checkpointConfig.StorageType = cfg.Checkpoint.Storage
+	checkpointConfig.StoragePath = cfg.Checkpoint.Path
+	checkpointConfig.Interval = parseDuration(cfg.Checkpoint.Interval, checkpointConfig.Interval)
+	checkpointConfig.Store = metaStore
+	log.Info("Checkpoint storage: %s", checkpointConfig.StorageType)
+
+	// Capture the log lines of each execution in the metadata store
+	var logCapture *jobmanager.LogCapture
+	if execLogs := cfg.Observability.Logs.Execution; execLogs.Enabled {
+		captureConfig := jobmanager.DefaultLogCaptureConfig()
+		captureConfig.MaxLines = execLogs.MaxLines
+		logCapture = jobmanager.NewLogCapture(jobManager, captureConfig)
+		log.AddHook(logCapture)
+	}
+
+	executor := jobmanager.NewExecutor(jobManager, registry, &jobmanager.ExecutorConfig{
+		WorkerID:          localWorker.WorkerID,
+		MaxConcurrentJobs: cfg.Worker.MaxConcurrentJobs,
+		CheckpointConfig:  checkpointConfig,
+		LogCapture:        logCapture,
+	})
+
+	// Start scheduler
+	sched := scheduler.NewScheduler(executor, nil)
+	if err := sched.Start(); err != nil {
+		log.Fatal("Failed to start scheduler: %v", err)
+	}
+	jobManager.SetScheduler(sched)
+	scheduleJobs(jobManager, sched)
+
+	// Start web server
+	serverConfig := api.DefaultServerConfig()
+	serverConfig.Host = cfg.Server.Host
+	serverConfig.Port = cfg.Server.Port
+	if cfg.Server.Mode == "dev" {
+		serverConfig.Mode = gin.DebugMode
+	}
+	handler := api.NewHandler(jobManager, executor, workerPool, registry, metaStore)
+	server := api.NewServer(serverConfig, handler)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Start()
+	}()
 
 	log.Info("FustGo DataX is ready")
 	log.Info("Web UI available at: http://%s:%d", cfg.Server.Host, cfg.Server.Port)
 
-	// Keep running
-	select {}
-}
\ No newline at end of file
+	// Wait for shutdown signal
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case sig := <-signals:
+		log.Info("Received signal %s, shutting down", sig)
+	case err := <-serverErr:
+		if err != nil {
+			log.Error("API server stopped: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Stop running pipelines first so their checkpoints are saved
+	if err := executor.Shutdown(ctx); err != nil {
+		log.Error("Failed to stop running jobs: %v", err)
+	}
+	if err := sched.Stop(); err != nil {
+		log.Error("Failed to stop scheduler: %v", err)
+	}
+	if logCapture != nil {
+		// Save the last lines while the store is open
+		log.RemoveHook(logCapture)
+		logCapture.Close()
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("Failed to stop API server: %v", err)
+	}
+	if err := workerPool.UnregisterWorker(localWorker.WorkerID); err != nil {
+		log.Error("Failed to unregister worker: %v", err)
+	}
+	if err := workerPool.Stop(); err != nil {
+		log.Error("Failed to stop worker pool: %v", err)
+	}
+	if pusher != nil {
+		pusher.Stop()
+	}
+	if tracer != nil {
+		if err := tracer.Shutdown(ctx); err != nil {
+			log.Error("Failed to export remaining traces: %v", err)
+		}
+	}
+
+	log.Info("FustGo DataX stopped")
+}
+
+// scheduleJobs registers all enabled jobs that have a cron schedule. The
+// job manager keeps the schedules of jobs created, updated or deleted later
+// up to date.
+func scheduleJobs(jobManager *jobmanager.Manager, sched *scheduler.Scheduler) {
+	jobs, err := jobManager.ListJobs(nil)
+	if err != nil {
+		logger.Error("Failed to load jobs for scheduling: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if err := sched.SyncJob(job); err != nil {
+			logger.Warn("Failed to schedule job %s: %v", job.JobID, err)
+		}
+	}
+}
+
+// parseDuration parses a duration string, falling back to a default value
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// newLogger creates the logger described by the logging configuration,
+// shipping its entries to OpenObserve when enabled
+func newLogger(cfg *config.LogsConfig) (*logger.Logger, error) {
+	level, err := logger.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	logConfig := logger.DefaultConfig()
+	logConfig.Level = level
+	logConfig.Format = logger.Format(cfg.Format)
+	if cfg.Local.Enabled {
+		logConfig.File = cfg.Local.Path + "/fustgo.log"
+		if logConfig.MaxSizeMB, err = cfg.Local.MaxSizeMB(); err != nil {
+			return nil, err
+		}
+		logConfig.MaxAgeDays = cfg.Local.MaxAge
+		logConfig.MaxBackups = cfg.Local.MaxBackups
+		logConfig.Compress = cfg.Local.Compress
+	}
+
+	log, err := logger.New(logConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if shipping := cfg.OpenObserve; shipping.Enabled {
+		shipperConfig := logger.DefaultShipperConfig()
+		shipperConfig.Endpoint = shipping.Endpoint
+		if shipping.Organization != "" {
+			shipperConfig.Organization = shipping.Organization
+		}
+		if shipping.Stream != "" {
+			shipperConfig.Stream = shipping.Stream
+		}
+		shipperConfig.Username = shipping.Username
+		shipperConfig.Password = shipping.Password
+		if shipping.BatchSize > 0 {
+			shipperConfig.BatchSize = shipping.BatchSize
+		}
+		shipperConfig.FlushInterval = parseDuration(shipping.FlushInterval, shipperConfig.FlushInterval)
+
+		shipper, err := logger.NewShipper(shipperConfig)
+		if err != nil {
+			log.Close()
+			return nil, err
+		}
+		// Closing the logger flushes the shipper
+		log.AddHook(shipper)
+	}
+	return log, nil
+}
@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // DataType represents the type of a column value
 type DataType int
@@ -61,6 +64,17 @@ type Schema struct {
 	PrimaryKeys []string `json:"primary_keys,omitempty"`
 }
 
+// ColumnIndex returns the index of the named column, or -1 if the schema
+// has no such column
+func (s *Schema) ColumnIndex(name string) int {
+	for i, col := range s.Columns {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
 // Record represents a single row of data
 type Record struct {
 	Values   []interface{}     `json:"values"`
@@ -74,12 +88,88 @@ type Checkpoint struct {
 	Metadata  map[string]string `json:"metadata,omitempty"`
 }
 
+// Checkpoint metadata keys set by the pipeline
+const (
+	// CheckpointCompleted is "true" on the last checkpoint of a run that
+	// completed
+	CheckpointCompleted = "completed"
+
+	// CheckpointOutputPosition holds the JSON-encoded output position of
+	// a ResumableOutput
+	CheckpointOutputPosition = "output_position"
+
+	// CheckpointShardPrefix prefixes the keys holding the JSON-encoded
+	// checkpoints of the shards of a split input, followed by the shard ID
+	CheckpointShardPrefix = "shard:"
+)
+
+// IsCompleted reports whether the checkpoint is the last one of a run that
+// completed
+func (c *Checkpoint) IsCompleted() bool {
+	return c != nil && c.Metadata[CheckpointCompleted] == "true"
+}
+
+// ToInt64 converts a number of a checkpoint position or plugin
+// configuration, which is float64 after a JSON round trip
+func ToInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// SchemaEvent records a difference between the schema of a batch and the
+// schema established by the first batch written in a run, and how the
+// pipeline handled it
+type SchemaEvent struct {
+	Time   time.Time `json:"time"`
+	Change string    `json:"change"` // "column_added", "column_missing" or "type_changed"
+	Column string    `json:"column"`
+	From   string    `json:"from,omitempty"` // Established data type of a changed column
+	To     string    `json:"to,omitempty"`   // Data type of the column in the batch
+	Action string    `json:"action"`         // "failed", "added", "dropped", "filled", "converted" or "widened"
+}
+
+// RejectedRecord is a record that a plugin could not handle. Processors
+// reject records of the batch given to them, inputs records of the batch
+// they return.
+type RejectedRecord struct {
+	Record Record `json:"record"`
+	Error  string `json:"error"`
+}
+
+// RecordWriteError is returned by the WriteBatch of an output plugin that
+// wrote the other records of the batch but rejected some of them, such as
+// records that cannot be encoded in the output format. The pipeline applies
+// its error policy to the rejected records.
+type RecordWriteError struct {
+	Rejected []RejectedRecord
+}
+
+// Error returns the number of rejected records and the first error
+func (e *RecordWriteError) Error() string {
+	if len(e.Rejected) == 0 {
+		return "no records rejected"
+	}
+	return fmt.Sprintf("%d records rejected: %s", len(e.Rejected), e.Rejected[0].Error)
+}
+
 // DataBatch represents a batch of records with schema
 type DataBatch struct {
 	Schema     Schema            `json:"schema"`
 	Records    []Record          `json:"records"`
 	Metadata   map[string]string `json:"metadata,omitempty"`
 	Checkpoint *Checkpoint       `json:"checkpoint,omitempty"`
+
+	// Rejected holds the records the producing plugin could not handle.
+	// The pipeline applies its error policy to them.
+	Rejected []RejectedRecord `json:"rejected,omitempty"`
 }
 
 // Size returns the number of records in the batch
@@ -92,6 +182,39 @@ func (db *DataBatch) IsEmpty() bool {
 	return len(db.Records) == 0
 }
 
+// MemorySize estimates the memory held by the records of the batch, in
+// bytes. Strings and byte slices count their length, other values a fixed
+// size.
+func (db *DataBatch) MemorySize() int64 {
+	var size int64
+	for _, record := range db.Records {
+		size += 24 // Slice header
+		for _, v := range record.Values {
+			size += valueSize(v)
+		}
+		for k, v := range record.Metadata {
+			size += int64(len(k) + len(v))
+		}
+	}
+	return size
+}
+
+// valueSize estimates the memory held by a record value
+func valueSize(v interface{}) int64 {
+	switch val := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return 16 + int64(len(val))
+	case []byte:
+		return 24 + int64(len(val))
+	case time.Time:
+		return 24
+	default:
+		return 16
+	}
+}
+
 // Progress represents the progress of a data operation
 type Progress struct {
 	TotalRecords     int64     `json:"total_records"`
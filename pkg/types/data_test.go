@@ -46,6 +46,23 @@ func TestDataBatch_Size(t *testing.T) {
 	assert.Equal(t, 3, batch.Size())
 }
 
+func TestDataBatch_MemorySize(t *testing.T) {
+	assert.Equal(t, int64(0), (&DataBatch{}).MemorySize())
+
+	narrow := &DataBatch{
+		Records: []Record{
+			{Values: []interface{}{1, "a"}},
+		},
+	}
+	wide := &DataBatch{
+		Records: []Record{
+			{Values: []interface{}{1, string(make([]byte, 1000))}},
+		},
+	}
+	assert.Greater(t, narrow.MemorySize(), int64(0))
+	assert.Equal(t, narrow.MemorySize()+999, wide.MemorySize())
+}
+
 func TestDataBatch_IsEmpty(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -159,6 +176,8 @@ func TestSchema(t *testing.T) {
 	assert.Len(t, schema.Columns, 2)
 	assert.Len(t, schema.PrimaryKeys, 1)
 	assert.Equal(t, "id", schema.PrimaryKeys[0])
+	assert.Equal(t, 1, schema.ColumnIndex("name"))
+	assert.Equal(t, -1, schema.ColumnIndex("missing"))
 }
 
 func TestRecord(t *testing.T) {
@@ -191,3 +210,16 @@ func TestCheckpoint(t *testing.T) {
 	assert.Equal(t, now, checkpoint.Timestamp)
 	assert.Equal(t, "data.csv", checkpoint.Metadata["file"])
 }
+
+func TestToInt64(t *testing.T) {
+	for _, v := range []interface{}{7, int64(7), float64(7)} {
+		n, ok := ToInt64(v)
+		assert.True(t, ok)
+		assert.Equal(t, int64(7), n)
+	}
+
+	for _, v := range []interface{}{nil, "7", int32(7)} {
+		_, ok := ToInt64(v)
+		assert.False(t, ok)
+	}
+}
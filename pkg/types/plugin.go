@@ -58,7 +58,55 @@ type InputPlugin interface {
 	GetProgress() *Progress
 }
 
-// ProcessorPlugin defines the interface for data processing plugins
+// ResumableInput is implemented by input plugins that can continue reading
+// from a checkpoint they produced earlier in DataBatch.Checkpoint
+type ResumableInput interface {
+	InputPlugin
+
+	// Seek positions the input just after the given checkpoint. It is
+	// called after Connect and before the first ReadBatch. The checkpoint
+	// of a run that completed is marked with CheckpointCompleted; inputs
+	// that read everything on every run start over, incremental inputs
+	// continue after it.
+	Seek(checkpoint *Checkpoint) error
+}
+
+// SplittableInput is implemented by input plugins whose data can be divided
+// into shards that are read concurrently, such as byte ranges of a file,
+// key ranges of a table or a list of files
+type SplittableInput interface {
+	InputPlugin
+
+	// Split divides the input into at most n shards. It is called after
+	// Connect; the input itself is not read afterwards. The pipeline
+	// connects, seeks and closes the shard inputs. Splitting the same data
+	// into the same number of shards must give the same shard IDs, so that
+	// an interrupted run resumes each shard from its own checkpoint. The
+	// shards of a ResumableInput must be resumable as well.
+	Split(n int) ([]InputShard, error)
+}
+
+// InputShard is a part of a split input
+type InputShard struct {
+	// ID identifies the shard among the shards of the input
+	ID string
+
+	// Input reads the shard
+	Input InputPlugin
+
+	// Checkpoint is the position the shard starts at, stored for the shard
+	// until it produces a checkpoint of its own. Inputs whose shards depend
+	// on the data, such as key ranges, record the bounds of the shard in
+	// it, so that a resumed run reads the same shards. May be nil.
+	Checkpoint *Checkpoint
+}
+
+// ProcessorPlugin defines the interface for data processing plugins.
+//
+// The pipeline never calls Process concurrently on one instance: a stage
+// that runs on several workers gives each worker its own instance, created
+// and initialized with the same configuration, so processors need not be
+// safe for concurrent use.
 type ProcessorPlugin interface {
 	Plugin
 
@@ -76,7 +124,8 @@ type OutputPlugin interface {
 	// Connect establishes connection to the target system
 	Connect() error
 
-	// WriteBatch writes a batch of data
+	// WriteBatch writes a batch of data. An output that wrote all records
+	// but some bad ones returns a *RecordWriteError listing them.
 	WriteBatch(data *DataBatch) error
 
 	// Flush flushes any buffered data
@@ -85,3 +134,47 @@ type OutputPlugin interface {
 	// GetWriteStatistics returns write statistics
 	GetWriteStatistics() *WriteStatistics
 }
+
+// ResumableOutput is implemented by output plugins that can continue the
+// output of an interrupted run, so that a resumed run neither duplicates
+// nor loses records
+type ResumableOutput interface {
+	OutputPlugin
+
+	// Position returns the position of the flushed output. It is called
+	// after a successful Flush and stored with the checkpoint.
+	Position() (interface{}, error)
+
+	// Resume makes the output continue at a position returned by Position,
+	// discarding anything written after it. Outputs that cannot take back
+	// written records, such as tables committed batch by batch, keep them.
+	// It is called before Connect.
+	Resume(position interface{}) error
+}
+
+// TransactionalOutput is implemented by output plugins whose WriteBatch
+// writes all records of a batch or none of them, such as tables loaded in a
+// transaction. When a batch of such an output fails, the pipeline retries it
+// record by record to find the bad records. Other outputs may already hold
+// part of a failed batch, so their batches are never written twice.
+type TransactionalOutput interface {
+	OutputPlugin
+
+	// Transactional reports whether a failed WriteBatch wrote nothing
+	Transactional() bool
+}
+
+// SchemaEvolver is implemented by output plugins that can change the
+// schema of their target when the schema of the data drifts, such as
+// tables that take new columns. The pipeline calls it before writing the
+// first batch with the changed schema.
+type SchemaEvolver interface {
+	OutputPlugin
+
+	// AddColumns adds nullable columns to the target
+	AddColumns(columns []Column) error
+
+	// AlterColumns changes the data types of existing columns of the target
+	// to the wider types of the given columns
+	AlterColumns(columns []Column) error
+}
@@ -1,28 +1,83 @@
 package csv
 
 import (
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"os"
 	"strconv"
 	"strings"
 
+	"golang.org/x/text/encoding"
+
+	"github.com/atlanssia/fustgo/internal/fileio"
 	"github.com/atlanssia/fustgo/pkg/types"
 )
 
-// CSVInputPlugin reads data from CSV files
+// minShardSize is the smallest byte range a file is split into for
+// parallel reads
+const minShardSize = 1 << 20
+
+// CSVInputPlugin reads data from CSV files. The path is a file, a
+// directory or a glob pattern; the files are read one after the other in
+// lexical order and must all have the same header.
+//
+// Compressed files are decompressed according to their extension or the
+// compression option. They are not split into byte ranges, and resuming
+// in one decompresses it again up to the checkpoint.
+//
+// Columns have the types declared in the schema configuration. The types
+// of the other columns are inferred from a sample of the first rows, or
+// are strings when inference is disabled.
+//
+// Several files are split into shards of whole files for parallel reads.
+// A single file is split into byte ranges when quoted_newlines is false:
+// each range starts at the first line break in it, which would cut a row
+// whose quoted field holds a line break in two.
 type CSVInputPlugin struct {
-	config       map[string]interface{}
-	file         *os.File
-	reader       *csv.Reader
-	header       []string
-	schema       *types.Schema
-	hasHeader    bool
-	delimiter    rune
-	currentRow   int
-	totalRows    int
-	progress     *types.Progress
+	config          map[string]interface{}
+	files           []string // Files to read, in order
+	doneFiles       []string // Files read to the end
+	done            map[string]bool
+	fileIndex       int // Index of the open file in files
+	file            *os.File
+	stream          io.ReadCloser // Decompressor of the open file, nil if not compressed
+	codec           fileio.Codec  // Compression of the open file
+	path            string // Path of the open file
+	fileDone        bool   // The open file has been read to the end
+	connected       bool
+	reader          *rowReader
+	header          []string
+	headerFile      string // File the header was read from
+	schema          *types.Schema
+	columns         []column // Columns of the schema, with their conversion
+	declared        []column // Columns declared in the configuration
+	hasHeader       bool
+	recursive       bool
+	dialect         dialect
+	encoding        encoding.Encoding // nil for UTF-8
+	compression     string
+	nullValues      []string
+	dateFormat      string
+	timestampFormat string
+	inferSchema     bool
+	inferRows       int
+	quotedNewlines  bool // Quoted fields may hold line breaks
+	currentRow      int        // Row in the open file
+	baseOffset      int64      // File offset the reader started at
+	dataOffset      int64      // File offset of the first data row
+	byteRange       *byteRange // Part of the file read by a shard
+	totalRows       int
+	progress        *types.Progress
+}
+
+// byteRange is the part of a file read by a shard: the rows starting at
+// offsets from start up to, but not including, end
+type byteRange struct {
+	start, end int64
 }
 
 // Name returns the plugin name
@@ -39,16 +94,74 @@ func (p *CSVInputPlugin) Type() types.PluginType {
 func (p *CSVInputPlugin) Initialize(config map[string]interface{}) error {
 	p.config = config
 	p.hasHeader = true
-	p.delimiter = ','
 	p.currentRow = 0
+	p.nullValues = []string{""}
+	p.inferSchema = true
+	p.inferRows = defaultInferRows
+	p.quotedNewlines = true
 	
 	// Parse configuration
 	if hasHeader, ok := config["has_header"].(bool); ok {
 		p.hasHeader = hasHeader
 	}
 	
-	if delimiter, ok := config["delimiter"].(string); ok && len(delimiter) > 0 {
-		p.delimiter = rune(delimiter[0])
+	if recursive, ok := config["recursive"].(bool); ok {
+		p.recursive = recursive
+	}
+	
+	dialect, err := parseDialect(config)
+	if err != nil {
+		return fmt.Errorf("csv input: %w", err)
+	}
+	p.dialect = dialect
+	
+	if compression, ok := config["compression"].(string); ok {
+		if _, err := fileio.ParseCodec(compression, ""); err != nil {
+			return fmt.Errorf("csv input: %w", err)
+		}
+		p.compression = compression
+	}
+	
+	if name, ok := config["encoding"].(string); ok {
+		enc, err := parseEncoding(name)
+		if err != nil {
+			return fmt.Errorf("csv input: %w", err)
+		}
+		p.encoding = enc
+	}
+	
+	declared, err := parseColumns(config)
+	if err != nil {
+		return fmt.Errorf("csv input: %w", err)
+	}
+	p.declared = declared
+	
+	if config["null_values"] != nil {
+		nullValues, ok := toStrings(config["null_values"])
+		if !ok {
+			return fmt.Errorf("csv input: null_values must be a list of strings")
+		}
+		p.nullValues = nullValues
+	}
+	
+	if format, ok := config["date_format"].(string); ok {
+		p.dateFormat = format
+	}
+	
+	if format, ok := config["timestamp_format"].(string); ok {
+		p.timestampFormat = format
+	}
+	
+	if inferSchema, ok := config["infer_schema"].(bool); ok {
+		p.inferSchema = inferSchema
+	}
+	
+	if inferRows, ok := types.ToInt64(config["infer_rows"]); ok && inferRows > 0 {
+		p.inferRows = int(inferRows)
+	}
+	
+	if quotedNewlines, ok := config["quoted_newlines"].(bool); ok {
+		p.quotedNewlines = quotedNewlines
 	}
 	
 	// Initialize progress
@@ -68,76 +181,475 @@ func (p *CSVInputPlugin) Validate() error {
 	return nil
 }
 
-// Connect opens the CSV file and reads the header
+// Connect finds the files to read, checks that their headers match and
+// opens the first one
 func (p *CSVInputPlugin) Connect() error {
-	path, ok := p.config["path"].(string)
-	if !ok {
-		return fmt.Errorf("csv input: invalid path configuration")
+	// Shards are given their files by the input they were split from
+	if p.files == nil {
+		path, ok := p.config["path"].(string)
+		if !ok {
+			return fmt.Errorf("csv input: invalid path configuration")
+		}
+		
+		files, err := fileio.ListFiles(path, ".csv", p.recursive)
+		if err != nil {
+			return fmt.Errorf("csv input: failed to list files: %w", err)
+		}
+		p.files = files
+		
+		if p.hasHeader {
+			if err := p.readHeaders(); err != nil {
+				return err
+			}
+		}
+		
+		sample, err := p.sampleRows()
+		if err != nil {
+			return err
+		}
+		if err := p.buildSchema(sample); err != nil {
+			return err
+		}
+	}
+	p.connected = true
+	
+	if len(p.files) == 0 {
+		p.fileDone = true
+		return nil
+	}
+	if err := p.openFile(0, 0); err != nil {
+		return err
+	}
+	if p.byteRange != nil {
+		return p.seekRange()
+	}
+	return nil
+}
+
+// readHeaders reads the header of every file, so that files with a
+// different header are reported before anything is read. The first
+// header defines the schema.
+func (p *CSVInputPlugin) readHeaders() error {
+	for _, path := range p.files {
+		data, err := p.openData(path)
+		if err != nil {
+			return err
+		}
+		header, err := newRowReader(data, p.dialect, p.encoding, true).Read()
+		data.Close()
+		if err != nil {
+			return fmt.Errorf("csv input: failed to read header of %s: %w", path, err)
+		}
+		if err := p.checkHeader(path, header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkHeader checks that the header of a file matches the header of the
+// first file, taking it as the header if it is the first
+func (p *CSVInputPlugin) checkHeader(path string, header []string) error {
+	names := make([]string, len(header))
+	for i, name := range header {
+		names[i] = strings.TrimSpace(name)
+	}
+	
+	if p.header == nil {
+		p.header = names
+		p.headerFile = path
+		return nil
+	}
+	
+	if strings.Join(names, "\x00") != strings.Join(p.header, "\x00") {
+		return fmt.Errorf("csv input: header of %s (%s) differs from the header of %s (%s)",
+			path, strings.Join(names, ", "), p.headerFile, strings.Join(p.header, ", "))
+	}
+	return nil
+}
+
+// sampleRows reads the first rows of the files for schema inference. When
+// types are not inferred, the first row still gives the number of columns
+// of files without header.
+func (p *CSVInputPlugin) sampleRows() ([][]string, error) {
+	limit := p.inferRows
+	if !p.inferSchema {
+		limit = 1
+	}
+	
+	var sample [][]string
+	for _, path := range p.files {
+		if len(sample) >= limit {
+			break
+		}
+		data, err := p.openData(path)
+		if err != nil {
+			return nil, err
+		}
+		reader := newRowReader(data, p.dialect, p.encoding, true)
+		reader.FieldsPerRecord = -1
+		if p.hasHeader {
+			if _, err := reader.Read(); err != nil {
+				data.Close()
+				return nil, fmt.Errorf("csv input: failed to read header of %s: %w", path, err)
+			}
+		}
+		
+		for len(sample) < limit {
+			row, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			// Malformed rows are rejected when read, not sampled
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				continue
+			}
+			if err != nil {
+				data.Close()
+				return nil, fmt.Errorf("csv input: failed to sample rows of %s: %w", path, err)
+			}
+			sample = append(sample, row)
+		}
+		data.Close()
+	}
+	return sample, nil
+}
+
+// openData opens a file for reading its decompressed contents once
+func (p *CSVInputPlugin) openData(path string) (io.ReadCloser, error) {
+	codec, err := fileio.ParseCodec(p.compression, path)
+	if err != nil {
+		return nil, fmt.Errorf("csv input: %w", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("csv input: failed to open file: %w", err)
+	}
+	stream, err := fileio.NewReader(file, codec)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("csv input: failed to decompress %s: %w", path, err)
+	}
+	return &dataFile{ReadCloser: stream, file: file}, nil
+}
+
+// dataFile is the decompressed contents of a file. Closing it closes the
+// file as well.
+type dataFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (d *dataFile) Close() error {
+	d.ReadCloser.Close()
+	return d.file.Close()
+}
+
+// openFile opens files[index], skips its header and positions the reader
+// at offset, or at the first data row when offset is 0
+func (p *CSVInputPlugin) openFile(index int, offset int64) error {
+	if err := p.closeFile(); err != nil {
+		return fmt.Errorf("csv input: failed to close file: %w", err)
 	}
 	
+	path := p.files[index]
+	codec, err := fileio.ParseCodec(p.compression, path)
+	if err != nil {
+		return fmt.Errorf("csv input: %w", err)
+	}
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("csv input: failed to open file: %w", err)
 	}
 	p.file = file
+	p.codec = codec
+	p.path = path
+	p.fileIndex = index
+	p.fileDone = false
+	p.currentRow = 0
+	p.dataOffset = 0
+	if err := p.rewind(0); err != nil {
+		return err
+	}
 	
-	p.reader = csv.NewReader(file)
-	p.reader.Comma = p.delimiter
-	p.reader.TrimLeadingSpace = true
-	
-	// Read header if configured
 	if p.hasHeader {
 		header, err := p.reader.Read()
 		if err != nil {
-			return fmt.Errorf("csv input: failed to read header: %w", err)
+			return fmt.Errorf("csv input: failed to read header of %s: %w", path, err)
 		}
-		p.header = header
-		
-		// Build schema from header
-		columns := make([]types.Column, len(header))
-		for i, name := range header {
-			columns[i] = types.Column{
-				Name:     strings.TrimSpace(name),
-				DataType: types.DataTypeString, // Default to string, can be inferred
-				Nullable: true,
-			}
+		if err := p.checkHeader(path, header); err != nil {
+			return err
 		}
-		p.schema = &types.Schema{
-			Columns: columns,
+		p.dataOffset = p.reader.InputOffset()
+	}
+	
+	if offset > 0 {
+		return p.rewind(offset)
+	}
+	return nil
+}
+
+// rewind positions the reader at an offset of the decompressed contents of
+// the open file. Compressed files are decompressed again from the start,
+// up to the offset.
+func (p *CSVInputPlugin) rewind(offset int64) error {
+	if err := p.closeStream(); err != nil {
+		return fmt.Errorf("csv input: failed to close decompressor: %w", err)
+	}
+	if p.codec == fileio.CodecNone {
+		if _, err := p.file.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("csv input: failed to seek to offset %d of %s: %w", offset, p.path, err)
 		}
+		p.newReader(p.file, offset)
+		return nil
 	}
 	
+	if _, err := p.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("csv input: failed to seek to the start of %s: %w", p.path, err)
+	}
+	stream, err := fileio.NewReader(p.file, p.codec)
+	if err != nil {
+		return fmt.Errorf("csv input: failed to decompress %s: %w", p.path, err)
+	}
+	p.stream = stream
+	if _, err := io.CopyN(io.Discard, stream, offset); err != nil {
+		return fmt.Errorf("csv input: failed to skip to offset %d of %s: %w", offset, p.path, err)
+	}
+	p.newReader(stream, offset)
 	return nil
 }
 
-// ReadBatch reads a batch of records from the CSV file
+// nextFile opens the next file not read yet. It returns false when all
+// files have been read.
+func (p *CSVInputPlugin) nextFile() (bool, error) {
+	if p.path != "" {
+		p.markDone(p.path)
+		p.path = ""
+	}
+	if err := p.closeFile(); err != nil {
+		return false, fmt.Errorf("csv input: failed to close file: %w", err)
+	}
+	
+	// A byte range never goes past its file
+	if p.byteRange != nil {
+		return false, nil
+	}
+	
+	// Files that appeared before a resumed run may sort before the file it
+	// resumed in, so the first file not done is next
+	for index, path := range p.files {
+		if !p.done[path] {
+			return true, p.openFile(index, 0)
+		}
+	}
+	return false, nil
+}
+
+// markDone records a file as read to the end
+func (p *CSVInputPlugin) markDone(path string) {
+	if p.done == nil {
+		p.done = make(map[string]bool)
+	}
+	if !p.done[path] {
+		p.done[path] = true
+		p.doneFiles = append(p.doneFiles, path)
+	}
+}
+
+// Split divides the input into n shards: the files, grouped by a hash of
+// their path so that a file stays in the same shard when files are added,
+// or the byte ranges of a single file whose quoted fields hold no line
+// breaks. Files are not split into ranges smaller than 1 MiB, and
+// compressed files are not split at all.
+func (p *CSVInputPlugin) Split(n int) ([]types.InputShard, error) {
+	if !p.connected {
+		return nil, fmt.Errorf("csv input: not connected")
+	}
+	if len(p.files) > 1 {
+		return p.splitFiles(n)
+	}
+	// A file is read whole unless its rows can be found from any offset
+	if p.codec != fileio.CodecNone || p.quotedNewlines {
+		shard, err := p.newShard(p.files)
+		if err != nil {
+			return nil, err
+		}
+		return []types.InputShard{{ID: "0", Input: shard}}, nil
+	}
+	
+	info, err := os.Stat(p.files[0])
+	if err != nil {
+		return nil, fmt.Errorf("csv input: failed to stat file: %w", err)
+	}
+	size := info.Size() - p.dataOffset
+	if limit := size / minShardSize; int64(n) > limit {
+		n = int(limit)
+	}
+	if n < 1 {
+		n = 1
+	}
+	
+	width := size / int64(n)
+	shards := make([]types.InputShard, 0, n)
+	for i := 0; i < n; i++ {
+		r := &byteRange{start: p.dataOffset + int64(i)*width, end: info.Size()}
+		if i < n-1 {
+			r.end = r.start + width
+		}
+		
+		shard, err := p.newShard(p.files)
+		if err != nil {
+			return nil, err
+		}
+		shard.byteRange = r
+		
+		shards = append(shards, types.InputShard{ID: strconv.Itoa(i), Input: shard})
+	}
+	return shards, nil
+}
+
+// splitFiles divides the files into n shards
+func (p *CSVInputPlugin) splitFiles(n int) ([]types.InputShard, error) {
+	groups := make([][]string, n)
+	for _, path := range p.files {
+		hash := fnv.New32a()
+		hash.Write([]byte(path))
+		i := hash.Sum32() % uint32(n)
+		groups[i] = append(groups[i], path)
+	}
+	
+	shards := make([]types.InputShard, n)
+	for i, files := range groups {
+		if files == nil {
+			files = []string{}
+		}
+		shard, err := p.newShard(files)
+		if err != nil {
+			return nil, err
+		}
+		shards[i] = types.InputShard{ID: strconv.Itoa(i), Input: shard}
+	}
+	return shards, nil
+}
+
+// newShard creates a shard reading the given files with the header of
+// this input
+func (p *CSVInputPlugin) newShard(files []string) (*CSVInputPlugin, error) {
+	shard := &CSVInputPlugin{}
+	if err := shard.Initialize(p.config); err != nil {
+		return nil, err
+	}
+	shard.files = files
+	shard.header = p.header
+	shard.headerFile = p.headerFile
+	shard.schema = p.schema
+	shard.columns = p.columns
+	return shard, nil
+}
+
+// seekRange positions a shard at the first row starting in its byte range,
+// which follows the first line break before or at the range start
+func (p *CSVInputPlugin) seekRange() error {
+	start := p.byteRange.start
+	if start > p.dataOffset {
+		next, err := p.nextLineStart(start - 1)
+		if err != nil {
+			return fmt.Errorf("csv input: failed to find the first row at offset %d: %w", start, err)
+		}
+		start = next
+	}
+	
+	return p.rewind(start)
+}
+
+// nextLineStart returns the offset following the first line break at or
+// after offset, or the file size if there is none
+func (p *CSVInputPlugin) nextLineStart(offset int64) (int64, error) {
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := p.file.ReadAt(buf, offset)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return offset + int64(i) + 1, nil
+		}
+		offset += int64(n)
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// ReadBatch reads a batch of records. A batch holds rows of one file only.
 func (p *CSVInputPlugin) ReadBatch(batchSize int) (*types.DataBatch, error) {
-	if p.reader == nil {
+	if !p.connected {
 		return nil, fmt.Errorf("csv input: not connected")
 	}
 	
+	for {
+		if p.fileDone {
+			more, err := p.nextFile()
+			if err != nil {
+				return nil, err
+			}
+			if !more {
+				return nil, io.EOF
+			}
+		}
+		
+		records, rejected, err := p.readRows(batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 || len(rejected) > 0 {
+			return p.newBatch(records, rejected), nil
+		}
+	}
+}
+
+// readRows reads up to batchSize rows of the open file
+func (p *CSVInputPlugin) readRows(batchSize int) ([]types.Record, []types.RejectedRecord, error) {
 	var records []types.Record
+	var rejected []types.RejectedRecord
 	
 	for i := 0; i < batchSize; i++ {
+		// A shard stops at the first row starting after its range
+		offset := p.baseOffset + p.reader.InputOffset()
+		if p.byteRange != nil && offset >= p.byteRange.end {
+			p.fileDone = true
+			break
+		}
+		
 		row, err := p.reader.Read()
 		if err == io.EOF {
+			p.fileDone = true
 			break
 		}
+		
+		// Malformed rows are rejected, the rest of the file is still read
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rejected = append(rejected, p.rejectRow(row, offset, parseErr))
+			p.currentRow++
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("csv input: failed to read row %d: %w", p.currentRow, err)
+			return nil, nil, fmt.Errorf("csv input: failed to read row %d of %s: %w", p.currentRow, p.path, err)
 		}
 		
-		// Convert row to record
-		values := make([]interface{}, len(row))
-		for j, val := range row {
-			values[j] = p.inferValue(val)
+		// Rows with a value of the wrong type are rejected as well
+		values, err := p.convertRow(row)
+		if err != nil {
+			rejected = append(rejected, p.rejectRow(row, offset, err))
+			p.currentRow++
+			continue
 		}
 		
 		record := types.Record{
-			Values: values,
-			Metadata: map[string]string{
-				"row_number": strconv.Itoa(p.currentRow),
-			},
+			Values:   values,
+			Metadata: p.rowMetadata(offset),
 		}
 		
 		records = append(records, record)
@@ -145,20 +657,170 @@ func (p *CSVInputPlugin) ReadBatch(batchSize int) (*types.DataBatch, error) {
 		p.progress.ProcessedRecords++
 	}
 	
-	if len(records) == 0 {
-		return nil, io.EOF
+	return records, rejected, nil
+}
+
+// newBatch builds a batch of rows of the open file, with the checkpoint
+// after them
+func (p *CSVInputPlugin) newBatch(records []types.Record, rejected []types.RejectedRecord) *types.DataBatch {
+	position := map[string]interface{}{
+		"file":   p.path,
+		"offset": p.baseOffset + p.reader.InputOffset(),
+		"row":    p.currentRow,
+	}
+	if len(p.doneFiles) > 0 {
+		position["done_files"] = append([]string(nil), p.doneFiles...)
 	}
 	
-	batch := &types.DataBatch{
-		Schema:  *p.schema,
-		Records: records,
-		Metadata: map[string]string{
+	return &types.DataBatch{
+		Schema:     *p.schema,
+		Records:    records,
+		Rejected:   rejected,
+		Checkpoint: &types.Checkpoint{
+			Position: position,
+		},
+		Metadata:   map[string]string{
 			"source": "csv",
-			"file":   p.config["path"].(string),
+			"file":   p.path,
 		},
 	}
+}
+
+// Seek continues reading after the row of a checkpoint, skipping the files
+// read to the end. The checkpoint of a completed run is ignored, as every
+// run reads all files.
+func (p *CSVInputPlugin) Seek(checkpoint *types.Checkpoint) error {
+	if !p.connected {
+		return fmt.Errorf("csv input: not connected")
+	}
+	if checkpoint == nil || checkpoint.IsCompleted() {
+		return nil
+	}
+	
+	position, ok := checkpoint.Position.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("csv input: unexpected checkpoint position %T", checkpoint.Position)
+	}
+	offset, okOffset := types.ToInt64(position["offset"])
+	row, okRow := types.ToInt64(position["row"])
+	if !okOffset || !okRow {
+		return fmt.Errorf("csv input: invalid checkpoint position %v", position)
+	}
+	
+	doneFiles, ok := toStrings(position["done_files"])
+	if !ok {
+		return fmt.Errorf("csv input: invalid done files in checkpoint position %v", position)
+	}
+	p.doneFiles, p.done = nil, nil
+	for _, path := range doneFiles {
+		p.markDone(path)
+	}
+	
+	// Checkpoints written before multiple files were supported are in the
+	// first file
+	index := 0
+	if path, ok := position["file"].(string); ok {
+		index = -1
+		for i, file := range p.files {
+			if file == path {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("csv input: file %s of the checkpoint no longer matches the path", path)
+		}
+	}
+	if len(p.files) == 0 {
+		return fmt.Errorf("csv input: no file to resume")
+	}
+	
+	if err := p.openFile(index, offset); err != nil {
+		return err
+	}
+	p.currentRow = int(row)
+	p.progress.ProcessedRecords = row
+	
+	return nil
+}
+
+// newReader creates the CSV reader of the file contents r, positioned at
+// offset
+func (p *CSVInputPlugin) newReader(r io.Reader, offset int64) {
+	p.reader = newRowReader(r, p.dialect, p.encoding, offset == 0)
+	if p.columns != nil {
+		p.reader.FieldsPerRecord = len(p.columns)
+	}
+	p.baseOffset = offset
+}
+
+// convertRow converts the values of a row to the types of their columns
+func (p *CSVInputPlugin) convertRow(row []string) ([]interface{}, error) {
+	values := make([]interface{}, len(row))
+	for i, raw := range row {
+		value, err := p.convert(raw, &p.columns[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// rowMetadata returns the metadata of the row at offset. Rows read by a
+// shard of a single file carry their offset, as their number is only known
+// after reading the file up to the shard.
+func (p *CSVInputPlugin) rowMetadata(offset int64) map[string]string {
+	if p.byteRange != nil {
+		return map[string]string{
+			"file":   p.path,
+			"offset": strconv.FormatInt(offset, 10),
+		}
+	}
+	return map[string]string{
+		"file":       p.path,
+		"row_number": strconv.Itoa(p.currentRow),
+	}
+}
+
+// toStrings converts a checkpoint list of strings, which is []interface{}
+// after a JSON round trip. A missing list is empty.
+func toStrings(v interface{}) ([]string, bool) {
+	switch list := v.(type) {
+	case nil:
+		return nil, true
+	case []string:
+		return append([]string(nil), list...), true
+	case []interface{}:
+		strs := make([]string, len(list))
+		for i, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			strs[i] = s
+		}
+		return strs, true
+	default:
+		return nil, false
+	}
+}
+
+// rejectRow builds the rejected record for a malformed row or a row with a
+// value that does not convert to the type of its column
+func (p *CSVInputPlugin) rejectRow(row []string, offset int64, err error) types.RejectedRecord {
+	var values []interface{}
+	for _, val := range row {
+		values = append(values, val)
+	}
 	
-	return batch, nil
+	return types.RejectedRecord{
+		Record: types.Record{
+			Values:   values,
+			Metadata: p.rowMetadata(offset),
+		},
+		Error: err.Error(),
+	}
 }
 
 // HasNext checks if there are more records to read
@@ -172,10 +834,29 @@ func (p *CSVInputPlugin) GetProgress() *types.Progress {
 	return p.progress
 }
 
-// Close closes the CSV file
+// Close closes the open file. Calling Close more than once is a no-op.
 func (p *CSVInputPlugin) Close() error {
+	return p.closeFile()
+}
+
+// closeFile closes the open file, if any
+func (p *CSVInputPlugin) closeFile() error {
 	if p.file != nil {
-		return p.file.Close()
+		p.closeStream()
+		err := p.file.Close()
+		p.file = nil
+		p.reader = nil
+		return err
+	}
+	return nil
+}
+
+// closeStream closes the decompressor of the open file, if any
+func (p *CSVInputPlugin) closeStream() error {
+	if p.stream != nil {
+		err := p.stream.Close()
+		p.stream = nil
+		return err
 	}
 	return nil
 }
@@ -186,14 +867,24 @@ func (p *CSVInputPlugin) GetMetadata() types.PluginMetadata {
 		Name:        "csv",
 		Type:        types.PluginTypeInput,
 		Version:     "1.0.0",
-		Description: "CSV file input plugin",
+		Description: "CSV file input plugin reading one or many files",
 		DataSourceType: "file",
 		ConfigSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"path": map[string]interface{}{
 					"type":        "string",
-					"description": "Path to CSV file",
+					"description": "CSV file, directory or glob pattern, in which ** matches any number of directories",
+				},
+				"compression": map[string]interface{}{
+					"type":        "string",
+					"description": "Compression of the files: auto (by extension), none, gzip, zstd, bzip2 or snappy",
+					"default":     "auto",
+				},
+				"recursive": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Read the CSV files in the subdirectories of a directory path",
+					"default":     false,
 				},
 				"has_header": map[string]interface{}{
 					"type":        "boolean",
@@ -202,39 +893,62 @@ func (p *CSVInputPlugin) GetMetadata() types.PluginMetadata {
 				},
 				"delimiter": map[string]interface{}{
 					"type":        "string",
-					"description": "Field delimiter character",
+					"description": "Field delimiter character, \\t for tabs",
 					"default":     ",",
 				},
+				"quote": map[string]interface{}{
+					"type":        "string",
+					"description": "Quote character",
+					"default":     "\"",
+				},
+				"escape": map[string]interface{}{
+					"type":        "string",
+					"description": "Character escaping the next one; by default quotes are escaped by doubling them",
+				},
+				"comment": map[string]interface{}{
+					"type":        "string",
+					"description": "Character starting comment lines, which are skipped",
+				},
+				"encoding": map[string]interface{}{
+					"type":        "string",
+					"description": "File encoding: utf-8, gbk, gb18030, latin1 or windows-1252",
+					"default":     "utf-8",
+				},
+				"schema": map[string]interface{}{
+					"type":        "array",
+					"description": "Declared columns with name, type, date or timestamp format and nullable; positional in files without header",
+				},
+				"infer_schema": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Infer the types of undeclared columns from a sample of rows; otherwise they are strings",
+					"default":     true,
+				},
+				"infer_rows": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of rows sampled for schema inference",
+					"default":     defaultInferRows,
+				},
+				"quoted_newlines": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Quoted fields may hold line breaks; set to false to split a single file into byte ranges for parallel reads",
+					"default":     true,
+				},
+				"null_values": map[string]interface{}{
+					"type":        "array",
+					"description": "Values read as null, compared after trimming spaces",
+					"default":     []string{""},
+				},
+				"date_format": map[string]interface{}{
+					"type":        "string",
+					"description": "Go layout of dates, such as 02/01/2006",
+				},
+				"timestamp_format": map[string]interface{}{
+					"type":        "string",
+					"description": "Go layout of timestamps, such as 2006-01-02 15:04:05",
+				},
 			},
 			"required": []string{"path"},
 		},
 	}
 }
 
-// inferValue attempts to infer the type of a string value
-func (p *CSVInputPlugin) inferValue(val string) interface{} {
-	val = strings.TrimSpace(val)
-	
-	// Empty string
-	if val == "" {
-		return nil
-	}
-	
-	// Try integer
-	if intVal, err := strconv.ParseInt(val, 10, 64); err == nil {
-		return intVal
-	}
-	
-	// Try float
-	if floatVal, err := strconv.ParseFloat(val, 64); err == nil {
-		return floatVal
-	}
-	
-	// Try boolean
-	if boolVal, err := strconv.ParseBool(val); err == nil {
-		return boolVal
-	}
-	
-	// Default to string
-	return val
-}
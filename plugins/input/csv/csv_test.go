@@ -0,0 +1,262 @@
+package csv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/fileio"
+	"github.com/atlanssia/fustgo/internal/plugintest"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func newInput(t *testing.T, config map[string]interface{}) *CSVInputPlugin {
+	t.Helper()
+	p := &CSVInputPlugin{}
+	plugintest.StartInput(t, p, config)
+	return p
+}
+
+func TestCSVInputMultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{
+		"part-2.csv": "id,name\n4,d\n",
+		"part-1.csv": "id,name\n1,a\n2,b\n3,c\n",
+		"part-3.csv": "id,name\n",
+	})
+
+	p := newInput(t, map[string]interface{}{"path": filepath.Join(dir, "part-*.csv")})
+	records := plugintest.ReadAll(t, p, 2)
+
+	require.Len(t, records, 4)
+	for i, record := range records {
+		assert.Equal(t, int64(i+1), record.Values[0])
+	}
+	assert.Equal(t, filepath.Join(dir, "part-1.csv"), records[2].Metadata["file"])
+	assert.Equal(t, "2", records[2].Metadata["row_number"])
+	assert.Equal(t, filepath.Join(dir, "part-2.csv"), records[3].Metadata["file"])
+	assert.Equal(t, "0", records[3].Metadata["row_number"])
+}
+
+func TestCSVInputMultipleFilesResume(t *testing.T) {
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{
+		"a.csv": "id\n1\n2\n",
+		"b.csv": "id\n3\n4\n5\n",
+		"c.csv": "id\n6\n",
+	})
+	config := map[string]interface{}{"path": dir}
+
+	p := newInput(t, config)
+	var checkpoint *types.Checkpoint
+	for i := 0; i < 2; i++ {
+		batch, err := p.ReadBatch(2)
+		require.NoError(t, err)
+		checkpoint = batch.Checkpoint
+	}
+	position := checkpoint.Position.(map[string]interface{})
+	assert.Equal(t, filepath.Join(dir, "b.csv"), position["file"])
+	assert.Equal(t, []string{filepath.Join(dir, "a.csv")}, position["done_files"])
+
+	// A file sorting before the others arrives before the resumed run, which
+	// reads it after the file it resumed in
+	plugintest.WriteFiles(t, dir, map[string]string{"0.csv": "id\n0\n"})
+	resumed := newInput(t, config)
+	require.NoError(t, resumed.Seek(plugintest.RoundTrip(t, checkpoint)))
+
+	var ids []interface{}
+	for _, record := range plugintest.ReadAll(t, resumed, 10) {
+		ids = append(ids, record.Values[0])
+	}
+	assert.Equal(t, []interface{}{int64(5), int64(0), int64(6)}, ids)
+}
+
+func TestCSVInputHeaderMismatch(t *testing.T) {
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{
+		"a.csv": "id,name\n1,a\n",
+		"b.csv": "id, name\n2,b\n",
+		"c.csv": "id,email\n3,c\n",
+	})
+
+	p := &CSVInputPlugin{}
+	require.NoError(t, p.Initialize(map[string]interface{}{"path": dir}))
+	err := p.Connect()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "c.csv")
+}
+
+func TestCSVInputSplitFiles(t *testing.T) {
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{
+		"a.csv": "id\n1\n",
+		"b.csv": "id\n2\n",
+		"c.csv": "id\n3\n",
+		"d.csv": "id\n4\n",
+		"e.csv": "id\n5\n",
+	})
+
+	p := newInput(t, map[string]interface{}{"path": dir})
+	shards, err := p.Split(3)
+	require.NoError(t, err)
+	require.Len(t, shards, 3)
+
+	var ids []int64
+	for _, shard := range shards {
+		input := shard.Input.(*CSVInputPlugin)
+		require.NoError(t, input.Connect())
+		t.Cleanup(func() { input.Close() })
+		for _, record := range plugintest.ReadAll(t, input, 10) {
+			ids = append(ids, record.Values[0].(int64))
+		}
+	}
+	assert.ElementsMatch(t, []int64{1, 2, 3, 4, 5}, ids)
+}
+
+func TestCSVInputSplitRanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.csv")
+	var content strings.Builder
+	content.WriteString("id,name\n")
+	for i := 0; i < 150000; i++ {
+		fmt.Fprintf(&content, "%d,name-%d\n", i, i)
+	}
+	plugintest.WriteFiles(t, filepath.Dir(path), map[string]string{"big.csv": content.String()})
+
+	p := newInput(t, map[string]interface{}{"path": path, "quoted_newlines": false})
+	shards, err := p.Split(8)
+	require.NoError(t, err)
+	require.Len(t, shards, 2, "ranges are at least 1 MiB")
+
+	seen := make(map[int64]int)
+	for _, shard := range shards {
+		input := shard.Input.(*CSVInputPlugin)
+		require.NoError(t, input.Connect())
+		t.Cleanup(func() { input.Close() })
+		records := plugintest.ReadAll(t, input, 1000)
+		require.NotEmpty(t, records)
+		assert.NotEmpty(t, records[0].Metadata["offset"])
+		for _, record := range records {
+			seen[record.Values[0].(int64)]++
+		}
+	}
+
+	require.Len(t, seen, 150000)
+	for id, count := range seen {
+		require.Equal(t, 1, count, "row %d", id)
+	}
+}
+
+func TestCSVInputSplitQuotedNewlines(t *testing.T) {
+	// A quoted field with a line break is written between two halves of
+	// about 1 MiB, so that the middle of the file falls inside it
+	rows := func(from, size int) string {
+		var rows strings.Builder
+		for i := from; rows.Len() < size; i++ {
+			fmt.Fprintf(&rows, "%d,name-%d\n", i, i)
+		}
+		return rows.String()
+	}
+	head := "id,name\n" + rows(0, 1<<20)
+	field := `"` + strings.Repeat("a", 4096) + "\n" + strings.Repeat("b", 4096) + `"`
+	content := head + "-1," + field + "\n" + rows(100000, len(head))
+	fieldStart := int64(len(head) + len("-1,"))
+	fieldEnd := fieldStart + int64(len(field))
+
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{"big.csv": content})
+	path := filepath.Join(dir, "big.csv")
+
+	// Byte ranges would cut the field in two
+	p := newInput(t, map[string]interface{}{"path": path, "quoted_newlines": false})
+	shards, err := p.Split(2)
+	require.NoError(t, err)
+	require.Len(t, shards, 2)
+	boundary := shards[1].Input.(*CSVInputPlugin).byteRange.start
+	require.True(t, fieldStart < boundary && boundary < fieldEnd, "boundary %d", boundary)
+
+	// By default the file is read whole, and the field stays in its row
+	p = newInput(t, map[string]interface{}{"path": path})
+	shards, err = p.Split(2)
+	require.NoError(t, err)
+	require.Len(t, shards, 1)
+	input := shards[0].Input.(*CSVInputPlugin)
+	require.NoError(t, input.Connect())
+	t.Cleanup(func() { input.Close() })
+
+	var names []string
+	for _, record := range plugintest.ReadAll(t, input, 1000) {
+		if record.Values[0].(int64) == -1 {
+			names = append(names, record.Values[1].(string))
+		}
+	}
+	assert.Equal(t, []string{strings.Repeat("a", 4096) + "\n" + strings.Repeat("b", 4096)}, names)
+}
+
+// compress returns data compressed with a codec
+func compress(t *testing.T, codec fileio.Codec, data string) string {
+	var buf bytes.Buffer
+	w, err := fileio.NewWriter(&buf, codec)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.String()
+}
+
+func TestCSVInputCompressedFiles(t *testing.T) {
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{
+		"a.csv.gz":  compress(t, fileio.CodecGzip, "id,name\n1,a\n2,b\n"),
+		"b.csv.zst": compress(t, fileio.CodecZstd, "id,name\n3,c\n"),
+		"c.csv.sz":  compress(t, fileio.CodecSnappy, "id,name\n4,d\n"),
+		"d.csv":     "id,name\n5,e\n",
+		"e.txt.gz":  compress(t, fileio.CodecGzip, "not csv\n"),
+	})
+
+	p := newInput(t, map[string]interface{}{"path": dir})
+	var ids []interface{}
+	for _, record := range plugintest.ReadAll(t, p, 10) {
+		ids = append(ids, record.Values[0])
+	}
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(3), int64(4), int64(5)}, ids)
+
+	// The compression option applies to files without extension
+	plugintest.WriteFiles(t, dir, map[string]string{"export": compress(t, fileio.CodecGzip, "id\n6\n")})
+	p = newInput(t, map[string]interface{}{"path": filepath.Join(dir, "export"), "compression": "gzip"})
+	records := plugintest.ReadAll(t, p, 10)
+	require.Len(t, records, 1)
+	assert.Equal(t, int64(6), records[0].Values[0])
+}
+
+func TestCSVInputCompressedResume(t *testing.T) {
+	dir := t.TempDir()
+	var content strings.Builder
+	content.WriteString("id\n")
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&content, "%d\n", i)
+	}
+	plugintest.WriteFiles(t, dir, map[string]string{"data.csv.gz": compress(t, fileio.CodecGzip, content.String())})
+	config := map[string]interface{}{"path": filepath.Join(dir, "data.csv.gz")}
+
+	p := newInput(t, config)
+	batch, err := p.ReadBatch(4)
+	require.NoError(t, err)
+
+	// A compressed file is never split into byte ranges
+	shards, err := p.Split(4)
+	require.NoError(t, err)
+	assert.Len(t, shards, 1)
+
+	resumed := newInput(t, config)
+	require.NoError(t, resumed.Seek(plugintest.RoundTrip(t, batch.Checkpoint)))
+	records := plugintest.ReadAll(t, resumed, 10)
+	require.Len(t, records, 6)
+	assert.Equal(t, int64(4), records[0].Values[0])
+	assert.Equal(t, "4", records[0].Metadata["row_number"])
+}
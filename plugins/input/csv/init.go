@@ -2,9 +2,10 @@ package csv
 
 import (
 	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/pkg/types"
 )
 
 func init() {
 	// Register CSV input plugin
-	plugin.RegisterInput("csv", &CSVInputPlugin{})
+	plugin.RegisterInput("csv", func() types.InputPlugin { return &CSVInputPlugin{} })
 }
@@ -0,0 +1,349 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// dialect holds the characters that structure a CSV file. They are ASCII,
+// so that rows can be split on raw bytes before fields are decoded.
+type dialect struct {
+	comma   byte
+	quote   byte
+	escape  byte // Equal to quote when quotes are escaped by doubling them
+	comment byte // 0 when the file has no comment lines
+}
+
+// parseDialect reads the delimiter, quote, escape and comment characters
+// from the configuration
+func parseDialect(config map[string]interface{}) (dialect, error) {
+	d := dialect{comma: ',', quote: '"'}
+	options := []struct {
+		key    string
+		target *byte
+	}{
+		{"delimiter", &d.comma},
+		{"quote", &d.quote},
+		{"escape", &d.escape},
+		{"comment", &d.comment},
+	}
+	for _, option := range options {
+		value, ok := config[option.key].(string)
+		if !ok || value == "" {
+			continue
+		}
+		if value == `\t` {
+			value = "\t"
+		}
+		if len(value) != 1 || value[0] >= 0x80 || value[0] == '\n' || value[0] == '\r' {
+			return d, fmt.Errorf("%s must be a single ASCII character other than a line break, got %q", option.key, value)
+		}
+		*option.target = value[0]
+	}
+	if d.escape == 0 {
+		d.escape = d.quote
+	}
+
+	if d.comma == d.quote || d.comma == d.comment || d.quote == d.comment || (d.escape != d.quote && d.escape == d.comma) {
+		return d, fmt.Errorf("delimiter, quote, escape and comment characters must differ")
+	}
+	return d, nil
+}
+
+// encodings are the supported non-UTF-8 file encodings
+var encodings = map[string]encoding.Encoding{
+	"gbk":          simplifiedchinese.GBK,
+	"gb18030":      simplifiedchinese.GB18030,
+	"latin1":       charmap.ISO8859_1,
+	"latin-1":      charmap.ISO8859_1,
+	"iso-8859-1":   charmap.ISO8859_1,
+	"windows-1252": charmap.Windows1252,
+	"cp1252":       charmap.Windows1252,
+}
+
+// parseEncoding returns the encoding of the given name, or nil for UTF-8
+func parseEncoding(name string) (encoding.Encoding, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "utf-8" || name == "utf8" {
+		return nil, nil
+	}
+	enc, ok := encodings[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+	return enc, nil
+}
+
+// utf8BOM is skipped at the start of UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// rowReader reads CSV rows. Rows are split on the raw bytes of the file and
+// only their fields are decoded, so that offsets are file offsets whatever
+// the encoding. Errors in rows are *csv.ParseError, like those of
+// encoding/csv, and the reader continues with the next row.
+type rowReader struct {
+	r       *bufio.Reader
+	dialect dialect
+	decoder *encoding.Decoder // nil for UTF-8
+
+	// FieldsPerRecord is the number of fields each row must have. With 0 it
+	// is set by the first row; a negative value allows any number.
+	FieldsPerRecord int
+
+	offset int64 // Bytes read, relative to the start of the reader
+	line   int
+	field  bytes.Buffer
+}
+
+// newRowReader creates a reader of rows from r. atStart tells that r is at
+// the start of the file, where a UTF-8 byte order mark is skipped.
+func newRowReader(r io.Reader, d dialect, enc encoding.Encoding, atStart bool) *rowReader {
+	reader := &rowReader{r: bufio.NewReaderSize(r, 64<<10), dialect: d}
+	if enc != nil {
+		reader.decoder = enc.NewDecoder()
+	} else if atStart {
+		if bom, err := reader.r.Peek(len(utf8BOM)); err == nil && bytes.Equal(bom, utf8BOM) {
+			reader.r.Discard(len(utf8BOM))
+			reader.offset = int64(len(utf8BOM))
+		}
+	}
+	return reader
+}
+
+// InputOffset returns the offset after the last row read, relative to the
+// start of the reader
+func (r *rowReader) InputOffset() int64 {
+	return r.offset
+}
+
+// Read reads the next row. Empty lines and comment lines are skipped. It
+// returns io.EOF at the end of the input.
+func (r *rowReader) Read() ([]string, error) {
+	for {
+		b, err := r.peek()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case b == '\n' || b == '\r':
+			r.readByte()
+			if b == '\n' {
+				r.line++
+			}
+			continue
+		case r.dialect.comment != 0 && b == r.dialect.comment:
+			r.skipLine()
+			continue
+		}
+		return r.readRow()
+	}
+}
+
+// readRow reads the fields of a row, up to and including its line break
+func (r *rowReader) readRow() ([]string, error) {
+	startLine := r.line + 1
+	var fields []string
+	var rowErr error
+
+	for {
+		// Leading spaces of fields are trimmed
+		for {
+			b, err := r.peek()
+			if err != nil || (b != ' ' && b != '\t') || b == r.dialect.comma {
+				break
+			}
+			r.readByte()
+		}
+
+		r.field.Reset()
+		var end byte
+		var err error
+		if b, peekErr := r.peek(); peekErr == nil && b == r.dialect.quote {
+			r.readByte()
+			end, err = r.readQuoted()
+		} else {
+			end, err = r.readUnquoted()
+		}
+
+		value, decodeErr := r.decode(r.field.Bytes())
+		fields = append(fields, value)
+		if err == nil && decodeErr != nil {
+			err = decodeErr
+		}
+		if err != nil {
+			// Drop the rest of the row, so that reading goes on after it
+			if end != '\n' && end != 0 {
+				end = r.skipLine()
+			}
+			if end == '\n' {
+				r.line++
+			}
+			rowErr = &csv.ParseError{StartLine: startLine, Line: r.line + 1, Column: len(fields), Err: err}
+			return fields, rowErr
+		}
+
+		if end != r.dialect.comma {
+			if end == '\n' {
+				r.line++
+			}
+			break
+		}
+	}
+
+	if r.FieldsPerRecord == 0 {
+		r.FieldsPerRecord = len(fields)
+	} else if r.FieldsPerRecord > 0 && len(fields) != r.FieldsPerRecord {
+		return fields, &csv.ParseError{StartLine: startLine, Line: startLine, Column: 1, Err: csv.ErrFieldCount}
+	}
+	return fields, nil
+}
+
+// readUnquoted reads an unquoted field and returns the delimiter or line
+// break that ended it, or 0 at the end of the input
+func (r *rowReader) readUnquoted() (byte, error) {
+	d := r.dialect
+	for {
+		b, err := r.readByte()
+		if err == io.EOF {
+			r.trimCR()
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case b == d.comma:
+			return b, nil
+		case b == '\n':
+			r.trimCR()
+			return b, nil
+		case b == d.quote:
+			return b, csv.ErrBareQuote
+		case b == d.escape && d.escape != d.quote:
+			next, err := r.readByte()
+			if err != nil {
+				return 0, csv.ErrQuote
+			}
+			r.field.WriteByte(next)
+		default:
+			r.field.WriteByte(b)
+		}
+	}
+}
+
+// readQuoted reads a quoted field after its opening quote and returns the
+// delimiter or line break following it, or 0 at the end of the input
+func (r *rowReader) readQuoted() (byte, error) {
+	d := r.dialect
+	for {
+		b, err := r.readByte()
+		if err == io.EOF {
+			return 0, csv.ErrQuote
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case b == d.escape && d.escape != d.quote:
+			next, err := r.readByte()
+			if err != nil {
+				return 0, csv.ErrQuote
+			}
+			r.field.WriteByte(next)
+		case b == d.quote:
+			next, err := r.peek()
+			if err == nil && next == d.quote && d.escape == d.quote {
+				r.readByte()
+				r.field.WriteByte(b)
+				continue
+			}
+
+			// The closing quote ends the field
+			after, err := r.readByte()
+			if err == io.EOF {
+				return 0, nil
+			}
+			if after == '\r' {
+				if next, err := r.peek(); err == nil && next == '\n' {
+					after, _ = r.readByte()
+				}
+			}
+			if after == d.comma || after == '\n' {
+				return after, nil
+			}
+			return after, csv.ErrQuote
+		case b == '\n':
+			// Line breaks in quoted fields are kept as \n
+			r.line++
+			if n := r.field.Len(); n > 0 && r.field.Bytes()[n-1] == '\r' {
+				r.field.Truncate(n - 1)
+			}
+			r.field.WriteByte(b)
+		default:
+			r.field.WriteByte(b)
+		}
+	}
+}
+
+// skipLine reads up to and including the next line break, and returns it,
+// or 0 at the end of the input
+func (r *rowReader) skipLine() byte {
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return 0
+		}
+		if b == '\n' {
+			return b
+		}
+	}
+}
+
+// trimCR drops a carriage return ending the field before a line break
+func (r *rowReader) trimCR() {
+	if n := r.field.Len(); n > 0 && r.field.Bytes()[n-1] == '\r' {
+		r.field.Truncate(n - 1)
+	}
+}
+
+// decode converts the raw bytes of a field to a string
+func (r *rowReader) decode(raw []byte) (string, error) {
+	if r.decoder == nil {
+		return string(raw), nil
+	}
+	decoded, err := r.decoder.Bytes(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid encoding: %w", err)
+	}
+	return string(decoded), nil
+}
+
+func (r *rowReader) readByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.offset++
+	}
+	return b, err
+}
+
+func (r *rowReader) peek() (byte, error) {
+	b, err := r.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
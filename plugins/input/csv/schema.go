@@ -0,0 +1,307 @@
+package csv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atlanssia/fustgo/internal/expr"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// defaultInferRows is the number of rows sampled to infer column types
+const defaultInferRows = 1000
+
+// defaultDateFormat is the layout of dates recognized by schema inference
+// when no date_format is configured
+const defaultDateFormat = "2006-01-02"
+
+// column describes a column of the file and how its values are converted
+type column struct {
+	name     string
+	dataType types.DataType
+	format   string // Layout of dates and timestamps, empty for the defaults
+	nullable bool
+}
+
+// parseColumns reads the declared columns from the schema configuration
+func parseColumns(config map[string]interface{}) ([]column, error) {
+	list, ok := config["schema"].([]interface{})
+	if !ok {
+		if config["schema"] != nil {
+			return nil, fmt.Errorf("schema must be a list of columns")
+		}
+		return nil, nil
+	}
+
+	columns := make([]column, len(list))
+	for i, item := range list {
+		spec, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("schema column %d must be a map", i)
+		}
+		name, _ := spec["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("schema column %d has no name", i)
+		}
+
+		col := column{name: name, dataType: types.DataTypeString, nullable: true}
+		if typeName, ok := spec["type"].(string); ok {
+			dataType, err := expr.ParseDataType(typeName)
+			if err != nil {
+				return nil, fmt.Errorf("schema column %s: %w", name, err)
+			}
+			col.dataType = dataType
+		}
+		col.format, _ = spec["format"].(string)
+		if nullable, ok := spec["nullable"].(bool); ok {
+			col.nullable = nullable
+		}
+		columns[i] = col
+	}
+	return columns, nil
+}
+
+// buildSchema settles the columns of the file: named after the header or
+// the declared schema, typed as declared, and otherwise inferred from a
+// sample of rows
+func (p *CSVInputPlugin) buildSchema(sample [][]string) error {
+	var names []string
+	switch {
+	case p.hasHeader:
+		names = p.header
+	case len(p.declared) > 0:
+		for _, col := range p.declared {
+			names = append(names, col.name)
+		}
+	default:
+		width := 0
+		if len(sample) > 0 {
+			width = len(sample[0])
+		}
+		for i := 0; i < width; i++ {
+			names = append(names, fmt.Sprintf("column_%d", i+1))
+		}
+	}
+
+	declared := make(map[string]column, len(p.declared))
+	for _, col := range p.declared {
+		declared[col.name] = col
+	}
+
+	p.columns = make([]column, len(names))
+	for i, name := range names {
+		col, ok := declared[name]
+		if ok {
+			delete(declared, name)
+		} else {
+			col = column{name: name, dataType: types.DataTypeString, nullable: true}
+			if p.inferSchema {
+				col.dataType = p.inferType(sample, i)
+			}
+		}
+		if col.format == "" && col.dataType == types.DataTypeDate {
+			col.format = p.dateFormat
+			if col.format == "" && !ok {
+				col.format = defaultDateFormat
+			}
+		} else if col.format == "" && col.dataType == types.DataTypeTimestamp {
+			col.format = p.timestampFormat
+		}
+		p.columns[i] = col
+	}
+	for _, col := range p.declared {
+		if _, ok := declared[col.name]; ok {
+			return fmt.Errorf("csv input: schema column %s is not in the header", col.name)
+		}
+	}
+
+	columns := make([]types.Column, len(p.columns))
+	for i, col := range p.columns {
+		columns[i] = types.Column{
+			Name:     col.name,
+			DataType: col.dataType,
+			Nullable: col.nullable,
+		}
+	}
+	p.schema = &types.Schema{Columns: columns}
+	return nil
+}
+
+// inferType returns the narrowest type that all sampled values of a column
+// convert to without loss. Numbers with leading zeros, such as zip codes,
+// stay strings. A column without values in the sample is a string column.
+func (p *CSVInputPlugin) inferType(sample [][]string, index int) types.DataType {
+	candidates := []types.DataType{
+		types.DataTypeBigInt,
+		types.DataTypeDouble,
+		types.DataTypeBool,
+		types.DataTypeDate,
+		types.DataTypeTimestamp,
+	}
+	seen := false
+
+	for _, row := range sample {
+		if index >= len(row) {
+			continue
+		}
+		value := strings.TrimSpace(row[index])
+		if p.isNull(value) {
+			continue
+		}
+		seen = true
+
+		kept := candidates[:0]
+		for _, dataType := range candidates {
+			if p.looksLike(value, dataType) {
+				kept = append(kept, dataType)
+			}
+		}
+		candidates = kept
+		if len(candidates) == 0 {
+			return types.DataTypeString
+		}
+	}
+
+	if !seen {
+		return types.DataTypeString
+	}
+	return candidates[0]
+}
+
+// looksLike reports whether a value of an inferred column of the given
+// type could hold the value
+func (p *CSVInputPlugin) looksLike(value string, dataType types.DataType) bool {
+	switch dataType {
+	case types.DataTypeBigInt:
+		if !isDecimal(value, false) {
+			return false
+		}
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case types.DataTypeDouble:
+		if !isDecimal(value, true) {
+			return false
+		}
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case types.DataTypeBool:
+		return strings.EqualFold(value, "true") || strings.EqualFold(value, "false")
+	case types.DataTypeDate:
+		layout := p.dateFormat
+		if layout == "" {
+			layout = defaultDateFormat
+		}
+		_, err := time.Parse(layout, value)
+		return err == nil
+	case types.DataTypeTimestamp:
+		_, err := parseTime(value, p.timestampFormat, types.DataTypeTimestamp)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// isDecimal reports whether a value is a plain decimal number without
+// leading zeros. Integral numbers too large for int64 are not inferred as
+// numbers either, as they would lose digits.
+func isDecimal(value string, fraction bool) bool {
+	s := strings.TrimLeft(value, "+-")
+	if len(s) != len(value) && len(value)-len(s) > 1 {
+		return false
+	}
+	digits, dot, exponent := 0, false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && fraction && !dot && !exponent:
+			dot = true
+		case (c == 'e' || c == 'E') && fraction && !exponent && digits > 0:
+			exponent = true
+			if i+1 < len(s) && (s[i+1] == '+' || s[i+1] == '-') {
+				i++
+			}
+		default:
+			return false
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	// A leading zero is only kept before the decimal point
+	return !(len(s) > 1 && s[0] == '0' && s[1] != '.')
+}
+
+// isNull reports whether a trimmed value is one of the null tokens
+func (p *CSVInputPlugin) isNull(value string) bool {
+	for _, token := range p.nullValues {
+		if value == token {
+			return true
+		}
+	}
+	return false
+}
+
+// convert converts the raw value of a cell to the type of its column
+func (p *CSVInputPlugin) convert(raw string, col *column) (interface{}, error) {
+	value := strings.TrimSpace(raw)
+	if p.isNull(value) {
+		if !col.nullable {
+			return nil, fmt.Errorf("column %s is not nullable", col.name)
+		}
+		return nil, nil
+	}
+
+	switch col.dataType {
+	case types.DataTypeString:
+		return value, nil
+	case types.DataTypeInt, types.DataTypeBigInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: cannot convert %q to %s", col.name, value, col.dataType)
+		}
+		return n, nil
+	case types.DataTypeFloat, types.DataTypeDouble:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: cannot convert %q to %s", col.name, value, col.dataType)
+		}
+		return f, nil
+	case types.DataTypeDate, types.DataTypeTimestamp:
+		t, err := parseTime(value, col.format, col.dataType)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.name, err)
+		}
+		return t, nil
+	default:
+		converted, err := expr.Cast(value, col.dataType)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.name, err)
+		}
+		return converted, nil
+	}
+}
+
+// parseTime parses a date or timestamp with the given layout, or with the
+// layouts of expressions when it is empty
+func parseTime(value, layout string, dataType types.DataType) (time.Time, error) {
+	if layout == "" {
+		converted, err := expr.Cast(value, dataType)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return converted.(time.Time), nil
+	}
+
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q with layout %q", value, layout)
+	}
+	if dataType == types.DataTypeDate {
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return t, nil
+}
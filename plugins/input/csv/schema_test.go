@@ -0,0 +1,224 @@
+package csv
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	"github.com/atlanssia/fustgo/internal/plugintest"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func columnTypes(schema *types.Schema) map[string]types.DataType {
+	dataTypes := make(map[string]types.DataType)
+	for _, col := range schema.Columns {
+		dataTypes[col.Name] = col.DataType
+	}
+	return dataTypes
+}
+
+func TestCSVInputInferSchema(t *testing.T) {
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{
+		"data.csv": "id,zip,price,active,day,note,empty\n" +
+			"1,00123,1.5,true,2024-01-02,x,\n" +
+			"2,10001,2,false,2024-03-04,7,\n" +
+			"3,,-0.25,,2024-05-06,y,\n",
+	})
+
+	p := newInput(t, map[string]interface{}{"path": filepath.Join(dir, "data.csv")})
+	assert.Equal(t, map[string]types.DataType{
+		"id":     types.DataTypeBigInt,
+		"zip":    types.DataTypeString,
+		"price":  types.DataTypeDouble,
+		"active": types.DataTypeBool,
+		"day":    types.DataTypeDate,
+		"note":   types.DataTypeString,
+		"empty":  types.DataTypeString,
+	}, columnTypes(p.schema))
+
+	records := plugintest.ReadAll(t, p, 10)
+	require.Len(t, records, 3)
+	assert.Equal(t, []interface{}{int64(1), "00123", 1.5, true, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "x", nil}, records[0].Values)
+	assert.Equal(t, "7", records[1].Values[5])
+	assert.Nil(t, records[2].Values[1])
+	assert.Nil(t, records[2].Values[3])
+}
+
+func TestCSVInputDeclaredSchema(t *testing.T) {
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{
+		"data.csv": "id,zip,born,seen\n" +
+			"1,00123,02/01/2006,2024-01-02 03:04:05\n" +
+			"2,abc,03/01/2006,2024-01-02 03:04:06\n" +
+			"NA,10001,04/01/2006,2024-01-02 03:04:07\n",
+	})
+
+	p := newInput(t, map[string]interface{}{
+		"path": filepath.Join(dir, "data.csv"),
+		"schema": []interface{}{
+			map[string]interface{}{"name": "zip", "type": "int", "nullable": false},
+			map[string]interface{}{"name": "born", "type": "date", "format": "02/01/2006"},
+		},
+		"null_values":      []interface{}{"", "NA"},
+		"timestamp_format": "2006-01-02 15:04:05",
+	})
+	assert.Equal(t, map[string]types.DataType{
+		"id":   types.DataTypeBigInt,
+		"zip":  types.DataTypeInt,
+		"born": types.DataTypeDate,
+		"seen": types.DataTypeTimestamp,
+	}, columnTypes(p.schema))
+
+	batch, err := p.ReadBatch(10)
+	require.NoError(t, err)
+	require.Len(t, batch.Records, 2)
+	assert.Equal(t, []interface{}{
+		int64(1),
+		int64(123),
+		time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}, batch.Records[0].Values)
+	assert.Nil(t, batch.Records[1].Values[0])
+
+	// A value that does not convert rejects its row
+	require.Len(t, batch.Rejected, 1)
+	assert.Equal(t, []interface{}{"2", "abc", "03/01/2006", "2024-01-02 03:04:06"}, batch.Rejected[0].Record.Values)
+	assert.Contains(t, batch.Rejected[0].Error, `column zip: cannot convert "abc" to INT`)
+}
+
+func TestCSVInputSchemaErrors(t *testing.T) {
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{"data.csv": "id\n1\n"})
+	path := filepath.Join(dir, "data.csv")
+
+	p := &CSVInputPlugin{}
+	require.NoError(t, p.Initialize(map[string]interface{}{
+		"path":   path,
+		"schema": []interface{}{map[string]interface{}{"name": "missing"}},
+	}))
+	assert.ErrorContains(t, p.Connect(), "schema column missing is not in the header")
+
+	for _, config := range []map[string]interface{}{
+		{"path": path, "schema": []interface{}{map[string]interface{}{"name": "id", "type": "blob"}}},
+		{"path": path, "encoding": "ebcdic"},
+		{"path": path, "delimiter": ";;"},
+		{"path": path, "quote": ","},
+	} {
+		assert.Error(t, (&CSVInputPlugin{}).Initialize(config), "%v", config)
+	}
+}
+
+func TestCSVInputWithoutHeader(t *testing.T) {
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{"data.csv": "1,a\n2,b\n3,c,extra\n"})
+	path := filepath.Join(dir, "data.csv")
+
+	// Columns are numbered, and inferred
+	p := newInput(t, map[string]interface{}{"path": path, "has_header": false})
+	assert.Equal(t, map[string]types.DataType{
+		"column_1": types.DataTypeBigInt,
+		"column_2": types.DataTypeString,
+	}, columnTypes(p.schema))
+
+	batch, err := p.ReadBatch(10)
+	require.NoError(t, err)
+	require.Len(t, batch.Records, 2)
+	assert.Equal(t, []interface{}{int64(1), "a"}, batch.Records[0].Values)
+	assert.Len(t, batch.Rejected, 1)
+
+	// Or named by the declared schema
+	p = newInput(t, map[string]interface{}{
+		"path":       path,
+		"has_header": false,
+		"schema": []interface{}{
+			map[string]interface{}{"name": "id", "type": "string"},
+			map[string]interface{}{"name": "name"},
+		},
+	})
+	assert.Equal(t, "id", p.schema.Columns[0].Name)
+	records := plugintest.ReadAll(t, p, 10)
+	require.Len(t, records, 2)
+	assert.Equal(t, []interface{}{"2", "b"}, records[1].Values)
+}
+
+func TestCSVInputDialect(t *testing.T) {
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{
+		"data.tsv": "# exported data\n" +
+			"id\tname\n" +
+			"1\t'it\\'s'\n" +
+			"# skipped\n" +
+			"2\ta\\\tb\n" +
+			"3\t'multi\nline'\n",
+	})
+
+	p := newInput(t, map[string]interface{}{
+		"path":      filepath.Join(dir, "data.tsv"),
+		"delimiter": `\t`,
+		"quote":     "'",
+		"escape":    `\`,
+		"comment":   "#",
+	})
+	records := plugintest.ReadAll(t, p, 10)
+	require.Len(t, records, 3)
+	assert.Equal(t, "it's", records[0].Values[1])
+	assert.Equal(t, "a\tb", records[1].Values[1])
+	assert.Equal(t, "multi\nline", records[2].Values[1])
+}
+
+func TestCSVInputEncodings(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String("编号,名字\n1,张三\n2,李四\n")
+	require.NoError(t, err)
+	latin1, err := charmap.ISO8859_1.NewEncoder().String("id,city\n1,Zürich\n2,Besançon\n")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	plugintest.WriteFiles(t, dir, map[string]string{
+		"gbk.csv":    gbk,
+		"latin1.csv": latin1,
+		"bom.csv":    "\xEF\xBB\xBFid,name\n1,a\n",
+	})
+
+	p := newInput(t, map[string]interface{}{"path": filepath.Join(dir, "gbk.csv"), "encoding": "GBK"})
+	assert.Equal(t, "名字", p.schema.Columns[1].Name)
+	batch, err := p.ReadBatch(1)
+	require.NoError(t, err)
+	assert.Equal(t, "张三", batch.Records[0].Values[1])
+
+	// Offsets are those of the encoded file, so reading resumes at the
+	// next row
+	resumed := newInput(t, map[string]interface{}{"path": filepath.Join(dir, "gbk.csv"), "encoding": "GBK"})
+	require.NoError(t, resumed.Seek(plugintest.RoundTrip(t, batch.Checkpoint)))
+	records := plugintest.ReadAll(t, resumed, 10)
+	require.Len(t, records, 1)
+	assert.Equal(t, "李四", records[0].Values[1])
+
+	p = newInput(t, map[string]interface{}{"path": filepath.Join(dir, "latin1.csv"), "encoding": "latin1"})
+	records = plugintest.ReadAll(t, p, 10)
+	require.Len(t, records, 2)
+	assert.Equal(t, "Zürich", records[0].Values[1])
+	assert.Equal(t, "Besançon", records[1].Values[1])
+
+	// The byte order mark of UTF-8 files is not part of the first column
+	p = newInput(t, map[string]interface{}{"path": filepath.Join(dir, "bom.csv")})
+	assert.Equal(t, "id", p.schema.Columns[0].Name)
+}
+
+func TestIsDecimal(t *testing.T) {
+	for value, want := range map[string]bool{
+		"0": true, "-12": true, "+7": true, "00123": false, "1e5": false, "": false, "--1": false,
+	} {
+		assert.Equal(t, want, isDecimal(value, false), value)
+	}
+	for value, want := range map[string]bool{
+		"0.5": true, "-1.25": true, "1e5": true, "2.5E-3": true, "01.5": false, ".": false, "1.2.3": false, "NaN": false,
+	} {
+		assert.Equal(t, want, isDecimal(value, true), value)
+	}
+}
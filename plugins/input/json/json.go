@@ -0,0 +1,544 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/atlanssia/fustgo/internal/expr"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+const (
+	formatAuto   = "auto"
+	formatNDJSON = "ndjson"
+	formatArray  = "array"
+
+	defaultSampleSize = 100
+)
+
+// JSONInputPlugin reads records from NDJSON files or files holding a
+// top-level array of objects. Files are decoded as a stream, so only the
+// schema sample is held in memory.
+type JSONInputPlugin struct {
+	config     map[string]interface{}
+	path       string
+	format     string
+	flatten    bool
+	separator  string
+	sampleSize int
+
+	file     *os.File
+	decoder  *json.Decoder
+	inArray  bool
+	done     bool
+	pending  [][]field // Records read while sampling the schema
+	schema   *types.Schema
+	index    map[string]int
+	record   int
+	progress *types.Progress
+}
+
+// field is a decoded top-level (or flattened) object member
+type field struct {
+	name  string
+	value interface{}
+	json  bool // Value is a nested object or array kept as JSON text
+}
+
+// Name returns the plugin name
+func (p *JSONInputPlugin) Name() string {
+	return "json"
+}
+
+// Type returns the plugin type
+func (p *JSONInputPlugin) Type() types.PluginType {
+	return types.PluginTypeInput
+}
+
+// Initialize initializes the JSON input plugin
+func (p *JSONInputPlugin) Initialize(config map[string]interface{}) error {
+	p.config = config
+	p.format = formatAuto
+	p.separator = "."
+	p.sampleSize = defaultSampleSize
+	p.record = 0
+
+	// Parse configuration
+	p.path, _ = config["path"].(string)
+
+	if format, ok := config["format"].(string); ok && format != "" {
+		switch format {
+		case formatAuto, formatNDJSON, formatArray:
+			p.format = format
+		default:
+			return fmt.Errorf("json input: invalid format '%s', must be 'auto', 'ndjson' or 'array'", format)
+		}
+	}
+
+	if flatten, ok := config["flatten"].(bool); ok {
+		p.flatten = flatten
+	}
+
+	if separator, ok := config["flatten_separator"].(string); ok && separator != "" {
+		p.separator = separator
+	}
+
+	if sampleSize, ok := types.ToInt64(config["sample_size"]); ok && sampleSize > 0 {
+		p.sampleSize = int(sampleSize)
+	}
+
+	// Initialize progress
+	p.progress = &types.Progress{
+		TotalRecords:     0,
+		ProcessedRecords: 0,
+	}
+
+	return nil
+}
+
+// Validate validates the configuration
+func (p *JSONInputPlugin) Validate() error {
+	if p.path == "" {
+		return fmt.Errorf("json input: path is required")
+	}
+	return nil
+}
+
+// Connect opens the file and infers the schema from a sample of records
+func (p *JSONInputPlugin) Connect() error {
+	if p.path == "" {
+		return fmt.Errorf("json input: invalid path configuration")
+	}
+
+	file, err := os.Open(p.path)
+	if err != nil {
+		return fmt.Errorf("json input: failed to open file: %w", err)
+	}
+	p.file = file
+
+	reader := bufio.NewReader(file)
+	format := p.format
+	if format == formatAuto {
+		format, err = detectFormat(reader)
+		if err != nil {
+			return fmt.Errorf("json input: %w", err)
+		}
+	}
+
+	p.decoder = json.NewDecoder(reader)
+	p.decoder.UseNumber()
+
+	if format == formatArray {
+		tok, err := p.decoder.Token()
+		if err == io.EOF {
+			p.done = true
+		} else if err != nil {
+			return fmt.Errorf("json input: failed to read array start: %w", err)
+		} else if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return fmt.Errorf("json input: expected a top-level array")
+		}
+		p.inArray = true
+	}
+
+	// Read a sample of records to infer the schema
+	for len(p.pending) < p.sampleSize {
+		fields, err := p.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		p.pending = append(p.pending, fields)
+	}
+
+	p.schema = inferSchema(p.pending)
+	p.index = make(map[string]int, len(p.schema.Columns))
+	for i, col := range p.schema.Columns {
+		p.index[col.Name] = i
+	}
+
+	return nil
+}
+
+// ReadBatch reads a batch of records from the JSON file. A record with keys
+// that were not in the schema sample adds them to the schema as nullable
+// columns and starts a new batch, so every batch holds records of a single
+// schema and the schema policy of the pipeline decides about the new
+// columns.
+func (p *JSONInputPlugin) ReadBatch(batchSize int) (*types.DataBatch, error) {
+	if p.decoder == nil {
+		return nil, fmt.Errorf("json input: not connected")
+	}
+
+	var records []types.Record
+
+	for len(records) < batchSize {
+		var fields []field
+		if len(p.pending) > 0 {
+			fields = p.pending[0]
+			p.pending = p.pending[1:]
+		} else {
+			var err error
+			fields, err = p.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if unknown := p.unknownFields(fields); len(unknown) > 0 {
+			if len(records) > 0 {
+				// The record goes first in the next batch
+				p.pending = append([][]field{fields}, p.pending...)
+				break
+			}
+			p.addColumns(unknown)
+		}
+
+		records = append(records, types.Record{
+			Values: p.toValues(fields),
+			Metadata: map[string]string{
+				"record_number": strconv.Itoa(p.record),
+			},
+		})
+		p.record++
+		p.progress.ProcessedRecords++
+	}
+
+	if len(records) == 0 {
+		return nil, io.EOF
+	}
+
+	batch := &types.DataBatch{
+		Schema:  *p.schema,
+		Records: records,
+		Metadata: map[string]string{
+			"source": "json",
+			"file":   p.path,
+		},
+	}
+
+	return batch, nil
+}
+
+// HasNext checks if there are more records to read
+func (p *JSONInputPlugin) HasNext() bool {
+	return len(p.pending) > 0 || !p.done
+}
+
+// GetProgress returns the current reading progress
+func (p *JSONInputPlugin) GetProgress() *types.Progress {
+	return p.progress
+}
+
+// Close closes the JSON file. Calling Close more than once is a no-op.
+func (p *JSONInputPlugin) Close() error {
+	if p.file != nil {
+		err := p.file.Close()
+		p.file = nil
+		p.decoder = nil
+		p.pending = nil
+		return err
+	}
+	return nil
+}
+
+// GetMetadata returns plugin metadata
+func (p *JSONInputPlugin) GetMetadata() types.PluginMetadata {
+	return types.PluginMetadata{
+		Name:           "json",
+		Type:           types.PluginTypeInput,
+		Version:        "1.0.0",
+		Description:    "JSON Lines and JSON array file input plugin",
+		DataSourceType: "file",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to JSON file",
+				},
+				"format": map[string]interface{}{
+					"type":        "string",
+					"description": "File format: 'ndjson' (one object per line), 'array' (top-level array) or 'auto'",
+					"enum":        []string{formatAuto, formatNDJSON, formatArray},
+					"default":     formatAuto,
+				},
+				"flatten": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Flatten nested objects into columns; otherwise they are kept as JSON columns",
+					"default":     false,
+				},
+				"flatten_separator": map[string]interface{}{
+					"type":        "string",
+					"description": "Separator between parent and child names of flattened columns",
+					"default":     ".",
+				},
+				"sample_size": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of records used to infer the schema; later records with other keys add columns",
+					"default":     defaultSampleSize,
+				},
+			},
+			"required": []string{"path"},
+		},
+	}
+}
+
+// next decodes the next object from the stream
+func (p *JSONInputPlugin) next() ([]field, error) {
+	if p.done {
+		return nil, io.EOF
+	}
+
+	if p.inArray && !p.decoder.More() {
+		// Consume the closing bracket
+		if _, err := p.decoder.Token(); err != nil {
+			return nil, fmt.Errorf("json input: failed to read array end: %w", err)
+		}
+		p.done = true
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := p.decoder.Decode(&raw); err != nil {
+		if err == io.EOF {
+			p.done = true
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("json input: failed to decode record %d: %w", p.record+len(p.pending), err)
+	}
+
+	fields, err := decodeObject(raw, p.flatten, p.separator)
+	if err != nil {
+		return nil, fmt.Errorf("json input: record %d: %w", p.record+len(p.pending), err)
+	}
+	return fields, nil
+}
+
+// toValues orders the fields of a record by the schema and converts values
+// to the column type when possible
+func (p *JSONInputPlugin) toValues(fields []field) []interface{} {
+	values := make([]interface{}, len(p.schema.Columns))
+	for _, f := range fields {
+		idx := p.index[f.name]
+		values[idx] = f.value
+		if converted, err := expr.Cast(f.value, p.schema.Columns[idx].DataType); err == nil {
+			values[idx] = converted
+		}
+	}
+	return values
+}
+
+// unknownFields returns the fields of a record whose keys are not in the
+// schema
+func (p *JSONInputPlugin) unknownFields(fields []field) []field {
+	var unknown []field
+	for _, f := range fields {
+		if _, ok := p.index[f.name]; !ok {
+			unknown = append(unknown, f)
+		}
+	}
+	return unknown
+}
+
+// addColumns appends columns for new keys to the schema, typed by their
+// values. Batches already read keep the schema they were read with.
+func (p *JSONInputPlugin) addColumns(fields []field) {
+	schema := &types.Schema{
+		Columns:     append([]types.Column(nil), p.schema.Columns...),
+		PrimaryKeys: p.schema.PrimaryKeys,
+	}
+	for _, col := range inferSchema([][]field{fields}).Columns {
+		p.index[col.Name] = len(schema.Columns)
+		schema.Columns = append(schema.Columns, col)
+	}
+	p.schema = schema
+}
+
+// detectFormat peeks at the first non-whitespace byte to tell a top-level
+// array from NDJSON
+func detectFormat(reader *bufio.Reader) (string, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return formatNDJSON, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case 0xEF:
+			// UTF-8 byte order mark
+			if _, err := reader.Discard(2); err != nil {
+				return "", fmt.Errorf("failed to read file: %w", err)
+			}
+			continue
+		}
+
+		if err := reader.UnreadByte(); err != nil {
+			return "", err
+		}
+		if b == '[' {
+			return formatArray, nil
+		}
+		return formatNDJSON, nil
+	}
+}
+
+// decodeObject decodes a JSON object into its members, preserving key
+// order. Nested objects are flattened when requested; otherwise nested
+// objects and arrays are kept as JSON text.
+func decodeObject(raw json.RawMessage, flatten bool, separator string) ([]field, error) {
+	var fields []field
+	if err := appendObject(&fields, raw, "", flatten, separator); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func appendObject(fields *[]field, raw json.RawMessage, prefix string, flatten bool, separator string) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a JSON object")
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name := prefix + tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+
+		switch firstByte(value) {
+		case '{':
+			if flatten {
+				if err := appendObject(fields, value, name+separator, flatten, separator); err != nil {
+					return err
+				}
+				continue
+			}
+			fallthrough
+		case '[':
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, value); err != nil {
+				return err
+			}
+			*fields = append(*fields, field{name: name, value: compact.String(), json: true})
+		default:
+			scalar, err := decodeScalar(value)
+			if err != nil {
+				return err
+			}
+			*fields = append(*fields, field{name: name, value: scalar})
+		}
+	}
+
+	return nil
+}
+
+// decodeScalar decodes a JSON scalar to nil, int64, float64, string or bool
+func decodeScalar(raw json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+	return v, nil
+}
+
+func firstByte(raw json.RawMessage) byte {
+	for _, b := range raw {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b
+	}
+	return 0
+}
+
+// inferSchema builds a schema from sampled records. Columns appear in the
+// order their keys were first seen. A column holding both integers and
+// floats becomes a double; any other mix of types becomes a string.
+func inferSchema(sample [][]field) *types.Schema {
+	schema := &types.Schema{}
+	index := make(map[string]int)
+
+	for _, fields := range sample {
+		for _, f := range fields {
+			dataType := types.DataTypeJSON
+			if !f.json {
+				dataType = expr.ValueType(f.value)
+			}
+
+			idx, ok := index[f.name]
+			if !ok {
+				index[f.name] = len(schema.Columns)
+				schema.Columns = append(schema.Columns, types.Column{
+					Name:     f.name,
+					DataType: dataType,
+					Nullable: true,
+				})
+				continue
+			}
+
+			col := &schema.Columns[idx]
+			col.DataType = mergeTypes(col.DataType, dataType)
+		}
+	}
+
+	// Columns that were always null default to strings
+	for i := range schema.Columns {
+		if schema.Columns[i].DataType == types.DataTypeUnknown {
+			schema.Columns[i].DataType = types.DataTypeString
+		}
+	}
+
+	return schema
+}
+
+// mergeTypes widens a column type to accommodate another observed type
+func mergeTypes(current, observed types.DataType) types.DataType {
+	switch {
+	case observed == types.DataTypeUnknown || current == observed:
+		return current
+	case current == types.DataTypeUnknown:
+		return observed
+	case current == types.DataTypeBigInt && observed == types.DataTypeDouble,
+		current == types.DataTypeDouble && observed == types.DataTypeBigInt:
+		return types.DataTypeDouble
+	default:
+		return types.DataTypeString
+	}
+}
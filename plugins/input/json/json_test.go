@@ -0,0 +1,169 @@
+package json
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/plugintest"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func newInput(t *testing.T, data string, config map[string]interface{}) *JSONInputPlugin {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.json")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	config["path"] = path
+	p := &JSONInputPlugin{}
+	plugintest.StartInput(t, p, config)
+	return p
+}
+
+func values(records []types.Record) [][]interface{} {
+	var rows [][]interface{}
+	for _, record := range records {
+		rows = append(rows, record.Values)
+	}
+	return rows
+}
+
+func TestJSONInputNDJSON(t *testing.T) {
+	p := newInput(t, `{"id": 1, "name": "a", "tags": ["x"], "address": {"city": "Oslo"}}
+
+{"id": 2, "name": null, "score": 1.5, "active": true}
+`, nil)
+
+	assert.Equal(t, []types.Column{
+		{Name: "id", DataType: types.DataTypeBigInt, Nullable: true},
+		{Name: "name", DataType: types.DataTypeString, Nullable: true},
+		{Name: "tags", DataType: types.DataTypeJSON, Nullable: true},
+		{Name: "address", DataType: types.DataTypeJSON, Nullable: true},
+		{Name: "score", DataType: types.DataTypeDouble, Nullable: true},
+		{Name: "active", DataType: types.DataTypeBool, Nullable: true},
+	}, p.schema.Columns)
+
+	records := plugintest.ReadAll(t, p, 1)
+	assert.Equal(t, [][]interface{}{
+		{int64(1), "a", `["x"]`, `{"city":"Oslo"}`, nil, nil},
+		{int64(2), nil, nil, nil, 1.5, true},
+	}, values(records))
+	assert.Equal(t, "1", records[1].Metadata["record_number"])
+	assert.Equal(t, int64(2), p.GetProgress().ProcessedRecords)
+}
+
+func TestJSONInputArray(t *testing.T) {
+	data := "\xef\xbb\xbf [\n" +
+		`{"id": 1, "address": {"city": "Oslo", "geo": {"lat": 59.9}}},` +
+		`{"id": 2, "address": {"city": "Rome"}}` + "\n]\n"
+
+	// The format is detected from the first byte after the byte order mark
+	p := newInput(t, data, map[string]interface{}{"flatten": true, "flatten_separator": "_"})
+	records := plugintest.ReadAll(t, p, 10)
+	assert.Equal(t, []types.Column{
+		{Name: "id", DataType: types.DataTypeBigInt, Nullable: true},
+		{Name: "address_city", DataType: types.DataTypeString, Nullable: true},
+		{Name: "address_geo_lat", DataType: types.DataTypeDouble, Nullable: true},
+	}, p.schema.Columns)
+	assert.Equal(t, [][]interface{}{
+		{int64(1), "Oslo", 59.9},
+		{int64(2), "Rome", nil},
+	}, values(records))
+
+	// An empty array holds no records
+	p = newInput(t, "[]", map[string]interface{}{"format": "array"})
+	records = plugintest.ReadAll(t, p, 10)
+	assert.Empty(t, records)
+
+	// NDJSON is not an array
+	p = &JSONInputPlugin{}
+	path := filepath.Join(t.TempDir(), "input.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"id": 1}`), 0644))
+	require.NoError(t, p.Initialize(map[string]interface{}{"path": path, "format": "array"}))
+	assert.Error(t, p.Connect())
+	p.Close()
+}
+
+func TestJSONInputSchemaSample(t *testing.T) {
+	data := `{"id": 1, "amount": 2, "code": 7, "note": null}
+{"id": 2, "amount": 2.5, "code": "x"}
+{"id": 3, "amount": 4, "code": 8, "extra": true, "other": 1}
+{"id": 4, "amount": 5}
+`
+	// Configurations decoded from JSON hold float64 numbers
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"sample_size": 2}`), &config))
+	p := newInput(t, data, config)
+	assert.Equal(t, 2, p.sampleSize)
+
+	// Integers and floats make a double, other mixes a string, and columns
+	// that were always null default to strings
+	assert.Equal(t, []types.Column{
+		{Name: "id", DataType: types.DataTypeBigInt, Nullable: true},
+		{Name: "amount", DataType: types.DataTypeDouble, Nullable: true},
+		{Name: "code", DataType: types.DataTypeString, Nullable: true},
+		{Name: "note", DataType: types.DataTypeString, Nullable: true},
+	}, p.schema.Columns)
+
+	// Keys after the sample add columns and start a new batch, which the
+	// schema policy of the pipeline then handles
+	batch, err := p.ReadBatch(10)
+	require.NoError(t, err)
+	assert.Len(t, batch.Schema.Columns, 4)
+	assert.Equal(t, [][]interface{}{
+		{int64(1), 2.0, "7", nil},
+		{int64(2), 2.5, "x", nil},
+	}, values(batch.Records))
+
+	batch, err = p.ReadBatch(10)
+	require.NoError(t, err)
+	assert.Equal(t, []types.Column{
+		{Name: "id", DataType: types.DataTypeBigInt, Nullable: true},
+		{Name: "amount", DataType: types.DataTypeDouble, Nullable: true},
+		{Name: "code", DataType: types.DataTypeString, Nullable: true},
+		{Name: "note", DataType: types.DataTypeString, Nullable: true},
+		{Name: "extra", DataType: types.DataTypeBool, Nullable: true},
+		{Name: "other", DataType: types.DataTypeBigInt, Nullable: true},
+	}, batch.Schema.Columns)
+	assert.Equal(t, [][]interface{}{
+		{int64(3), 4.0, "8", nil, true, int64(1)},
+		{int64(4), 5.0, nil, nil, nil, nil},
+	}, values(batch.Records))
+	assert.Equal(t, "2", batch.Records[0].Metadata["record_number"])
+	assert.Equal(t, int64(4), p.GetProgress().ProcessedRecords)
+
+	_, err = p.ReadBatch(10)
+	assert.Equal(t, io.EOF, err)
+
+	// Batches read before keep their schema
+	p = newInput(t, data, map[string]interface{}{"sample_size": int64(2)})
+	first, err := p.ReadBatch(2)
+	require.NoError(t, err)
+	_, err = p.ReadBatch(2)
+	require.NoError(t, err)
+	assert.Len(t, first.Schema.Columns, 4)
+	assert.Len(t, p.schema.Columns, 6)
+}
+
+func TestJSONInputErrors(t *testing.T) {
+	p := &JSONInputPlugin{}
+	assert.Error(t, p.Initialize(map[string]interface{}{"path": "in.json", "format": "xml"}))
+	require.NoError(t, p.Initialize(map[string]interface{}{}))
+	assert.Error(t, p.Validate())
+
+	_, err := p.ReadBatch(10)
+	assert.Error(t, err)
+
+	// Records that are not objects fail the read
+	p = newInput(t, "{\"id\": 1}\n{\"id\": 2}\n[1, 2]\n", map[string]interface{}{"sample_size": 1})
+	_, err = p.ReadBatch(10)
+	assert.Error(t, err)
+}
@@ -0,0 +1,11 @@
+package parquet
+
+import (
+	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func init() {
+	// Register Parquet input plugin
+	plugin.RegisterInput("parquet", func() types.InputPlugin { return &ParquetInputPlugin{} })
+}
@@ -0,0 +1,448 @@
+package parquet
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/atlanssia/fustgo/internal/fileio"
+	"github.com/atlanssia/fustgo/internal/parquet"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// ParquetInputPlugin reads data from Parquet files. The path is a file, a
+// directory or a glob pattern; the files are read one after the other in
+// lexical order and must all have the columns read, with the same types.
+//
+// Only the projected columns are read. Row groups are read whole, so that
+// memory use grows with the size of the row groups of the files.
+type ParquetInputPlugin struct {
+	config     map[string]interface{}
+	files      []string // Files to read, in order
+	doneFiles  []string // Files read to the end
+	done       map[string]bool
+	file       *os.File
+	reader     *parquet.Reader
+	path       string // Path of the open file
+	fileDone   bool   // The open file has been read to the end
+	connected  bool
+	recursive  bool
+	projection []string // Names of the columns to read, all when empty
+	indices    []int    // Indices of the projected columns in the open file
+	schema     *types.Schema
+	rowGroup   int             // Row group of the open file being read
+	groupStart int64           // Rows of the open file before the row group
+	rows       [][]interface{} // Rows of the row group
+	row        int             // Next row in rows
+	fileRows   map[string]int64
+	progress   *types.Progress
+}
+
+// Name returns the plugin name
+func (p *ParquetInputPlugin) Name() string {
+	return "parquet"
+}
+
+// Type returns the plugin type
+func (p *ParquetInputPlugin) Type() types.PluginType {
+	return types.PluginTypeInput
+}
+
+// Initialize initializes the Parquet input plugin
+func (p *ParquetInputPlugin) Initialize(config map[string]interface{}) error {
+	p.config = config
+
+	if recursive, ok := config["recursive"].(bool); ok {
+		p.recursive = recursive
+	}
+
+	if config["columns"] != nil {
+		columns, ok := toStrings(config["columns"])
+		if !ok || len(columns) == 0 {
+			return fmt.Errorf("parquet input: columns must be a non-empty list of column names")
+		}
+		p.projection = columns
+	}
+
+	p.progress = &types.Progress{}
+	return nil
+}
+
+// Validate validates the configuration
+func (p *ParquetInputPlugin) Validate() error {
+	if p.config["path"] == nil {
+		return fmt.Errorf("parquet input: path is required")
+	}
+	return nil
+}
+
+// Connect finds the files to read, checks that they have the projected
+// columns and opens the first one
+func (p *ParquetInputPlugin) Connect() error {
+	path, ok := p.config["path"].(string)
+	if !ok {
+		return fmt.Errorf("parquet input: invalid path configuration")
+	}
+
+	files, err := fileio.ListFiles(path, ".parquet", p.recursive)
+	if err != nil {
+		return fmt.Errorf("parquet input: failed to list files: %w", err)
+	}
+	p.files = files
+
+	// Read the footer of every file, so that files without the columns
+	// are reported before anything is read
+	p.progress.TotalRecords = 0
+	p.fileRows = make(map[string]int64)
+	for i, path := range p.files {
+		if err := p.openFile(i); err != nil {
+			return err
+		}
+		p.fileRows[path] = p.reader.NumRows()
+		p.progress.TotalRecords += p.reader.NumRows()
+	}
+	p.connected = true
+
+	return p.openFile(0)
+}
+
+// openFile opens files[index] and resolves the projected columns in it
+func (p *ParquetInputPlugin) openFile(index int) error {
+	if err := p.closeFile(); err != nil {
+		return fmt.Errorf("parquet input: failed to close file: %w", err)
+	}
+
+	path := p.files[index]
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("parquet input: failed to open file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("parquet input: failed to stat file: %w", err)
+	}
+	reader, err := parquet.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("parquet input: %s: %w", path, err)
+	}
+	p.file = file
+	p.reader = reader
+	p.path = path
+	p.fileDone = false
+	p.rowGroup, p.groupStart, p.rows, p.row = 0, 0, nil, 0
+
+	return p.resolveColumns()
+}
+
+// resolveColumns finds the projected columns in the open file. The first
+// file defines the schema; the others must have the same columns.
+func (p *ParquetInputPlugin) resolveColumns() error {
+	fileSchema := p.reader.Schema()
+	names := p.projection
+	if names == nil {
+		if p.schema != nil {
+			names = columnNames(p.schema)
+		} else {
+			names = columnNames(&fileSchema)
+		}
+	}
+
+	schema := &types.Schema{}
+	p.indices = make([]int, len(names))
+	for i, name := range names {
+		index := fileSchema.ColumnIndex(name)
+		if index < 0 {
+			return fmt.Errorf("parquet input: file %s has no column %s", p.path, name)
+		}
+		p.indices[i] = index
+		schema.Columns = append(schema.Columns, fileSchema.Columns[index])
+	}
+
+	if p.schema == nil {
+		p.schema = schema
+		return nil
+	}
+	for i, col := range schema.Columns {
+		if col.DataType != p.schema.Columns[i].DataType {
+			return fmt.Errorf("parquet input: column %s of %s is %s, not %s", col.Name, p.path, col.DataType, p.schema.Columns[i].DataType)
+		}
+	}
+	return nil
+}
+
+func columnNames(schema *types.Schema) []string {
+	names := make([]string, len(schema.Columns))
+	for i, col := range schema.Columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// nextFile opens the next file not read yet. It returns false when all
+// files have been read.
+func (p *ParquetInputPlugin) nextFile() (bool, error) {
+	if p.path != "" {
+		p.markDone(p.path)
+		p.path = ""
+	}
+	if err := p.closeFile(); err != nil {
+		return false, fmt.Errorf("parquet input: failed to close file: %w", err)
+	}
+
+	for index, path := range p.files {
+		if !p.done[path] {
+			return true, p.openFile(index)
+		}
+	}
+	return false, nil
+}
+
+// markDone records a file as read to the end
+func (p *ParquetInputPlugin) markDone(path string) {
+	if p.done == nil {
+		p.done = make(map[string]bool)
+	}
+	if !p.done[path] {
+		p.done[path] = true
+		p.doneFiles = append(p.doneFiles, path)
+	}
+}
+
+// ReadBatch reads up to batchSize rows of the open file
+func (p *ParquetInputPlugin) ReadBatch(batchSize int) (*types.DataBatch, error) {
+	if !p.connected {
+		return nil, fmt.Errorf("parquet input: not connected")
+	}
+
+	for {
+		if p.fileDone {
+			more, err := p.nextFile()
+			if err != nil {
+				return nil, err
+			}
+			if !more {
+				return nil, io.EOF
+			}
+		}
+
+		if p.row >= len(p.rows) {
+			if p.rows != nil {
+				p.groupStart += int64(len(p.rows))
+				p.rowGroup++
+			}
+			if err := p.loadRowGroup(); err != nil {
+				return nil, err
+			}
+			if p.fileDone {
+				continue
+			}
+		}
+
+		end := p.row + batchSize
+		if end > len(p.rows) {
+			end = len(p.rows)
+		}
+		records := make([]types.Record, 0, end-p.row)
+		for ; p.row < end; p.row++ {
+			records = append(records, types.Record{
+				Values: p.rows[p.row],
+				Metadata: map[string]string{
+					"file":       p.path,
+					"row_number": strconv.FormatInt(p.groupStart+int64(p.row), 10),
+				},
+			})
+		}
+		p.progress.ProcessedRecords += int64(len(records))
+		return p.newBatch(records), nil
+	}
+}
+
+// loadRowGroup reads the current row group of the open file, or marks the
+// file done after its last row group
+func (p *ParquetInputPlugin) loadRowGroup() error {
+	p.rows, p.row = nil, 0
+	for p.rowGroup < p.reader.NumRowGroups() {
+		rows, err := p.reader.ReadRowGroup(p.rowGroup, p.indices)
+		if err != nil {
+			return fmt.Errorf("parquet input: failed to read %s: %w", p.path, err)
+		}
+		if len(rows) > 0 {
+			p.rows = rows
+			return nil
+		}
+		p.rowGroup++
+	}
+	p.fileDone = true
+	return nil
+}
+
+// newBatch builds a batch of rows of the open file, with the checkpoint
+// after them
+func (p *ParquetInputPlugin) newBatch(records []types.Record) *types.DataBatch {
+	position := map[string]interface{}{
+		"file":      p.path,
+		"row_group": p.rowGroup,
+		"row":       p.row,
+	}
+	if len(p.doneFiles) > 0 {
+		position["done_files"] = append([]string(nil), p.doneFiles...)
+	}
+
+	return &types.DataBatch{
+		Schema:  *p.schema,
+		Records: records,
+		Checkpoint: &types.Checkpoint{
+			Position: position,
+		},
+		Metadata: map[string]string{
+			"source": "parquet",
+			"file":   p.path,
+		},
+	}
+}
+
+// Seek continues reading after the row of a checkpoint, skipping the files
+// read to the end. The checkpoint of a completed run is ignored, as every
+// run reads all files.
+func (p *ParquetInputPlugin) Seek(checkpoint *types.Checkpoint) error {
+	if !p.connected {
+		return fmt.Errorf("parquet input: not connected")
+	}
+	if checkpoint == nil || checkpoint.IsCompleted() {
+		return nil
+	}
+
+	position, ok := checkpoint.Position.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("parquet input: unexpected checkpoint position %T", checkpoint.Position)
+	}
+	path, okPath := position["file"].(string)
+	rowGroup, okGroup := types.ToInt64(position["row_group"])
+	row, okRow := types.ToInt64(position["row"])
+	doneFiles, okDone := toStrings(position["done_files"])
+	if !okPath || !okGroup || !okRow || !okDone {
+		return fmt.Errorf("parquet input: invalid checkpoint position %v", position)
+	}
+
+	p.doneFiles, p.done = nil, nil
+	var processed int64
+	for _, done := range doneFiles {
+		p.markDone(done)
+		processed += p.fileRows[done]
+	}
+
+	index := -1
+	for i, file := range p.files {
+		if file == path {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("parquet input: file %s of the checkpoint no longer matches the path", path)
+	}
+	if err := p.openFile(index); err != nil {
+		return err
+	}
+
+	if rowGroup < 0 || int(rowGroup) > p.reader.NumRowGroups() {
+		return fmt.Errorf("parquet input: file %s has no row group %d", path, rowGroup)
+	}
+	for i := 0; i < int(rowGroup); i++ {
+		p.groupStart += p.reader.RowGroupRows(i)
+	}
+	p.rowGroup = int(rowGroup)
+	if err := p.loadRowGroup(); err != nil {
+		return err
+	}
+	if int(row) > len(p.rows) {
+		return fmt.Errorf("parquet input: row group %d of %s has no row %d", rowGroup, path, row)
+	}
+	p.row = int(row)
+
+	p.progress.ProcessedRecords = processed + p.groupStart + row
+	return nil
+}
+
+// toStrings converts a list of strings, which is []interface{} after a
+// JSON round trip. A missing list is empty.
+func toStrings(v interface{}) ([]string, bool) {
+	switch list := v.(type) {
+	case nil:
+		return nil, true
+	case []string:
+		return append([]string(nil), list...), true
+	case []interface{}:
+		strs := make([]string, len(list))
+		for i, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			strs[i] = s
+		}
+		return strs, true
+	default:
+		return nil, false
+	}
+}
+
+// HasNext checks if there are more records to read
+func (p *ParquetInputPlugin) HasNext() bool {
+	return !p.fileDone || len(p.done) < len(p.files)
+}
+
+// GetProgress returns the current reading progress
+func (p *ParquetInputPlugin) GetProgress() *types.Progress {
+	return p.progress
+}
+
+// Close closes the open file. Calling Close more than once is a no-op.
+func (p *ParquetInputPlugin) Close() error {
+	return p.closeFile()
+}
+
+// closeFile closes the open file, if any
+func (p *ParquetInputPlugin) closeFile() error {
+	if p.file != nil {
+		err := p.file.Close()
+		p.file = nil
+		p.reader = nil
+		p.rows = nil
+		return err
+	}
+	return nil
+}
+
+// GetMetadata returns plugin metadata
+func (p *ParquetInputPlugin) GetMetadata() types.PluginMetadata {
+	return types.PluginMetadata{
+		Name:           "parquet",
+		Type:           types.PluginTypeInput,
+		Version:        "1.0.0",
+		Description:    "Parquet file input plugin reading one or many files",
+		DataSourceType: "file",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Parquet file, directory or glob pattern, in which ** matches any number of directories",
+				},
+				"recursive": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Read the Parquet files in the subdirectories of a directory path",
+					"default":     false,
+				},
+				"columns": map[string]interface{}{
+					"type":        "array",
+					"description": "Names of the columns to read, in the order of the batches; all columns by default",
+				},
+			},
+			"required": []string{"path"},
+		},
+	}
+}
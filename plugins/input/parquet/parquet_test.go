@@ -0,0 +1,119 @@
+package parquet
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/parquet"
+	"github.com/atlanssia/fustgo/internal/plugintest"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+var testSchema = types.Schema{Columns: []types.Column{
+	{Name: "id", DataType: types.DataTypeBigInt},
+	{Name: "name", DataType: types.DataTypeString, Nullable: true},
+	{Name: "day", DataType: types.DataTypeDate, Nullable: true},
+}}
+
+// writeFile writes a Parquet file of rows with ids from..to-1, in row
+// groups of two rows
+func writeFile(t *testing.T, path string, from, to int64) {
+	t.Helper()
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	w, err := parquet.NewWriter(file, testSchema, parquet.WriterConfig{RowGroupRows: 2})
+	require.NoError(t, err)
+	for id := from; id < to; id++ {
+		var name interface{}
+		if id%2 == 0 {
+			name = "even"
+		}
+		require.NoError(t, w.Write([]interface{}{id, name, time.Date(2024, 1, int(id), 0, 0, 0, 0, time.UTC)}))
+	}
+	require.NoError(t, w.Close())
+}
+
+func newInput(t *testing.T, config map[string]interface{}) *ParquetInputPlugin {
+	t.Helper()
+	p := &ParquetInputPlugin{}
+	plugintest.StartInput(t, p, config)
+	return p
+}
+
+func ids(records []types.Record) []interface{} {
+	ids := []interface{}{}
+	for _, record := range records {
+		ids = append(ids, record.Values[0])
+	}
+	return ids
+}
+
+func TestParquetInputFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "b.parquet"), 4, 6)
+	writeFile(t, filepath.Join(dir, "a.parquet"), 1, 4)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644))
+
+	p := newInput(t, map[string]interface{}{"path": dir})
+	records := plugintest.ReadAll(t, p, 2)
+
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(3), int64(4), int64(5)}, ids(records))
+	assert.Equal(t, []interface{}{int64(2), "even", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}, records[1].Values)
+	assert.Equal(t, map[string]string{"file": filepath.Join(dir, "a.parquet"), "row_number": "2"}, records[2].Metadata)
+	assert.Equal(t, int64(5), p.GetProgress().TotalRecords)
+	assert.Equal(t, int64(5), p.GetProgress().ProcessedRecords)
+}
+
+func TestParquetInputProjection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.parquet")
+	writeFile(t, path, 1, 3)
+
+	p := newInput(t, map[string]interface{}{"path": path, "columns": []interface{}{"name", "id"}})
+	batch, err := p.ReadBatch(10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "id"}, []string{batch.Schema.Columns[0].Name, batch.Schema.Columns[1].Name})
+	assert.Equal(t, []interface{}{nil, int64(1)}, batch.Records[0].Values)
+
+	p = &ParquetInputPlugin{}
+	require.NoError(t, p.Initialize(map[string]interface{}{"path": path, "columns": []interface{}{"missing"}}))
+	assert.Error(t, p.Connect())
+}
+
+func TestParquetInputSeek(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.parquet"), 1, 4)
+	writeFile(t, filepath.Join(dir, "b.parquet"), 4, 9)
+	all := []interface{}{int64(1), int64(2), int64(3), int64(4), int64(5), int64(6), int64(7), int64(8)}
+
+	// Resume after every checkpoint of a full read
+	p := newInput(t, map[string]interface{}{"path": dir})
+	read := 0
+	for {
+		batch, err := p.ReadBatch(3)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		read += len(batch.Records)
+
+		data, err := json.Marshal(batch.Checkpoint)
+		require.NoError(t, err)
+		var checkpoint types.Checkpoint
+		require.NoError(t, json.Unmarshal(data, &checkpoint))
+
+		resumed := newInput(t, map[string]interface{}{"path": dir})
+		require.NoError(t, resumed.Seek(&checkpoint))
+		assert.Equal(t, int64(read), resumed.GetProgress().ProcessedRecords, "%v", checkpoint.Position)
+		rest := plugintest.ReadAll(t, resumed, 3)
+		assert.Equal(t, all[read:], ids(rest), "%v", checkpoint.Position)
+	}
+	assert.Equal(t, 8, read)
+}
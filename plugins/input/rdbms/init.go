@@ -0,0 +1,13 @@
+package rdbms
+
+import (
+	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func init() {
+	plugin.RegisterInput("sql", func() types.InputPlugin { return &SQLInputPlugin{} })
+	plugin.RegisterInput("mysql", func() types.InputPlugin { return &SQLInputPlugin{dialectName: "mysql"} })
+	plugin.RegisterInput("postgresql", func() types.InputPlugin { return &SQLInputPlugin{dialectName: "postgresql"} })
+	plugin.RegisterInput("sqlite", func() types.InputPlugin { return &SQLInputPlugin{dialectName: "sqlite"} })
+}
@@ -0,0 +1,743 @@
+package rdbms
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/atlanssia/fustgo/internal/expr"
+	"github.com/atlanssia/fustgo/internal/sqldb"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+const defaultChunkSize = 100000
+
+// SQLInputPlugin reads a table or query result through database/sql.
+//
+// With an incremental column, only rows whose watermark is greater than the
+// last one read are extracted, and the watermark is carried in
+// DataBatch.Checkpoint so that the next run continues from it. With a split
+// column, the key range is read in chunks of chunk_size keys, each ordered
+// by the key, so that large tables are never scanned in one query. The key
+// range can also be split into shards read in parallel.
+type SQLInputPlugin struct {
+	dialectName string // Preset by the registered plugin name, empty for "sql"
+
+	config    map[string]interface{}
+	connCfg   *sqldb.Config
+	db        *sql.DB
+	dialect   sqldb.Dialect
+	table     string
+	query     string
+	where     string
+	columns   []string
+	wmColumn  string
+	wmInitial interface{}
+	keyColumn string
+	chunkSize int64
+	shardKeys *keyRange // Keys read by a shard, nil for the whole range
+
+	schema      *types.Schema
+	columnTypes []types.DataType
+	wmIndex     int
+	keyIndex    int
+	typesFixed  bool
+
+	// Read state
+	rows          *sql.Rows
+	lookahead     []interface{}
+	started       bool
+	done          bool
+	watermark     interface{} // Exclusive lower bound of the watermark
+	highWatermark interface{} // Largest watermark read in this run
+	lastKey       *int64      // Last split key read
+	nextKey       int64       // Start of the next key range
+	keysDone      bool        // Whether the chunk ending at maxKey was read
+	maxKey        int64
+	progress      *types.Progress
+}
+
+// keyRange is an inclusive range of split keys
+type keyRange struct {
+	min, max int64
+}
+
+// Name returns the plugin name
+func (p *SQLInputPlugin) Name() string {
+	if p.dialectName != "" {
+		return p.dialectName
+	}
+	return "sql"
+}
+
+// Type returns the plugin type
+func (p *SQLInputPlugin) Type() types.PluginType {
+	return types.PluginTypeInput
+}
+
+// Initialize initializes the SQL input plugin
+func (p *SQLInputPlugin) Initialize(config map[string]interface{}) error {
+	p.config = config
+	p.chunkSize = defaultChunkSize
+	p.wmIndex = -1
+	p.keyIndex = -1
+
+	connCfg, err := sqldb.ParseConfig(config, p.dialectName)
+	if err != nil {
+		return fmt.Errorf("%s input: %w", p.Name(), err)
+	}
+	p.connCfg = connCfg
+
+	// Parse configuration
+	p.table, _ = config["table"].(string)
+	p.query, _ = config["query"].(string)
+	p.where, _ = config["where"].(string)
+	if columns, ok := config["columns"].([]interface{}); ok {
+		for _, col := range columns {
+			if name, ok := col.(string); ok {
+				p.columns = append(p.columns, name)
+			}
+		}
+	}
+
+	if incremental, ok := config["incremental"].(map[string]interface{}); ok {
+		p.wmColumn, _ = incremental["column"].(string)
+		p.wmInitial = incremental["initial_value"]
+		if p.wmColumn == "" {
+			return fmt.Errorf("%s input: incremental.column is required", p.Name())
+		}
+	}
+
+	if split, ok := config["split"].(map[string]interface{}); ok {
+		p.keyColumn, _ = split["column"].(string)
+		if size, ok := types.ToInt64(split["chunk_size"]); ok && size > 0 {
+			p.chunkSize = size
+		}
+		if p.keyColumn == "" {
+			return fmt.Errorf("%s input: split.column is required", p.Name())
+		}
+	}
+
+	// Initialize progress
+	p.progress = &types.Progress{
+		TotalRecords:     0,
+		ProcessedRecords: 0,
+	}
+
+	return nil
+}
+
+// Validate validates the configuration
+func (p *SQLInputPlugin) Validate() error {
+	if p.table == "" && p.query == "" {
+		return fmt.Errorf("%s input: table or query is required", p.Name())
+	}
+	if p.table != "" && p.query != "" {
+		return fmt.Errorf("%s input: table and query are mutually exclusive", p.Name())
+	}
+	return nil
+}
+
+// Connect opens the database and discovers the result schema
+func (p *SQLInputPlugin) Connect() error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	db, dialect, err := sqldb.Open(p.connCfg)
+	if err != nil {
+		return fmt.Errorf("%s input: %w", p.Name(), err)
+	}
+	p.db = db
+	p.dialect = dialect
+
+	// Run the query without rows to learn the column names and types
+	rows, err := db.Query(p.selectSQL([]string{"1 = 0"}, ""))
+	if err != nil {
+		return fmt.Errorf("%s input: failed to query schema: %w", p.Name(), err)
+	}
+	columnTypes, err := rows.ColumnTypes()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("%s input: failed to read column types: %w", p.Name(), err)
+	}
+
+	p.schema = &types.Schema{Columns: make([]types.Column, len(columnTypes))}
+	p.columnTypes = make([]types.DataType, len(columnTypes))
+	for i, ct := range columnTypes {
+		nullable, ok := ct.Nullable()
+		p.columnTypes[i] = sqldb.DataTypeOf(ct.DatabaseTypeName())
+		p.schema.Columns[i] = types.Column{
+			Name:     ct.Name(),
+			DataType: p.columnTypes[i],
+			Nullable: nullable || !ok,
+		}
+		if ct.Name() == p.wmColumn {
+			p.wmIndex = i
+		}
+		if ct.Name() == p.keyColumn {
+			p.keyIndex = i
+		}
+	}
+
+	if p.wmColumn != "" && p.wmIndex < 0 {
+		return fmt.Errorf("%s input: incremental column %s is not in the result", p.Name(), p.wmColumn)
+	}
+	if p.keyColumn != "" && p.keyIndex < 0 {
+		return fmt.Errorf("%s input: split column %s is not in the result", p.Name(), p.keyColumn)
+	}
+
+	if p.wmInitial != nil {
+		p.watermark = p.castWatermark(p.wmInitial)
+		p.highWatermark = p.watermark
+	}
+
+	return nil
+}
+
+// Seek continues from a checkpoint produced by an earlier run. A finished
+// run starts the next one from its highest watermark; an interrupted one
+// continues after the last key or watermark it read.
+func (p *SQLInputPlugin) Seek(checkpoint *types.Checkpoint) error {
+	if checkpoint == nil || (p.wmColumn == "" && p.keyColumn == "") {
+		return nil
+	}
+
+	position, ok := checkpoint.Position.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s input: unexpected checkpoint position %T", p.Name(), checkpoint.Position)
+	}
+
+	done, _ := position["done"].(bool)
+	done = done || checkpoint.IsCompleted()
+	if p.wmColumn != "" {
+		if done {
+			p.watermark = p.castWatermark(position["high_watermark"])
+		} else {
+			p.watermark = p.castWatermark(position["watermark"])
+		}
+		p.highWatermark = p.castWatermark(position["high_watermark"])
+	}
+
+	// A shard keeps the key range it was given in the interrupted run
+	if p.shardKeys != nil && !done {
+		minKey, errMin := expr.Cast(position["min_key"], types.DataTypeBigInt)
+		maxKey, errMax := expr.Cast(position["max_key"], types.DataTypeBigInt)
+		if errMin != nil || errMax != nil || minKey == nil || maxKey == nil {
+			return fmt.Errorf("%s input: invalid shard key range in checkpoint", p.Name())
+		}
+		p.shardKeys = &keyRange{min: minKey.(int64), max: maxKey.(int64)}
+	}
+
+	if p.keyColumn != "" && !done && position["last_key"] != nil {
+		key, err := expr.Cast(position["last_key"], types.DataTypeBigInt)
+		if err != nil {
+			return fmt.Errorf("%s input: invalid split key in checkpoint: %w", p.Name(), err)
+		}
+		last := key.(int64)
+		p.lastKey = &last
+	}
+
+	return nil
+}
+
+// Split divides the key range of the split column into n ranges of about
+// the same width, each read by a shard with its own connection
+func (p *SQLInputPlugin) Split(n int) ([]types.InputShard, error) {
+	if p.db == nil {
+		return nil, fmt.Errorf("%s input: not connected", p.Name())
+	}
+	if p.keyColumn == "" {
+		return nil, fmt.Errorf("%s input: split.column is required for parallel reads", p.Name())
+	}
+	if p.wmColumn != "" {
+		return nil, fmt.Errorf("%s input: parallel reads cannot be combined with incremental extraction", p.Name())
+	}
+
+	conditions, args := p.baseConditions()
+	keys, err := p.queryKeyRange(conditions, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ranges are never narrower than one key. Key arithmetic is unsigned,
+	// as the number of keys of the full int64 range does not fit in one.
+	var last uint64 // Number of keys minus one
+	if keys.min <= keys.max {
+		last = uint64(keys.max) - uint64(keys.min)
+	}
+	if n < 1 {
+		n = 1
+	}
+	if uint64(n-1) > last {
+		n = int(last + 1)
+	}
+	width := last/uint64(n) + 1 // Keys per range, rounded up
+
+	shards := make([]types.InputShard, 0, n)
+	for i := 0; i < n; i++ {
+		lo := uint64(keys.min) + uint64(i)*width
+		shardKeys := &keyRange{min: int64(lo), max: keys.max}
+		if i < n-1 {
+			shardKeys.max = int64(lo + width - 1)
+		}
+
+		shard := &SQLInputPlugin{dialectName: p.dialectName}
+		if err := shard.Initialize(p.config); err != nil {
+			return nil, err
+		}
+		shard.shardKeys = shardKeys
+
+		shards = append(shards, types.InputShard{
+			ID:    strconv.Itoa(i),
+			Input: shard,
+			Checkpoint: &types.Checkpoint{Position: map[string]interface{}{
+				"done":    false,
+				"min_key": shardKeys.min,
+				"max_key": shardKeys.max,
+			}},
+		})
+	}
+	return shards, nil
+}
+
+// ReadBatch reads a batch of records. Rows sharing the order key of the last
+// row are always returned in the same batch, so a checkpoint never falls
+// between them.
+func (p *SQLInputPlugin) ReadBatch(batchSize int) (*types.DataBatch, error) {
+	if p.db == nil {
+		return nil, fmt.Errorf("%s input: not connected", p.Name())
+	}
+
+	var records []types.Record
+	for len(records) < batchSize {
+		row, err := p.nextRow()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			break
+		}
+		records = append(records, types.Record{Values: row})
+	}
+
+	// Keep rows with the same order key together
+	if orderIndex := p.orderIndex(); orderIndex >= 0 && len(records) > 0 {
+		last := records[len(records)-1].Values[orderIndex]
+		for {
+			next, err := p.peekRow()
+			if err != nil {
+				return nil, err
+			}
+			if next == nil || !sameValue(next[orderIndex], last) {
+				break
+			}
+			p.lookahead = nil
+			records = append(records, types.Record{Values: next})
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, io.EOF
+	}
+
+	// Know whether this is the last batch, so its checkpoint can say so
+	next, err := p.peekRow()
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		p.done = true
+	}
+
+	p.fixUnknownTypes(records)
+	p.progress.ProcessedRecords += int64(len(records))
+
+	batch := &types.DataBatch{
+		Schema:     *p.schema,
+		Records:    records,
+		Checkpoint: p.checkpoint(records),
+		Metadata: map[string]string{
+			"source": p.Name(),
+		},
+	}
+	if p.table != "" {
+		batch.Metadata["table"] = p.table
+	}
+
+	return batch, nil
+}
+
+// HasNext checks if there are more records to read
+func (p *SQLInputPlugin) HasNext() bool {
+	return !p.done || p.lookahead != nil
+}
+
+// GetProgress returns the current reading progress
+func (p *SQLInputPlugin) GetProgress() *types.Progress {
+	return p.progress
+}
+
+// Close closes the result set and the database. Calling Close more than
+// once is a no-op.
+func (p *SQLInputPlugin) Close() error {
+	if p.rows != nil {
+		p.rows.Close()
+		p.rows = nil
+	}
+	if p.db != nil {
+		err := p.db.Close()
+		p.db = nil
+		return err
+	}
+	return nil
+}
+
+// GetMetadata returns plugin metadata
+func (p *SQLInputPlugin) GetMetadata() types.PluginMetadata {
+	return types.PluginMetadata{
+		Name:           p.Name(),
+		Type:           types.PluginTypeInput,
+		Version:        "1.0.0",
+		Description:    "SQL database input plugin (MySQL, PostgreSQL, SQLite) with incremental extraction",
+		DataSourceType: "database",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"connection": map[string]interface{}{
+					"type":        "object",
+					"description": "Connection settings: dsn, or host, port, database (file path for SQLite), user, password, params",
+				},
+				"dialect": map[string]interface{}{
+					"type":        "string",
+					"description": "Database dialect for the generic 'sql' plugin: mysql, postgresql or sqlite",
+				},
+				"table": map[string]interface{}{
+					"type":        "string",
+					"description": "Table to read",
+				},
+				"columns": map[string]interface{}{
+					"type":        "array",
+					"description": "Columns to read from the table (default all)",
+					"items":       map[string]interface{}{"type": "string"},
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Query to read instead of a table",
+				},
+				"where": map[string]interface{}{
+					"type":        "string",
+					"description": "Additional SQL filter condition",
+				},
+				"incremental": map[string]interface{}{
+					"type":        "object",
+					"description": "Incremental extraction: column (watermark) and optional initial_value",
+				},
+				"split": map[string]interface{}{
+					"type":        "object",
+					"description": "Key-range splitting: integer column and chunk_size (keys per query); the key range is also split for parallel reads",
+				},
+			},
+		},
+	}
+}
+
+// nextRow returns the next row, advancing through key ranges as needed.
+// It returns nil when all rows have been read.
+func (p *SQLInputPlugin) nextRow() ([]interface{}, error) {
+	row, err := p.peekRow()
+	p.lookahead = nil
+	return row, err
+}
+
+// peekRow returns the next row without consuming it
+func (p *SQLInputPlugin) peekRow() ([]interface{}, error) {
+	if p.lookahead != nil {
+		return p.lookahead, nil
+	}
+
+	for {
+		if p.rows == nil {
+			more, err := p.openNextQuery()
+			if err != nil {
+				return nil, err
+			}
+			if !more {
+				return nil, nil
+			}
+		}
+
+		if p.rows.Next() {
+			row, err := p.scanRow()
+			if err != nil {
+				return nil, err
+			}
+			p.lookahead = row
+			return row, nil
+		}
+
+		err := p.rows.Err()
+		p.rows.Close()
+		p.rows = nil
+		if err != nil {
+			return nil, fmt.Errorf("%s input: failed to read rows: %w", p.Name(), err)
+		}
+	}
+}
+
+// openNextQuery starts the query for the next key range, or the single
+// query when not splitting. It returns false when nothing is left.
+func (p *SQLInputPlugin) openNextQuery() (bool, error) {
+	conditions, args := p.baseConditions()
+
+	if p.keyColumn == "" {
+		if p.started {
+			return false, nil
+		}
+		p.started = true
+
+		orderBy := ""
+		if p.wmColumn != "" {
+			orderBy = p.dialect.QuoteIdentifier(p.wmColumn)
+		}
+		return true, p.openRows(p.selectSQL(conditions, orderBy), args)
+	}
+
+	if !p.started {
+		p.started = true
+		if err := p.loadKeyRange(conditions, args); err != nil {
+			return false, err
+		}
+	}
+	if p.keysDone || p.nextKey > p.maxKey {
+		return false, nil
+	}
+
+	// Chunks end at the last key of the range rather than past it, so that
+	// a range ending at the largest int64 does not overflow
+	lo := p.nextKey
+	hi := p.maxKey // Stay within the range of a shard
+	if uint64(p.maxKey)-uint64(lo) >= uint64(p.chunkSize) {
+		hi = lo + p.chunkSize - 1
+	}
+	if hi == p.maxKey {
+		p.keysDone = true
+	} else {
+		p.nextKey = hi + 1
+	}
+
+	key := p.dialect.QuoteIdentifier(p.keyColumn)
+	conditions = append(conditions,
+		fmt.Sprintf("%s >= %s", key, p.dialect.Placeholder(len(args)+1)),
+		fmt.Sprintf("%s <= %s", key, p.dialect.Placeholder(len(args)+2)),
+	)
+	args = append(args, lo, hi)
+
+	return true, p.openRows(p.selectSQL(conditions, key), args)
+}
+
+// loadKeyRange finds the range of split keys still to be read
+func (p *SQLInputPlugin) loadKeyRange(conditions []string, args []interface{}) error {
+	keys := p.shardKeys
+	if keys == nil {
+		var err error
+		if keys, err = p.queryKeyRange(conditions, args); err != nil {
+			return err
+		}
+	}
+
+	p.nextKey, p.maxKey = keys.min, keys.max
+	p.keysDone = false
+	if p.lastKey != nil && *p.lastKey >= p.maxKey {
+		p.keysDone = true
+	} else if p.lastKey != nil && *p.lastKey >= p.nextKey {
+		p.nextKey = *p.lastKey + 1
+	}
+	return nil
+}
+
+// queryKeyRange reads the smallest and largest split keys. The range is
+// empty, with min greater than max, when there are no rows.
+func (p *SQLInputPlugin) queryKeyRange(conditions []string, args []interface{}) (*keyRange, error) {
+	key := p.dialect.QuoteIdentifier(p.keyColumn)
+	query := fmt.Sprintf("SELECT MIN(%s), MAX(%s) FROM %s", key, key, p.fromClause())
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var minKey, maxKey sql.NullInt64
+	if err := p.db.QueryRow(query, args...).Scan(&minKey, &maxKey); err != nil {
+		return nil, fmt.Errorf("%s input: failed to read key range of %s: %w", p.Name(), p.keyColumn, err)
+	}
+	if !minKey.Valid {
+		return &keyRange{min: 1, max: 0}, nil
+	}
+	return &keyRange{min: minKey.Int64, max: maxKey.Int64}, nil
+}
+
+// baseConditions returns the user filter and the watermark condition
+func (p *SQLInputPlugin) baseConditions() ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if p.where != "" {
+		conditions = append(conditions, "("+p.where+")")
+	}
+	if p.wmColumn != "" && p.watermark != nil {
+		args = append(args, p.watermark)
+		conditions = append(conditions, fmt.Sprintf("%s > %s",
+			p.dialect.QuoteIdentifier(p.wmColumn), p.dialect.Placeholder(len(args))))
+	}
+
+	return conditions, args
+}
+
+// selectSQL builds the SELECT statement for the table or query
+func (p *SQLInputPlugin) selectSQL(conditions []string, orderBy string) string {
+	columns := "*"
+	if p.table != "" && len(p.columns) > 0 {
+		quoted := make([]string, len(p.columns))
+		for i, col := range p.columns {
+			quoted[i] = p.dialect.QuoteIdentifier(col)
+		}
+		columns = strings.Join(quoted, ", ")
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", columns, p.fromClause())
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if orderBy != "" {
+		query += " ORDER BY " + orderBy
+	}
+	return query
+}
+
+// fromClause returns the table, or the query as a derived table
+func (p *SQLInputPlugin) fromClause() string {
+	if p.table != "" {
+		return p.dialect.QuoteIdentifier(p.table)
+	}
+	return "(" + strings.TrimRight(strings.TrimSpace(p.query), ";") + ") src"
+}
+
+func (p *SQLInputPlugin) openRows(query string, args []interface{}) error {
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("%s input: query failed: %w", p.Name(), err)
+	}
+	p.rows = rows
+	return nil
+}
+
+func (p *SQLInputPlugin) scanRow() ([]interface{}, error) {
+	values := make([]interface{}, len(p.columnTypes))
+	ptrs := make([]interface{}, len(values))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+
+	if err := p.rows.Scan(ptrs...); err != nil {
+		return nil, fmt.Errorf("%s input: failed to scan row: %w", p.Name(), err)
+	}
+
+	for i, v := range values {
+		values[i] = sqldb.ConvertValue(v, p.columnTypes[i])
+	}
+	return values, nil
+}
+
+// orderIndex returns the index of the column rows are ordered by, or -1
+func (p *SQLInputPlugin) orderIndex() int {
+	if p.keyColumn != "" {
+		return p.keyIndex
+	}
+	return p.wmIndex
+}
+
+// checkpoint records the read position after the given records
+func (p *SQLInputPlugin) checkpoint(records []types.Record) *types.Checkpoint {
+	if p.wmColumn == "" && p.keyColumn == "" {
+		return nil
+	}
+
+	for _, record := range records {
+		if p.wmIndex >= 0 {
+			v := record.Values[p.wmIndex]
+			if c, err := expr.Compare(v, p.highWatermark); err == nil && c > 0 {
+				p.highWatermark = v
+			}
+		}
+	}
+
+	last := records[len(records)-1]
+	if p.keyColumn == "" {
+		// Rows are ordered by the watermark, so it is the read position
+		if v := last.Values[p.wmIndex]; v != nil {
+			p.watermark = v
+		}
+	} else if key, err := expr.Cast(last.Values[p.keyIndex], types.DataTypeBigInt); err == nil && key != nil {
+		k := key.(int64)
+		p.lastKey = &k
+	}
+
+	position := map[string]interface{}{
+		"done": p.done,
+	}
+	if p.wmColumn != "" {
+		position["watermark"] = p.watermark
+		position["high_watermark"] = p.highWatermark
+	}
+	if p.lastKey != nil {
+		position["last_key"] = *p.lastKey
+	}
+	if p.shardKeys != nil {
+		position["min_key"] = p.shardKeys.min
+		position["max_key"] = p.shardKeys.max
+	}
+
+	return &types.Checkpoint{Position: position}
+}
+
+// castWatermark converts a watermark, e.g. decoded from a JSON checkpoint,
+// to the type of the watermark column
+func (p *SQLInputPlugin) castWatermark(v interface{}) interface{} {
+	if v == nil || p.wmIndex < 0 {
+		return v
+	}
+	converted, err := expr.Cast(v, p.columnTypes[p.wmIndex])
+	if err != nil {
+		return v
+	}
+	return converted
+}
+
+// fixUnknownTypes settles the data types of columns the driver did not
+// report, such as SQLite expressions, from the first values read
+func (p *SQLInputPlugin) fixUnknownTypes(records []types.Record) {
+	if p.typesFixed {
+		return
+	}
+	p.typesFixed = true
+
+	for i := range p.schema.Columns {
+		if p.schema.Columns[i].DataType != types.DataTypeUnknown {
+			continue
+		}
+		dataType := types.DataTypeString
+		for _, record := range records {
+			if v := record.Values[i]; v != nil {
+				dataType = expr.ValueType(v)
+				break
+			}
+		}
+		p.schema.Columns[i].DataType = dataType
+	}
+}
+
+func sameValue(a, b interface{}) bool {
+	c, err := expr.Compare(a, b)
+	return err == nil && c == 0
+}
@@ -0,0 +1,355 @@
+package rdbms
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/plugintest"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func createTestDB(t *testing.T, rows int) string {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE orders (
+		id INTEGER PRIMARY KEY,
+		customer VARCHAR(50),
+		amount DECIMAL(10,2),
+		paid BOOLEAN,
+		updated_at BIGINT
+	)`)
+	require.NoError(t, err)
+
+	for i := 1; i <= rows; i++ {
+		_, err = db.Exec("INSERT INTO orders VALUES (?, ?, ?, ?, ?)",
+			i, fmt.Sprintf("customer-%d", i), float64(i)*1.5, i%2 == 0, 1000+i/2)
+		require.NoError(t, err)
+	}
+
+	return path
+}
+
+func newInput(t *testing.T, config map[string]interface{}) *SQLInputPlugin {
+	t.Helper()
+	p := &SQLInputPlugin{dialectName: "sqlite"}
+	plugintest.StartInput(t, p, config)
+	return p
+}
+
+// readAll reads all batches and returns the ids and the last checkpoint
+func readAll(t *testing.T, p *SQLInputPlugin, batchSize int) ([]int64, *types.Checkpoint) {
+	var ids []int64
+	var checkpoint *types.Checkpoint
+	for p.HasNext() {
+		batch, err := p.ReadBatch(batchSize)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		for _, record := range batch.Records {
+			ids = append(ids, record.Values[0].(int64))
+		}
+		if batch.Checkpoint != nil {
+			checkpoint = batch.Checkpoint
+		}
+	}
+	return ids, checkpoint
+}
+
+func TestSQLInputTable(t *testing.T) {
+	path := createTestDB(t, 10)
+	p := newInput(t, map[string]interface{}{"path": path, "table": "orders"})
+
+	batch, err := p.ReadBatch(4)
+	require.NoError(t, err)
+	require.Len(t, batch.Records, 4)
+
+	expected := []types.DataType{
+		types.DataTypeInt, types.DataTypeString, types.DataTypeDouble,
+		types.DataTypeBool, types.DataTypeBigInt,
+	}
+	for i, col := range batch.Schema.Columns {
+		assert.Equal(t, expected[i], col.DataType, col.Name)
+	}
+
+	first := batch.Records[0].Values
+	assert.Equal(t, int64(1), first[0])
+	assert.Equal(t, "customer-1", first[1])
+	assert.Equal(t, 1.5, first[2])
+	assert.Equal(t, false, first[3])
+	assert.Nil(t, batch.Checkpoint)
+
+	ids, _ := readAll(t, p, 4)
+	assert.Len(t, ids, 6)
+	assert.False(t, p.HasNext())
+}
+
+func TestSQLInputQuery(t *testing.T) {
+	path := createTestDB(t, 10)
+	p := newInput(t, map[string]interface{}{
+		"path":  path,
+		"query": "SELECT id, customer || '!' AS tag FROM orders WHERE paid;",
+		"where": "id > 4",
+	})
+
+	ids, _ := readAll(t, p, 100)
+	assert.Equal(t, []int64{6, 8, 10}, ids)
+	assert.Equal(t, types.DataTypeString, p.schema.Columns[1].DataType)
+}
+
+func TestSQLInputSplit(t *testing.T) {
+	path := createTestDB(t, 25)
+	p := newInput(t, map[string]interface{}{
+		"path":  path,
+		"table": "orders",
+		"split": map[string]interface{}{"column": "id", "chunk_size": 7},
+	})
+
+	ids, checkpoint := readAll(t, p, 5)
+	require.Len(t, ids, 25)
+	for i, id := range ids {
+		assert.Equal(t, int64(i+1), id)
+	}
+
+	position := checkpoint.Position.(map[string]interface{})
+	assert.Equal(t, true, position["done"])
+	assert.Equal(t, int64(25), position["last_key"])
+}
+
+func TestSQLInputSplitResume(t *testing.T) {
+	path := createTestDB(t, 20)
+	config := map[string]interface{}{
+		"path":  path,
+		"table": "orders",
+		"split": map[string]interface{}{"column": "id", "chunk_size": 6},
+	}
+
+	p := newInput(t, config)
+	batch, err := p.ReadBatch(8)
+	require.NoError(t, err)
+	require.Len(t, batch.Records, 8)
+
+	// A new run continues after the last key of the checkpoint
+	resumed := newInput(t, config)
+	require.NoError(t, resumed.Seek(plugintest.RoundTrip(t, batch.Checkpoint)))
+	ids, _ := readAll(t, resumed, 100)
+	require.Len(t, ids, 12)
+	assert.Equal(t, int64(9), ids[0])
+}
+
+func TestSQLInputParallelSplit(t *testing.T) {
+	path := createTestDB(t, 25)
+	config := map[string]interface{}{
+		"path":  path,
+		"table": "orders",
+		"split": map[string]interface{}{"column": "id", "chunk_size": 3},
+	}
+
+	p := newInput(t, config)
+	shards, err := p.Split(4)
+	require.NoError(t, err)
+	require.Len(t, shards, 4)
+
+	var ids []int64
+	var checkpoints []*types.Checkpoint
+	for i, shard := range shards {
+		assert.Equal(t, fmt.Sprint(i), shard.ID)
+		input := shard.Input.(*SQLInputPlugin)
+		require.NoError(t, input.Connect())
+		t.Cleanup(func() { input.Close() })
+
+		shardIDs, _ := readAll(t, input, 4)
+		ids = append(ids, shardIDs...)
+		checkpoints = append(checkpoints, shard.Checkpoint)
+	}
+
+	// The shards cover the key range once, in order
+	require.Len(t, ids, 25)
+	for i, id := range ids {
+		assert.Equal(t, int64(i+1), id)
+	}
+	assert.Equal(t, int64(1), checkpoints[0].Position.(map[string]interface{})["min_key"])
+	assert.Equal(t, int64(25), checkpoints[3].Position.(map[string]interface{})["max_key"])
+
+	// Incremental extraction cannot be split
+	config["incremental"] = map[string]interface{}{"column": "updated_at"}
+	_, err = newInput(t, config).Split(2)
+	assert.Error(t, err)
+}
+
+func TestSQLInputParallelSplitResume(t *testing.T) {
+	path := createTestDB(t, 20)
+	config := map[string]interface{}{
+		"path":  path,
+		"table": "orders",
+		"split": map[string]interface{}{"column": "id"},
+	}
+
+	shards, err := newInput(t, config).Split(2)
+	require.NoError(t, err)
+	first := shards[1].Input.(*SQLInputPlugin)
+	require.NoError(t, first.Connect())
+	t.Cleanup(func() { first.Close() })
+	batch, err := first.ReadBatch(3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), batch.Records[0].Values[0])
+	first.Close()
+
+	// Rows added since change the split, but the resumed shard keeps its
+	// range
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO orders (id, customer) VALUES (40, 'late')")
+	require.NoError(t, err)
+	db.Close()
+
+	shards, err = newInput(t, config).Split(2)
+	require.NoError(t, err)
+	resumed := shards[1].Input.(*SQLInputPlugin)
+	require.NoError(t, resumed.Connect())
+	t.Cleanup(func() { resumed.Close() })
+	require.NoError(t, resumed.Seek(plugintest.RoundTrip(t, batch.Checkpoint)))
+
+	ids, _ := readAll(t, resumed, 100)
+	require.Len(t, ids, 7)
+	assert.Equal(t, int64(14), ids[0])
+	assert.Equal(t, int64(20), ids[6])
+}
+
+func TestSQLInputSplitExtremeKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	keys := []int64{math.MinInt64, math.MinInt64 + 1, -1, 0, 1, math.MaxInt64 - 1, math.MaxInt64}
+	for _, key := range keys {
+		_, err = db.Exec("INSERT INTO events VALUES (?)", key)
+		require.NoError(t, err)
+	}
+	db.Close()
+
+	// chunk_size is a float64 in configurations decoded from JSON
+	config := map[string]interface{}{
+		"path":  path,
+		"table": "events",
+		"split": map[string]interface{}{"column": "id", "chunk_size": float64(1 << 62)},
+	}
+	p := newInput(t, config)
+	assert.Equal(t, int64(1<<62), p.chunkSize)
+
+	// Keys spanning the whole int64 range are read once, in chunks that
+	// stop at the largest key
+	ids, checkpoint := readAll(t, p, 3)
+	assert.Equal(t, keys, ids)
+	assert.Equal(t, int64(math.MaxInt64), checkpoint.Position.(map[string]interface{})["last_key"])
+
+	// Runs interrupted at the largest keys continue after them
+	for last, want := range map[int64][]int64{
+		math.MaxInt64 - 1: {math.MaxInt64},
+		math.MaxInt64:     nil,
+	} {
+		resumed := newInput(t, config)
+		require.NoError(t, resumed.Seek(&types.Checkpoint{Position: map[string]interface{}{"done": false, "last_key": last}}))
+		ids, _ = readAll(t, resumed, 3)
+		assert.Equal(t, want, ids, "after %d", last)
+	}
+
+	// Shards split the whole range without overflowing
+	for _, n := range []int{1, 2, 3, 16} {
+		shards, err := newInput(t, config).Split(n)
+		require.NoError(t, err)
+		require.Len(t, shards, n)
+
+		var all []int64
+		for _, shard := range shards {
+			input := shard.Input.(*SQLInputPlugin)
+			require.NoError(t, input.Connect())
+			t.Cleanup(func() { input.Close() })
+			shardIDs, _ := readAll(t, input, 3)
+			all = append(all, shardIDs...)
+		}
+		assert.Equal(t, keys, all, "%d shards", n)
+		assert.Equal(t, int64(math.MinInt64), shards[0].Checkpoint.Position.(map[string]interface{})["min_key"])
+		assert.Equal(t, int64(math.MaxInt64), shards[n-1].Checkpoint.Position.(map[string]interface{})["max_key"])
+	}
+}
+
+func TestSQLInputIncremental(t *testing.T) {
+	path := createTestDB(t, 9)
+	config := map[string]interface{}{
+		"path":        path,
+		"table":       "orders",
+		"incremental": map[string]interface{}{"column": "updated_at"},
+	}
+
+	// updated_at has two rows per value, which must stay in one batch
+	p := newInput(t, config)
+	batch, err := p.ReadBatch(2)
+	require.NoError(t, err)
+	assert.Len(t, batch.Records, 3)
+
+	ids, checkpoint := readAll(t, p, 3)
+	assert.Len(t, ids, 6)
+	position := checkpoint.Position.(map[string]interface{})
+	assert.Equal(t, true, position["done"])
+	assert.Equal(t, int64(1004), position["high_watermark"])
+
+	// A rerun only reads rows changed since the last one
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE orders SET updated_at = 1010 WHERE id IN (2, 5)")
+	require.NoError(t, err)
+	db.Close()
+
+	rerun := newInput(t, config)
+	require.NoError(t, rerun.Seek(plugintest.RoundTrip(t, checkpoint)))
+	ids, _ = readAll(t, rerun, 100)
+	assert.Equal(t, []int64{2, 5}, ids)
+}
+
+func TestSQLInputIncrementalInitialValue(t *testing.T) {
+	path := createTestDB(t, 9)
+	p := newInput(t, map[string]interface{}{
+		"path":  path,
+		"table": "orders",
+		"incremental": map[string]interface{}{
+			"column":        "updated_at",
+			"initial_value": 1003,
+		},
+		"split": map[string]interface{}{"column": "id", "chunk_size": 2},
+	})
+
+	ids, _ := readAll(t, p, 100)
+	assert.Equal(t, []int64{8, 9}, ids)
+}
+
+func TestSQLInputValidation(t *testing.T) {
+	p := &SQLInputPlugin{dialectName: "sqlite"}
+	require.NoError(t, p.Initialize(map[string]interface{}{"path": "x.db"}))
+	assert.Error(t, p.Connect())
+
+	path := createTestDB(t, 1)
+	p = &SQLInputPlugin{dialectName: "sqlite"}
+	require.NoError(t, p.Initialize(map[string]interface{}{
+		"path":        path,
+		"table":       "orders",
+		"incremental": map[string]interface{}{"column": "missing"},
+	}))
+	err := p.Connect()
+	p.Close()
+	assert.Error(t, err)
+
+	p = &SQLInputPlugin{}
+	assert.Error(t, p.Initialize(map[string]interface{}{"path": path}))
+}
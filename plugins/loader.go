@@ -5,13 +5,20 @@ package plugins
 import (
 	// Input plugins
 	_ "github.com/atlanssia/fustgo/plugins/input/csv"
+	_ "github.com/atlanssia/fustgo/plugins/input/json"
+	_ "github.com/atlanssia/fustgo/plugins/input/parquet"
+	_ "github.com/atlanssia/fustgo/plugins/input/rdbms"
 	
 	// Processor plugins
 	_ "github.com/atlanssia/fustgo/plugins/processor/filter"
 	_ "github.com/atlanssia/fustgo/plugins/processor/mapping"
+	_ "github.com/atlanssia/fustgo/plugins/processor/transform"
 	
 	// Output plugins
 	_ "github.com/atlanssia/fustgo/plugins/output/csv"
+	_ "github.com/atlanssia/fustgo/plugins/output/json"
+	_ "github.com/atlanssia/fustgo/plugins/output/parquet"
+	_ "github.com/atlanssia/fustgo/plugins/output/rdbms"
 )
 
 // This file ensures all plugins are imported and registered
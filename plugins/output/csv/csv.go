@@ -3,20 +3,25 @@ package csv
 import (
 	"encoding/csv"
 	"fmt"
-	"os"
 	"time"
 
+	"github.com/atlanssia/fustgo/internal/fileio"
 	"github.com/atlanssia/fustgo/pkg/types"
 )
 
-// CSVOutputPlugin writes data to CSV files
+// CSVOutputPlugin writes data to CSV files, compressed according to the
+// extension of the path or the compression option. With a rolling limit,
+// the path holds a verb for the file index, as in out-%05d.csv.gz, and
+// each file is renamed to its final name once complete.
 type CSVOutputPlugin struct {
 	config     map[string]interface{}
-	file       *os.File
+	output     *fileio.Output
 	writer     *csv.Writer
 	delimiter  rune
 	writeHeader bool
 	headerWritten bool
+	header     []string // Columns named in the header of the current file
+	resume     map[string]interface{} // Set by Resume, continues the output at this position
 	stats      *types.WriteStatistics
 	startTime  time.Time
 }
@@ -72,22 +77,32 @@ func (p *CSVOutputPlugin) Connect() error {
 		return fmt.Errorf("csv output: invalid path configuration")
 	}
 	
-	// Determine file mode
-	mode := os.O_CREATE | os.O_WRONLY
-	if append, ok := p.config["append"].(bool); ok && append {
-		mode |= os.O_APPEND
-		p.headerWritten = true // Don't write header in append mode
-	} else {
-		mode |= os.O_TRUNC
+	config := fileio.OutputConfig{Path: path}
+	config.Compression, _ = p.config["compression"].(string)
+	if size, ok := p.config["roll_size"].(int); ok && size > 0 {
+		config.RollSize = int64(size)
+	}
+	if records, ok := p.config["roll_records"].(int); ok && records > 0 {
+		config.RollRecords = int64(records)
+	}
+	if append, ok := p.config["append"].(bool); ok && append && p.resume == nil {
+		config.Append = true
 	}
 	
-	file, err := os.OpenFile(path, mode, 0644)
+	output, err := fileio.NewOutput(config)
 	if err != nil {
-		return fmt.Errorf("csv output: failed to open file: %w", err)
+		return fmt.Errorf("csv output: %w", err)
+	}
+	// Continue after the committed data of the interrupted run
+	if err := output.Open(p.resume); err != nil {
+		return fmt.Errorf("csv output: %w", err)
 	}
-	p.file = file
+	p.output = output
 	
-	p.writer = csv.NewWriter(file)
+	// Don't write the header in append mode or after resumed rows
+	p.headerWritten = config.Append || !output.Empty()
+	
+	p.writer = csv.NewWriter(output)
 	p.writer.Comma = p.delimiter
 	p.startTime = time.Now()
 	
@@ -104,20 +119,34 @@ func (p *CSVOutputPlugin) WriteBatch(data *types.DataBatch) error {
 		return nil
 	}
 	
-	// Write header if needed
-	if p.writeHeader && !p.headerWritten {
-		header := make([]string, len(data.Schema.Columns))
-		for i, col := range data.Schema.Columns {
-			header[i] = col.Name
-		}
-		if err := p.writer.Write(header); err != nil {
-			return fmt.Errorf("csv output: failed to write header: %w", err)
-		}
-		p.headerWritten = true
+	// Rows must line up with the header of the file, which a full file
+	// leaves to the next one
+	if err := p.roll(); err != nil {
+		return err
+	}
+	if p.header != nil && !sameHeader(p.header, &data.Schema) {
+		return fmt.Errorf("csv output: batch columns differ from the header of the file")
 	}
 	
 	// Write records
 	for _, record := range data.Records {
+		if err := p.roll(); err != nil {
+			return err
+		}
+		
+		// Write header if needed, at the start of every file
+		if p.writeHeader && !p.headerWritten {
+			header := make([]string, len(data.Schema.Columns))
+			for i, col := range data.Schema.Columns {
+				header[i] = col.Name
+			}
+			if err := p.writer.Write(header); err != nil {
+				return fmt.Errorf("csv output: failed to write header: %w", err)
+			}
+			p.headerWritten = true
+			p.header = header
+		}
+		
 		row := make([]string, len(record.Values))
 		for i, val := range record.Values {
 			row[i] = p.formatValue(val)
@@ -128,12 +157,43 @@ func (p *CSVOutputPlugin) WriteBatch(data *types.DataBatch) error {
 			return fmt.Errorf("csv output: failed to write record: %w", err)
 		}
 		
+		p.output.AddRecords(1)
 		p.stats.RecordsWritten++
 	}
 	
 	return nil
 }
 
+// roll starts a new file when the current one is full
+func (p *CSVOutputPlugin) roll() error {
+	if !p.output.Full() {
+		return nil
+	}
+	p.writer.Flush()
+	if err := p.writer.Error(); err != nil {
+		return fmt.Errorf("csv output: flush error: %w", err)
+	}
+	if err := p.output.Roll(); err != nil {
+		return fmt.Errorf("csv output: failed to roll file: %w", err)
+	}
+	p.headerWritten = false
+	p.header = nil
+	return nil
+}
+
+// sameHeader reports whether the schema has the columns of the header
+func sameHeader(header []string, schema *types.Schema) bool {
+	if len(header) != len(schema.Columns) {
+		return false
+	}
+	for i, col := range schema.Columns {
+		if header[i] != col.Name {
+			return false
+		}
+	}
+	return true
+}
+
 // Flush flushes any buffered data to the file
 func (p *CSVOutputPlugin) Flush() error {
 	if p.writer != nil {
@@ -142,31 +202,61 @@ func (p *CSVOutputPlugin) Flush() error {
 			return fmt.Errorf("csv output: flush error: %w", err)
 		}
 		
-		if p.file != nil {
-			if err := p.file.Sync(); err != nil {
-				return fmt.Errorf("csv output: sync error: %w", err)
-			}
+		if err := p.output.Flush(); err != nil {
+			return fmt.Errorf("csv output: %w", err)
 		}
+		p.stats.BytesWritten = p.output.BytesWritten()
 	}
 	
 	p.stats.Duration = time.Since(p.startTime)
 	return nil
 }
 
+// Position returns the size of the flushed file and, when rolling, its
+// index
+func (p *CSVOutputPlugin) Position() (interface{}, error) {
+	if p.output == nil {
+		return nil, fmt.Errorf("csv output: not connected")
+	}
+	return p.output.Position(), nil
+}
+
+// Resume continues the output at a position returned by Position, dropping
+// rows written after it
+func (p *CSVOutputPlugin) Resume(position interface{}) error {
+	pos, ok := position.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("csv output: unexpected position %T", position)
+	}
+	if _, ok := pos["offset"].(float64); !ok {
+		return fmt.Errorf("csv output: invalid position %v", position)
+	}
+	p.resume = pos
+	return nil
+}
+
 // GetWriteStatistics returns write statistics
 func (p *CSVOutputPlugin) GetWriteStatistics() *types.WriteStatistics {
 	p.stats.Duration = time.Since(p.startTime)
 	return p.stats
 }
 
-// Close closes the CSV file
+// Close flushes and completes the current file. Calling Close more than
+// once is a no-op.
 func (p *CSVOutputPlugin) Close() error {
+	if p.output == nil {
+		return nil
+	}
+
 	if err := p.Flush(); err != nil {
 		return err
 	}
-	
-	if p.file != nil {
-		return p.file.Close()
+
+	err := p.output.Close()
+	p.output = nil
+	p.writer = nil
+	if err != nil {
+		return fmt.Errorf("csv output: %w", err)
 	}
 	return nil
 }
@@ -184,7 +274,20 @@ func (p *CSVOutputPlugin) GetMetadata() types.PluginMetadata {
 			"properties": map[string]interface{}{
 				"path": map[string]interface{}{
 					"type":        "string",
-					"description": "Path to output CSV file",
+					"description": "Path to output CSV file; with rolling, it holds a verb for the file index, such as out-%05d.csv.gz",
+				},
+				"compression": map[string]interface{}{
+					"type":        "string",
+					"description": "Compression: auto (by extension), none, gzip, zstd or snappy",
+					"default":     "auto",
+				},
+				"roll_size": map[string]interface{}{
+					"type":        "integer",
+					"description": "Start a new file once the current one reaches this many bytes",
+				},
+				"roll_records": map[string]interface{}{
+					"type":        "integer",
+					"description": "Start a new file once the current one holds this many records",
 				},
 				"write_header": map[string]interface{}{
 					"type":        "boolean",
@@ -0,0 +1,77 @@
+package csv
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/fileio"
+	"github.com/atlanssia/fustgo/internal/plugintest"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func newOutput() types.ResumableOutput {
+	return &CSVOutputPlugin{}
+}
+
+// readFile returns the decompressed contents of a file
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	r, err := fileio.NewReader(file, fileio.DetectCodec(path))
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// readIDs returns the ids of a file, which must start with the header
+func readIDs(t *testing.T, path string) []int64 {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(readFile(t, path), "\n"), "\n")
+	require.Equal(t, "id,name", lines[0], path)
+	var ids []int64
+	for _, line := range lines[1:] {
+		id, err := strconv.ParseInt(strings.Split(line, ",")[0], 10, 64)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestCSVOutputRolling(t *testing.T) {
+	// Every file has a header
+	plugintest.CheckRolling(t, newOutput, map[string]interface{}{}, "out-%05d.csv.gz", readIDs)
+}
+
+func TestCSVOutputResumeCompressed(t *testing.T) {
+	for _, name := range []string{"out.csv.zst", "out-%d.csv.gz"} {
+		plugintest.CheckResume(t, newOutput, map[string]interface{}{}, name, readIDs)
+	}
+}
+
+func TestCSVOutputHeaderMismatch(t *testing.T) {
+	dir := t.TempDir()
+	p := &CSVOutputPlugin{}
+	plugintest.StartOutput(t, p, map[string]interface{}{"path": filepath.Join(dir, "out-%d.csv"), "roll_records": 2}, nil)
+	require.NoError(t, p.WriteBatch(plugintest.Batch(1)))
+
+	// Rows with other columns would not match the header
+	other := plugintest.Batch(2)
+	other.Schema.Columns[0].Name = "key"
+	assert.Error(t, p.WriteBatch(other))
+
+	// A new file starts with the header of its first batch
+	require.NoError(t, p.WriteBatch(plugintest.Batch(2)))
+	require.NoError(t, p.WriteBatch(other))
+	require.NoError(t, p.Close())
+	assert.Equal(t, "key,name\n2,n2\n", readFile(t, filepath.Join(dir, "out-1.csv")))
+}
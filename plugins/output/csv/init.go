@@ -2,9 +2,10 @@ package csv
 
 import (
 	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/pkg/types"
 )
 
 func init() {
 	// Register CSV output plugin
-	plugin.RegisterOutput("csv", &CSVOutputPlugin{})
+	plugin.RegisterOutput("csv", func() types.OutputPlugin { return &CSVOutputPlugin{} })
 }
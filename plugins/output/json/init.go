@@ -0,0 +1,10 @@
+package json
+
+import (
+	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func init() {
+	plugin.RegisterOutput("json", func() types.OutputPlugin { return &JSONOutputPlugin{} })
+}
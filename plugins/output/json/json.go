@@ -0,0 +1,403 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+const (
+	formatNDJSON = "ndjson"
+	formatArray  = "array"
+)
+
+// JSONOutputPlugin writes records as JSON Lines or as a JSON array
+type JSONOutputPlugin struct {
+	config    map[string]interface{}
+	path      string
+	format    string
+	pretty    bool
+	file      *os.File
+	writer    *bufio.Writer
+	written   int64 // Records written, used to place array separators
+	resumed   bool  // Set by Resume, continues the file at resumeAt
+	resumeAt  int64
+	stats     *types.WriteStatistics
+	startTime time.Time
+}
+
+// Name returns the plugin name
+func (p *JSONOutputPlugin) Name() string {
+	return "json"
+}
+
+// Type returns the plugin type
+func (p *JSONOutputPlugin) Type() types.PluginType {
+	return types.PluginTypeOutput
+}
+
+// Initialize initializes the JSON output plugin
+func (p *JSONOutputPlugin) Initialize(config map[string]interface{}) error {
+	p.config = config
+	p.format = formatNDJSON
+	p.written = 0
+	p.resumed = false
+	p.resumeAt = 0
+
+	// Parse configuration
+	p.path, _ = config["path"].(string)
+
+	if format, ok := config["format"].(string); ok && format != "" {
+		if format != formatNDJSON && format != formatArray {
+			return fmt.Errorf("json output: invalid format '%s', must be 'ndjson' or 'array'", format)
+		}
+		p.format = format
+	}
+
+	// Arrays are pretty-printed unless disabled
+	p.pretty = p.format == formatArray
+	if pretty, ok := config["pretty"].(bool); ok {
+		if pretty && p.format == formatNDJSON {
+			return fmt.Errorf("json output: pretty printing is only supported for the array format")
+		}
+		p.pretty = pretty
+	}
+
+	// Initialize statistics
+	p.stats = &types.WriteStatistics{
+		RecordsWritten: 0,
+		RecordsFailed:  0,
+		BytesWritten:   0,
+	}
+
+	return nil
+}
+
+// Validate validates the configuration
+func (p *JSONOutputPlugin) Validate() error {
+	if p.path == "" {
+		return fmt.Errorf("json output: path is required")
+	}
+	return nil
+}
+
+// Connect creates the JSON file
+func (p *JSONOutputPlugin) Connect() error {
+	if p.path == "" {
+		return fmt.Errorf("json output: invalid path configuration")
+	}
+
+	// Arrays cannot be appended to, NDJSON can
+	mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if p.resumed {
+		// Continue after the committed data of the interrupted run
+		mode = os.O_CREATE | os.O_WRONLY
+	} else if appendMode, ok := p.config["append"].(bool); ok && appendMode {
+		if p.format == formatArray {
+			return fmt.Errorf("json output: append is not supported for the array format")
+		}
+		mode = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(p.path, mode, 0644)
+	if err != nil {
+		return fmt.Errorf("json output: failed to open file: %w", err)
+	}
+	p.file = file
+	p.writer = bufio.NewWriter(&countingWriter{w: file, count: &p.stats.BytesWritten})
+	p.startTime = time.Now()
+
+	if p.resumed {
+		if err := truncateAt(file, p.resumeAt); err != nil {
+			return fmt.Errorf("json output: failed to resume file: %w", err)
+		}
+	}
+
+	if p.format == formatArray && p.resumeAt == 0 {
+		if _, err := p.writer.WriteString("["); err != nil {
+			return fmt.Errorf("json output: failed to write array start: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// WriteBatch writes a batch of records to the JSON file. Records that cannot
+// be encoded, such as records holding NaN, are skipped and returned in a
+// *types.RecordWriteError.
+func (p *JSONOutputPlugin) WriteBatch(data *types.DataBatch) error {
+	if p.writer == nil {
+		return fmt.Errorf("json output: not connected")
+	}
+
+	if data == nil || data.IsEmpty() {
+		return nil
+	}
+
+	var rejected []types.RejectedRecord
+	for _, record := range data.Records {
+		obj, err := encodeRecord(data.Schema, record)
+		if err != nil {
+			p.stats.RecordsFailed++
+			rejected = append(rejected, types.RejectedRecord{
+				Record: record,
+				Error:  fmt.Sprintf("json output: failed to encode record: %v", err),
+			})
+			continue
+		}
+
+		if err := p.writeObject(obj); err != nil {
+			p.stats.RecordsFailed++
+			return fmt.Errorf("json output: failed to write record: %w", err)
+		}
+
+		p.written++
+		p.stats.RecordsWritten++
+	}
+
+	if len(rejected) > 0 {
+		return &types.RecordWriteError{Rejected: rejected}
+	}
+	return nil
+}
+
+// writeObject writes one encoded object with the separators of the format
+func (p *JSONOutputPlugin) writeObject(obj []byte) error {
+	if p.format == formatNDJSON {
+		if _, err := p.writer.Write(obj); err != nil {
+			return err
+		}
+		return p.writer.WriteByte('\n')
+	}
+
+	if p.written > 0 {
+		if err := p.writer.WriteByte(','); err != nil {
+			return err
+		}
+	}
+
+	if !p.pretty {
+		_, err := p.writer.Write(obj)
+		return err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, obj, "  ", "  "); err != nil {
+		return err
+	}
+	if _, err := p.writer.WriteString("\n  "); err != nil {
+		return err
+	}
+	_, err := indented.WriteTo(p.writer)
+	return err
+}
+
+// Flush flushes any buffered data to the file
+func (p *JSONOutputPlugin) Flush() error {
+	if p.writer != nil {
+		if err := p.writer.Flush(); err != nil {
+			return fmt.Errorf("json output: flush error: %w", err)
+		}
+
+		if p.file != nil {
+			if err := p.file.Sync(); err != nil {
+				return fmt.Errorf("json output: sync error: %w", err)
+			}
+		}
+	}
+
+	p.stats.Duration = time.Since(p.startTime)
+	return nil
+}
+
+// Position returns the size of the flushed file and the number of records
+// in it
+func (p *JSONOutputPlugin) Position() (interface{}, error) {
+	if p.file == nil {
+		return nil, fmt.Errorf("json output: not connected")
+	}
+	offset, err := p.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("json output: failed to get file position: %w", err)
+	}
+	return map[string]interface{}{"offset": offset, "records": p.written}, nil
+}
+
+// Resume continues the file at a position returned by Position, dropping
+// records written after it
+func (p *JSONOutputPlugin) Resume(position interface{}) error {
+	pos, ok := position.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("json output: unexpected position %T", position)
+	}
+	offset, okOffset := types.ToInt64(pos["offset"])
+	written, okRecords := types.ToInt64(pos["records"])
+	if !okOffset || !okRecords || offset < 0 || written < 0 {
+		return fmt.Errorf("json output: invalid resume position %v", position)
+	}
+	p.resumed = true
+	p.resumeAt = offset
+	p.written = written
+	return nil
+}
+
+// GetWriteStatistics returns write statistics
+func (p *JSONOutputPlugin) GetWriteStatistics() *types.WriteStatistics {
+	p.stats.Duration = time.Since(p.startTime)
+	return p.stats
+}
+
+// Close terminates the array, if any, and closes the file. Calling Close
+// more than once is a no-op.
+func (p *JSONOutputPlugin) Close() error {
+	if p.file == nil {
+		return nil
+	}
+
+	if p.format == formatArray {
+		end := "]\n"
+		if p.pretty && p.written > 0 {
+			end = "\n]\n"
+		}
+		if _, err := p.writer.WriteString(end); err != nil {
+			return fmt.Errorf("json output: failed to write array end: %w", err)
+		}
+	}
+
+	if err := p.Flush(); err != nil {
+		return err
+	}
+
+	err := p.file.Close()
+	p.file = nil
+	p.writer = nil
+	return err
+}
+
+// GetMetadata returns plugin metadata
+func (p *JSONOutputPlugin) GetMetadata() types.PluginMetadata {
+	return types.PluginMetadata{
+		Name:           "json",
+		Type:           types.PluginTypeOutput,
+		Version:        "1.0.0",
+		Description:    "JSON Lines and JSON array file output plugin",
+		DataSourceType: "file",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to output JSON file",
+				},
+				"format": map[string]interface{}{
+					"type":        "string",
+					"description": "Output format: 'ndjson' (one object per line) or 'array' (top-level array)",
+					"enum":        []string{formatNDJSON, formatArray},
+					"default":     formatNDJSON,
+				},
+				"pretty": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Indent objects; only for the array format, where it is the default",
+				},
+				"append": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Append to existing file (ndjson only)",
+					"default":     false,
+				},
+			},
+			"required": []string{"path"},
+		},
+	}
+}
+
+// encodeRecord encodes a record as a JSON object with keys in schema order.
+// JSON columns are embedded as nested values rather than strings.
+func encodeRecord(schema types.Schema, record types.Record) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, col := range schema.Columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(col.Name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		var value interface{}
+		if i < len(record.Values) {
+			value = record.Values[i]
+		}
+
+		encoded, err := encodeValue(col.DataType, value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		buf.Write(encoded)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeValue encodes a single column value
+func encodeValue(dataType types.DataType, value interface{}) ([]byte, error) {
+	if dataType == types.DataTypeJSON {
+		switch v := value.(type) {
+		case string:
+			if json.Valid([]byte(v)) {
+				return []byte(v), nil
+			}
+		case []byte:
+			if json.Valid(v) {
+				return v, nil
+			}
+		}
+	}
+
+	if dataType == types.DataTypeDate {
+		if t, ok := value.(time.Time); ok {
+			return json.Marshal(t.Format("2006-01-02"))
+		}
+	}
+
+	return json.Marshal(value)
+}
+
+// truncateAt cuts the file to offset and continues writing there
+func truncateAt(file *os.File, offset int64) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < offset {
+		return fmt.Errorf("file is shorter (%d bytes) than the checkpoint (%d bytes)", info.Size(), offset)
+	}
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err = file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// countingWriter counts the bytes written to the underlying writer
+type countingWriter struct {
+	w     io.Writer
+	count *int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	*cw.count += int64(n)
+	return n, err
+}
@@ -0,0 +1,184 @@
+package json
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/plugintest"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func newOutput() types.ResumableOutput {
+	return &JSONOutputPlugin{}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// readIDs returns the ids of an NDJSON file or a file holding an array
+func readIDs(t *testing.T, path string) []int64 {
+	t.Helper()
+	data := readFile(t, path)
+	var records []map[string]interface{}
+	if strings.HasPrefix(data, "[") {
+		require.NoError(t, json.Unmarshal([]byte(data), &records))
+	} else {
+		decoder := json.NewDecoder(strings.NewReader(data))
+		for decoder.More() {
+			var record map[string]interface{}
+			require.NoError(t, decoder.Decode(&record))
+			records = append(records, record)
+		}
+	}
+	var ids []int64
+	for _, record := range records {
+		ids = append(ids, int64(record["id"].(float64)))
+	}
+	return ids
+}
+
+func TestJSONOutputNDJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.ndjson")
+	p := &JSONOutputPlugin{}
+	plugintest.StartOutput(t, p, map[string]interface{}{"path": path}, nil)
+
+	batch := &types.DataBatch{
+		Schema: types.Schema{Columns: []types.Column{
+			{Name: "id", DataType: types.DataTypeBigInt},
+			{Name: "born", DataType: types.DataTypeDate},
+			{Name: "attrs", DataType: types.DataTypeJSON},
+			{Name: "score", DataType: types.DataTypeDouble},
+		}},
+		Records: []types.Record{
+			{Values: []interface{}{int64(1), time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), `{"a":[1,2]}`, 1.5}},
+			{Values: []interface{}{int64(2), nil, "not json", nil}},
+			{Values: []interface{}{int64(3), nil, nil, math.NaN()}},
+			{Values: []interface{}{int64(4)}},
+		},
+	}
+
+	// Records JSON cannot encode are rejected, the others written
+	err := p.WriteBatch(batch)
+	var writeErr *types.RecordWriteError
+	require.ErrorAs(t, err, &writeErr)
+	require.Len(t, writeErr.Rejected, 1)
+	assert.Equal(t, int64(3), writeErr.Rejected[0].Record.Values[0])
+	assert.Contains(t, writeErr.Rejected[0].Error, "column score")
+	assert.Equal(t, int64(3), p.GetWriteStatistics().RecordsWritten)
+	assert.Equal(t, int64(1), p.GetWriteStatistics().RecordsFailed)
+
+	require.NoError(t, p.Close())
+	assert.Equal(t, `{"id":1,"born":"2024-05-06","attrs":{"a":[1,2]},"score":1.5}
+{"id":2,"born":null,"attrs":"not json","score":null}
+{"id":4,"born":null,"attrs":null,"score":null}
+`, readFile(t, path))
+	assert.Equal(t, int64(len(readFile(t, path))), p.GetWriteStatistics().BytesWritten)
+
+	// Appending keeps the records of the file
+	p = &JSONOutputPlugin{}
+	plugintest.StartOutput(t, p, map[string]interface{}{"path": path, "append": true}, nil)
+	require.NoError(t, p.WriteBatch(plugintest.Batch(5)))
+	require.NoError(t, p.Close())
+	assert.Contains(t, readFile(t, path), "{\"id\":4,")
+	assert.Contains(t, readFile(t, path), "{\"id\":5,")
+}
+
+func TestJSONOutputArray(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name   string
+		config map[string]interface{}
+		ids    []int64
+		want   string
+	}{
+		{"pretty", map[string]interface{}{}, []int64{1, 2}, "[\n  {\n    \"id\": 1,\n    \"name\": \"n1\"\n  },\n  {\n    \"id\": 2,\n    \"name\": \"n2\"\n  }\n]\n"},
+		{"compact", map[string]interface{}{"pretty": false}, []int64{1, 2}, "[{\"id\":1,\"name\":\"n1\"},{\"id\":2,\"name\":\"n2\"}]\n"},
+		{"empty", map[string]interface{}{}, nil, "[]\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			tt.config["path"] = path
+			tt.config["format"] = "array"
+			p := &JSONOutputPlugin{}
+			plugintest.StartOutput(t, p, tt.config, nil)
+			require.NoError(t, p.WriteBatch(plugintest.Batch(tt.ids...)))
+			require.NoError(t, p.Close())
+			assert.Equal(t, tt.want, readFile(t, path))
+			assert.True(t, json.Valid([]byte(readFile(t, path))))
+		})
+	}
+}
+
+func TestJSONOutputResume(t *testing.T) {
+	for _, format := range []string{"ndjson", "array"} {
+		plugintest.CheckResume(t, newOutput, map[string]interface{}{"format": format}, "out.json", readIDs)
+	}
+}
+
+func TestJSONOutputResumePositions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.ndjson")
+	p := &JSONOutputPlugin{}
+	plugintest.StartOutput(t, p, map[string]interface{}{"path": path}, nil)
+	require.NoError(t, p.WriteBatch(plugintest.Batch(1)))
+	require.NoError(t, p.Flush())
+
+	// Positions read back from JSON hold float64 numbers, in-memory ones the
+	// int64 returned by Position or plain ints
+	pos, err := p.Position()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"offset": int64(21), "records": int64(1)}, pos)
+	for _, at := range []interface{}{
+		pos,
+		plugintest.Position(t, p),
+		map[string]interface{}{"offset": 21, "records": 1},
+	} {
+		resumed := &JSONOutputPlugin{}
+		require.NoError(t, resumed.Initialize(map[string]interface{}{"path": path}))
+		require.NoError(t, resumed.Resume(at), "%#v", at)
+		assert.Equal(t, int64(21), resumed.resumeAt)
+		assert.Equal(t, int64(1), resumed.written)
+	}
+
+	for _, at := range []interface{}{
+		"21",
+		map[string]interface{}{"offset": "21", "records": 1},
+		map[string]interface{}{"offset": 21},
+		map[string]interface{}{"offset": -1, "records": 1},
+	} {
+		resumed := &JSONOutputPlugin{}
+		require.NoError(t, resumed.Initialize(map[string]interface{}{"path": path}))
+		assert.Error(t, resumed.Resume(at), "%#v", at)
+	}
+
+	// A file shorter than the checkpoint cannot be resumed
+	resumed := &JSONOutputPlugin{}
+	require.NoError(t, resumed.Initialize(map[string]interface{}{"path": filepath.Join(t.TempDir(), "new.ndjson")}))
+	require.NoError(t, resumed.Resume(map[string]interface{}{"offset": 21, "records": 1}))
+	assert.Error(t, resumed.Connect())
+	resumed.Close()
+}
+
+func TestJSONOutputConfig(t *testing.T) {
+	p := &JSONOutputPlugin{}
+	assert.Error(t, p.Initialize(map[string]interface{}{"path": "out.json", "format": "xml"}))
+	assert.Error(t, p.Initialize(map[string]interface{}{"path": "out.json", "pretty": true}))
+	require.NoError(t, p.Initialize(map[string]interface{}{}))
+	assert.Error(t, p.Validate())
+	assert.Error(t, p.WriteBatch(plugintest.Batch(1)))
+
+	p = &JSONOutputPlugin{}
+	require.NoError(t, p.Initialize(map[string]interface{}{"path": filepath.Join(t.TempDir(), "out.json"), "format": "array", "append": true}))
+	assert.Error(t, p.Connect())
+}
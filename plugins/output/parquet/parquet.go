@@ -0,0 +1,357 @@
+package parquet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/atlanssia/fustgo/internal/fileio"
+	"github.com/atlanssia/fustgo/internal/parquet"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// ParquetOutputPlugin writes data to Parquet files. Rows are buffered and
+// written as a row group once the group reaches row_group_size bytes or
+// row_group_rows rows, and at every checkpoint, which keeps the file
+// resumable; the footer is written when the file is complete. With a
+// rolling limit, the path holds a verb for the file index, as in
+// part-%05d.parquet, and each file is renamed to its final name once
+// complete.
+type ParquetOutputPlugin struct {
+	config       map[string]interface{}
+	output       *fileio.Output
+	writer       *parquet.Writer
+	writerConfig parquet.WriterConfig
+	schema       *types.Schema          // Schema of the open file
+	resume       map[string]interface{} // Set by Resume, continues the output at this position
+	stats        *types.WriteStatistics
+	startTime    time.Time
+}
+
+// Name returns the plugin name
+func (p *ParquetOutputPlugin) Name() string {
+	return "parquet"
+}
+
+// Type returns the plugin type
+func (p *ParquetOutputPlugin) Type() types.PluginType {
+	return types.PluginTypeOutput
+}
+
+// Initialize initializes the Parquet output plugin
+func (p *ParquetOutputPlugin) Initialize(config map[string]interface{}) error {
+	p.config = config
+
+	compression, _ := config["compression"].(string)
+	codec, err := parquet.ParseCodec(compression)
+	if err != nil {
+		return fmt.Errorf("parquet output: %w", err)
+	}
+	p.writerConfig = parquet.WriterConfig{Compression: codec}
+	if size, ok := config["row_group_size"].(int); ok && size > 0 {
+		p.writerConfig.RowGroupSize = int64(size)
+	}
+	if rows, ok := config["row_group_rows"].(int); ok && rows > 0 {
+		p.writerConfig.RowGroupRows = int64(rows)
+	}
+	if size, ok := config["page_size"].(int); ok && size > 0 {
+		p.writerConfig.PageSize = size
+	}
+
+	p.stats = &types.WriteStatistics{}
+	return nil
+}
+
+// Validate validates the configuration
+func (p *ParquetOutputPlugin) Validate() error {
+	if p.config["path"] == nil {
+		return fmt.Errorf("parquet output: path is required")
+	}
+	return nil
+}
+
+// Connect creates the file or, when resuming, reopens the file of the
+// interrupted run
+func (p *ParquetOutputPlugin) Connect() error {
+	path, ok := p.config["path"].(string)
+	if !ok {
+		return fmt.Errorf("parquet output: invalid path configuration")
+	}
+
+	// Parquet compresses column chunks, not the file
+	config := fileio.OutputConfig{Path: path, Compression: string(fileio.CodecNone)}
+	if size, ok := p.config["roll_size"].(int); ok && size > 0 {
+		config.RollSize = int64(size)
+	}
+	if records, ok := p.config["roll_records"].(int); ok && records > 0 {
+		config.RollRecords = int64(records)
+	}
+
+	output, err := fileio.NewOutput(config)
+	if err != nil {
+		return fmt.Errorf("parquet output: %w", err)
+	}
+	if err := output.Open(p.resume); err != nil {
+		return fmt.Errorf("parquet output: %w", err)
+	}
+	p.output = output
+	p.startTime = time.Now()
+
+	// Continue after the row groups written by the interrupted run
+	if p.resume != nil && !output.Empty() {
+		if err := p.resumeWriter(); err != nil {
+			output.Close()
+			p.output = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// resumeWriter rebuilds the writer of the file of the resume position
+func (p *ParquetOutputPlugin) resumeWriter() error {
+	var schema types.Schema
+	data, err := json.Marshal(p.resume["schema"])
+	if err == nil {
+		err = json.Unmarshal(data, &schema)
+	}
+	if err != nil || len(schema.Columns) == 0 {
+		return fmt.Errorf("parquet output: invalid schema in position %v", p.resume)
+	}
+
+	var rowGroups []int64
+	list, _ := p.resume["row_groups"].([]interface{})
+	for _, item := range list {
+		rows, ok := item.(float64)
+		if !ok {
+			return fmt.Errorf("parquet output: invalid row groups in position %v", p.resume)
+		}
+		rowGroups = append(rowGroups, int64(rows))
+	}
+
+	offset, _ := p.resume["offset"].(float64)
+	writer, err := parquet.NewWriter(p.output, schema, p.writerConfig)
+	if err != nil {
+		return fmt.Errorf("parquet output: %w", err)
+	}
+	if err := writer.Resume(p.output, int64(offset), rowGroups); err != nil {
+		return fmt.Errorf("parquet output: failed to resume file: %w", err)
+	}
+	p.writer = writer
+	p.schema = &schema
+	return nil
+}
+
+// WriteBatch buffers a batch of records, writing row groups when full.
+// Records that do not fit the schema, such as nulls in required columns,
+// are skipped and returned in a *types.RecordWriteError.
+func (p *ParquetOutputPlugin) WriteBatch(data *types.DataBatch) error {
+	if p.output == nil {
+		return fmt.Errorf("parquet output: not connected")
+	}
+
+	if data == nil || data.IsEmpty() {
+		return nil
+	}
+
+	if p.schema != nil && !sameColumns(p.schema, &data.Schema) {
+		return fmt.Errorf("parquet output: batch schema differs from the schema of the file")
+	}
+
+	var rejected []types.RejectedRecord
+	for _, record := range data.Records {
+		if err := p.roll(); err != nil {
+			return err
+		}
+
+		if p.writer == nil {
+			writer, err := parquet.NewWriter(p.output, data.Schema, p.writerConfig)
+			if err != nil {
+				return fmt.Errorf("parquet output: %w", err)
+			}
+			p.writer = writer
+			schema := data.Schema
+			p.schema = &schema
+		}
+
+		if err := p.writer.Write(record.Values); err != nil {
+			p.stats.RecordsFailed++
+			if errors.Is(err, parquet.ErrInvalidRow) {
+				rejected = append(rejected, types.RejectedRecord{
+					Record: record,
+					Error:  fmt.Sprintf("parquet output: failed to write record: %v", err),
+				})
+				continue
+			}
+			return fmt.Errorf("parquet output: failed to write record: %w", err)
+		}
+
+		p.output.AddRecords(1)
+		p.stats.RecordsWritten++
+	}
+
+	if len(rejected) > 0 {
+		return &types.RecordWriteError{Rejected: rejected}
+	}
+	return nil
+}
+
+// sameColumns reports whether two schemas have the same column names and
+// types
+func sameColumns(a, b *types.Schema) bool {
+	if len(a.Columns) != len(b.Columns) {
+		return false
+	}
+	for i := range a.Columns {
+		if a.Columns[i].Name != b.Columns[i].Name || a.Columns[i].DataType != b.Columns[i].DataType {
+			return false
+		}
+	}
+	return true
+}
+
+// roll completes the current file and starts a new one when it is full
+func (p *ParquetOutputPlugin) roll() error {
+	if !p.output.Full() {
+		return nil
+	}
+	if err := p.closeWriter(); err != nil {
+		return err
+	}
+	if err := p.output.Roll(); err != nil {
+		return fmt.Errorf("parquet output: failed to roll file: %w", err)
+	}
+	return nil
+}
+
+// closeWriter writes the footer of the current file
+func (p *ParquetOutputPlugin) closeWriter() error {
+	if p.writer == nil {
+		return nil
+	}
+	err := p.writer.Close()
+	p.writer = nil
+	p.schema = nil
+	if err != nil {
+		return fmt.Errorf("parquet output: %w", err)
+	}
+	return nil
+}
+
+// Flush writes the buffered rows as a row group and syncs the file
+func (p *ParquetOutputPlugin) Flush() error {
+	if p.writer != nil {
+		if err := p.writer.Flush(); err != nil {
+			return fmt.Errorf("parquet output: %w", err)
+		}
+	}
+	if p.output != nil {
+		if err := p.output.Flush(); err != nil {
+			return fmt.Errorf("parquet output: %w", err)
+		}
+		p.stats.BytesWritten = p.output.BytesWritten()
+	}
+
+	p.stats.Duration = time.Since(p.startTime)
+	return nil
+}
+
+// Position returns the size of the flushed file, the rows of its row
+// groups and its schema, which are needed to write its footer on resume,
+// and, when rolling, its index
+func (p *ParquetOutputPlugin) Position() (interface{}, error) {
+	if p.output == nil {
+		return nil, fmt.Errorf("parquet output: not connected")
+	}
+	position := p.output.Position()
+	if p.writer != nil {
+		position["row_groups"] = p.writer.RowGroups()
+		position["schema"] = p.schema
+	}
+	return position, nil
+}
+
+// Resume continues the output at a position returned by Position, dropping
+// rows written after it
+func (p *ParquetOutputPlugin) Resume(position interface{}) error {
+	pos, ok := position.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("parquet output: unexpected position %T", position)
+	}
+	if _, ok := pos["offset"].(float64); !ok {
+		return fmt.Errorf("parquet output: invalid position %v", position)
+	}
+	p.resume = pos
+	return nil
+}
+
+// GetWriteStatistics returns write statistics
+func (p *ParquetOutputPlugin) GetWriteStatistics() *types.WriteStatistics {
+	p.stats.Duration = time.Since(p.startTime)
+	return p.stats
+}
+
+// Close writes the remaining rows and the footer, and completes the
+// current file. Calling Close more than once is a no-op.
+func (p *ParquetOutputPlugin) Close() error {
+	if p.output == nil {
+		return nil
+	}
+
+	err := p.closeWriter()
+	if closeErr := p.output.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("parquet output: %w", closeErr)
+	}
+	p.stats.BytesWritten = p.output.BytesWritten()
+	p.output = nil
+	return err
+}
+
+// GetMetadata returns plugin metadata
+func (p *ParquetOutputPlugin) GetMetadata() types.PluginMetadata {
+	return types.PluginMetadata{
+		Name:           "parquet",
+		Type:           types.PluginTypeOutput,
+		Version:        "1.0.0",
+		Description:    "Parquet file output plugin",
+		DataSourceType: "file",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to output Parquet file; with rolling, it holds a verb for the file index, such as part-%05d.parquet",
+				},
+				"compression": map[string]interface{}{
+					"type":        "string",
+					"description": "Compression of the column chunks: snappy, gzip, zstd, brotli, lz4_raw or none",
+					"default":     "snappy",
+				},
+				"row_group_size": map[string]interface{}{
+					"type":        "integer",
+					"description": "Write a row group once the buffered rows reach this many bytes before compression",
+					"default":     parquet.DefaultRowGroupSize,
+				},
+				"row_group_rows": map[string]interface{}{
+					"type":        "integer",
+					"description": "Write a row group once this many rows are buffered",
+				},
+				"page_size": map[string]interface{}{
+					"type":        "integer",
+					"description": "Size of the data pages before compression",
+					"default":     parquet.DefaultPageSize,
+				},
+				"roll_size": map[string]interface{}{
+					"type":        "integer",
+					"description": "Start a new file once the current one reaches this many bytes",
+				},
+				"roll_records": map[string]interface{}{
+					"type":        "integer",
+					"description": "Start a new file once the current one holds this many records",
+				},
+			},
+			"required": []string{"path"},
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package parquet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/internal/parquet"
+	"github.com/atlanssia/fustgo/internal/plugintest"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func newOutput() types.ResumableOutput {
+	return &ParquetOutputPlugin{}
+}
+
+// readIDs returns the ids of a Parquet file
+func readIDs(t *testing.T, path string) []int64 {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	info, err := file.Stat()
+	require.NoError(t, err)
+	r, err := parquet.NewReader(file, info.Size())
+	require.NoError(t, err)
+
+	var ids []int64
+	for i := 0; i < r.NumRowGroups(); i++ {
+		rows, err := r.ReadRowGroup(i, []int{0})
+		require.NoError(t, err)
+		for _, row := range rows {
+			ids = append(ids, row[0].(int64))
+		}
+	}
+	return ids
+}
+
+func TestParquetOutputRolling(t *testing.T) {
+	plugintest.CheckRolling(t, newOutput, map[string]interface{}{"compression": "zstd"}, "part-%02d.parquet", readIDs)
+}
+
+func TestParquetOutputResume(t *testing.T) {
+	for _, name := range []string{"out.parquet", "part-%d.parquet"} {
+		plugintest.CheckResume(t, newOutput, map[string]interface{}{}, name, readIDs)
+	}
+}
+
+func TestParquetOutputErrors(t *testing.T) {
+	p := &ParquetOutputPlugin{}
+	assert.Error(t, p.Initialize(map[string]interface{}{"path": "out.parquet", "compression": "lz4"}))
+
+	path := filepath.Join(t.TempDir(), "out.parquet")
+	p = &ParquetOutputPlugin{}
+	plugintest.StartOutput(t, p, map[string]interface{}{"path": path}, nil)
+	require.NoError(t, p.WriteBatch(plugintest.Batch(1)))
+
+	// Schemas cannot change within a file
+	other := plugintest.Batch(2)
+	other.Schema.Columns[1].DataType = types.DataTypeDate
+	assert.Error(t, p.WriteBatch(other))
+
+	// Nulls are only written to nullable columns; the other records of the
+	// batch are written
+	batch := plugintest.Batch(3, 4, 5)
+	batch.Records[1].Values[0] = nil
+	err := p.WriteBatch(batch)
+	var writeErr *types.RecordWriteError
+	require.ErrorAs(t, err, &writeErr)
+	require.Len(t, writeErr.Rejected, 1)
+	assert.Nil(t, writeErr.Rejected[0].Record.Values[0])
+	assert.Equal(t, int64(1), p.GetWriteStatistics().RecordsFailed)
+	assert.Equal(t, int64(3), p.GetWriteStatistics().RecordsWritten)
+
+	require.NoError(t, p.Close())
+	assert.Equal(t, []int64{1, 3, 5}, readIDs(t, path))
+}
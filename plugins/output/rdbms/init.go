@@ -0,0 +1,13 @@
+package rdbms
+
+import (
+	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func init() {
+	plugin.RegisterOutput("sql", func() types.OutputPlugin { return &SQLOutputPlugin{} })
+	plugin.RegisterOutput("mysql", func() types.OutputPlugin { return &SQLOutputPlugin{dialectName: "mysql"} })
+	plugin.RegisterOutput("postgresql", func() types.OutputPlugin { return &SQLOutputPlugin{dialectName: "postgresql"} })
+	plugin.RegisterOutput("sqlite", func() types.OutputPlugin { return &SQLOutputPlugin{dialectName: "sqlite"} })
+}
@@ -0,0 +1,486 @@
+package rdbms
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atlanssia/fustgo/internal/sqldb"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+const (
+	modeInsert   = "insert"
+	modeUpsert   = "upsert"
+	modeTruncate = "truncate"
+
+	defaultBatchSize = 500
+)
+
+// SQLOutputPlugin writes records to a database table through database/sql.
+//
+// Each batch is written in one transaction with prepared multi-row INSERT
+// statements, so a failed batch leaves nothing behind. In upsert mode rows
+// conflicting on the key columns replace the existing ones, and in truncate
+// mode the table is emptied before loading, unless the run resumes an
+// interrupted one. When the schema of the data evolves, new columns are
+// added to the table and widened columns change type.
+type SQLOutputPlugin struct {
+	dialectName string // Preset by the registered plugin name, empty for "sql"
+
+	config      map[string]interface{}
+	connCfg     *sqldb.Config
+	db          *sql.DB
+	dialect     sqldb.Dialect
+	table       string
+	mode        string
+	keyColumns  []string
+	batchSize   int
+	createTable bool
+
+	// Statement state for the current schema
+	columns     []string
+	columnTypes []types.DataType
+	upsertKeys  []string
+	tableKeys   []string // Primary keys of the table created from the schema
+	rowsPerStmt int
+	prepared    bool
+
+	// Set by Resume: the rows written before the checkpoint are kept
+	resumed     bool
+	resumedRows int64
+
+	stats     *types.WriteStatistics
+	startTime time.Time
+}
+
+// Name returns the plugin name
+func (p *SQLOutputPlugin) Name() string {
+	if p.dialectName != "" {
+		return p.dialectName
+	}
+	return "sql"
+}
+
+// Type returns the plugin type
+func (p *SQLOutputPlugin) Type() types.PluginType {
+	return types.PluginTypeOutput
+}
+
+// Initialize initializes the SQL output plugin
+func (p *SQLOutputPlugin) Initialize(config map[string]interface{}) error {
+	p.config = config
+	p.mode = modeInsert
+	p.batchSize = defaultBatchSize
+	p.createTable = true
+	p.prepared = false
+
+	connCfg, err := sqldb.ParseConfig(config, p.dialectName)
+	if err != nil {
+		return fmt.Errorf("%s output: %w", p.Name(), err)
+	}
+	p.connCfg = connCfg
+
+	// Parse configuration
+	p.table, _ = config["table"].(string)
+
+	if mode, ok := config["mode"].(string); ok && mode != "" {
+		if mode != modeInsert && mode != modeUpsert && mode != modeTruncate {
+			return fmt.Errorf("%s output: invalid mode '%s', must be 'insert', 'upsert' or 'truncate'", p.Name(), mode)
+		}
+		p.mode = mode
+	}
+
+	if keys, ok := config["key_columns"].([]interface{}); ok {
+		for _, key := range keys {
+			if name, ok := key.(string); ok {
+				p.keyColumns = append(p.keyColumns, name)
+			}
+		}
+	}
+
+	if size, ok := config["batch_size"].(int); ok && size > 0 {
+		p.batchSize = size
+	}
+
+	if create, ok := config["create_table"].(bool); ok {
+		p.createTable = create
+	}
+
+	// Initialize statistics
+	p.stats = &types.WriteStatistics{
+		RecordsWritten: 0,
+		RecordsFailed:  0,
+		BytesWritten:   0,
+	}
+
+	return nil
+}
+
+// Validate validates the configuration
+func (p *SQLOutputPlugin) Validate() error {
+	if p.table == "" {
+		return fmt.Errorf("%s output: table is required", p.Name())
+	}
+	return nil
+}
+
+// Connect opens the database. In truncate mode an existing table is
+// emptied here, so that it is empty even when no records arrive, unless the
+// run resumes.
+func (p *SQLOutputPlugin) Connect() error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	db, dialect, err := sqldb.Open(p.connCfg)
+	if err != nil {
+		return fmt.Errorf("%s output: %w", p.Name(), err)
+	}
+	p.db = db
+	p.dialect = dialect
+	p.startTime = time.Now()
+
+	if p.mode == modeTruncate && !p.resumed && sqldb.TableExists(db, dialect, p.table) {
+		if _, err := db.Exec(dialect.TruncateSQL(p.table)); err != nil {
+			return fmt.Errorf("%s output: failed to truncate %s: %w", p.Name(), p.table, err)
+		}
+	}
+
+	return nil
+}
+
+// WriteBatch writes a batch of records in a single transaction
+func (p *SQLOutputPlugin) WriteBatch(data *types.DataBatch) error {
+	if p.db == nil {
+		return fmt.Errorf("%s output: not connected", p.Name())
+	}
+
+	if data == nil || data.IsEmpty() {
+		return nil
+	}
+
+	if err := p.prepare(&data.Schema); err != nil {
+		return err
+	}
+
+	bytes, err := p.insert(data.Records)
+	if err != nil {
+		p.stats.RecordsFailed += int64(len(data.Records))
+		return fmt.Errorf("%s output: failed to write batch: %w", p.Name(), err)
+	}
+
+	p.stats.RecordsWritten += int64(len(data.Records))
+	p.stats.BytesWritten += bytes
+	return nil
+}
+
+// Transactional reports that a failed batch leaves nothing behind, so the
+// pipeline can retry it record by record
+func (p *SQLOutputPlugin) Transactional() bool {
+	return true
+}
+
+// prepare creates the table if needed and builds the statement layout for
+// the batch schema. It does nothing while the columns stay the same.
+func (p *SQLOutputPlugin) prepare(schema *types.Schema) error {
+	if p.prepared && sameColumns(p.columns, schema) {
+		return nil
+	}
+
+	if len(schema.Columns) == 0 {
+		return fmt.Errorf("%s output: batch has no columns", p.Name())
+	}
+
+	p.upsertKeys = nil
+	if p.mode == modeUpsert {
+		p.upsertKeys = p.keyColumns
+		if len(p.upsertKeys) == 0 {
+			p.upsertKeys = schema.PrimaryKeys
+		}
+		if len(p.upsertKeys) == 0 {
+			return fmt.Errorf("%s output: upsert requires primary keys in the schema or key_columns", p.Name())
+		}
+		for _, key := range p.upsertKeys {
+			if schema.ColumnIndex(key) < 0 {
+				return fmt.Errorf("%s output: key column %s is not in the schema", p.Name(), key)
+			}
+		}
+	}
+
+	if p.createTable {
+		tableSchema := *schema
+		if len(p.keyColumns) > 0 {
+			tableSchema.PrimaryKeys = p.keyColumns
+		}
+		if _, err := p.db.Exec(sqldb.CreateTableSQL(p.dialect, p.table, &tableSchema)); err != nil {
+			return fmt.Errorf("%s output: failed to create table %s: %w", p.Name(), p.table, err)
+		}
+		p.tableKeys = tableSchema.PrimaryKeys
+	}
+
+	p.columns = make([]string, len(schema.Columns))
+	p.columnTypes = make([]types.DataType, len(schema.Columns))
+	for i, col := range schema.Columns {
+		p.columns[i] = col.Name
+		p.columnTypes[i] = col.DataType
+	}
+
+	p.rowsPerStmt = p.batchSize
+	if limit := p.dialect.MaxParams() / len(p.columns); limit < p.rowsPerStmt {
+		p.rowsPerStmt = limit
+	}
+	if p.rowsPerStmt < 1 {
+		p.rowsPerStmt = 1
+	}
+
+	p.prepared = true
+	return nil
+}
+
+// insert writes records with prepared multi-row statements in one
+// transaction and returns the approximate number of bytes bound
+func (p *SQLOutputPlugin) insert(records []types.Record) (int64, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var bytes int64
+	statements := make(map[int]*sql.Stmt)
+	args := make([]interface{}, 0, p.rowsPerStmt*len(p.columns))
+
+	for start := 0; start < len(records); start += p.rowsPerStmt {
+		end := start + p.rowsPerStmt
+		if end > len(records) {
+			end = len(records)
+		}
+
+		// At most two statements: full chunks and the remainder
+		rows := end - start
+		stmt, ok := statements[rows]
+		if !ok {
+			query := sqldb.InsertSQL(p.dialect, p.table, p.columns, rows, p.upsertKeys)
+			stmt, err = tx.Prepare(query)
+			if err != nil {
+				return 0, fmt.Errorf("failed to prepare insert: %w", err)
+			}
+			defer stmt.Close()
+			statements[rows] = stmt
+		}
+
+		args = args[:0]
+		for _, record := range records[start:end] {
+			for i := range p.columns {
+				var v interface{}
+				if i < len(record.Values) {
+					v = sqldb.ConvertValue(record.Values[i], p.columnTypes[i])
+				}
+				bytes += valueSize(v)
+				args = append(args, v)
+			}
+		}
+
+		if _, err := stmt.Exec(args...); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return bytes, nil
+}
+
+// AddColumns adds nullable columns to the table when the schema of the
+// data gains columns. Columns the table already has are skipped.
+func (p *SQLOutputPlugin) AddColumns(columns []types.Column) error {
+	if p.db == nil {
+		return fmt.Errorf("%s output: not connected", p.Name())
+	}
+
+	existing, err := sqldb.TableColumns(p.db, p.dialect, p.table)
+	if err != nil {
+		return fmt.Errorf("%s output: failed to read columns of %s: %w", p.Name(), p.table, err)
+	}
+	has := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		has[strings.ToLower(name)] = true
+	}
+
+	for _, col := range columns {
+		if has[strings.ToLower(col.Name)] {
+			continue
+		}
+		col.Nullable = true
+		if _, err := p.db.Exec(sqldb.AddColumnSQL(p.dialect, p.table, col)); err != nil {
+			return fmt.Errorf("%s output: failed to add column %s to %s: %w", p.Name(), col.Name, p.table, err)
+		}
+	}
+
+	p.prepared = false
+	return nil
+}
+
+// AlterColumns changes the types of columns of the table when the schema of
+// the data widens them
+func (p *SQLOutputPlugin) AlterColumns(columns []types.Column) error {
+	if p.db == nil {
+		return fmt.Errorf("%s output: not connected", p.Name())
+	}
+
+	for _, col := range columns {
+		query := sqldb.AlterColumnSQL(p.dialect, p.table, col, p.isKey(col.Name))
+		if query == "" {
+			continue
+		}
+		if _, err := p.db.Exec(query); err != nil {
+			return fmt.Errorf("%s output: failed to change type of column %s of %s: %w", p.Name(), col.Name, p.table, err)
+		}
+	}
+
+	p.prepared = false
+	return nil
+}
+
+// isKey reports whether a column is a key of the table
+func (p *SQLOutputPlugin) isKey(name string) bool {
+	for _, keys := range [][]string{p.keyColumns, p.upsertKeys, p.tableKeys} {
+		for _, key := range keys {
+			if key == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Flush is a no-op, as every batch is committed by WriteBatch
+func (p *SQLOutputPlugin) Flush() error {
+	p.stats.Duration = time.Since(p.startTime)
+	return nil
+}
+
+// Position returns the number of rows written by the run, counting those
+// of the interrupted runs it resumes
+func (p *SQLOutputPlugin) Position() (interface{}, error) {
+	return map[string]interface{}{"rows": p.resumedRows + p.stats.RecordsWritten}, nil
+}
+
+// Resume continues an interrupted run at a position returned by Position.
+// The rows committed before the checkpoint are kept, also in truncate mode.
+// Committed rows cannot be taken back, so the batches committed after the
+// checkpoint are written again; upsert mode keeps them unique.
+func (p *SQLOutputPlugin) Resume(position interface{}) error {
+	pos, ok := position.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s output: unexpected position %T", p.Name(), position)
+	}
+	rows, ok := types.ToInt64(pos["rows"])
+	if !ok {
+		return fmt.Errorf("%s output: invalid position %v", p.Name(), position)
+	}
+	p.resumed = true
+	p.resumedRows = rows
+	return nil
+}
+
+// GetWriteStatistics returns write statistics. BytesWritten is the size of
+// the bound values, not of the wire protocol.
+func (p *SQLOutputPlugin) GetWriteStatistics() *types.WriteStatistics {
+	p.stats.Duration = time.Since(p.startTime)
+	return p.stats
+}
+
+// Close closes the database. Calling Close more than once is a no-op.
+func (p *SQLOutputPlugin) Close() error {
+	if p.db == nil {
+		return nil
+	}
+
+	p.stats.Duration = time.Since(p.startTime)
+	err := p.db.Close()
+	p.db = nil
+	return err
+}
+
+// GetMetadata returns plugin metadata
+func (p *SQLOutputPlugin) GetMetadata() types.PluginMetadata {
+	return types.PluginMetadata{
+		Name:           p.Name(),
+		Type:           types.PluginTypeOutput,
+		Version:        "1.0.0",
+		Description:    "SQL database output plugin (MySQL, PostgreSQL, SQLite) with batch insert and upsert",
+		DataSourceType: "database",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"connection": map[string]interface{}{
+					"type":        "object",
+					"description": "Connection settings: dsn, or host, port, database (file path for SQLite), user, password, params",
+				},
+				"dialect": map[string]interface{}{
+					"type":        "string",
+					"description": "Database dialect for the generic 'sql' plugin: mysql, postgresql or sqlite",
+				},
+				"table": map[string]interface{}{
+					"type":        "string",
+					"description": "Target table",
+				},
+				"mode": map[string]interface{}{
+					"type":        "string",
+					"description": "Write mode: 'insert', 'upsert' (update rows with the same key) or 'truncate' (empty the table, then insert)",
+					"enum":        []string{modeInsert, modeUpsert, modeTruncate},
+					"default":     modeInsert,
+				},
+				"key_columns": map[string]interface{}{
+					"type":        "array",
+					"description": "Key columns for upsert and table creation (default schema primary keys)",
+					"items":       map[string]interface{}{"type": "string"},
+				},
+				"batch_size": map[string]interface{}{
+					"type":        "integer",
+					"description": "Rows per INSERT statement",
+					"default":     defaultBatchSize,
+				},
+				"create_table": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Create the table from the schema if it does not exist",
+					"default":     true,
+				},
+			},
+			"required": []string{"table"},
+		},
+	}
+}
+
+// sameColumns reports whether the schema has the given column names
+func sameColumns(columns []string, schema *types.Schema) bool {
+	if len(columns) != len(schema.Columns) {
+		return false
+	}
+	for i, col := range schema.Columns {
+		if columns[i] != col.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// valueSize approximates the size of a bound value
+func valueSize(v interface{}) int64 {
+	switch val := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(val))
+	case []byte:
+		return int64(len(val))
+	case bool:
+		return 1
+	default:
+		return 8
+	}
+}
@@ -0,0 +1,205 @@
+package rdbms
+
+import (
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+var testSchema = types.Schema{
+	Columns: []types.Column{
+		{Name: "id", DataType: types.DataTypeBigInt},
+		{Name: "name", DataType: types.DataTypeString, Nullable: true},
+		{Name: "score", DataType: types.DataTypeDouble, Nullable: true},
+	},
+	PrimaryKeys: []string{"id"},
+}
+
+func testBatch(from, to int, name string) *types.DataBatch {
+	batch := &types.DataBatch{Schema: testSchema}
+	for i := from; i <= to; i++ {
+		batch.Records = append(batch.Records, types.Record{
+			Values: []interface{}{int64(i), name, float64(i) / 2},
+		})
+	}
+	return batch
+}
+
+func newOutput(t *testing.T, path string, config map[string]interface{}) *SQLOutputPlugin {
+	config["path"] = path
+	config["table"] = "scores"
+
+	p := &SQLOutputPlugin{dialectName: "sqlite"}
+	require.NoError(t, p.Initialize(config))
+	require.NoError(t, p.Connect())
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func queryRows(t *testing.T, path, query string) map[int64]string {
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query(query)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	result := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var name string
+		require.NoError(t, rows.Scan(&id, &name))
+		result[id] = name
+	}
+	return result
+}
+
+func TestSQLOutputInsert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.db")
+
+	// Small statements exercise the full-chunk and remainder statements
+	p := newOutput(t, path, map[string]interface{}{"batch_size": 4})
+	require.NoError(t, p.WriteBatch(testBatch(1, 10, "a")))
+	require.NoError(t, p.WriteBatch(testBatch(11, 12, "b")))
+	require.NoError(t, p.Flush())
+
+	stats := p.GetWriteStatistics()
+	assert.Equal(t, int64(12), stats.RecordsWritten)
+	assert.Equal(t, int64(0), stats.RecordsFailed)
+	assert.Greater(t, stats.BytesWritten, int64(0))
+
+	rows := queryRows(t, path, "SELECT id, name FROM scores")
+	assert.Len(t, rows, 12)
+	assert.Equal(t, "b", rows[12])
+
+	// A duplicate key fails the whole batch
+	err := p.WriteBatch(testBatch(12, 13, "c"))
+	assert.Error(t, err)
+	assert.Equal(t, int64(12), p.GetWriteStatistics().RecordsWritten)
+	assert.Equal(t, int64(2), p.GetWriteStatistics().RecordsFailed)
+	assert.Len(t, queryRows(t, path, "SELECT id, name FROM scores"), 12)
+}
+
+func TestSQLOutputUpsert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.db")
+
+	p := newOutput(t, path, map[string]interface{}{"mode": "upsert"})
+	require.NoError(t, p.WriteBatch(testBatch(1, 5, "old")))
+	require.NoError(t, p.WriteBatch(testBatch(4, 6, "new")))
+
+	rows := queryRows(t, path, "SELECT id, name FROM scores")
+	assert.Len(t, rows, 6)
+	assert.Equal(t, "old", rows[3])
+	assert.Equal(t, "new", rows[4])
+	assert.Equal(t, int64(8), p.GetWriteStatistics().RecordsWritten)
+}
+
+func TestSQLOutputUpsertRequiresKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.db")
+
+	p := newOutput(t, path, map[string]interface{}{"mode": "upsert"})
+	batch := testBatch(1, 1, "x")
+	batch.Schema.PrimaryKeys = nil
+	assert.Error(t, p.WriteBatch(batch))
+
+	p = newOutput(t, path, map[string]interface{}{
+		"mode":        "upsert",
+		"key_columns": []interface{}{"id"},
+	})
+	assert.NoError(t, p.WriteBatch(batch))
+}
+
+func TestSQLOutputTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.db")
+
+	p := newOutput(t, path, map[string]interface{}{})
+	require.NoError(t, p.WriteBatch(testBatch(1, 5, "first")))
+	require.NoError(t, p.Close())
+
+	p = newOutput(t, path, map[string]interface{}{"mode": "truncate"})
+	require.NoError(t, p.WriteBatch(testBatch(3, 4, "second")))
+
+	rows := queryRows(t, path, "SELECT id, name FROM scores")
+	assert.Equal(t, map[int64]string{3: "second", 4: "second"}, rows)
+}
+
+func TestSQLOutputResumeTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.db")
+	config := map[string]interface{}{"mode": "truncate"}
+
+	// The interrupted run committed two batches before its checkpoint
+	p := newOutput(t, path, config)
+	require.NoError(t, p.WriteBatch(testBatch(1, 3, "first")))
+	require.NoError(t, p.WriteBatch(testBatch(4, 5, "first")))
+	require.NoError(t, p.Flush())
+	position, err := p.Position()
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+
+	// The pipeline stores the position as JSON
+	encoded, err := json.Marshal(position)
+	require.NoError(t, err)
+	var decoded interface{}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+
+	// The resumed run keeps them and adds the rest
+	p = &SQLOutputPlugin{dialectName: "sqlite"}
+	require.NoError(t, p.Initialize(map[string]interface{}{"path": path, "table": "scores", "mode": "truncate"}))
+	require.NoError(t, p.Resume(decoded))
+	require.NoError(t, p.Connect())
+	require.NoError(t, p.WriteBatch(testBatch(6, 7, "second")))
+	position, err = p.Position()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"rows": int64(7)}, position)
+	require.NoError(t, p.Close())
+	assert.Len(t, queryRows(t, path, "SELECT id, name FROM scores"), 7)
+
+	// Positions set in Go resume as well
+	p = &SQLOutputPlugin{dialectName: "sqlite"}
+	require.NoError(t, p.Initialize(map[string]interface{}{"path": path, "table": "scores"}))
+	assert.NoError(t, p.Resume(map[string]interface{}{"rows": 7}))
+	assert.Error(t, p.Resume(map[string]interface{}{"rows": "7"}))
+	assert.Error(t, p.Resume("7"))
+
+	// A new run empties the table
+	p = newOutput(t, path, map[string]interface{}{"mode": "truncate"})
+	require.NoError(t, p.WriteBatch(testBatch(1, 1, "third")))
+	assert.Equal(t, map[int64]string{1: "third"}, queryRows(t, path, "SELECT id, name FROM scores"))
+}
+
+func TestSQLOutputExistingTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE scores (id INTEGER, name TEXT, score REAL, extra TEXT DEFAULT 'x')")
+	require.NoError(t, err)
+	db.Close()
+
+	p := newOutput(t, path, map[string]interface{}{"create_table": false})
+	require.NoError(t, p.WriteBatch(testBatch(1, 2, "a")))
+	assert.Len(t, queryRows(t, path, "SELECT id, extra FROM scores"), 2)
+}
+
+func TestSQLOutputAddColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.db")
+	p := newOutput(t, path, map[string]interface{}{})
+	require.NoError(t, p.WriteBatch(testBatch(1, 2, "a")))
+
+	// Columns the table already has are skipped
+	level := types.Column{Name: "level", DataType: types.DataTypeInt}
+	require.NoError(t, p.AddColumns([]types.Column{level, {Name: "name", DataType: types.DataTypeString}}))
+	require.NoError(t, p.AlterColumns([]types.Column{{Name: "score", DataType: types.DataTypeString, Nullable: true}}))
+
+	batch := &types.DataBatch{Schema: types.Schema{Columns: append(append([]types.Column(nil), testSchema.Columns...), level)}}
+	batch.Records = []types.Record{{Values: []interface{}{int64(3), "b", 1.5, 7}}}
+	require.NoError(t, p.WriteBatch(batch))
+
+	assert.Equal(t, map[int64]string{1: "", 2: "", 3: "7"}, queryRows(t, path, "SELECT id, COALESCE(level, '') FROM scores"))
+}
@@ -2,9 +2,9 @@ package filter
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
+	"github.com/atlanssia/fustgo/internal/expr"
 	"github.com/atlanssia/fustgo/pkg/types"
 )
 
@@ -12,6 +12,7 @@ import (
 type FilterProcessor struct {
 	config    map[string]interface{}
 	condition string
+	program   *expr.Program
 	mode      string // "include" or "exclude"
 	stats     *types.ProcessStatistics
 	startTime time.Time
@@ -30,12 +31,23 @@ func (p *FilterProcessor) Type() types.PluginType {
 // Initialize initializes the filter processor
 func (p *FilterProcessor) Initialize(config map[string]interface{}) error {
 	p.config = config
+	p.condition = ""
+	p.program = nil
 	p.mode = "include"
 	
 	// Parse configuration
 	if condition, ok := config["condition"].(string); ok {
 		p.condition = condition
 	}
+
+	// Compile the condition once, so syntax errors surface before any data is read
+	if p.condition != "" {
+		program, err := expr.Compile(p.condition)
+		if err != nil {
+			return fmt.Errorf("filter: %w", err)
+		}
+		p.program = program
+	}
 	
 	if mode, ok := config["mode"].(string); ok {
 		if mode == "include" || mode == "exclude" {
@@ -71,15 +83,23 @@ func (p *FilterProcessor) Process(input *types.DataBatch) (*types.DataBatch, err
 	if input == nil || input.IsEmpty() {
 		return input, nil
 	}
+	if p.program == nil {
+		return nil, fmt.Errorf("filter: condition is required")
+	}
 	
 	var filteredRecords []types.Record
+	var rejected []types.RejectedRecord
+	env := expr.NewRecordEnv(input.Schema)
 	
-	for _, record := range input.Records {
+	for i := range input.Records {
+		record := input.Records[i]
 		p.stats.RecordsIn++
 		
-		match, err := p.evaluateCondition(record, input.Schema)
+		env.SetRecord(&record)
+		match, err := p.program.EvalBool(env)
 		if err != nil {
 			p.stats.Errors++
+			rejected = append(rejected, types.RejectedRecord{Record: record, Error: err.Error()})
 			continue
 		}
 		
@@ -99,6 +119,7 @@ func (p *FilterProcessor) Process(input *types.DataBatch) (*types.DataBatch, err
 		Records:    filteredRecords,
 		Metadata:   input.Metadata,
 		Checkpoint: input.Checkpoint,
+		Rejected:   rejected,
 	}
 	
 	return output, nil
@@ -112,7 +133,9 @@ func (p *FilterProcessor) GetStatistics() *types.ProcessStatistics {
 
 // Close closes the processor
 func (p *FilterProcessor) Close() error {
-	p.stats.Duration = time.Since(p.startTime)
+	if p.stats != nil {
+		p.stats.Duration = time.Since(p.startTime)
+	}
 	return nil
 }
 
@@ -128,7 +151,7 @@ func (p *FilterProcessor) GetMetadata() types.PluginMetadata {
 			"properties": map[string]interface{}{
 				"condition": map[string]interface{}{
 					"type":        "string",
-					"description": "Filter condition expression",
+					"description": "Filter condition expression, e.g. \"age >= 18 AND country IN ('US', 'CA')\"",
 				},
 				"mode": map[string]interface{}{
 					"type":        "string",
@@ -141,73 +164,3 @@ func (p *FilterProcessor) GetMetadata() types.PluginMetadata {
 		},
 	}
 }
-
-// evaluateCondition evaluates the filter condition for a record
-// This is a simple implementation - production version should use a proper expression evaluator
-func (p *FilterProcessor) evaluateCondition(record types.Record, schema types.Schema) (bool, error) {
-	// Simple field existence check: "field_name"
-	if !strings.Contains(p.condition, " ") {
-		fieldName := strings.TrimSpace(p.condition)
-		fieldIdx := p.findFieldIndex(fieldName, schema)
-		if fieldIdx >= 0 && fieldIdx < len(record.Values) {
-			return record.Values[fieldIdx] != nil, nil
-		}
-		return false, nil
-	}
-	
-	// Simple comparisons: "field_name > value", "field_name = value", etc.
-	parts := strings.Fields(p.condition)
-	if len(parts) >= 3 {
-		fieldName := parts[0]
-		operator := parts[1]
-		valueStr := strings.Join(parts[2:], " ")
-		
-		fieldIdx := p.findFieldIndex(fieldName, schema)
-		if fieldIdx < 0 || fieldIdx >= len(record.Values) {
-			return false, nil
-		}
-		
-		fieldValue := record.Values[fieldIdx]
-		if fieldValue == nil {
-			return operator == "=" && valueStr == "null", nil
-		}
-		
-		return p.compareValues(fieldValue, operator, valueStr)
-	}
-	
-	// Default: include all records if condition format is unknown
-	return true, nil
-}
-
-// findFieldIndex finds the index of a field by name
-func (p *FilterProcessor) findFieldIndex(fieldName string, schema types.Schema) int {
-	for i, col := range schema.Columns {
-		if col.Name == fieldName {
-			return i
-		}
-	}
-	return -1
-}
-
-// compareValues compares a field value with a target value using an operator
-func (p *FilterProcessor) compareValues(fieldValue interface{}, operator, targetValue string) (bool, error) {
-	switch operator {
-	case "=", "==":
-		return fmt.Sprintf("%v", fieldValue) == targetValue, nil
-	case "!=":
-		return fmt.Sprintf("%v", fieldValue) != targetValue, nil
-	case ">":
-		// Simple numeric comparison (would need proper type handling in production)
-		return fmt.Sprintf("%v", fieldValue) > targetValue, nil
-	case "<":
-		return fmt.Sprintf("%v", fieldValue) < targetValue, nil
-	case ">=":
-		return fmt.Sprintf("%v", fieldValue) >= targetValue, nil
-	case "<=":
-		return fmt.Sprintf("%v", fieldValue) <= targetValue, nil
-	case "contains":
-		return strings.Contains(fmt.Sprintf("%v", fieldValue), targetValue), nil
-	default:
-		return false, fmt.Errorf("unknown operator: %s", operator)
-	}
-}
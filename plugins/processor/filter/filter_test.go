@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func newProcessor(t *testing.T, config map[string]interface{}) *FilterProcessor {
+	t.Helper()
+	p := &FilterProcessor{}
+	require.NoError(t, p.Initialize(config))
+	require.NoError(t, p.Validate())
+	return p
+}
+
+func newBatch(rows ...[]interface{}) *types.DataBatch {
+	batch := &types.DataBatch{
+		Schema: types.Schema{
+			Columns: []types.Column{
+				{Name: "id", DataType: types.DataTypeBigInt},
+				{Name: "amount", DataType: types.DataTypeDouble, Nullable: true},
+				{Name: "country", DataType: types.DataTypeString, Nullable: true},
+			},
+		},
+		Checkpoint: &types.Checkpoint{Position: 7},
+		Metadata:   map[string]string{"source": "test"},
+	}
+	for _, row := range rows {
+		batch.Records = append(batch.Records, types.Record{Values: row})
+	}
+	return batch
+}
+
+// ids returns the id of each record
+func ids(records []types.Record) []int64 {
+	var ids []int64
+	for _, record := range records {
+		ids = append(ids, record.Values[0].(int64))
+	}
+	return ids
+}
+
+func TestFilterNumericColumn(t *testing.T) {
+	rows := [][]interface{}{
+		{int64(1), 50.0, "US"},
+		{int64(2), 100.0, "CA"},
+		{int64(3), 150.5, "US"},
+		{int64(4), nil, "FR"},
+		{int64(5), int64(200), "US"},
+	}
+
+	// Nulls never match a comparison, so exclude keeps them
+	for _, tt := range []struct {
+		mode string
+		want []int64
+	}{
+		{"include", []int64{2, 3, 5}},
+		{"exclude", []int64{1, 4}},
+	} {
+		p := newProcessor(t, map[string]interface{}{"condition": "amount >= 100", "mode": tt.mode})
+		out, err := p.Process(newBatch(rows...))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, ids(out.Records), tt.mode)
+		assert.Empty(t, out.Rejected)
+
+		stats := p.GetStatistics()
+		assert.Equal(t, int64(5), stats.RecordsIn)
+		assert.Equal(t, int64(len(tt.want)), stats.RecordsOut)
+		assert.Equal(t, int64(5-len(tt.want)), stats.Filtered)
+	}
+
+	p := newProcessor(t, map[string]interface{}{"condition": "amount > 60 AND country IN ('US', 'CA')"})
+	out, err := p.Process(newBatch(rows...))
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3, 5}, ids(out.Records))
+
+	// The batch keeps its schema, checkpoint and metadata
+	assert.Equal(t, newBatch().Schema, out.Schema)
+	assert.Equal(t, 7, out.Checkpoint.Position)
+	assert.Equal(t, "test", out.Metadata["source"])
+}
+
+func TestFilterRejectsRecords(t *testing.T) {
+	p := newProcessor(t, map[string]interface{}{"condition": "id * 2 > 4", "mode": "exclude"})
+
+	// Records the condition fails on are rejected with the error, in both
+	// modes, rather than kept or dropped
+	out, err := p.Process(newBatch(
+		[]interface{}{int64(1), 1.0, "US"},
+		[]interface{}{"x1", 1.0, "US"},
+		[]interface{}{int64(math.MaxInt64), 1.0, "US"},
+		[]interface{}{int64(3), 1.0, "US"},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, ids(out.Records))
+	require.Len(t, out.Rejected, 2)
+	assert.Equal(t, "x1", out.Rejected[0].Record.Values[0])
+	assert.Contains(t, out.Rejected[0].Error, "cannot apply *")
+	assert.Equal(t, int64(math.MaxInt64), out.Rejected[1].Record.Values[0])
+	assert.Contains(t, out.Rejected[1].Error, "integer overflow")
+
+	stats := p.GetStatistics()
+	assert.Equal(t, int64(4), stats.RecordsIn)
+	assert.Equal(t, int64(1), stats.RecordsOut)
+	assert.Equal(t, int64(1), stats.Filtered)
+	assert.Equal(t, int64(2), stats.Errors)
+}
+
+func TestFilterEmptyBatch(t *testing.T) {
+	p := newProcessor(t, map[string]interface{}{"condition": "id > 1"})
+	out, err := p.Process(newBatch())
+	require.NoError(t, err)
+	assert.Empty(t, out.Records)
+
+	out, err = p.Process(nil)
+	require.NoError(t, err)
+	assert.Nil(t, out)
+}
+
+func TestFilterConfig(t *testing.T) {
+	p := &FilterProcessor{}
+	assert.Error(t, p.Initialize(map[string]interface{}{"condition": "id >"}))
+	assert.Error(t, p.Initialize(map[string]interface{}{"condition": "id > 1", "mode": "keep"}))
+
+	require.NoError(t, p.Initialize(map[string]interface{}{}))
+	assert.Error(t, p.Validate())
+	_, err := p.Process(newBatch([]interface{}{int64(1), 1.0, "US"}))
+	assert.Error(t, err)
+
+	// Conditions on columns the batch lacks reject its records
+	p = newProcessor(t, map[string]interface{}{"condition": "missing > 1"})
+	out, err := p.Process(newBatch([]interface{}{int64(1), 1.0, "US"}))
+	require.NoError(t, err)
+	assert.Empty(t, out.Records)
+	require.Len(t, out.Rejected, 1)
+	assert.Contains(t, out.Rejected[0].Error, "missing")
+}
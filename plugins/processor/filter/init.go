@@ -2,8 +2,9 @@ package filter
 
 import (
 	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/pkg/types"
 )
 
 func init() {
-	plugin.RegisterProcessor("filter", &FilterProcessor{})
+	plugin.RegisterProcessor("filter", func() types.ProcessorPlugin { return &FilterProcessor{} })
 }
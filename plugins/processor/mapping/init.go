@@ -2,8 +2,9 @@ package mapping
 
 import (
 	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/pkg/types"
 )
 
 func init() {
-	plugin.RegisterProcessor("mapping", &MappingProcessor{})
+	plugin.RegisterProcessor("mapping", func() types.ProcessorPlugin { return &MappingProcessor{} })
 }
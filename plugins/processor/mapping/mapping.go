@@ -103,7 +103,9 @@ func (p *MappingProcessor) GetStatistics() *types.ProcessStatistics {
 
 // Close closes the processor
 func (p *MappingProcessor) Close() error {
-	p.stats.Duration = time.Since(p.startTime)
+	if p.stats != nil {
+		p.stats.Duration = time.Since(p.startTime)
+	}
 	return nil
 }
 
@@ -0,0 +1,10 @@
+package transform
+
+import (
+	"github.com/atlanssia/fustgo/internal/plugin"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func init() {
+	plugin.RegisterProcessor("transform", func() types.ProcessorPlugin { return &TransformProcessor{} })
+}
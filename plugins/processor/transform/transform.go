@@ -0,0 +1,385 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atlanssia/fustgo/internal/expr"
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+// TransformProcessor adds, overwrites, drops and reorders columns
+type TransformProcessor struct {
+	config    map[string]interface{}
+	columns   []columnDef
+	drop      []string
+	order     []string
+	stats     *types.ProcessStatistics
+	startTime time.Time
+}
+
+// columnDef is a computed column, e.g. "full_name = concat(first, ' ', last)"
+type columnDef struct {
+	name     string
+	program  *expr.Program
+	dataType types.DataType // DataTypeUnknown means inferred from the expression
+}
+
+// plan describes how records of one input schema are transformed
+type plan struct {
+	schema     types.Schema // Output schema
+	working    types.Schema // Input columns plus appended computed columns
+	targets    []int        // Working index written by each column definition
+	projection []int        // Working index of each output column
+}
+
+// Name returns the plugin name
+func (p *TransformProcessor) Name() string {
+	return "transform"
+}
+
+// Type returns the plugin type
+func (p *TransformProcessor) Type() types.PluginType {
+	return types.PluginTypeProcessor
+}
+
+// Initialize initializes the transform processor
+func (p *TransformProcessor) Initialize(config map[string]interface{}) error {
+	p.config = config
+	p.columns = nil
+
+	// Parse computed columns. Each entry is either "name = expression" or a
+	// map with name, expression and an optional type.
+	if columns, ok := config["columns"].([]interface{}); ok {
+		for i, entry := range columns {
+			def, err := parseColumnDef(entry)
+			if err != nil {
+				return fmt.Errorf("transform: column %d: %w", i+1, err)
+			}
+			p.columns = append(p.columns, def)
+		}
+	}
+
+	p.drop = toStringList(config["drop"])
+	p.order = toStringList(config["order"])
+
+	// Initialize statistics
+	p.stats = &types.ProcessStatistics{
+		RecordsIn:  0,
+		RecordsOut: 0,
+		Filtered:   0,
+		Errors:     0,
+	}
+
+	p.startTime = time.Now()
+
+	return nil
+}
+
+// Validate validates the configuration
+func (p *TransformProcessor) Validate() error {
+	if len(p.columns) == 0 && len(p.drop) == 0 && len(p.order) == 0 {
+		return fmt.Errorf("transform: at least one of columns, drop or order is required")
+	}
+	return nil
+}
+
+// Process processes a batch of data
+func (p *TransformProcessor) Process(input *types.DataBatch) (*types.DataBatch, error) {
+	if input == nil || input.IsEmpty() {
+		return input, nil
+	}
+
+	pl, err := p.buildPlan(input.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]types.Record, 0, len(input.Records))
+	var rejected []types.RejectedRecord
+	env := expr.NewRecordEnv(pl.working)
+	working := types.Record{}
+
+	for _, record := range input.Records {
+		p.stats.RecordsIn++
+
+		values := make([]interface{}, len(pl.working.Columns))
+		copy(values, record.Values)
+		working.Values = values
+		env.SetRecord(&working)
+
+		if err := p.applyColumns(pl, env, values); err != nil {
+			p.stats.Errors++
+			rejected = append(rejected, types.RejectedRecord{Record: record, Error: err.Error()})
+			continue
+		}
+
+		out := make([]interface{}, len(pl.projection))
+		for i, idx := range pl.projection {
+			out[i] = values[idx]
+		}
+		records = append(records, types.Record{Values: out, Metadata: record.Metadata})
+		p.stats.RecordsOut++
+	}
+
+	output := &types.DataBatch{
+		Schema:     pl.schema,
+		Records:    records,
+		Metadata:   input.Metadata,
+		Checkpoint: input.Checkpoint,
+		Rejected:   rejected,
+	}
+
+	return output, nil
+}
+
+// applyColumns evaluates the computed columns in order. Later columns see
+// the results of earlier ones.
+func (p *TransformProcessor) applyColumns(pl *plan, env expr.Env, values []interface{}) error {
+	for i, def := range p.columns {
+		v, err := def.program.Eval(env)
+		if err != nil {
+			return fmt.Errorf("transform: column %s: %w", def.name, err)
+		}
+		if def.dataType != types.DataTypeUnknown {
+			if v, err = expr.Cast(v, def.dataType); err != nil {
+				return fmt.Errorf("transform: column %s: %w", def.name, err)
+			}
+		}
+		values[pl.targets[i]] = v
+	}
+	return nil
+}
+
+// buildPlan resolves column positions and data types for an input schema
+func (p *TransformProcessor) buildPlan(schema types.Schema) (*plan, error) {
+	pl := &plan{
+		working: types.Schema{Columns: append([]types.Column(nil), schema.Columns...)},
+		targets: make([]int, len(p.columns)),
+	}
+
+	for i, def := range p.columns {
+		// Expressions may only reference input columns and earlier computed columns
+		for _, field := range def.program.Fields() {
+			if findColumn(pl.working, field) < 0 {
+				return nil, fmt.Errorf("transform: column %s references unknown field %s", def.name, field)
+			}
+		}
+
+		dataType := def.dataType
+		if dataType == types.DataTypeUnknown {
+			dataType = def.program.ResultType(pl.working)
+		}
+
+		idx := findColumn(pl.working, def.name)
+		if idx < 0 {
+			pl.working.Columns = append(pl.working.Columns, types.Column{
+				Name:     def.name,
+				DataType: dataType,
+				Nullable: true,
+			})
+			idx = len(pl.working.Columns) - 1
+		} else if dataType != types.DataTypeUnknown {
+			pl.working.Columns[idx].DataType = dataType
+			pl.working.Columns[idx].DefaultValue = nil
+		}
+		pl.targets[i] = idx
+	}
+
+	// Drop columns
+	dropped := make(map[string]bool, len(p.drop))
+	for _, name := range p.drop {
+		if findColumn(pl.working, name) < 0 {
+			return nil, fmt.Errorf("transform: cannot drop unknown column %s", name)
+		}
+		dropped[name] = true
+	}
+
+	// Listed columns come first, the remaining columns keep their order
+	used := make(map[string]bool, len(pl.working.Columns))
+	for _, name := range p.order {
+		idx := findColumn(pl.working, name)
+		if idx < 0 {
+			return nil, fmt.Errorf("transform: cannot order unknown column %s", name)
+		}
+		if dropped[name] {
+			return nil, fmt.Errorf("transform: column %s is both dropped and ordered", name)
+		}
+		if !used[name] {
+			pl.projection = append(pl.projection, idx)
+			used[name] = true
+		}
+	}
+	for idx, col := range pl.working.Columns {
+		if !used[col.Name] && !dropped[col.Name] {
+			pl.projection = append(pl.projection, idx)
+		}
+	}
+
+	pl.schema.Columns = make([]types.Column, len(pl.projection))
+	for i, idx := range pl.projection {
+		pl.schema.Columns[i] = pl.working.Columns[idx]
+	}
+	for _, key := range schema.PrimaryKeys {
+		if !dropped[key] {
+			pl.schema.PrimaryKeys = append(pl.schema.PrimaryKeys, key)
+		}
+	}
+
+	return pl, nil
+}
+
+// GetStatistics returns processing statistics
+func (p *TransformProcessor) GetStatistics() *types.ProcessStatistics {
+	p.stats.Duration = time.Since(p.startTime)
+	return p.stats
+}
+
+// Close closes the processor
+func (p *TransformProcessor) Close() error {
+	if p.stats != nil {
+		p.stats.Duration = time.Since(p.startTime)
+	}
+	return nil
+}
+
+// GetMetadata returns plugin metadata
+func (p *TransformProcessor) GetMetadata() types.PluginMetadata {
+	return types.PluginMetadata{
+		Name:        "transform",
+		Type:        types.PluginTypeProcessor,
+		Version:     "1.0.0",
+		Description: "Add, overwrite, drop and reorder columns using expressions",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"columns": map[string]interface{}{
+					"type":        "array",
+					"description": "Computed columns evaluated in order, e.g. \"full_name = concat(first, ' ', last)\" or {name, expression, type}",
+					"items": map[string]interface{}{
+						"oneOf": []interface{}{
+							map[string]interface{}{"type": "string"},
+							map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"name":       map[string]interface{}{"type": "string"},
+									"expression": map[string]interface{}{"type": "string"},
+									"type": map[string]interface{}{
+										"type":        "string",
+										"description": "Result type: string, int, bigint, float, double, bool, date, timestamp, bytes or json",
+									},
+								},
+								"required": []string{"name", "expression"},
+							},
+						},
+					},
+				},
+				"drop": map[string]interface{}{
+					"type":        "array",
+					"description": "Columns to remove from the output",
+					"items":       map[string]interface{}{"type": "string"},
+				},
+				"order": map[string]interface{}{
+					"type":        "array",
+					"description": "Columns to place first, in this order; remaining columns follow",
+					"items":       map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+	}
+}
+
+// parseColumnDef parses a column definition from its configuration
+func parseColumnDef(entry interface{}) (columnDef, error) {
+	var def columnDef
+	var source string
+
+	switch v := entry.(type) {
+	case string:
+		name, expression, err := splitAssignment(v)
+		if err != nil {
+			return def, err
+		}
+		def.name, source = name, expression
+
+	case map[string]interface{}:
+		def.name, _ = v["name"].(string)
+		source, _ = v["expression"].(string)
+		if typeName, ok := v["type"].(string); ok && typeName != "" {
+			dataType, err := expr.ParseDataType(typeName)
+			if err != nil {
+				return def, err
+			}
+			def.dataType = dataType
+		}
+
+	default:
+		return def, fmt.Errorf("expected \"name = expression\" or a map, got %T", entry)
+	}
+
+	def.name = strings.TrimSpace(def.name)
+	if def.name == "" {
+		return def, fmt.Errorf("column name is required")
+	}
+	if strings.TrimSpace(source) == "" {
+		return def, fmt.Errorf("expression is required for column %s", def.name)
+	}
+
+	program, err := expr.Compile(source)
+	if err != nil {
+		return def, err
+	}
+	def.program = program
+
+	return def, nil
+}
+
+// splitAssignment splits "name = expression" at the first assignment, which
+// is an '=' that is not part of ==, !=, <=, >= or =~. Names containing spaces
+// can be quoted with backticks.
+func splitAssignment(s string) (string, string, error) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '=' {
+			continue
+		}
+		if i > 0 && strings.ContainsRune("=!<>", rune(s[i-1])) {
+			continue
+		}
+		if i+1 < len(s) && (s[i+1] == '=' || s[i+1] == '~') {
+			continue
+		}
+
+		name := strings.TrimSpace(s[:i])
+		if len(name) >= 2 && name[0] == '`' && name[len(name)-1] == '`' {
+			name = name[1 : len(name)-1]
+		}
+		return name, s[i+1:], nil
+	}
+	return "", "", fmt.Errorf("expected \"name = expression\", got %q", s)
+}
+
+// toStringList converts a YAML list to a list of strings
+func toStringList(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// findColumn returns the index of a column by name, or -1
+func findColumn(schema types.Schema, name string) int {
+	for i, col := range schema.Columns {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,168 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/atlanssia/fustgo/pkg/types"
+)
+
+func newProcessor(t *testing.T, config map[string]interface{}) *TransformProcessor {
+	t.Helper()
+	p := &TransformProcessor{}
+	require.NoError(t, p.Initialize(config))
+	require.NoError(t, p.Validate())
+	return p
+}
+
+func newBatch(rows ...[]interface{}) *types.DataBatch {
+	batch := &types.DataBatch{
+		Schema: types.Schema{
+			Columns: []types.Column{
+				{Name: "id", DataType: types.DataTypeBigInt, DefaultValue: int64(0)},
+				{Name: "first", DataType: types.DataTypeString, Nullable: true},
+				{Name: "last", DataType: types.DataTypeString, Nullable: true},
+				{Name: "amount", DataType: types.DataTypeDouble, Nullable: true},
+				{Name: "code", DataType: types.DataTypeString, Nullable: true},
+			},
+			PrimaryKeys: []string{"id", "code"},
+		},
+		Checkpoint: &types.Checkpoint{Position: 7},
+		Metadata:   map[string]string{"source": "test"},
+	}
+	for _, row := range rows {
+		batch.Records = append(batch.Records, types.Record{Values: row, Metadata: map[string]string{"row": "1"}})
+	}
+	return batch
+}
+
+func TestTransformSchema(t *testing.T) {
+	p := newProcessor(t, map[string]interface{}{
+		"columns": []interface{}{
+			"full_name = concat(first, ' ', last)",
+			"amount_cents = int(amount * 100)",
+			"`greeting text` = concat('hi ', full_name)",
+			map[string]interface{}{"name": "id", "expression": "id * 10", "type": "int"},
+		},
+		"drop":  []interface{}{"first", "last", "code"},
+		"order": []interface{}{"full_name", "id"},
+	})
+
+	out, err := p.Process(newBatch([]interface{}{int64(1), "Ada", "Lovelace", 12.5, "a"}))
+	require.NoError(t, err)
+
+	// New columns take the type of their expression, overwritten columns
+	// the declared type without their old default, and dropped columns
+	// leave the primary key
+	assert.Equal(t, types.Schema{
+		Columns: []types.Column{
+			{Name: "full_name", DataType: types.DataTypeString, Nullable: true},
+			{Name: "id", DataType: types.DataTypeInt},
+			{Name: "amount", DataType: types.DataTypeDouble, Nullable: true},
+			{Name: "amount_cents", DataType: types.DataTypeBigInt, Nullable: true},
+			{Name: "greeting text", DataType: types.DataTypeString, Nullable: true},
+		},
+		PrimaryKeys: []string{"id"},
+	}, out.Schema)
+	require.Len(t, out.Records, 1)
+	assert.Equal(t, []interface{}{"Ada Lovelace", int64(10), 12.5, int64(1250), "hi Ada Lovelace"}, out.Records[0].Values)
+	assert.Equal(t, map[string]string{"row": "1"}, out.Records[0].Metadata)
+	assert.Equal(t, 7, out.Checkpoint.Position)
+	assert.Equal(t, "test", out.Metadata["source"])
+}
+
+func TestTransformDropAndOrder(t *testing.T) {
+	// Ordered columns come first, in their order, and the others keep theirs
+	p := newProcessor(t, map[string]interface{}{"order": []interface{}{"code", "amount", "code"}})
+	out, err := p.Process(newBatch([]interface{}{int64(1), "Ada", "Lovelace", 12.5, "a"}))
+	require.NoError(t, err)
+	names := func(schema types.Schema) []string {
+		var names []string
+		for _, col := range schema.Columns {
+			names = append(names, col.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"code", "amount", "id", "first", "last"}, names(out.Schema))
+	assert.Equal(t, []interface{}{"a", 12.5, int64(1), "Ada", "Lovelace"}, out.Records[0].Values)
+
+	p = newProcessor(t, map[string]interface{}{"drop": []interface{}{"first", "amount"}})
+	out, err = p.Process(newBatch([]interface{}{int64(1), "Ada", "Lovelace", 12.5, "a"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "last", "code"}, names(out.Schema))
+	assert.Equal(t, []interface{}{int64(1), "Lovelace", "a"}, out.Records[0].Values)
+	assert.Equal(t, []string{"id", "code"}, out.Schema.PrimaryKeys)
+
+	// Empty batches pass through
+	out, err = p.Process(newBatch())
+	require.NoError(t, err)
+	assert.Empty(t, out.Records)
+}
+
+func TestTransformRejectsRecords(t *testing.T) {
+	p := newProcessor(t, map[string]interface{}{
+		"columns": []interface{}{
+			map[string]interface{}{"name": "code", "expression": "code", "type": "int"},
+		},
+	})
+
+	// Records whose values fail to convert are rejected with their input
+	// values, the others are transformed
+	out, err := p.Process(newBatch(
+		[]interface{}{int64(1), "Ada", "Lovelace", 1.0, "42"},
+		[]interface{}{int64(2), "Alan", "Turing", 2.0, "x1"},
+		[]interface{}{int64(3), "Grace", "Hopper", 3.0, nil},
+	))
+	require.NoError(t, err)
+	require.Len(t, out.Records, 2)
+	assert.Equal(t, int64(42), out.Records[0].Values[4])
+	assert.Nil(t, out.Records[1].Values[4])
+	require.Len(t, out.Rejected, 1)
+	assert.Equal(t, []interface{}{int64(2), "Alan", "Turing", 2.0, "x1"}, out.Rejected[0].Record.Values)
+	assert.Contains(t, out.Rejected[0].Error, "transform: column code")
+
+	stats := p.GetStatistics()
+	assert.Equal(t, int64(3), stats.RecordsIn)
+	assert.Equal(t, int64(2), stats.RecordsOut)
+	assert.Equal(t, int64(1), stats.Errors)
+}
+
+func TestTransformPlanErrors(t *testing.T) {
+	for name, config := range map[string]map[string]interface{}{
+		"unknown field":       {"columns": []interface{}{"x = missing + 1"}},
+		"later column":        {"columns": []interface{}{"x = y", "y = 1"}},
+		"drop unknown":        {"drop": []interface{}{"missing"}},
+		"order unknown":       {"order": []interface{}{"missing"}},
+		"dropped and ordered": {"drop": []interface{}{"code"}, "order": []interface{}{"code"}},
+	} {
+		p := newProcessor(t, config)
+		_, err := p.Process(newBatch([]interface{}{int64(1), "Ada", "Lovelace", 12.5, "a"}))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestTransformConfig(t *testing.T) {
+	for name, entry := range map[string]interface{}{
+		"no assignment": "a == b",
+		"no name":       " = 1",
+		"no expression": map[string]interface{}{"name": "x"},
+		"bad type":      map[string]interface{}{"name": "x", "expression": "1", "type": "decimal(10)"},
+		"bad syntax":    "x = (1",
+		"not a column":  42,
+	} {
+		p := &TransformProcessor{}
+		assert.Error(t, p.Initialize(map[string]interface{}{"columns": []interface{}{entry}}), name)
+	}
+
+	p := &TransformProcessor{}
+	require.NoError(t, p.Initialize(map[string]interface{}{}))
+	assert.Error(t, p.Validate())
+
+	// Comparisons are not assignments
+	name, expression, err := splitAssignment("flag = a >= b and c != d")
+	require.NoError(t, err)
+	assert.Equal(t, "flag", name)
+	assert.Equal(t, " a >= b and c != d", expression)
+}